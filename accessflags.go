@@ -1,76 +1,320 @@
 package classfileparser
 
-// Type categorizes the different sets of JVM access flags.
-type Type uint8
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClassAccess holds the access_flags of a class.
+type ClassAccess uint16
+
+// FieldAccess holds the access_flags of a field.
+type FieldAccess uint16
+
+// MethodAccess holds the access_flags of a method.
+type MethodAccess uint16
+
+// InnerClassAccess holds the inner_class_access_flags of an InnerClasses entry.
+type InnerClassAccess uint16
+
+// ModuleAccess holds the module_flags of a Module attribute.
+type ModuleAccess uint16
+
+// RequiresAccess holds the requires_flags of a requires entry of a Module attribute.
+type RequiresAccess uint16
+
+// ExportsAccess holds the exports_flags or opens_flags of an exports or opens entry of a Module attribute.
+type ExportsAccess uint16
+
+// ParameterAccess holds the access_flags of a MethodParameters entry.
+type ParameterAccess uint16
+
+// Class access flags.
+const (
+	ClassPublic     ClassAccess = 0x0001
+	ClassFinal      ClassAccess = 0x0010
+	ClassSuper      ClassAccess = 0x0020
+	ClassInterface  ClassAccess = 0x0200
+	ClassAbstract   ClassAccess = 0x0400
+	ClassSynthetic  ClassAccess = 0x1000
+	ClassAnnotation ClassAccess = 0x2000
+	ClassEnum       ClassAccess = 0x4000
+	ClassModule     ClassAccess = 0x8000
+)
+
+// Field access flags.
+const (
+	FieldPublic    FieldAccess = 0x0001
+	FieldPrivate   FieldAccess = 0x0002
+	FieldProtected FieldAccess = 0x0004
+	FieldStatic    FieldAccess = 0x0008
+	FieldFinal     FieldAccess = 0x0010
+	FieldVolatile  FieldAccess = 0x0040
+	FieldTransient FieldAccess = 0x0080
+	FieldSynthetic FieldAccess = 0x1000
+	FieldEnum      FieldAccess = 0x4000
+)
 
+// Method access flags.
 const (
-	// ClassT describes the access flags applicable to a class.
-	ClassT Type = iota
-	// FieldT describes the access flags applicable to a field.
-	FieldT
-	// MethodT describes the access flags applicable to a method.
-	MethodT
-	// NestedT describes the access flags applicable to a nested class.
-	NestedT
+	MethodPublic       MethodAccess = 0x0001
+	MethodPrivate      MethodAccess = 0x0002
+	MethodProtected    MethodAccess = 0x0004
+	MethodStatic       MethodAccess = 0x0008
+	MethodFinal        MethodAccess = 0x0010
+	MethodSynchronized MethodAccess = 0x0020
+	MethodBridge       MethodAccess = 0x0040
+	MethodVarargs      MethodAccess = 0x0080
+	MethodNative       MethodAccess = 0x0100
+	MethodAbstract     MethodAccess = 0x0400
+	MethodStrict       MethodAccess = 0x0800
+	MethodSynthetic    MethodAccess = 0x1000
 )
 
-var flags map[Type]map[int]string = map[Type]map[int]string{
-	ClassT: {
-		0x0001: "ACC_PUBLIC",
-		0x0010: "ACC_FINAL",
-		0x0020: "ACC_SUPER",
-		0x0200: "ACC_INTERFACE",
-		0x0400: "ACC_ABSTRACT",
-		0x1000: "ACC_SYNTHETIC",
-		0x2000: "ACC_ANNOTATION",
-		0x4000: "ACC_ENUM",
-		0x8000: "ACC_MODULE",
-	},
-	FieldT: {
-		0x0001: "ACC_PUBLIC",
-		0x0002: "ACC_PRIVATE",
-		0x0004: "ACC_PROTECTED",
-		0x0008: "ACC_STATIC",
-		0x0010: "ACC_FINAL",
-		0x0040: "ACC_VOLATILE",
-		0x0080: "ACC_TRANSIENT",
-		0x1000: "ACC_SYNTHETIC",
-		0x4000: "ACC_ENUM",
-	},
-	MethodT: {
-		0x0001: "ACC_PUBLIC",
-		0x0002: "ACC_PRIVATE",
-		0x0004: "ACC_PROTECTED",
-		0x0008: "ACC_STATIC",
-		0x0010: "ACC_FINAL",
-		0x0020: "ACC_SYNCHRONIZED",
-		0x0040: "ACC_BRIDGE",
-		0x0080: "ACC_VARARGS",
-		0x0100: "ACC_NATIVE",
-		0x0400: "ACC_ABSTRACT",
-		0x0800: "ACC_STRICT",
-		0x1000: "ACC_SYNTHETIC",
-	},
-	NestedT: {
-		0x0001: "ACC_PUBLIC",
-		0x0002: "ACC_PRIVATE",
-		0x0004: "ACC_PROTECTED",
-		0x0008: "ACC_STATIC",
-		0x0010: "ACC_FINAL",
-		0x0200: "ACC_INTERFACE",
-		0x0400: "ACC_ABSTRACT",
-		0x1000: "ACC_SYNTHETIC",
-		0x2000: "ACC_ANNOTATION",
-		0x4000: "ACC_ENUM",
-	},
+// Inner class access flags.
+const (
+	InnerClassPublic     InnerClassAccess = 0x0001
+	InnerClassPrivate    InnerClassAccess = 0x0002
+	InnerClassProtected  InnerClassAccess = 0x0004
+	InnerClassStatic     InnerClassAccess = 0x0008
+	InnerClassFinal      InnerClassAccess = 0x0010
+	InnerClassInterface  InnerClassAccess = 0x0200
+	InnerClassAbstract   InnerClassAccess = 0x0400
+	InnerClassSynthetic  InnerClassAccess = 0x1000
+	InnerClassAnnotation InnerClassAccess = 0x2000
+	InnerClassEnum       InnerClassAccess = 0x4000
+)
+
+// Module flags.
+const (
+	ModuleOpen      ModuleAccess = 0x0020
+	ModuleSynthetic ModuleAccess = 0x1000
+	ModuleMandated  ModuleAccess = 0x8000
+)
+
+// Requires flags.
+const (
+	RequiresTransitive  RequiresAccess = 0x0020
+	RequiresStaticPhase RequiresAccess = 0x0040
+	RequiresSynthetic   RequiresAccess = 0x1000
+	RequiresMandated    RequiresAccess = 0x8000
+)
+
+// Exports and opens flags.
+const (
+	ExportsSynthetic ExportsAccess = 0x1000
+	ExportsMandated  ExportsAccess = 0x8000
+)
+
+// Method parameter flags.
+const (
+	ParameterFinal     ParameterAccess = 0x0010
+	ParameterSynthetic ParameterAccess = 0x1000
+	ParameterMandated  ParameterAccess = 0x8000
+)
+
+// accessFlag names a flag and, if it has one, the Java keyword it stands for.
+type accessFlag struct {
+	flag    uint16
+	name    string
+	keyword string
 }
 
-func findFlags(t Type, value uint16) []string {
-	var ret []string
-	for flag, name := range flags[t] {
-		if value&uint16(flag) != 0 {
-			ret = append(ret, name)
+// Flag tables, in the order of the JVMS tables.
+var (
+	classFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"},
+		{0x0010, "ACC_FINAL", "final"},
+		{0x0020, "ACC_SUPER", ""},
+		{0x0200, "ACC_INTERFACE", ""},
+		{0x0400, "ACC_ABSTRACT", "abstract"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x2000, "ACC_ANNOTATION", ""},
+		{0x4000, "ACC_ENUM", ""},
+		{0x8000, "ACC_MODULE", ""},
+	}
+	fieldFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"},
+		{0x0002, "ACC_PRIVATE", "private"},
+		{0x0004, "ACC_PROTECTED", "protected"},
+		{0x0008, "ACC_STATIC", "static"},
+		{0x0010, "ACC_FINAL", "final"},
+		{0x0040, "ACC_VOLATILE", "volatile"},
+		{0x0080, "ACC_TRANSIENT", "transient"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x4000, "ACC_ENUM", ""},
+	}
+	methodFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"},
+		{0x0002, "ACC_PRIVATE", "private"},
+		{0x0004, "ACC_PROTECTED", "protected"},
+		{0x0008, "ACC_STATIC", "static"},
+		{0x0010, "ACC_FINAL", "final"},
+		{0x0020, "ACC_SYNCHRONIZED", "synchronized"},
+		{0x0040, "ACC_BRIDGE", ""},
+		{0x0080, "ACC_VARARGS", ""},
+		{0x0100, "ACC_NATIVE", "native"},
+		{0x0400, "ACC_ABSTRACT", "abstract"},
+		{0x0800, "ACC_STRICT", "strictfp"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+	}
+	innerClassFlags = []accessFlag{
+		{0x0001, "ACC_PUBLIC", "public"},
+		{0x0002, "ACC_PRIVATE", "private"},
+		{0x0004, "ACC_PROTECTED", "protected"},
+		{0x0008, "ACC_STATIC", "static"},
+		{0x0010, "ACC_FINAL", "final"},
+		{0x0200, "ACC_INTERFACE", ""},
+		{0x0400, "ACC_ABSTRACT", "abstract"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x2000, "ACC_ANNOTATION", ""},
+		{0x4000, "ACC_ENUM", ""},
+	}
+	moduleFlags = []accessFlag{
+		{0x0020, "ACC_OPEN", "open"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x8000, "ACC_MANDATED", ""},
+	}
+	requiresFlags = []accessFlag{
+		{0x0020, "ACC_TRANSITIVE", "transitive"},
+		{0x0040, "ACC_STATIC_PHASE", "static"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x8000, "ACC_MANDATED", ""},
+	}
+	exportsFlags = []accessFlag{
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x8000, "ACC_MANDATED", ""},
+	}
+	parameterFlags = []accessFlag{
+		{0x0010, "ACC_FINAL", "final"},
+		{0x1000, "ACC_SYNTHETIC", ""},
+		{0x8000, "ACC_MANDATED", ""},
+	}
+)
+
+// keywordOrder is the order in which Java source conventionally lists modifiers.
+var keywordOrder = []string{
+	"public", "protected", "private", "abstract", "static", "final", "transient", "volatile",
+	"synchronized", "native", "strictfp", "open", "transitive",
+}
+
+// flagNames renders the set flags of value like javap, e.g. ACC_PUBLIC, ACC_SUPER. Bits
+// without a name are rendered in hexadecimal.
+func flagNames(value uint16, table []accessFlag) string {
+	var names []string
+	for _, flag := range table {
+		if value&flag.flag != 0 {
+			names = append(names, flag.name)
+			value &^= flag.flag
+		}
+	}
+	if value != 0 {
+		names = append(names, fmt.Sprintf("0x%04X", value))
+	}
+	return strings.Join(names, ", ")
+}
+
+// flagKeywords renders the set flags of value that have a Java keyword, in source order.
+func flagKeywords(value uint16, table []accessFlag) string {
+	var keywords []string
+	for _, flag := range table {
+		if value&flag.flag != 0 && flag.keyword != "" {
+			keywords = append(keywords, flag.keyword)
+		}
+	}
+	rank := func(keyword string) int {
+		for i, k := range keywordOrder {
+			if k == keyword {
+				return i
+			}
 		}
+		return len(keywordOrder)
+	}
+	sort.SliceStable(keywords, func(i, j int) bool { return rank(keywords[i]) < rank(keywords[j]) })
+	return strings.Join(keywords, " ")
+}
+
+// Has reports whether every flag of flag is set.
+func (a ClassAccess) Has(flag ClassAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_PUBLIC, ACC_SUPER.
+func (a ClassAccess) String() string { return flagNames(uint16(a), classFlags) }
+
+// Keywords returns the modifiers of a class declaration, e.g. public final. Interfaces are
+// implicitly abstract, so abstract is left out for them.
+func (a ClassAccess) Keywords() string {
+	if a.Has(ClassInterface) {
+		a &^= ClassAbstract
+	}
+	return flagKeywords(uint16(a), classFlags)
+}
+
+// Has reports whether every flag of flag is set.
+func (a FieldAccess) Has(flag FieldAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_PRIVATE, ACC_STATIC.
+func (a FieldAccess) String() string { return flagNames(uint16(a), fieldFlags) }
+
+// Keywords returns the modifiers of a field declaration, e.g. private static final.
+func (a FieldAccess) Keywords() string { return flagKeywords(uint16(a), fieldFlags) }
+
+// Has reports whether every flag of flag is set.
+func (a MethodAccess) Has(flag MethodAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_PUBLIC, ACC_STATIC.
+func (a MethodAccess) String() string { return flagNames(uint16(a), methodFlags) }
+
+// Keywords returns the modifiers of a method declaration, e.g. public static synchronized.
+func (a MethodAccess) Keywords() string { return flagKeywords(uint16(a), methodFlags) }
+
+// Has reports whether every flag of flag is set.
+func (a InnerClassAccess) Has(flag InnerClassAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_PUBLIC, ACC_STATIC.
+func (a InnerClassAccess) String() string { return flagNames(uint16(a), innerClassFlags) }
+
+// Keywords returns the modifiers of a nested class declaration, e.g. public static. Interfaces
+// are implicitly abstract, so abstract is left out for them.
+func (a InnerClassAccess) Keywords() string {
+	if a.Has(InnerClassInterface) {
+		a &^= InnerClassAbstract
 	}
-	return ret
+	return flagKeywords(uint16(a), innerClassFlags)
 }
+
+// Has reports whether every flag of flag is set.
+func (a ModuleAccess) Has(flag ModuleAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_OPEN.
+func (a ModuleAccess) String() string { return flagNames(uint16(a), moduleFlags) }
+
+// Keywords returns the modifiers of a module declaration, i.e. open or nothing.
+func (a ModuleAccess) Keywords() string { return flagKeywords(uint16(a), moduleFlags) }
+
+// Has reports whether every flag of flag is set.
+func (a RequiresAccess) Has(flag RequiresAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_TRANSITIVE, ACC_STATIC_PHASE.
+func (a RequiresAccess) String() string { return flagNames(uint16(a), requiresFlags) }
+
+// Keywords returns the modifiers of a requires directive, e.g. static transitive.
+func (a RequiresAccess) Keywords() string { return flagKeywords(uint16(a), requiresFlags) }
+
+// Has reports whether every flag of flag is set.
+func (a ExportsAccess) Has(flag ExportsAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_MANDATED.
+func (a ExportsAccess) String() string { return flagNames(uint16(a), exportsFlags) }
+
+// Keywords returns an empty string, since exports and opens directives have no modifiers.
+func (a ExportsAccess) Keywords() string { return flagKeywords(uint16(a), exportsFlags) }
+
+// Has reports whether every flag of flag is set.
+func (a ParameterAccess) Has(flag ParameterAccess) bool { return a&flag == flag }
+
+// String returns the set flags in JVMS order, e.g. ACC_FINAL, ACC_MANDATED.
+func (a ParameterAccess) String() string { return flagNames(uint16(a), parameterFlags) }
+
+// Keywords returns the modifiers of a parameter declaration, i.e. final or nothing.
+func (a ParameterAccess) Keywords() string { return flagKeywords(uint16(a), parameterFlags) }
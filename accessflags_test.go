@@ -0,0 +1,67 @@
+package classfileparser
+
+import "testing"
+
+func TestAccessFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		access   interface{ String() string }
+		keywords string
+		want     string
+	}{
+		{"class", ClassPublic | ClassSuper | ClassFinal, "public final", "ACC_PUBLIC, ACC_FINAL, ACC_SUPER"},
+		{"interface", ClassPublic | ClassInterface | ClassAbstract, "public", "ACC_PUBLIC, ACC_INTERFACE, ACC_ABSTRACT"},
+		{"annotation", ClassInterface | ClassAbstract | ClassAnnotation, "", "ACC_INTERFACE, ACC_ABSTRACT, ACC_ANNOTATION"},
+		{"field", FieldFinal | FieldStatic | FieldPrivate, "private static final", "ACC_PRIVATE, ACC_STATIC, ACC_FINAL"},
+		{"volatile field", FieldVolatile | FieldTransient | FieldProtected, "protected transient volatile", "ACC_PROTECTED, ACC_VOLATILE, ACC_TRANSIENT"},
+		{"enum constant", FieldPublic | FieldStatic | FieldFinal | FieldEnum, "public static final", "ACC_PUBLIC, ACC_STATIC, ACC_FINAL, ACC_ENUM"},
+		{"bridge method", MethodPublic | MethodBridge | MethodSynthetic, "public", "ACC_PUBLIC, ACC_BRIDGE, ACC_SYNTHETIC"},
+		{"varargs method", MethodStatic | MethodVarargs | MethodProtected, "protected static", "ACC_PROTECTED, ACC_STATIC, ACC_VARARGS"},
+		{"method", MethodStrict | MethodSynchronized | MethodNative | MethodFinal | MethodPublic, "public final synchronized native strictfp", "ACC_PUBLIC, ACC_FINAL, ACC_SYNCHRONIZED, ACC_NATIVE, ACC_STRICT"},
+		{"abstract method", MethodAbstract | MethodPublic, "public abstract", "ACC_PUBLIC, ACC_ABSTRACT"},
+		{"inner class", InnerClassStatic | InnerClassPrivate | InnerClassFinal, "private static final", "ACC_PRIVATE, ACC_STATIC, ACC_FINAL"},
+		{"inner interface", InnerClassInterface | InnerClassAbstract | InnerClassStatic, "static", "ACC_STATIC, ACC_INTERFACE, ACC_ABSTRACT"},
+		{"module", ModuleOpen | ModuleMandated, "open", "ACC_OPEN, ACC_MANDATED"},
+		{"requires", RequiresStaticPhase | RequiresTransitive, "static transitive", "ACC_TRANSITIVE, ACC_STATIC_PHASE"},
+		{"exports", ExportsMandated, "", "ACC_MANDATED"},
+		{"parameter", ParameterFinal | ParameterSynthetic, "final", "ACC_FINAL, ACC_SYNTHETIC"},
+		{"unknown bits", FieldPublic | 0x0100 | 0x0020, "public", "ACC_PUBLIC, 0x0120"},
+		{"none", MethodAccess(0), "", ""},
+	}
+	for _, test := range tests {
+		if got := test.access.String(); got != test.want {
+			t.Errorf("%s: String() = %q, want %q", test.name, got, test.want)
+		}
+		if got := test.access.(interface{ Keywords() string }).Keywords(); got != test.keywords {
+			t.Errorf("%s: Keywords() = %q, want %q", test.name, got, test.keywords)
+		}
+	}
+}
+
+func TestAccessFlagKinds(t *testing.T) {
+	// 0x0040 and 0x0080 mean volatile and transient on fields, bridge and varargs on methods
+	if got := FieldAccess(0x00C0).String(); got != "ACC_VOLATILE, ACC_TRANSIENT" {
+		t.Errorf("field 0x00C0 = %q", got)
+	}
+	if got := MethodAccess(0x00C0).String(); got != "ACC_BRIDGE, ACC_VARARGS" {
+		t.Errorf("method 0x00C0 = %q", got)
+	}
+	if got := MethodAccess(0x0040).Keywords(); got != "" {
+		t.Errorf("bridge keywords = %q", got)
+	}
+	// 0x0020 is ACC_SUPER on classes and synchronized on methods
+	if got := ClassAccess(0x0020).Keywords(); got != "" {
+		t.Errorf("class 0x0020 keywords = %q", got)
+	}
+	if got := MethodAccess(0x0020).Keywords(); got != "synchronized" {
+		t.Errorf("method 0x0020 keywords = %q", got)
+	}
+	// 0x0040 is static on requires
+	if got := RequiresAccess(0x0040).Keywords(); got != "static" {
+		t.Errorf("requires 0x0040 keywords = %q", got)
+	}
+
+	if !MethodAccess(0x0009).Has(MethodPublic|MethodStatic) || MethodAccess(0x0001).Has(MethodPublic|MethodStatic) {
+		t.Error("Has does not require every flag")
+	}
+}
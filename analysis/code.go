@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// MethodError reports the method of a class file whose code ComputeCode could not complete
+type MethodError struct {
+	Index int    // Index of the method in the class file
+	Name  string // Name of the method
+	Type  string // Descriptor of the method
+	Err   error
+}
+
+func (e *MethodError) Error() string {
+	return fmt.Sprintf("method %s%s: %v", e.Name, e.Type, e.Err)
+}
+
+func (e *MethodError) Unwrap() error {
+	return e.Err
+}
+
+// ComputeCode completes the Code attributes of the methods of file, as written by a generator
+// that numbers instructions by the offsets EncodeCode gave them: MaxStack and MaxLocals are
+// computed with ComputeMaxs and, for class file versions 50 and above, the StackMapTable with
+// ComputeFrames, merging class types through resolver. declared, which may be nil, returns the
+// max_stack and max_locals declared for the method at an index, -1 for each to compute.
+//
+// file is written and read back first, so that its counts and lengths need not match its
+// contents, and the result is the completed class file as Open reads it. Failures of a method
+// are reported as a *MethodError.
+func ComputeCode(file *classfileparser.ClassFile, resolver SuperclassResolver, declared func(index int) (maxStack, maxLocals int)) (*classfileparser.ClassFile, error) {
+	file, err := reopen(file)
+	if err != nil {
+		return nil, err
+	}
+	class, err := file.GetClassFile()
+	if err != nil {
+		return nil, err
+	}
+	pool := classfileparser.NewPoolBuilder(file.ConstantPool)
+	for i := range class.Methods {
+		m := &class.Methods[i]
+		for j, attribute := range m.Attributes {
+			if _, ok := attribute.(classfileparser.Code); !ok {
+				continue
+			}
+			maxStack, maxLocals := -1, -1
+			if declared != nil {
+				maxStack, maxLocals = declared(i)
+			}
+			err := computeCode(class, m, j, maxStack, maxLocals, file.MajorVersion, resolver)
+			if err == nil {
+				file.Methods[i].Attributes[j], err = classfileparser.EncodeCode(m.Attributes[j].(classfileparser.Code), pool)
+			}
+			if err != nil {
+				return nil, &MethodError{Index: i, Name: m.Name, Type: m.Type, Err: err}
+			}
+		}
+	}
+	if err := pool.Err(); err != nil {
+		return nil, err
+	}
+	file.ConstantPool = pool.Pool()
+	return reopen(file)
+}
+
+// computeCode computes the limits not declared and the frames of the Code attribute at index
+func computeCode(class *classfileparser.ClassStruct, m *classfileparser.Method, index, maxStack, maxLocals int, version uint16, resolver SuperclassResolver) error {
+	if maxStack < 0 || maxLocals < 0 {
+		if err := ComputeMaxs(m); err != nil {
+			return err
+		}
+		code := m.Attributes[index].(classfileparser.Code)
+		if maxStack >= 0 {
+			code.MaxStack = uint16(maxStack)
+		}
+		if maxLocals >= 0 {
+			code.MaxLocals = uint16(maxLocals)
+		}
+		m.Attributes[index] = code
+	}
+	if version >= 50 {
+		return ComputeFrames(class, m, resolver)
+	}
+	return nil
+}
+
+// reopen writes file and reads it back, so that its counts and lengths match its contents
+func reopen(file *classfileparser.ClassFile) (*classfileparser.ClassFile, error) {
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return classfileparser.Open(&buf)
+}
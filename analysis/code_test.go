@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+)
+
+// encodedClass returns a version 52 class file declaring the static method m of com/x/T twice,
+// with the code of method encoded without limits or frames
+func encodedClass(t *testing.T, method testMethod) *cf.ClassFile {
+	t.Helper()
+	pool := cf.NewPoolBuilder(nil)
+	file := &cf.ClassFile{Magic: 0xCAFEBABE, MajorVersion: 52, ThisClass: pool.Class("com/x/T"), SuperClass: pool.Class("java/lang/Object")}
+	for range 2 {
+		encoded, err := cf.EncodeCode(cf.Code{Code: method.code, ExceptionTable: method.exceptions}, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Methods = append(file.Methods, cf.MethodInfo{
+			AccessFlags:     uint16(cf.MethodPublic | cf.MethodStatic),
+			NameIndex:       pool.Utf8("m"),
+			DescriptorIndex: pool.Utf8(method.descriptor),
+			Attributes:      []cf.AttributeInfo{encoded},
+		})
+	}
+	file.ConstantPool = pool.Pool()
+	return file
+}
+
+func TestComputeCode(t *testing.T) {
+	file, err := ComputeCode(encodedClass(t, ifZero()), nil, func(index int) (int, int) {
+		if index == 1 {
+			return -1, 3
+		}
+		return -1, -1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	class, err := file.GetClassFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []uint16{1, 3} {
+		m := &class.Methods[i]
+		code := m.Attributes[0].(cf.Code)
+		if code.MaxStack != 1 || code.MaxLocals != want {
+			t.Errorf("method %d: limits = %d, %d, want 1, %d", i, code.MaxStack, code.MaxLocals, want)
+		}
+		if got := fmt.Sprint(computedFrames(m)); got != "[6:6]" {
+			t.Errorf("method %d: frames = %s", i, got)
+		}
+	}
+
+	unreachable := testMethod{descriptor: "()V", code: []cf.Instruction{cf.Return{Location: at(0)}, cf.Nop{Location: at(1)}}}
+	_, err = ComputeCode(encodedClass(t, unreachable), nil, nil)
+	var methodErr *MethodError
+	if !errors.As(err, &methodErr) || methodErr.Index != 0 || methodErr.Name != "m" || methodErr.Type != "()V" {
+		t.Errorf("error = %v, want a *MethodError for method 0", err)
+	}
+}
@@ -0,0 +1,351 @@
+// Package analysis computes properties of method bodies from their decoded instructions, such as
+// the operand stack depth at each instruction and the max_stack and max_locals they need.
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// Effect is the number of operand stack slots an instruction pops, then pushes. long and double
+// values take two slots.
+type Effect struct {
+	Pop  int
+	Push int
+}
+
+// Delta returns the change in stack depth caused by the instruction
+func (e Effect) Delta() int { return e.Push - e.Pop }
+
+// effects holds the effect of the instructions whose effect does not depend on their operands
+var effects = map[uint8]Effect{
+	0x00: {0, 0}, // nop
+	0x01: {0, 1}, // aconst_null
+	0x02: {0, 1}, // iconst_m1
+	0x03: {0, 1}, // iconst_0
+	0x04: {0, 1}, // iconst_1
+	0x05: {0, 1}, // iconst_2
+	0x06: {0, 1}, // iconst_3
+	0x07: {0, 1}, // iconst_4
+	0x08: {0, 1}, // iconst_5
+	0x09: {0, 2}, // lconst_0
+	0x0A: {0, 2}, // lconst_1
+	0x0B: {0, 1}, // fconst_0
+	0x0C: {0, 1}, // fconst_1
+	0x0D: {0, 1}, // fconst_2
+	0x0E: {0, 2}, // dconst_0
+	0x0F: {0, 2}, // dconst_1
+	0x10: {0, 1}, // bipush
+	0x11: {0, 1}, // sipush
+	0x12: {0, 1}, // ldc
+	0x13: {0, 1}, // ldc_w
+	0x14: {0, 2}, // ldc2_w
+	0x15: {0, 1}, // iload
+	0x16: {0, 2}, // lload
+	0x17: {0, 1}, // fload
+	0x18: {0, 2}, // dload
+	0x19: {0, 1}, // aload
+	0x1A: {0, 1}, // iload_0
+	0x1B: {0, 1}, // iload_1
+	0x1C: {0, 1}, // iload_2
+	0x1D: {0, 1}, // iload_3
+	0x1E: {0, 2}, // lload_0
+	0x1F: {0, 2}, // lload_1
+	0x20: {0, 2}, // lload_2
+	0x21: {0, 2}, // lload_3
+	0x22: {0, 1}, // fload_0
+	0x23: {0, 1}, // fload_1
+	0x24: {0, 1}, // fload_2
+	0x25: {0, 1}, // fload_3
+	0x26: {0, 2}, // dload_0
+	0x27: {0, 2}, // dload_1
+	0x28: {0, 2}, // dload_2
+	0x29: {0, 2}, // dload_3
+	0x2A: {0, 1}, // aload_0
+	0x2B: {0, 1}, // aload_1
+	0x2C: {0, 1}, // aload_2
+	0x2D: {0, 1}, // aload_3
+	0x2E: {2, 1}, // iaload
+	0x2F: {2, 2}, // laload
+	0x30: {2, 1}, // faload
+	0x31: {2, 2}, // daload
+	0x32: {2, 1}, // aaload
+	0x33: {2, 1}, // baload
+	0x34: {2, 1}, // caload
+	0x35: {2, 1}, // saload
+	0x36: {1, 0}, // istore
+	0x37: {2, 0}, // lstore
+	0x38: {1, 0}, // fstore
+	0x39: {2, 0}, // dstore
+	0x3A: {1, 0}, // astore
+	0x3B: {1, 0}, // istore_0
+	0x3C: {1, 0}, // istore_1
+	0x3D: {1, 0}, // istore_2
+	0x3E: {1, 0}, // istore_3
+	0x3F: {2, 0}, // lstore_0
+	0x40: {2, 0}, // lstore_1
+	0x41: {2, 0}, // lstore_2
+	0x42: {2, 0}, // lstore_3
+	0x43: {1, 0}, // fstore_0
+	0x44: {1, 0}, // fstore_1
+	0x45: {1, 0}, // fstore_2
+	0x46: {1, 0}, // fstore_3
+	0x47: {2, 0}, // dstore_0
+	0x48: {2, 0}, // dstore_1
+	0x49: {2, 0}, // dstore_2
+	0x4A: {2, 0}, // dstore_3
+	0x4B: {1, 0}, // astore_0
+	0x4C: {1, 0}, // astore_1
+	0x4D: {1, 0}, // astore_2
+	0x4E: {1, 0}, // astore_3
+	0x4F: {3, 0}, // iastore
+	0x50: {4, 0}, // lastore
+	0x51: {3, 0}, // fastore
+	0x52: {4, 0}, // dastore
+	0x53: {3, 0}, // aastore
+	0x54: {3, 0}, // bastore
+	0x55: {3, 0}, // castore
+	0x56: {3, 0}, // sastore
+	0x57: {1, 0}, // pop
+	0x58: {2, 0}, // pop2
+	0x59: {1, 2}, // dup
+	0x5A: {2, 3}, // dup_x1
+	0x5B: {3, 4}, // dup_x2
+	0x5C: {2, 4}, // dup2
+	0x5D: {3, 5}, // dup2_x1
+	0x5E: {4, 6}, // dup2_x2
+	0x5F: {2, 2}, // swap
+	0x60: {2, 1}, // iadd
+	0x61: {4, 2}, // ladd
+	0x62: {2, 1}, // fadd
+	0x63: {4, 2}, // dadd
+	0x64: {2, 1}, // isub
+	0x65: {4, 2}, // lsub
+	0x66: {2, 1}, // fsub
+	0x67: {4, 2}, // dsub
+	0x68: {2, 1}, // imul
+	0x69: {4, 2}, // lmul
+	0x6A: {2, 1}, // fmul
+	0x6B: {4, 2}, // dmul
+	0x6C: {2, 1}, // idiv
+	0x6D: {4, 2}, // ldiv
+	0x6E: {2, 1}, // fdiv
+	0x6F: {4, 2}, // ddiv
+	0x70: {2, 1}, // irem
+	0x71: {4, 2}, // lrem
+	0x72: {2, 1}, // frem
+	0x73: {4, 2}, // drem
+	0x74: {1, 1}, // ineg
+	0x75: {2, 2}, // lneg
+	0x76: {1, 1}, // fneg
+	0x77: {2, 2}, // dneg
+	0x78: {2, 1}, // ishl
+	0x79: {3, 2}, // lshl
+	0x7A: {2, 1}, // ishr
+	0x7B: {3, 2}, // lshr
+	0x7C: {2, 1}, // iushr
+	0x7D: {3, 2}, // lushr
+	0x7E: {2, 1}, // iand
+	0x7F: {4, 2}, // land
+	0x80: {2, 1}, // ior
+	0x81: {4, 2}, // lor
+	0x82: {2, 1}, // ixor
+	0x83: {4, 2}, // lxor
+	0x84: {0, 0}, // iinc
+	0x85: {1, 2}, // i2l
+	0x86: {1, 1}, // i2f
+	0x87: {1, 2}, // i2d
+	0x88: {2, 1}, // l2i
+	0x89: {2, 1}, // l2f
+	0x8A: {2, 2}, // l2d
+	0x8B: {1, 1}, // f2i
+	0x8C: {1, 2}, // f2l
+	0x8D: {1, 2}, // f2d
+	0x8E: {2, 1}, // d2i
+	0x8F: {2, 2}, // d2l
+	0x90: {2, 1}, // d2f
+	0x91: {1, 1}, // i2b
+	0x92: {1, 1}, // i2c
+	0x93: {1, 1}, // i2s
+	0x94: {4, 1}, // lcmp
+	0x95: {2, 1}, // fcmpl
+	0x96: {2, 1}, // fcmpg
+	0x97: {4, 1}, // dcmpl
+	0x98: {4, 1}, // dcmpg
+	0x99: {1, 0}, // ifeq
+	0x9A: {1, 0}, // ifne
+	0x9B: {1, 0}, // iflt
+	0x9C: {1, 0}, // ifge
+	0x9D: {1, 0}, // ifgt
+	0x9E: {1, 0}, // ifle
+	0x9F: {2, 0}, // if_icmpeq
+	0xA0: {2, 0}, // if_icmpne
+	0xA1: {2, 0}, // if_icmplt
+	0xA2: {2, 0}, // if_icmpge
+	0xA3: {2, 0}, // if_icmpgt
+	0xA4: {2, 0}, // if_icmple
+	0xA5: {2, 0}, // if_acmpeq
+	0xA6: {2, 0}, // if_acmpne
+	0xA7: {0, 0}, // goto
+	0xA8: {0, 1}, // jsr
+	0xA9: {0, 0}, // ret
+	0xAA: {1, 0}, // tableswitch
+	0xAB: {1, 0}, // lookupswitch
+	0xAC: {1, 0}, // ireturn
+	0xAD: {2, 0}, // lreturn
+	0xAE: {1, 0}, // freturn
+	0xAF: {2, 0}, // dreturn
+	0xB0: {1, 0}, // areturn
+	0xB1: {0, 0}, // return
+	0xBB: {0, 1}, // new
+	0xBC: {1, 1}, // newarray
+	0xBD: {1, 1}, // anewarray
+	0xBE: {1, 1}, // arraylength
+	0xBF: {1, 0}, // athrow
+	0xC0: {1, 1}, // checkcast
+	0xC1: {1, 1}, // instanceof
+	0xC2: {1, 0}, // monitorenter
+	0xC3: {1, 0}, // monitorexit
+	0xC6: {1, 0}, // ifnull
+	0xC7: {1, 0}, // ifnonnull
+	0xC8: {0, 0}, // goto_w
+	0xC9: {0, 1}, // jsr_w
+}
+
+// StackEffect returns the effect of instruction on the operand stack. Field accesses and invokes
+// are sized from their descriptors, wide and multianewarray from their operands. athrow is
+// given its single pop, the stack being discarded afterwards.
+func StackEffect(instruction classfileparser.Instruction) (Effect, error) {
+	switch instruction := instruction.(type) {
+	case classfileparser.Getstatic:
+		size, err := fieldSlots(instruction.Type)
+		return Effect{0, size}, err
+	case classfileparser.Putstatic:
+		size, err := fieldSlots(instruction.Type)
+		return Effect{size, 0}, err
+	case classfileparser.Getfield:
+		size, err := fieldSlots(instruction.Type)
+		return Effect{1, size}, err
+	case classfileparser.Putfield:
+		size, err := fieldSlots(instruction.Type)
+		return Effect{1 + size, 0}, err
+	case classfileparser.Invokevirtual:
+		return invokeEffect(instruction.Type, true)
+	case classfileparser.Invokespecial:
+		return invokeEffect(instruction.Type, true)
+	case classfileparser.Invokestatic:
+		return invokeEffect(instruction.Type, false)
+	case classfileparser.Invokeinterface:
+		return invokeEffect(instruction.InterfaceMethodref.Type, true)
+	case classfileparser.Invokedynamic:
+		return invokeEffect(instruction.InvokeDynamic.Type, false)
+	case classfileparser.Multianewarray:
+		return Effect{int(instruction.Dimension), 1}, nil
+	case classfileparser.Wide:
+		return wideEffect(instruction)
+	}
+	effect, ok := effects[instruction.Opcode()]
+	if !ok {
+		return Effect{}, fmt.Errorf("unknown opcode 0x%02X", instruction.Opcode())
+	}
+	return effect, nil
+}
+
+func fieldSlots(raw string) (int, error) {
+	field, err := descriptor.ParseField(raw)
+	if err != nil {
+		return 0, err
+	}
+	return field.Slots(), nil
+}
+
+// invokeEffect returns the effect of invoking a method, popping the receiver for instance methods
+func invokeEffect(raw string, instance bool) (Effect, error) {
+	method, err := descriptor.ParseMethod(raw)
+	if err != nil {
+		return Effect{}, err
+	}
+	effect := Effect{method.ArgumentSlots(), method.Return.Slots()}
+	if instance {
+		effect.Pop++
+	}
+	return effect, nil
+}
+
+func wideEffect(instruction classfileparser.Wide) (Effect, error) {
+	switch instruction.OpCode {
+	case 0x84, 0xA9: // iinc, ret
+		return Effect{}, nil
+	case 0x15, 0x16, 0x17, 0x18, 0x19, 0x36, 0x37, 0x38, 0x39, 0x3A:
+		return effects[instruction.OpCode], nil
+	}
+	return Effect{}, fmt.Errorf("wide cannot modify opcode 0x%02X", instruction.OpCode)
+}
+
+// LocalSlots returns the number of local variable slots needed by instruction, that is the
+// index of the last slot it reads or writes plus one, or 0 if it uses no local variable
+func LocalSlots(instruction classfileparser.Instruction) int {
+	if index, t, _, ok := localOperand(instruction); ok {
+		return index + t.Size()
+	}
+	switch instruction := instruction.(type) {
+	case classfileparser.Iinc:
+		return int(instruction.LocalIndex) + 1
+	case classfileparser.Ret:
+		return int(instruction.LocalIndex) + 1
+	case classfileparser.Wide:
+		// iinc and ret
+		return int(instruction.LocalIndex) + 1
+	}
+	return 0
+}
+
+// localTypes are the types loaded and stored by the i, l, f, d and a forms of load and store,
+// a Reference without name standing for any reference
+var localTypes = [...]Type{IntType, LongType, FloatType, DoubleType, {Kind: Reference}}
+
+// localOperand returns the local variable read by a load or written by a store, wide forms
+// included, and the type of the value moved
+func localOperand(instruction classfileparser.Instruction) (index int, t Type, store, ok bool) {
+	opcode := instruction.Opcode()
+	switch instruction := instruction.(type) {
+	case classfileparser.Iload:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Lload:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Fload:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Dload:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Aload:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Istore:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Lstore:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Fstore:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Dstore:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Astore:
+		index = int(instruction.LocalIndex)
+	case classfileparser.Wide:
+		index, opcode = int(instruction.LocalIndex), instruction.OpCode
+	}
+
+	switch {
+	case opcode >= 0x15 && opcode <= 0x19: // iload to aload
+		return index, localTypes[opcode-0x15], false, true
+	case opcode >= 0x1A && opcode <= 0x2D: // iload_0 to aload_3
+		n := opcode - 0x1A
+		return int(n % 4), localTypes[n/4], false, true
+	case opcode >= 0x36 && opcode <= 0x3A: // istore to astore
+		return index, localTypes[opcode-0x36], true, true
+	case opcode >= 0x3B && opcode <= 0x4E: // istore_0 to astore_3
+		n := opcode - 0x3B
+		return int(n % 4), localTypes[n/4], true, true
+	}
+	return 0, Type{}, false, false
+}
@@ -0,0 +1,318 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/cfg"
+)
+
+// SuperclassResolver finds the class two class types merge into where control flow joins.
+// *hierarchy.Hierarchy and *hierarchy.Resolver implement it.
+type SuperclassResolver interface {
+	// CommonSuperclass returns the most specific class that both a and b are or extend
+	CommonSuperclass(a, b string) (string, error)
+}
+
+// ComputeFrames replaces the StackMapTable of the Code attribute of method, declared by class,
+// with frames inferred from its instructions, as ASM's COMPUTE_FRAMES does. Frames are emitted
+// only where the type checker requires them, at branch targets, exception handlers and
+// instructions following an unconditional transfer, and each is written in its most compact
+// form. The attribute is removed when no frame is needed.
+//
+// Where control flow joins, two class types merge into their common superclass found through
+// resolver, and arrays of references merge element-wise. A nil resolver merges distinct classes
+// into java/lang/Object. Instructions must have distinct increasing pcs, as decoded code has,
+// and MaxLocals must cover the locals used. Unreachable code, jsr and ret cannot be given frames
+// and are reported as errors.
+func ComputeFrames(class *classfileparser.ClassStruct, method *classfileparser.Method, resolver SuperclassResolver) error {
+	code, index, err := codeOf(method)
+	if err != nil {
+		return err
+	}
+	in, err := newInterpreter(class, method, code)
+	if err != nil {
+		return err
+	}
+	// Operand stack overflows are for ComputeMaxs to fix
+	in.code.MaxStack = 0xFFFF
+	initial := in.frame.clone()
+	frames, err := inferFrames(in, resolver)
+	if err != nil {
+		return err
+	}
+
+	var attributes []classfileparser.Attribute
+	for _, attribute := range code.Attributes {
+		if _, ok := attribute.(classfileparser.StackMapTable); !ok {
+			attributes = append(attributes, attribute)
+		}
+	}
+	if entries := compressFrames(initial, frames); len(entries) > 0 {
+		attributes = append(attributes, classfileparser.StackMapTable{NumberOfEntries: uint16(len(entries)), Entries: entries})
+	}
+	code.Attributes = attributes
+	method.Attributes[index] = code
+	return nil
+}
+
+// newInterpreter returns an interpreter for the code of method, positioned on its initial frame
+func newInterpreter(class *classfileparser.ClassStruct, m *classfileparser.Method, code classfileparser.Code) (*interpreter, error) {
+	if len(code.Code) == 0 {
+		return nil, errors.New("empty code")
+	}
+	for i := 1; i < len(code.Code); i++ {
+		if code.Code[i].PC() <= code.Code[i-1].PC() {
+			return nil, fmt.Errorf("instruction %d at pc %d does not follow pc %d", i, code.Code[i].PC(), code.Code[i-1].PC())
+		}
+	}
+	in := &interpreter{
+		method: method{
+			class:      class.ThisClass,
+			super:      class.SuperClass,
+			name:       m.Name,
+			descriptor: m.Descriptor,
+			static:     m.Access.Has(classfileparser.MethodStatic),
+		},
+		code: code,
+	}
+	var err error
+	in.frame, err = in.method.initialFrame(int(code.MaxLocals))
+	return in, err
+}
+
+// frameAt is an inferred frame and the pc it applies to
+type frameAt struct {
+	pc    int
+	frame Frame
+}
+
+// inferFrames computes the type state at the start of every code block by data-flow analysis
+// from the current frame of in, and returns those of the blocks needing a declared frame,
+// sorted by pc
+func inferFrames(in *interpreter, resolver SuperclassResolver) ([]frameAt, error) {
+	graph, err := cfg.New(in.code)
+	if err != nil {
+		return nil, err
+	}
+	entry := map[*cfg.Block]Frame{}
+	queued := map[*cfg.Block]bool{}
+	var worklist []*cfg.Block
+	reach := func(block *cfg.Block, frame Frame) error {
+		if block.Kind != cfg.CodeBlock {
+			return nil
+		}
+		if previous, ok := entry[block]; ok {
+			merged, changed, err := mergeFrames(previous, frame, resolver)
+			if err != nil {
+				first := block.Instructions[0]
+				return fmt.Errorf("pc %d (%s): %w", first.PC(), first.Mnemonic(), err)
+			}
+			if !changed {
+				return nil
+			}
+			frame = merged
+		}
+		entry[block] = frame.clone()
+		if !queued[block] {
+			queued[block] = true
+			worklist = append(worklist, block)
+		}
+		return nil
+	}
+
+	if err := reach(graph.Blocks[1], in.frame); err != nil {
+		return nil, err
+	}
+	for len(worklist) > 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		queued[block] = false
+
+		in.frame = entry[block].clone()
+		for _, instruction := range block.Instructions {
+			pc := instruction.PC()
+			for _, handler := range in.code.ExceptionTable {
+				if pc < int(handler.StartPc) || pc >= int(handler.EndPc) {
+					continue
+				}
+				caught := ThrowableType
+				if handler.CatchClass != "" {
+					caught = ReferenceType(handler.CatchClass)
+				}
+				target, _ := graph.BlockAt(int(handler.HandlerPc))
+				locals := append([]Type(nil), in.frame.Locals...)
+				if err := reach(target, Frame{Locals: locals, Stack: []Type{caught}}); err != nil {
+					return nil, err
+				}
+			}
+			if err := in.execute(instruction); err != nil {
+				return nil, fmt.Errorf("pc %d (%s): %w", pc, instruction.Mnemonic(), err)
+			}
+		}
+		for _, edge := range block.Succs {
+			switch edge.Kind {
+			case cfg.Fallthrough, cfg.Jump, cfg.Switch:
+				if err := reach(edge.To, in.frame); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var frames []frameAt
+	for _, block := range graph.Blocks[1 : len(graph.Blocks)-1] {
+		frame, ok := entry[block]
+		if !ok {
+			first := block.Instructions[0]
+			return nil, fmt.Errorf("pc %d (%s): unreachable code cannot be given a frame", first.PC(), first.Mnemonic())
+		}
+		if needsFrame(block) {
+			frames = append(frames, frameAt{pc: block.Start, frame: frame})
+		}
+	}
+	return frames, nil
+}
+
+// needsFrame reports whether the type checker requires a declared frame at the start of block:
+// it is reached otherwise than by falling through from the previous instruction
+func needsFrame(block *cfg.Block) bool {
+	for _, edge := range block.Preds {
+		if edge.Kind != cfg.Fallthrough {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFrames merges the type states a and b meeting at an instruction, and reports whether the
+// result differs from a
+func mergeFrames(a, b Frame, resolver SuperclassResolver) (Frame, bool, error) {
+	if len(a.Stack) != len(b.Stack) {
+		return Frame{}, false, fmt.Errorf("stack %v differs in depth from %v along another path", b.Stack, a.Stack)
+	}
+	merged := Frame{Locals: make([]Type, len(a.Locals)), Stack: make([]Type, len(a.Stack))}
+	changed := false
+	for i := range a.Locals {
+		t, err := mergeType(a.Locals[i], b.Locals[i], resolver)
+		if err != nil {
+			return Frame{}, false, err
+		}
+		merged.Locals[i] = t
+		changed = changed || t != a.Locals[i]
+	}
+	for i := range a.Stack {
+		t, err := mergeType(a.Stack[i], b.Stack[i], resolver)
+		if err != nil {
+			return Frame{}, false, err
+		}
+		if t.Kind == Top {
+			return Frame{}, false, mismatch(fmt.Sprintf("stack value %d has incompatible types along different paths", i), a.Stack[i], b.Stack[i])
+		}
+		merged.Stack[i] = t
+		changed = changed || t != a.Stack[i]
+	}
+	return merged, changed, nil
+}
+
+// mergeType returns the most specific type both a and b are assignable to, Top when they have
+// none in common
+func mergeType(a, b Type, resolver SuperclassResolver) (Type, error) {
+	switch {
+	case a == b:
+		return a, nil
+	case a.Kind == Null && b.Kind == Reference:
+		return b, nil
+	case b.Kind == Null && a.Kind == Reference:
+		return a, nil
+	case a.Kind == Reference && b.Kind == Reference:
+		if a.Name == "" || b.Name == "" {
+			return ObjectType, nil
+		}
+		name, err := mergeNames(a.Name, b.Name, resolver)
+		return ReferenceType(name), err
+	}
+	return TopType, nil
+}
+
+// mergeNames merges two classes or arrays named as in CONSTANT_Class entries
+func mergeNames(a, b string, resolver SuperclassResolver) (string, error) {
+	if a == b {
+		return a, nil
+	}
+	aArray, bArray := a[0] == '[', b[0] == '['
+	switch {
+	case aArray && bArray:
+		aComponent, aReference := arrayComponentName(a)
+		bComponent, bReference := arrayComponentName(b)
+		if !aReference || !bReference {
+			return "java/lang/Object", nil
+		}
+		component, err := mergeNames(aComponent, bComponent, resolver)
+		return arrayOf(component), err
+	case aArray || bArray || resolver == nil:
+		return "java/lang/Object", nil
+	}
+	name, err := resolver.CommonSuperclass(a, b)
+	if err != nil {
+		return "", fmt.Errorf("merging %s and %s: %w", a, b, err)
+	}
+	return name, nil
+}
+
+// compressFrames encodes frames as StackMapTable entries, each relative to the previous one and
+// the first to the initial frame, picking the most compact kind
+func compressFrames(initial Frame, frames []frameAt) []classfileparser.StackMapFrame {
+	entries := make([]classfileparser.StackMapFrame, 0, len(frames))
+	previous, previousPc := initial.localValues(), -1
+	for _, f := range frames {
+		locals := f.frame.localValues()
+		delta := f.pc - previousPc - 1
+		entry := classfileparser.StackMapFrame{Pc: uint16(f.pc), OffsetDelta: uint16(delta)}
+		shared := commonPrefix(previous, locals)
+		switch {
+		case shared == len(previous) && shared == len(locals) && len(f.frame.Stack) == 0:
+			entry.FrameType = 251 // same_frame_extended
+			if delta <= 63 {
+				entry.FrameType = uint8(delta)
+			}
+		case shared == len(previous) && shared == len(locals) && len(f.frame.Stack) == 1:
+			entry.FrameType = 247 // same_locals_1_stack_item_frame_extended
+			if delta <= 63 {
+				entry.FrameType = uint8(64 + delta)
+			}
+			entry.StackItems = stackItems(f.frame.Stack)
+		case len(f.frame.Stack) == 0 && shared == len(locals) && len(previous)-shared <= 3:
+			entry.Chopped = uint8(len(previous) - shared)
+			entry.FrameType = 251 - entry.Chopped
+		case len(f.frame.Stack) == 0 && shared == len(previous) && len(locals)-shared <= 3:
+			entry.Locals = stackItems(locals[shared:])
+			entry.FrameType = uint8(251 + len(entry.Locals))
+		default:
+			entry.FrameType = 255
+			entry.Locals = stackItems(locals)
+			entry.StackItems = stackItems(f.frame.Stack)
+		}
+		entries = append(entries, entry)
+		previous, previousPc = locals, f.pc
+	}
+	return entries
+}
+
+// commonPrefix returns the number of leading types a and b share
+func commonPrefix(a, b []Type) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func stackItems(types []Type) []classfileparser.StackItem {
+	items := make([]classfileparser.StackItem, len(types))
+	for i, t := range types {
+		items[i] = stackItem(t)
+	}
+	return items
+}
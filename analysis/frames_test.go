@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+)
+
+// CommonSuperclass walks the superclass chain of a for the first class b is or extends
+func (h superclasses) CommonSuperclass(a, b string) (string, error) {
+	for name := a; name != ""; name = h[name] {
+		if ok, _ := h.IsAssignableFrom(name, b); ok {
+			return name, nil
+		}
+	}
+	return "java/lang/Object", nil
+}
+
+// nops returns n nop instructions from pc
+func nops(pc, n int) []cf.Instruction {
+	code := make([]cf.Instruction, n)
+	for i := range n {
+		code[i] = cf.Nop{Location: at(pc + i)}
+	}
+	return code
+}
+
+// instructions concatenates runs of instructions
+func instructions(runs ...[]cf.Instruction) []cf.Instruction {
+	var code []cf.Instruction
+	for _, run := range runs {
+		code = append(code, run...)
+	}
+	return code
+}
+
+// computedFrames describes the StackMapTable entries of the code of m as "pc:type"
+func computedFrames(m *cf.Method) []string {
+	var described []string
+	for _, attribute := range m.Attributes[0].(cf.Code).Attributes {
+		if table, ok := attribute.(cf.StackMapTable); ok {
+			for _, entry := range table.Entries {
+				described = append(described, fmt.Sprintf("%d:%d", entry.Pc, entry.FrameType))
+			}
+		}
+	}
+	return described
+}
+
+func TestComputeFrames(t *testing.T) {
+	join := ifZero()
+	join.code = []cf.Instruction{
+		cf.Iload0{Location: at(0)},
+		cf.Ifeq{Location: at(1), Offset: 7},
+		cf.Iconst1{Location: at(4)},
+		cf.Goto{Location: at(5), Offset: 4},
+		cf.Iconst2{Location: at(8)},
+		cf.Ireturn{Location: at(9)},
+	}
+	tests := []struct {
+		name   string
+		method testMethod
+		want   []string
+	}{
+		{"no branch", testMethod{"(II)I", 2, 2, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Iload1{Location: at(1)},
+			cf.Iadd{Location: at(2)},
+			cf.Ireturn{Location: at(3)},
+		}, nil, nil}, nil},
+		{"same", ifZero(), []string{"6:6"}},
+		{"same extended", testMethod{"(I)V", 1, 1, instructions(
+			[]cf.Instruction{cf.Iload0{Location: at(0)}, cf.Ifeq{Location: at(1), Offset: 67}},
+			nops(4, 64),
+			[]cf.Instruction{cf.Return{Location: at(68)}},
+		), nil, nil}, []string{"68:251"}},
+		{"same locals 1 stack item", join, []string{"8:8", "9:64"}},
+		{"same locals 1 stack item extended", testMethod{"(I)I", 1, 1, instructions(
+			[]cf.Instruction{
+				cf.Iload0{Location: at(0)},
+				cf.Ifeq{Location: at(1), Offset: 7},
+				cf.Iconst1{Location: at(4)},
+				cf.Goto{Location: at(5), Offset: 68},
+				cf.Iconst2{Location: at(8)},
+			},
+			nops(9, 64),
+			[]cf.Instruction{cf.Ireturn{Location: at(73)}},
+		), nil, nil}, []string{"8:8", "73:247"}},
+		{"append", testMethod{"(I)I", 2, 2, []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Istore1{Location: at(1)},
+			cf.Iload1{Location: at(2)},
+			cf.Iload0{Location: at(3)},
+			cf.IfIcmpge{Location: at(4), Offset: 9},
+			cf.Iinc{Location: at(7), LocalIndex: 1, Const: 1},
+			cf.Goto{Location: at(10), Offset: -8},
+			cf.Iload1{Location: at(13)},
+			cf.Ireturn{Location: at(14)},
+		}, nil, nil}, []string{"2:252", "13:10"}},
+		{"chop", testMethod{"(I)V", 1, 2, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Ifeq{Location: at(1), Offset: 9},
+			cf.Iconst0{Location: at(4)},
+			cf.Istore1{Location: at(5)},
+			cf.Iload1{Location: at(6)},
+			cf.Ifne{Location: at(7), Offset: -1},
+			cf.Return{Location: at(10)},
+		}, nil, nil}, []string{"6:252", "10:250"}},
+		{"full", testMethod{"(I)V", 1, 5, []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Istore1{Location: at(1)},
+			cf.Iconst0{Location: at(2)},
+			cf.Istore2{Location: at(3)},
+			cf.Iconst0{Location: at(4)},
+			cf.Istore3{Location: at(5)},
+			cf.Iconst0{Location: at(6)},
+			cf.Istore{Location: at(7), LocalIndex: 4},
+			cf.Iload0{Location: at(9)},
+			cf.Ifne{Location: at(10), Offset: -1},
+			cf.Return{Location: at(13)},
+		}, nil, nil}, []string{"9:255"}},
+		{"handler", testMethod{"(Ljava/lang/Object;)I", 1, 1, []cf.Instruction{
+			cf.Aload0{Location: at(0)},
+			cf.Invokevirtual{Location: at(1), Class: "java/lang/Object", Name: "hashCode", Type: "()I"},
+			cf.Ireturn{Location: at(4)},
+			cf.Pop{Location: at(5)},
+			cf.Iconst0{Location: at(6)},
+			cf.Ireturn{Location: at(7)},
+		}, []cf.ExceptionTableEntry{{StartPc: 0, EndPc: 4, HandlerPc: 5, CatchClass: "java/lang/RuntimeException"}}, nil}, []string{"5:69"}},
+	}
+	for _, test := range tests {
+		class, m := test.method.build()
+		if err := ComputeFrames(class, m, testHierarchy); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := computedFrames(m); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: frames = %v, want %v", test.name, got, test.want)
+		}
+		result, err := Verify(class, m, testHierarchy)
+		if err != nil || !result.OK() {
+			t.Errorf("%s: verification = %v, %v", test.name, result, err)
+		}
+	}
+}
+
+func TestComputeFramesReplaces(t *testing.T) {
+	// A stale frame at the wrong pc is dropped
+	method := ifZero()
+	method.frames = []cf.StackMapFrame{{FrameType: 4, OffsetDelta: 4, Pc: 4}}
+	class, m := method.build()
+	if err := ComputeFrames(class, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := computedFrames(m); fmt.Sprint(got) != "[6:6]" {
+		t.Errorf("frames = %v", got)
+	}
+}
+
+func TestComputeFramesMerge(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		resolver SuperclassResolver
+		want     string
+	}{
+		{"java/lang/RuntimeException", "java/lang/Exception", testHierarchy, "java/lang/Exception"},
+		{"java/lang/RuntimeException", "java/lang/Exception", nil, "java/lang/Object"},
+		{"java/lang/Integer", "java/lang/Exception", testHierarchy, "java/lang/Object"},
+		{"[Ljava/lang/RuntimeException;", "[Ljava/lang/Exception;", testHierarchy, "[Ljava/lang/Exception;"},
+		{"[[Ljava/lang/Integer;", "[[Ljava/lang/Number;", testHierarchy, "[[Ljava/lang/Number;"},
+		{"[[Ljava/lang/Integer;", "[Ljava/lang/Number;", testHierarchy, "[Ljava/lang/Object;"},
+		{"[I", "[F", testHierarchy, "java/lang/Object"},
+		{"[I", "java/lang/Integer", testHierarchy, "java/lang/Object"},
+	}
+	for _, test := range tests {
+		// The join at 15 holds a value cast to a or b
+		class, m := testMethod{"(I)Ljava/lang/Object;", 1, 1, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Ifeq{Location: at(1), Offset: 10},
+			cf.AconstNull{Location: at(4)},
+			cf.Checkcast{Location: at(5), Class: test.a},
+			cf.Goto{Location: at(8), Offset: 7},
+			cf.AconstNull{Location: at(11)},
+			cf.Checkcast{Location: at(12), Class: test.b},
+			cf.Areturn{Location: at(15)},
+		}, nil, nil}.build()
+		if err := ComputeFrames(class, m, test.resolver); err != nil {
+			t.Errorf("%s, %s: %v", test.a, test.b, err)
+			continue
+		}
+		entries := m.Attributes[0].(cf.Code).Attributes[0].(cf.StackMapTable).Entries
+		if len(entries) != 2 || len(entries[1].StackItems) != 1 {
+			t.Errorf("%s, %s: frames = %v", test.a, test.b, entries)
+			continue
+		}
+		if item := entries[1].StackItems[0]; item.Tag != cf.ItemObject || item.Class != test.want {
+			t.Errorf("%s, %s: merged into %v, want %s", test.a, test.b, item, test.want)
+		}
+	}
+}
+
+func TestComputeFramesRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		code []cf.Instruction
+	}{
+		{"unreachable code", []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Ireturn{Location: at(1)},
+			cf.Iconst1{Location: at(2)},
+			cf.Ireturn{Location: at(3)},
+		}},
+		{"jsr", []cf.Instruction{
+			cf.Jsr{Location: at(0), Offset: 5},
+			cf.Iconst0{Location: at(3)},
+			cf.Ireturn{Location: at(4)},
+			cf.Astore0{Location: at(5)},
+			cf.Ret{Location: at(6), LocalIndex: 0},
+		}},
+		{"stack depths differ", []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Iconst0{Location: at(1)},
+			cf.Ifeq{Location: at(2), Offset: 4},
+			cf.Iconst1{Location: at(5)},
+			cf.Ireturn{Location: at(6)},
+		}},
+	}
+	for _, test := range tests {
+		class, m := testMethod{"()I", 2, 1, test.code, nil, nil}.build()
+		if err := ComputeFrames(class, m, nil); err == nil {
+			t.Errorf("%s: frames = %v, want an error", test.name, computedFrames(m))
+		}
+	}
+}
@@ -0,0 +1,670 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// Hierarchy answers the subtype questions of the verifier. *hierarchy.Hierarchy implements it.
+type Hierarchy interface {
+	// IsAssignableFrom reports whether source is target or one of its subtypes
+	IsAssignableFrom(target, source string) (bool, error)
+	// IsInterface reports whether name is an interface
+	IsInterface(name string) bool
+}
+
+// typeError is an instruction applied to operands or locals of the wrong type
+type typeError struct {
+	message  string
+	expected string
+	actual   string
+}
+
+func (e *typeError) Error() string {
+	if e.expected == "" {
+		return e.message
+	}
+	return fmt.Sprintf("%s: expected %s, found %s", e.message, e.expected, e.actual)
+}
+
+// method describes the method whose code is interpreted
+type method struct {
+	class      string // Internal name of the declaring class
+	super      string // Internal name of its superclass, empty for java/lang/Object
+	name       string
+	descriptor descriptor.Method
+	static     bool
+}
+
+// initialFrame returns the frame on entry to the method (JVMS §4.10.1.6)
+func (m method) initialFrame(maxLocals int) (Frame, error) {
+	var values []Type
+	if !m.static {
+		if m.name == "<init>" && m.class != "java/lang/Object" {
+			values = append(values, Type{Kind: UninitializedThis})
+		} else {
+			values = append(values, ReferenceType(m.class))
+		}
+	}
+	for _, param := range m.descriptor.Params {
+		values = append(values, FieldType(param))
+	}
+	locals, err := slots(values, maxLocals)
+	return Frame{Locals: locals}, err
+}
+
+// interpreter applies instructions to a frame, checking their operands
+type interpreter struct {
+	method    method
+	code      classfileparser.Code
+	hierarchy Hierarchy // nil to accept any reference where a reference is expected
+	frame     Frame
+}
+
+// assignable reports whether a value of type from can be used where a value of type to is
+// expected (JVMS §4.10.1.2). A Reference without name stands for any reference.
+func (in *interpreter) assignable(from, to Type) bool {
+	if from == to || to.Kind == Top {
+		return true
+	}
+	if to.Kind != Reference {
+		return false
+	}
+	if to.Name == "" {
+		return from.IsReference()
+	}
+	switch from.Kind {
+	case Null:
+		return true
+	case Reference:
+		return from.Name == "" || in.javaAssignable(to.Name, from.Name)
+	}
+	return false
+}
+
+// javaAssignable reports whether the class or array source is assignable to target. Interfaces
+// are treated like java/lang/Object, as the type checker does, and relations the hierarchy
+// cannot decide because classes are missing are assumed to hold.
+func (in *interpreter) javaAssignable(target, source string) bool {
+	if target == source || target == "java/lang/Object" || in.hierarchy == nil {
+		return true
+	}
+	targetArray, sourceArray := strings.HasPrefix(target, "["), strings.HasPrefix(source, "[")
+	switch {
+	case targetArray && sourceArray:
+		targetComponent, targetReference := arrayComponentName(target)
+		sourceComponent, sourceReference := arrayComponentName(source)
+		return targetReference && sourceReference && in.javaAssignable(targetComponent, sourceComponent)
+	case targetArray:
+		return false
+	case sourceArray:
+		return target == "java/lang/Cloneable" || target == "java/io/Serializable"
+	}
+	if in.hierarchy.IsInterface(target) {
+		return true
+	}
+	ok, err := in.hierarchy.IsAssignableFrom(target, source)
+	return ok || err != nil
+}
+
+// arrayComponentName returns the component of an array descriptor as an internal name or array
+// descriptor, and whether it is a reference type
+func arrayComponentName(array string) (string, bool) {
+	component := array[1:]
+	switch {
+	case strings.HasPrefix(component, "["):
+		return component, true
+	case strings.HasPrefix(component, "L") && strings.HasSuffix(component, ";"):
+		return component[1 : len(component)-1], true
+	}
+	return component, false
+}
+
+func mismatch(message string, expected, actual interface{}) error {
+	return &typeError{message: message, expected: fmt.Sprint(expected), actual: fmt.Sprint(actual)}
+}
+
+func (in *interpreter) push(types ...Type) error {
+	for _, t := range types {
+		if t.Kind == Top {
+			continue
+		}
+		in.frame.Stack = append(in.frame.Stack, t)
+	}
+	if size := in.frame.StackSize(); size > int(in.code.MaxStack) {
+		return mismatch("operand stack overflow", fmt.Sprintf("at most %d slots", in.code.MaxStack), fmt.Sprintf("%d slots", size))
+	}
+	return nil
+}
+
+// pop pops a value assignable to expected
+func (in *interpreter) pop(expected Type) (Type, error) {
+	stack := in.frame.Stack
+	if len(stack) == 0 {
+		return Type{}, mismatch("operand stack underflow", expected, "an empty stack")
+	}
+	value := stack[len(stack)-1]
+	if !in.assignable(value, expected) {
+		return Type{}, mismatch("operand type mismatch", expected, value)
+	}
+	in.frame.Stack = stack[:len(stack)-1]
+	return value, nil
+}
+
+// popAll pops values assignable to types, given from bottom to top
+func (in *interpreter) popAll(types ...Type) error {
+	for i := len(types) - 1; i >= 0; i-- {
+		if _, err := in.pop(types[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// popReference pops any reference, initialized or not
+func (in *interpreter) popReference() (Type, error) {
+	return in.pop(Type{Kind: Reference})
+}
+
+// popArray pops null or an array type accepted by accepts
+func (in *interpreter) popArray(expected string, accepts func(array Type) bool) (Type, error) {
+	array, err := in.popReference()
+	if err != nil {
+		return Type{}, err
+	}
+	if array.Kind == Null || (array.IsArray() && accepts(array)) {
+		return array, nil
+	}
+	return Type{}, mismatch("operand type mismatch", expected, array)
+}
+
+// isAny accepts any array
+func isAny(Type) bool { return true }
+
+// isReferenceArray accepts arrays of references
+func isReferenceArray(array Type) bool {
+	component, ok := array.Component()
+	return ok && component.IsReference()
+}
+
+// popSlots pops values taking exactly n slots, returned from bottom to top
+func (in *interpreter) popSlots(n int) ([]Type, error) {
+	var values []Type
+	for size := 0; size < n; {
+		stack := in.frame.Stack
+		if len(stack) == 0 {
+			return nil, mismatch("operand stack underflow", fmt.Sprintf("%d slots", n), fmt.Sprintf("%d slots", size))
+		}
+		value := stack[len(stack)-1]
+		if size+value.Size() > n {
+			return nil, mismatch("operand type mismatch", "a category 1 value", value)
+		}
+		in.frame.Stack = stack[:len(stack)-1]
+		values = append([]Type{value}, values...)
+		size += value.Size()
+	}
+	return values, nil
+}
+
+// load pushes the local at index, which must hold a value assignable to expected
+func (in *interpreter) load(index int, expected Type) error {
+	local, err := in.local(index, expected)
+	if err != nil {
+		return err
+	}
+	return in.push(local)
+}
+
+// local returns the local at index, which must hold a value assignable to expected
+func (in *interpreter) local(index int, expected Type) (Type, error) {
+	if index+expected.Size() > len(in.frame.Locals) {
+		return Type{}, mismatch("local variable out of range", fmt.Sprintf("at most %d slots", len(in.frame.Locals)), fmt.Sprintf("slot %d", index+expected.Size()-1))
+	}
+	local := in.frame.Locals[index]
+	if !in.assignable(local, expected) {
+		return Type{}, mismatch(fmt.Sprintf("local variable %d type mismatch", index), expected, local)
+	}
+	return local, nil
+}
+
+// store pops a value assignable to expected into the local at index
+func (in *interpreter) store(index int, expected Type) error {
+	value, err := in.pop(expected)
+	if err != nil {
+		return err
+	}
+	return in.setLocal(index, value)
+}
+
+func (in *interpreter) setLocal(index int, value Type) error {
+	locals := in.frame.Locals
+	if index+value.Size() > len(locals) {
+		return mismatch("local variable out of range", fmt.Sprintf("at most %d slots", len(locals)), fmt.Sprintf("slot %d", index+value.Size()-1))
+	}
+	if index > 0 && locals[index-1].Size() == 2 {
+		// Overwriting the second slot of a long or double invalidates it
+		locals[index-1] = TopType
+	}
+	locals[index] = value
+	if value.Size() == 2 {
+		locals[index+1] = TopType
+	}
+	return nil
+}
+
+// simple holds the operands and results of the instructions that only move values of fixed types
+var simple = map[uint8]struct{ pop, push []Type }{}
+
+func init() {
+	i, l, f, d := IntType, LongType, FloatType, DoubleType
+	set := func(pop, push []Type, opcodes ...uint8) {
+		for _, opcode := range opcodes {
+			simple[opcode] = struct{ pop, push []Type }{pop, push}
+		}
+	}
+	set(nil, nil, 0x00, 0xA7, 0xC8)                                           // nop, goto, goto_w
+	set(nil, []Type{NullType}, 0x01)                                          // aconst_null
+	set(nil, []Type{i}, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x10, 0x11) // iconst_<i>, bipush, sipush
+	set(nil, []Type{l}, 0x09, 0x0A)                                           // lconst_<l>
+	set(nil, []Type{f}, 0x0B, 0x0C, 0x0D)                                     // fconst_<f>
+	set(nil, []Type{d}, 0x0E, 0x0F)                                           // dconst_<d>
+	for op, t := range []Type{i, l, f, d} {
+		for _, base := range []uint8{0x60, 0x64, 0x68, 0x6C, 0x70} { // add, sub, mul, div, rem
+			set([]Type{t, t}, []Type{t}, base+uint8(op))
+		}
+		set([]Type{t}, []Type{t}, 0x74+uint8(op)) // neg
+	}
+	set([]Type{i, i}, []Type{i}, 0x78, 0x7A, 0x7C, 0x7E, 0x80, 0x82) // ishl, ishr, iushr, iand, ior, ixor
+	set([]Type{l, i}, []Type{l}, 0x79, 0x7B, 0x7D)                   // lshl, lshr, lushr
+	set([]Type{l, l}, []Type{l}, 0x7F, 0x81, 0x83)                   // land, lor, lxor
+	conversions := []struct{ from, to Type }{
+		{i, l}, {i, f}, {i, d}, {l, i}, {l, f}, {l, d}, {f, i}, {f, l}, {f, d}, {d, i}, {d, l}, {d, f}, {i, i}, {i, i}, {i, i},
+	}
+	for n, conversion := range conversions { // i2l to i2s
+		set([]Type{conversion.from}, []Type{conversion.to}, 0x85+uint8(n))
+	}
+	set([]Type{l, l}, []Type{i}, 0x94)                                  // lcmp
+	set([]Type{f, f}, []Type{i}, 0x95, 0x96)                            // fcmpl, fcmpg
+	set([]Type{d, d}, []Type{i}, 0x97, 0x98)                            // dcmpl, dcmpg
+	set([]Type{i}, nil, 0x99, 0x9A, 0x9B, 0x9C, 0x9D, 0x9E, 0xAA, 0xAB) // if<cond>, tableswitch, lookupswitch
+	set([]Type{i, i}, nil, 0x9F, 0xA0, 0xA1, 0xA2, 0xA3, 0xA4)          // if_icmp<cond>
+	set([]Type{{Kind: Reference}, {Kind: Reference}}, nil, 0xA5, 0xA6)  // if_acmp<cond>
+	set([]Type{{Kind: Reference}}, nil, 0xC2, 0xC3, 0xC6, 0xC7)         // monitorenter, monitorexit, ifnull, ifnonnull
+}
+
+// primitiveArrays gives the array types accepted by the array loads and stores, from iaload and
+// iastore to saload and sastore, the aaload and aastore entry being empty
+var primitiveArrays = [...][]string{{"[I"}, {"[J"}, {"[F"}, {"[D"}, nil, {"[B", "[Z"}, {"[C"}, {"[S"}}
+
+// execute applies instruction to the frame
+func (in *interpreter) execute(instruction classfileparser.Instruction) error {
+	opcode := instruction.Opcode()
+	if effect, ok := simple[opcode]; ok {
+		if err := in.popAll(effect.pop...); err != nil {
+			return err
+		}
+		return in.push(effect.push...)
+	}
+	if index, t, store, ok := localOperand(instruction); ok {
+		if store {
+			return in.store(index, t)
+		}
+		return in.load(index, t)
+	}
+
+	switch {
+	case opcode >= 0x2E && opcode <= 0x35: // iaload to saload
+		return in.arrayLoad(int(opcode - 0x2E))
+	case opcode >= 0x4F && opcode <= 0x56: // iastore to sastore
+		return in.arrayStore(int(opcode - 0x4F))
+	case opcode >= 0xAC && opcode <= 0xB1: // ireturn to return
+		return in.returns(instruction)
+	}
+
+	switch instruction := instruction.(type) {
+	case classfileparser.Ldc:
+		return in.ldc(instruction.Value, false)
+	case classfileparser.LdcW:
+		return in.ldc(instruction.Value, false)
+	case classfileparser.Ldc2W:
+		return in.ldc(instruction.Value, true)
+	case classfileparser.Iinc:
+		_, err := in.local(int(instruction.LocalIndex), IntType)
+		return err
+	case classfileparser.Wide:
+		if instruction.OpCode != 0x84 {
+			return errors.New("jsr and ret are not allowed by the type checking verifier")
+		}
+		_, err := in.local(int(instruction.LocalIndex), IntType)
+		return err
+	case classfileparser.Pop:
+		_, err := in.popSlots(1)
+		return err
+	case classfileparser.Pop2:
+		_, err := in.popSlots(2)
+		return err
+	case classfileparser.Dup:
+		return in.dup(1, 0)
+	case classfileparser.DupX1:
+		return in.dup(1, 1)
+	case classfileparser.DupX2:
+		return in.dup(1, 2)
+	case classfileparser.Dup2:
+		return in.dup(2, 0)
+	case classfileparser.Dup2X1:
+		return in.dup(2, 1)
+	case classfileparser.Dup2X2:
+		return in.dup(2, 2)
+	case classfileparser.Swap:
+		top, err := in.popSlots(1)
+		if err != nil {
+			return err
+		}
+		below, err := in.popSlots(1)
+		if err != nil {
+			return err
+		}
+		return in.push(append(top, below...)...)
+	case classfileparser.Jsr, classfileparser.JsrW, classfileparser.Ret:
+		return errors.New("jsr and ret are not allowed by the type checking verifier")
+	case classfileparser.Getstatic:
+		return in.field(instruction.Type, "", false)
+	case classfileparser.Putstatic:
+		return in.field(instruction.Type, "", true)
+	case classfileparser.Getfield:
+		return in.field(instruction.Type, instruction.Class, false)
+	case classfileparser.Putfield:
+		return in.field(instruction.Type, instruction.Class, true)
+	case classfileparser.Invokevirtual:
+		return in.invoke(instruction.Type, instruction.Class, instruction.Name, false)
+	case classfileparser.Invokespecial:
+		return in.invoke(instruction.Type, instruction.Class, instruction.Name, true)
+	case classfileparser.Invokestatic:
+		return in.invoke(instruction.Type, "", instruction.Name, false)
+	case classfileparser.Invokeinterface:
+		ref := instruction.InterfaceMethodref
+		return in.invoke(ref.Type, ref.Class, ref.Name, false)
+	case classfileparser.Invokedynamic:
+		return in.invoke(instruction.InvokeDynamic.Type, "", instruction.InvokeDynamic.Name, false)
+	case classfileparser.New:
+		return in.push(Type{Kind: Uninitialized, Offset: instruction.Pc})
+	case classfileparser.Newarray:
+		name, ok := newarrayTypes[instruction.Type]
+		if !ok {
+			return fmt.Errorf("invalid newarray type %d", instruction.Type)
+		}
+		if _, err := in.pop(IntType); err != nil {
+			return err
+		}
+		return in.push(ReferenceType(name))
+	case classfileparser.Anewarray:
+		if _, err := in.pop(IntType); err != nil {
+			return err
+		}
+		return in.push(ReferenceType(arrayOf(instruction.Class)))
+	case classfileparser.Multianewarray:
+		for n := 0; n < int(instruction.Dimension); n++ {
+			if _, err := in.pop(IntType); err != nil {
+				return err
+			}
+		}
+		return in.push(ReferenceType(instruction.Class))
+	case classfileparser.Arraylength:
+		if _, err := in.popArray("an array", isAny); err != nil {
+			return err
+		}
+		return in.push(IntType)
+	case classfileparser.Athrow:
+		_, err := in.pop(ThrowableType)
+		return err
+	case classfileparser.Checkcast:
+		if _, err := in.pop(ObjectType); err != nil {
+			return err
+		}
+		return in.push(ReferenceType(instruction.Class))
+	case classfileparser.Instanceof:
+		if _, err := in.pop(ObjectType); err != nil {
+			return err
+		}
+		return in.push(IntType)
+	}
+	return fmt.Errorf("unknown opcode 0x%02X", opcode)
+}
+
+// newarrayTypes gives the array created by newarray for each atype
+var newarrayTypes = map[uint8]string{4: "[Z", 5: "[C", 6: "[F", 7: "[D", 8: "[B", 9: "[S", 10: "[I", 11: "[J"}
+
+// arrayOf returns the descriptor of the array of the class or array named as in a CONSTANT_Class entry
+func arrayOf(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return "[" + name
+	}
+	return "[L" + name + ";"
+}
+
+func (in *interpreter) arrayLoad(kind int) error {
+	if _, err := in.pop(IntType); err != nil {
+		return err
+	}
+	if kind == 4 { // aaload
+		array, err := in.popArray("an array of references", isReferenceArray)
+		if err != nil {
+			return err
+		}
+		if array.Kind == Null {
+			return in.push(NullType)
+		}
+		component, _ := array.Component()
+		return in.push(component)
+	}
+	if _, err := in.popPrimitiveArray(kind); err != nil {
+		return err
+	}
+	component, _ := ReferenceType(primitiveArrays[kind][0]).Component()
+	return in.push(component)
+}
+
+func (in *interpreter) arrayStore(kind int) error {
+	if kind == 4 { // aastore
+		if err := in.popAll(IntType, Type{Kind: Reference}); err != nil {
+			return err
+		}
+		_, err := in.popArray("an array of references", isReferenceArray)
+		return err
+	}
+	component, _ := ReferenceType(primitiveArrays[kind][0]).Component()
+	if err := in.popAll(IntType, component); err != nil {
+		return err
+	}
+	_, err := in.popPrimitiveArray(kind)
+	return err
+}
+
+// popPrimitiveArray pops null or an array accepted by the primitive array load or store kind
+func (in *interpreter) popPrimitiveArray(kind int) (Type, error) {
+	accepted := primitiveArrays[kind]
+	return in.popArray(strings.Join(accepted, " or "), func(array Type) bool {
+		for _, name := range accepted {
+			if array.Name == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// dup duplicates the values taking size slots on top of the stack, inserting the copy below the
+// values taking the next depth slots
+func (in *interpreter) dup(size, depth int) error {
+	top, err := in.popSlots(size)
+	if err != nil {
+		return err
+	}
+	below, err := in.popSlots(depth)
+	if err != nil {
+		return err
+	}
+	values := append(append(append([]Type(nil), top...), below...), top...)
+	return in.push(values...)
+}
+
+func (in *interpreter) returns(instruction classfileparser.Instruction) error {
+	expected := FieldType(in.method.descriptor.Return)
+	if instruction.Opcode() == 0xB1 { // return
+		if expected.Kind != Top {
+			return mismatch("return type mismatch", expected, "void")
+		}
+		if in.method.name == "<init>" {
+			for _, local := range in.frame.Locals {
+				if local.Kind == UninitializedThis {
+					return errors.New("constructor returns before calling a super or this constructor")
+				}
+			}
+		}
+		return nil
+	}
+	if expected.Kind == Top {
+		return mismatch("return type mismatch", "void", instruction.Mnemonic())
+	}
+	if kind := int(instruction.Opcode() - 0xAC); localTypes[kind].Kind != expected.Kind {
+		return mismatch("return type mismatch", expected, localTypes[kind])
+	}
+	_, err := in.pop(expected)
+	return err
+}
+
+func (in *interpreter) ldc(value interface{}, wide bool) error {
+	var t Type
+	switch value := value.(type) {
+	case int32:
+		t = IntType
+	case float32:
+		t = FloatType
+	case int64:
+		t = LongType
+	case float64:
+		t = DoubleType
+	case string:
+		t = StringType
+	case classfileparser.Class:
+		t = ReferenceType("java/lang/Class")
+	case classfileparser.MethodType:
+		t = ReferenceType("java/lang/invoke/MethodType")
+	case classfileparser.MethodHandle:
+		t = ReferenceType("java/lang/invoke/MethodHandle")
+	case classfileparser.Dynamic:
+		field, err := descriptor.ParseField(value.Type)
+		if err != nil {
+			return err
+		}
+		t = FieldType(field)
+	default:
+		return fmt.Errorf("cannot load a constant of type %T", value)
+	}
+	if wide && t.Size() != 2 {
+		return mismatch("constant size mismatch", "a long or double", t)
+	}
+	if !wide && t.Size() != 1 {
+		return mismatch("constant size mismatch", "a category 1 constant", t)
+	}
+	return in.push(t)
+}
+
+// field gets or puts a field of type raw, declared by class for instance fields
+func (in *interpreter) field(raw, class string, put bool) error {
+	field, err := descriptor.ParseField(raw)
+	if err != nil {
+		return err
+	}
+	t := FieldType(field)
+	if put {
+		if _, err := in.pop(t); err != nil {
+			return err
+		}
+	}
+	if class != "" {
+		receiver := ReferenceType(class)
+		if put && class == in.method.class && len(in.frame.Stack) > 0 && in.frame.Stack[len(in.frame.Stack)-1].Kind == UninitializedThis {
+			// A constructor may set the fields of its class before calling the super constructor
+			receiver = Type{Kind: UninitializedThis}
+		}
+		if _, err := in.pop(receiver); err != nil {
+			return err
+		}
+	}
+	if put {
+		return nil
+	}
+	return in.push(t)
+}
+
+// invoke calls the method name of type raw, declared by class for instance methods
+func (in *interpreter) invoke(raw, class, name string, special bool) error {
+	method, err := descriptor.ParseMethod(raw)
+	if err != nil {
+		return err
+	}
+	for i := len(method.Params) - 1; i >= 0; i-- {
+		if _, err := in.pop(FieldType(method.Params[i])); err != nil {
+			return err
+		}
+	}
+	if class != "" {
+		if special && name == "<init>" {
+			if err := in.initialize(class); err != nil {
+				return err
+			}
+		} else if _, err := in.pop(ReferenceType(class)); err != nil {
+			return err
+		}
+	}
+	return in.push(FieldType(method.Return))
+}
+
+// initialize pops the receiver of a constructor call and replaces every occurrence of its
+// uninitialized type with the initialized one
+func (in *interpreter) initialize(class string) error {
+	receiver, err := in.popReference()
+	if err != nil {
+		return err
+	}
+	var initialized Type
+	switch receiver.Kind {
+	case UninitializedThis:
+		if class != in.method.class && class != in.method.super {
+			return mismatch("constructor of the wrong class", fmt.Sprintf("%s or %s", in.method.class, in.method.super), class)
+		}
+		initialized = ReferenceType(in.method.class)
+	case Uninitialized:
+		index, ok := in.code.InstructionIndex(receiver.Offset)
+		created, isNew := classfileparser.Instruction(nil), false
+		if ok {
+			created = in.code.Code[index]
+			_, isNew = created.(classfileparser.New)
+		}
+		if !isNew {
+			return mismatch("uninitialized type does not refer to a new instruction", "new", receiver)
+		}
+		if createdClass := created.(classfileparser.New).Class; createdClass != class {
+			return mismatch("constructor of the wrong class", createdClass, class)
+		}
+		initialized = ReferenceType(class)
+	default:
+		return mismatch("operand type mismatch", "an uninitialized object", receiver)
+	}
+	for i, t := range in.frame.Locals {
+		if t == receiver {
+			in.frame.Locals[i] = initialized
+		}
+	}
+	for i, t := range in.frame.Stack {
+		if t == receiver {
+			in.frame.Stack[i] = initialized
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/cfg"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// StackErrorKind tells what is wrong with the operand stack
+type StackErrorKind uint8
+
+const (
+	// Underflow is an instruction popping more slots than the stack holds.
+	Underflow StackErrorKind = iota
+	// InconsistentDepth is an instruction reached with different stack depths along different paths.
+	InconsistentDepth
+	// MaxStackTooSmall is a stack deeper than the declared max_stack.
+	MaxStackTooSmall
+	// MaxLocalsTooSmall is a local variable beyond the declared max_locals.
+	MaxLocalsTooSmall
+)
+
+// StackError reports an operand stack or local variable problem at an instruction
+type StackError struct {
+	Kind     StackErrorKind
+	Pc       int
+	Mnemonic string
+	Actual   int // Depth or slots found at Pc
+	Expected int // Slots popped for Underflow, depth along another path for InconsistentDepth, declared maximum otherwise
+}
+
+func (e *StackError) Error() string {
+	at := fmt.Sprintf("pc %d (%s)", e.Pc, e.Mnemonic)
+	switch e.Kind {
+	case Underflow:
+		return fmt.Sprintf("%s: stack underflow, pops %d slots from a stack of %d", at, e.Expected, e.Actual)
+	case InconsistentDepth:
+		return fmt.Sprintf("%s: stack depth %d differs from depth %d along another path", at, e.Actual, e.Expected)
+	case MaxStackTooSmall:
+		return fmt.Sprintf("%s: stack depth %d exceeds max_stack %d", at, e.Actual, e.Expected)
+	default:
+		return fmt.Sprintf("%s: %d local variable slots exceed max_locals %d", at, e.Actual, e.Expected)
+	}
+}
+
+// StackDepths holds the result of simulating the operand stack of a method
+type StackDepths struct {
+	Before   map[int]int // Stack depth before each reachable instruction, by pc
+	MaxStack int         // Deepest stack reached, in slots
+	MaxPc    int         // Pc of the first instruction leaving the stack MaxStack deep
+}
+
+// SimulateStack computes the operand stack depth before every instruction of code reachable
+// from its start, following the control-flow graph. Exception handlers start with the thrown
+// exception on the stack, and jsr pushes its return address. A *StackError is returned for an
+// underflow or for an instruction reached with inconsistent depths.
+func SimulateStack(code classfileparser.Code) (*StackDepths, error) {
+	graph, err := cfg.New(code)
+	if err != nil {
+		return nil, err
+	}
+	return simulateStack(graph)
+}
+
+func simulateStack(graph *cfg.Graph) (*StackDepths, error) {
+	result := &StackDepths{Before: map[int]int{}}
+	entry := map[*cfg.Block]int{}
+	var worklist []*cfg.Block
+	reach := func(block *cfg.Block, depth int) error {
+		if block.Kind != cfg.CodeBlock {
+			return nil
+		}
+		if previous, ok := entry[block]; ok {
+			if previous != depth {
+				first := block.Instructions[0]
+				return &StackError{Kind: InconsistentDepth, Pc: first.PC(), Mnemonic: first.Mnemonic(), Actual: depth, Expected: previous}
+			}
+			return nil
+		}
+		entry[block] = depth
+		worklist = append(worklist, block)
+		return nil
+	}
+	record := func(instruction classfileparser.Instruction, depth int) {
+		if depth > result.MaxStack {
+			result.MaxStack = depth
+			result.MaxPc = instruction.PC()
+		}
+	}
+
+	if err := reach(graph.Blocks[1], 0); err != nil {
+		return nil, err
+	}
+	for len(worklist) > 0 {
+		block := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		depth := entry[block]
+		for _, instruction := range block.Instructions {
+			result.Before[instruction.PC()] = depth
+			effect, err := StackEffect(instruction)
+			if err != nil {
+				return nil, fmt.Errorf("pc %d (%s): %w", instruction.PC(), instruction.Mnemonic(), err)
+			}
+			if depth < effect.Pop {
+				return nil, &StackError{Kind: Underflow, Pc: instruction.PC(), Mnemonic: instruction.Mnemonic(), Actual: depth, Expected: effect.Pop}
+			}
+			depth += effect.Delta()
+			record(instruction, depth)
+		}
+
+		for _, edge := range block.Succs {
+			var err error
+			if edge.Kind == cfg.Exception {
+				// The handler starts with the exception alone on the stack
+				record(edge.To.Instructions[0], 1)
+				err = reach(edge.To, 1)
+			} else {
+				err = reach(edge.To, depth)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// MaxLocals returns the number of local variable slots code needs: those of the parameters,
+// this included for instance methods, and those its instructions read or write
+func MaxLocals(code classfileparser.Code, method descriptor.Method, static bool) int {
+	slots := method.ArgumentSlots()
+	if !static {
+		slots++
+	}
+	for _, instruction := range code.Code {
+		if used := LocalSlots(instruction); used > slots {
+			slots = used
+		}
+	}
+	return slots
+}
+
+// codeOf returns the Code attribute of method and its position in the attributes
+func codeOf(method *classfileparser.Method) (classfileparser.Code, int, error) {
+	for i, attribute := range method.Attributes {
+		if code, ok := attribute.(classfileparser.Code); ok {
+			return code, i, nil
+		}
+	}
+	return classfileparser.Code{}, 0, errors.New("method has no Code attribute")
+}
+
+// CheckMaxs simulates the operand stack of method and checks its declared MaxStack and
+// MaxLocals, returning a *StackError for the first problem found
+func CheckMaxs(method *classfileparser.Method) error {
+	code, _, err := codeOf(method)
+	if err != nil {
+		return err
+	}
+	depths, err := SimulateStack(code)
+	if err != nil {
+		return err
+	}
+	if depths.MaxStack > int(code.MaxStack) {
+		index, _ := code.InstructionIndex(depths.MaxPc)
+		return &StackError{Kind: MaxStackTooSmall, Pc: depths.MaxPc, Mnemonic: code.Code[index].Mnemonic(), Actual: depths.MaxStack, Expected: int(code.MaxStack)}
+	}
+
+	parameters := method.Descriptor.ArgumentSlots()
+	if !method.Access.Has(classfileparser.MethodStatic) {
+		parameters++
+	}
+	if parameters > int(code.MaxLocals) {
+		first := code.Code[0]
+		return &StackError{Kind: MaxLocalsTooSmall, Pc: first.PC(), Mnemonic: first.Mnemonic(), Actual: parameters, Expected: int(code.MaxLocals)}
+	}
+	for _, instruction := range code.Code {
+		if used := LocalSlots(instruction); used > int(code.MaxLocals) {
+			return &StackError{Kind: MaxLocalsTooSmall, Pc: instruction.PC(), Mnemonic: instruction.Mnemonic(), Actual: used, Expected: int(code.MaxLocals)}
+		}
+	}
+	return nil
+}
+
+// ComputeMaxs sets the MaxStack and MaxLocals of the Code attribute of method to the values its
+// instructions need, for instance after they were edited
+func ComputeMaxs(method *classfileparser.Method) error {
+	code, index, err := codeOf(method)
+	if err != nil {
+		return err
+	}
+	depths, err := SimulateStack(code)
+	if err != nil {
+		return err
+	}
+	maxLocals := MaxLocals(code, method.Descriptor, method.Access.Has(classfileparser.MethodStatic))
+	if depths.MaxStack > 0xFFFF || maxLocals > 0xFFFF {
+		return fmt.Errorf("max_stack %d or max_locals %d does not fit in 16 bits", depths.MaxStack, maxLocals)
+	}
+	code.MaxStack = uint16(depths.MaxStack)
+	code.MaxLocals = uint16(maxLocals)
+	method.Attributes[index] = code
+	return nil
+}
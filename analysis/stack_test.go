@@ -0,0 +1,288 @@
+package analysis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+func at(pc int) cf.Location { return cf.Location{Pc: pc} }
+
+func TestStackEffect(t *testing.T) {
+	tests := []struct {
+		instruction cf.Instruction
+		want        Effect
+	}{
+		{cf.DupX2{Location: at(0)}, Effect{3, 4}},
+		{cf.Dup2X2{Location: at(0)}, Effect{4, 6}},
+		{cf.Lconst0{Location: at(0)}, Effect{0, 2}},
+		{cf.Lload3{Location: at(0)}, Effect{0, 2}},
+		{cf.Dstore2{Location: at(0)}, Effect{2, 0}},
+		{cf.Ladd{Location: at(0)}, Effect{4, 2}},
+		{cf.Lreturn{Location: at(0)}, Effect{2, 0}},
+		{cf.Jsr{Location: at(0)}, Effect{0, 1}},
+		{cf.Wide{Location: at(0), OpCode: 0x16, LocalIndex: 300}, Effect{0, 2}},
+		{cf.Wide{Location: at(0), OpCode: 0x39, LocalIndex: 300}, Effect{2, 0}},
+		{cf.Wide{Location: at(0), OpCode: 0x84, LocalIndex: 300, Const: -1000}, Effect{0, 0}},
+		{cf.Getfield{Location: at(0), Type: "J"}, Effect{1, 2}},
+		{cf.Putfield{Location: at(0), Type: "D"}, Effect{3, 0}},
+		{cf.Getstatic{Location: at(0), Type: "[J"}, Effect{0, 1}},
+		{cf.Putstatic{Location: at(0), Type: "Ljava/lang/String;"}, Effect{1, 0}},
+		{cf.Invokevirtual{Location: at(0), Type: "(JLjava/lang/String;D)J"}, Effect{6, 2}},
+		{cf.Invokespecial{Location: at(0), Type: "()V"}, Effect{1, 0}},
+		{cf.Invokestatic{Location: at(0), Type: "(I[JD)D"}, Effect{4, 2}},
+		{cf.Invokeinterface{Location: at(0), InterfaceMethodref: cf.InterfaceMethodref{Type: "(JJ)Z"}, Count: 5}, Effect{5, 1}},
+		{cf.Invokedynamic{Location: at(0), InvokeDynamic: cf.InvokeDynamic{Type: "(ID)Ljava/lang/Runnable;"}}, Effect{3, 1}},
+		{cf.Multianewarray{Location: at(0), Class: "[[[I", Dimension: 2}, Effect{2, 1}},
+	}
+	for _, test := range tests {
+		got, err := StackEffect(test.instruction)
+		if err != nil || got != test.want {
+			t.Errorf("StackEffect(%s) = %v, %v, want %v", test.instruction.Mnemonic(), got, err, test.want)
+		}
+	}
+
+	for _, instruction := range []cf.Instruction{
+		cf.Wide{Location: at(0), OpCode: 0x60},
+		cf.Invokestatic{Location: at(0), Type: "(X)V"},
+		cf.Getfield{Location: at(0), Type: "V"},
+	} {
+		if _, err := StackEffect(instruction); err == nil {
+			t.Errorf("StackEffect(%#v) has no error", instruction)
+		}
+	}
+}
+
+func TestLocalSlots(t *testing.T) {
+	tests := []struct {
+		instruction cf.Instruction
+		want        int
+	}{
+		{cf.Nop{Location: at(0)}, 0},
+		{cf.Aload0{Location: at(0)}, 1},
+		{cf.Lload3{Location: at(0)}, 5},
+		{cf.Istore1{Location: at(0)}, 2},
+		{cf.Dstore2{Location: at(0)}, 4},
+		{cf.Lstore{Location: at(0), LocalIndex: 10}, 12},
+		{cf.Astore{Location: at(0), LocalIndex: 10}, 11},
+		{cf.Iinc{Location: at(0), LocalIndex: 7, Const: 1}, 8},
+		{cf.Wide{Location: at(0), OpCode: 0x39, LocalIndex: 300}, 302},
+		{cf.Wide{Location: at(0), OpCode: 0x84, LocalIndex: 300}, 301},
+	}
+	for _, test := range tests {
+		if got := LocalSlots(test.instruction); got != test.want {
+			t.Errorf("LocalSlots(%s) = %d, want %d", test.instruction.Mnemonic(), got, test.want)
+		}
+	}
+}
+
+func TestSimulateStack(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     cf.Code
+		before   map[int]int
+		maxStack int
+		maxPc    int
+	}{
+		{"dup_x2", cf.Code{Code: []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Iconst1{Location: at(1)},
+			cf.Iconst2{Location: at(2)},
+			cf.DupX2{Location: at(3)},
+			cf.Pop{Location: at(4)},
+			cf.Pop2{Location: at(5)},
+			cf.Pop{Location: at(6)},
+			cf.Return{Location: at(7)},
+		}}, map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 4: 4, 5: 3, 6: 1, 7: 0}, 4, 3},
+		{"dup2_x2 of longs", cf.Code{Code: []cf.Instruction{
+			cf.Lconst0{Location: at(0)},
+			cf.Lconst1{Location: at(1)},
+			cf.Dup2X2{Location: at(2)},
+			cf.Pop2{Location: at(3)},
+			cf.Pop2{Location: at(4)},
+			cf.Pop2{Location: at(5)},
+			cf.Return{Location: at(6)},
+		}}, map[int]int{0: 0, 1: 2, 2: 4, 3: 6, 4: 4, 5: 2, 6: 0}, 6, 2},
+		{"wide", cf.Code{Code: []cf.Instruction{
+			cf.Wide{Location: at(0), OpCode: 0x16, LocalIndex: 300},
+			cf.Wide{Location: at(4), OpCode: 0x39, LocalIndex: 302},
+			cf.Wide{Location: at(8), OpCode: 0x84, LocalIndex: 300, Const: 1000},
+			cf.Return{Location: at(14)},
+		}}, map[int]int{0: 0, 4: 2, 8: 0, 14: 0}, 2, 0},
+		{"invoke argument slots", cf.Code{Code: []cf.Instruction{
+			cf.Aload0{Location: at(0)},
+			cf.Lconst0{Location: at(1)},
+			cf.AconstNull{Location: at(2)},
+			cf.Dconst1{Location: at(3)},
+			cf.Invokevirtual{Location: at(4), Class: "A", Name: "m", Type: "(JLjava/lang/String;D)J"},
+			cf.Invokestatic{Location: at(7), Class: "A", Name: "s", Type: "(J)V"},
+			cf.Iconst0{Location: at(10)},
+			cf.Invokedynamic{Location: at(11), InvokeDynamic: cf.InvokeDynamic{Name: "get", Type: "(I)LI;"}},
+			cf.Invokeinterface{Location: at(16), InterfaceMethodref: cf.InterfaceMethodref{Class: "I", Name: "size", Type: "()I"}, Count: 1},
+			cf.Pop{Location: at(21)},
+			cf.Return{Location: at(22)},
+		}}, map[int]int{0: 0, 1: 1, 2: 3, 3: 4, 4: 6, 7: 2, 10: 0, 11: 1, 16: 1, 21: 1, 22: 0}, 6, 3},
+		{"exception handler", cf.Code{
+			Code: []cf.Instruction{
+				cf.Nop{Location: at(0)},
+				cf.Return{Location: at(1)},
+				cf.Pop{Location: at(2)},
+				cf.Return{Location: at(3)},
+			},
+			ExceptionTable: []cf.ExceptionTableEntry{{StartPc: 0, EndPc: 1, HandlerPc: 2}},
+		}, map[int]int{0: 0, 1: 0, 2: 1, 3: 0}, 1, 2},
+		{"jsr", cf.Code{Code: []cf.Instruction{
+			cf.Jsr{Location: at(0), Offset: 4},
+			cf.Return{Location: at(3)},
+			cf.Astore1{Location: at(4)},
+			cf.Ret{Location: at(5), LocalIndex: 1},
+		}}, map[int]int{0: 0, 3: 0, 4: 1, 5: 0}, 1, 0},
+		{"unreachable code", cf.Code{Code: []cf.Instruction{
+			cf.Return{Location: at(0)},
+			cf.Pop{Location: at(1)},
+			cf.Return{Location: at(2)},
+		}}, map[int]int{0: 0}, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			depths, err := SimulateStack(test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(depths.Before, test.before) {
+				t.Errorf("Before = %v, want %v", depths.Before, test.before)
+			}
+			if depths.MaxStack != test.maxStack || depths.MaxPc != test.maxPc {
+				t.Errorf("MaxStack = %d at pc %d, want %d at pc %d", depths.MaxStack, depths.MaxPc, test.maxStack, test.maxPc)
+			}
+		})
+	}
+}
+
+func TestSimulateStackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code cf.Code
+		want *StackError // nil for errors other than a *StackError
+		err  string
+	}{
+		{"underflow", cf.Code{Code: []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Pop2{Location: at(1)},
+			cf.Return{Location: at(2)},
+		}}, &StackError{Kind: Underflow, Pc: 1, Mnemonic: "pop2", Actual: 1, Expected: 2}, "pc 1 (pop2): stack underflow, pops 2 slots from a stack of 1"},
+		{"inconsistent depth", cf.Code{Code: []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Ifeq{Location: at(1), Offset: 5},
+			cf.Iconst1{Location: at(4)},
+			cf.Nop{Location: at(5)},
+			cf.Return{Location: at(6)},
+		}}, &StackError{Kind: InconsistentDepth, Pc: 6, Mnemonic: "return", Actual: 1, Expected: 0}, "pc 6 (return): stack depth 1 differs from depth 0 along another path"},
+		{"invalid descriptor", cf.Code{Code: []cf.Instruction{
+			cf.Invokestatic{Location: at(0), Type: "(X)V"},
+			cf.Return{Location: at(3)},
+		}}, nil, "pc 0 (invokestatic)"},
+		{"empty code", cf.Code{}, nil, "empty code"},
+	}
+	for _, test := range tests {
+		_, err := SimulateStack(test.code)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+			continue
+		}
+		var stackErr *StackError
+		if errors.As(err, &stackErr) != (test.want != nil) || test.want != nil && *stackErr != *test.want {
+			t.Errorf("%s: error = %#v, want %#v", test.name, err, test.want)
+		}
+	}
+}
+
+// maxsMethod returns a method with descriptor whose code uses 2 stack slots and 6 local slots
+func maxsMethod(access cf.MethodAccess, method string, maxStack, maxLocals uint16) *cf.Method {
+	return &cf.Method{
+		Access:     access,
+		Type:       method,
+		Descriptor: descriptor.MustParseMethod(method),
+		Attributes: []cf.Attribute{cf.Code{MaxStack: maxStack, MaxLocals: maxLocals, Code: []cf.Instruction{
+			cf.Lload0{Location: at(0)},
+			cf.Pop2{Location: at(1)},
+			cf.Iload2{Location: at(2)},
+			cf.Istore{Location: at(3), LocalIndex: 5},
+			cf.Return{Location: at(5)},
+		}}},
+	}
+}
+
+func TestCheckMaxs(t *testing.T) {
+	tests := []struct {
+		name   string
+		method *cf.Method
+		want   *StackError
+	}{
+		{"valid", maxsMethod(cf.MethodStatic, "(JI)V", 2, 6), nil},
+		{"larger than needed", maxsMethod(cf.MethodStatic, "(JI)V", 10, 10), nil},
+		{"max_stack too small", maxsMethod(cf.MethodStatic, "(JI)V", 1, 6),
+			&StackError{Kind: MaxStackTooSmall, Pc: 0, Mnemonic: "lload_0", Actual: 2, Expected: 1}},
+		{"parameters beyond max_locals", maxsMethod(cf.MethodStatic, "(JI)V", 2, 2),
+			&StackError{Kind: MaxLocalsTooSmall, Pc: 0, Mnemonic: "lload_0", Actual: 3, Expected: 2}},
+		{"this beyond max_locals", maxsMethod(cf.MethodPublic, "(JI)V", 2, 3),
+			&StackError{Kind: MaxLocalsTooSmall, Pc: 0, Mnemonic: "lload_0", Actual: 4, Expected: 3}},
+		{"local beyond max_locals", maxsMethod(cf.MethodStatic, "(JI)V", 2, 5),
+			&StackError{Kind: MaxLocalsTooSmall, Pc: 3, Mnemonic: "istore", Actual: 6, Expected: 5}},
+	}
+	for _, test := range tests {
+		err := CheckMaxs(test.method)
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		var stackErr *StackError
+		if !errors.As(err, &stackErr) || *stackErr != *test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+	if err := CheckMaxs(&cf.Method{Name: "abstract"}); err == nil {
+		t.Error("a method without code is checked")
+	}
+	want := "pc 0 (lload_0): stack depth 2 exceeds max_stack 1"
+	if err := CheckMaxs(maxsMethod(cf.MethodStatic, "(JI)V", 1, 6)); err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestComputeMaxs(t *testing.T) {
+	tests := []struct {
+		name                string
+		method              *cf.Method
+		maxStack, maxLocals uint16
+	}{
+		{"static", maxsMethod(cf.MethodStatic, "(JI)V", 0, 0), 2, 6},
+		{"too large", maxsMethod(cf.MethodStatic, "(JI)V", 100, 100), 2, 6},
+		// The parameters need more slots than the instructions
+		{"instance", maxsMethod(cf.MethodPublic, "(JIDJ)V", 0, 0), 2, 8},
+	}
+	for _, test := range tests {
+		if err := ComputeMaxs(test.method); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		code := test.method.Attributes[0].(cf.Code)
+		if code.MaxStack != test.maxStack || code.MaxLocals != test.maxLocals {
+			t.Errorf("%s: max_stack %d, max_locals %d, want %d and %d", test.name, code.MaxStack, code.MaxLocals, test.maxStack, test.maxLocals)
+		}
+		if err := CheckMaxs(test.method); err != nil {
+			t.Errorf("%s: computed maxs do not check: %v", test.name, err)
+		}
+	}
+
+	method := maxsMethod(cf.MethodStatic, "()V", 0, 0)
+	method.Attributes[0] = cf.Code{Code: []cf.Instruction{cf.Pop{Location: at(0)}}}
+	if err := ComputeMaxs(method); err == nil {
+		t.Error("maxs are computed for code that underflows")
+	}
+}
@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// TypeKind is the kind of a verification type (JVMS §4.10.1.2)
+type TypeKind uint8
+
+const (
+	// Top is an unusable value, such as an unassigned local or the second slot of a long or double.
+	Top TypeKind = iota
+	// Integer is an int, or a boolean, byte, char or short widened to int.
+	Integer
+	// Float is a float.
+	Float
+	// Long is a long, taking two local variable slots.
+	Long
+	// Double is a double, taking two local variable slots.
+	Double
+	// Null is the type of null, assignable to every reference type.
+	Null
+	// UninitializedThis is this in a constructor, before the super or this constructor is called.
+	UninitializedThis
+	// Uninitialized is an object created by the new instruction at Offset, not yet initialized.
+	Uninitialized
+	// Reference is an initialized class, interface or array type.
+	Reference
+)
+
+// Type is a verification type
+type Type struct {
+	Kind   TypeKind
+	Name   string // Reference: internal name of a class or interface, or descriptor of an array. Empty for any reference.
+	Offset int    // Uninitialized: pc of the new instruction
+}
+
+// Common verification types
+var (
+	TopType       = Type{Kind: Top}
+	IntType       = Type{Kind: Integer}
+	FloatType     = Type{Kind: Float}
+	LongType      = Type{Kind: Long}
+	DoubleType    = Type{Kind: Double}
+	NullType      = Type{Kind: Null}
+	ObjectType    = Type{Kind: Reference, Name: "java/lang/Object"}
+	StringType    = Type{Kind: Reference, Name: "java/lang/String"}
+	ThrowableType = Type{Kind: Reference, Name: "java/lang/Throwable"}
+)
+
+// ReferenceType returns the type of the class or array named as in a CONSTANT_Class entry
+func ReferenceType(name string) Type {
+	return Type{Kind: Reference, Name: name}
+}
+
+// Size returns the number of slots taken by a value of the type: 2 for long and double, 1 otherwise
+func (t Type) Size() int {
+	if t.Kind == Long || t.Kind == Double {
+		return 2
+	}
+	return 1
+}
+
+// IsReference reports whether the type is a reference: null, uninitialized or initialized
+func (t Type) IsReference() bool {
+	return t.Kind >= Null
+}
+
+// IsArray reports whether the type is an initialized array type
+func (t Type) IsArray() bool {
+	return t.Kind == Reference && strings.HasPrefix(t.Name, "[")
+}
+
+// Component returns the type of the elements of an array type
+func (t Type) Component() (Type, bool) {
+	if !t.IsArray() {
+		return Type{}, false
+	}
+	field, err := descriptor.ParseField(t.Name[1:])
+	if err != nil {
+		return Type{}, false
+	}
+	return FieldType(field), true
+}
+
+// String renders the type the way javap renders verification types (e.g. int, class java/lang/String)
+func (t Type) String() string {
+	switch t.Kind {
+	case Top:
+		return "top"
+	case Integer:
+		return "int"
+	case Float:
+		return "float"
+	case Long:
+		return "long"
+	case Double:
+		return "double"
+	case Null:
+		return "null"
+	case UninitializedThis:
+		return "uninitialized this"
+	case Uninitialized:
+		return fmt.Sprintf("uninitialized %d", t.Offset)
+	case Reference:
+		if t.Name == "" {
+			return "reference"
+		}
+		return "class " + t.Name
+	default:
+		return fmt.Sprintf("Type(%d)", t.Kind)
+	}
+}
+
+// FieldType returns the verification type of values of a field or parameter type: boolean,
+// byte, char and short are widened to int
+func FieldType(t descriptor.Type) Type {
+	switch t := t.(type) {
+	case descriptor.Base:
+		switch t {
+		case descriptor.Float:
+			return FloatType
+		case descriptor.Long:
+			return LongType
+		case descriptor.Double:
+			return DoubleType
+		case descriptor.Void:
+			return TopType
+		default:
+			return IntType
+		}
+	case descriptor.Object:
+		return ReferenceType(t.Name)
+	default:
+		return ReferenceType(t.Descriptor())
+	}
+}
+
+// itemType converts a verification_type_info of a StackMapTable frame
+func itemType(item classfileparser.StackItem) Type {
+	switch item.Tag {
+	case classfileparser.ItemInteger:
+		return IntType
+	case classfileparser.ItemFloat:
+		return FloatType
+	case classfileparser.ItemDouble:
+		return DoubleType
+	case classfileparser.ItemLong:
+		return LongType
+	case classfileparser.ItemNull:
+		return NullType
+	case classfileparser.ItemUninitializedThis:
+		return Type{Kind: UninitializedThis}
+	case classfileparser.ItemObject:
+		return ReferenceType(item.Class)
+	case classfileparser.ItemUninitialized:
+		return Type{Kind: Uninitialized, Offset: int(item.Offset)}
+	default:
+		return TopType
+	}
+}
+
+// stackItem converts a verification type into a verification_type_info of a StackMapTable frame
+func stackItem(t Type) classfileparser.StackItem {
+	switch t.Kind {
+	case Integer:
+		return classfileparser.StackItem{Tag: classfileparser.ItemInteger}
+	case Float:
+		return classfileparser.StackItem{Tag: classfileparser.ItemFloat}
+	case Long:
+		return classfileparser.StackItem{Tag: classfileparser.ItemLong}
+	case Double:
+		return classfileparser.StackItem{Tag: classfileparser.ItemDouble}
+	case Null:
+		return classfileparser.StackItem{Tag: classfileparser.ItemNull}
+	case UninitializedThis:
+		return classfileparser.StackItem{Tag: classfileparser.ItemUninitializedThis}
+	case Uninitialized:
+		return classfileparser.StackItem{Tag: classfileparser.ItemUninitialized, Offset: uint16(t.Offset)}
+	case Reference:
+		return classfileparser.StackItem{Tag: classfileparser.ItemObject, Class: t.Name}
+	default:
+		return classfileparser.StackItem{Tag: classfileparser.ItemTop}
+	}
+}
+
+// Frame is the type state before an instruction
+type Frame struct {
+	Locals []Type // One type per local variable slot, a long or double being followed by Top
+	Stack  []Type // One type per operand stack value, from bottom to top
+}
+
+// StackSize returns the depth of the operand stack in slots
+func (f Frame) StackSize() int {
+	size := 0
+	for _, t := range f.Stack {
+		size += t.Size()
+	}
+	return size
+}
+
+func (f Frame) clone() Frame {
+	return Frame{Locals: append([]Type(nil), f.Locals...), Stack: append([]Type(nil), f.Stack...)}
+}
+
+// String renders the frame as its locals and stack, e.g. locals [int, top] stack [long]
+func (f Frame) String() string {
+	return fmt.Sprintf("locals %v stack %v", f.Locals, f.Stack)
+}
+
+// localValues returns the locals of the frame as the values listed in a StackMapTable frame: a
+// long or double stands for its two slots, and trailing Top slots are left out
+func (f Frame) localValues() []Type {
+	var values []Type
+	for i := 0; i < len(f.Locals); i++ {
+		values = append(values, f.Locals[i])
+		if f.Locals[i].Size() == 2 {
+			i++
+		}
+	}
+	for len(values) > 0 && values[len(values)-1].Kind == Top {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+// slots expands the local values of a StackMapTable frame into maxLocals slots
+func slots(values []Type, maxLocals int) ([]Type, error) {
+	locals := make([]Type, 0, maxLocals)
+	for _, value := range values {
+		locals = append(locals, value)
+		if value.Size() == 2 {
+			locals = append(locals, TopType)
+		}
+	}
+	if len(locals) > maxLocals {
+		return nil, fmt.Errorf("%d local variable slots exceed max_locals %d", len(locals), maxLocals)
+	}
+	for len(locals) < maxLocals {
+		locals = append(locals, TopType)
+	}
+	return locals, nil
+}
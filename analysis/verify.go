@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// Diagnostic is a verification failure at an instruction
+type Diagnostic struct {
+	Pc       int
+	Mnemonic string
+	Message  string
+	Expected string // Expected type or frame, empty when the failure is not a mismatch
+	Actual   string // Type or frame found instead
+}
+
+func (d Diagnostic) String() string {
+	if d.Expected == "" {
+		return fmt.Sprintf("pc %d (%s): %s", d.Pc, d.Mnemonic, d.Message)
+	}
+	return fmt.Sprintf("pc %d (%s): %s: expected %s, found %s", d.Pc, d.Mnemonic, d.Message, d.Expected, d.Actual)
+}
+
+// Verification is the outcome of Verify
+type Verification struct {
+	Frames      map[int]Frame // Type state before each instruction that could be checked, by pc
+	Diagnostics []Diagnostic  // Failures, sorted by pc
+}
+
+// OK reports whether the method passed verification
+func (v *Verification) OK() bool { return len(v.Diagnostics) == 0 }
+
+// Verify type-checks the code of method, declared by class, against its StackMapTable as the
+// type checking verifier of JVMS §4.10.1 does. Instructions are checked in order: the type
+// state before each one is inferred from the previous instruction or taken from the frame
+// declared at its pc, and every branch target and exception handler must have a declared frame
+// the current state is assignable to. After a failure, checking resumes at the next declared
+// frame, so that several diagnostics can be reported.
+//
+// Reference types are compared through h, with interfaces treated like java/lang/Object; when
+// h is nil, any reference is accepted where a class or array is expected. Protected member
+// access is not checked, and jsr and ret, which the type checker rejects, are reported.
+// The error is reserved for methods that cannot be checked at all, such as a method without
+// code or a malformed StackMapTable.
+func Verify(class *classfileparser.ClassStruct, m *classfileparser.Method, h Hierarchy) (*Verification, error) {
+	code, _, err := codeOf(m)
+	if err != nil {
+		return nil, err
+	}
+	in, err := newInterpreter(class, m, code)
+	if err != nil {
+		return nil, err
+	}
+	in.hierarchy = h
+	initial := in.frame.clone()
+	declared, err := declaredFrames(code, initial)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StackMapTable: %w", err)
+	}
+
+	result := &Verification{Frames: map[int]Frame{}}
+	report := func(instruction classfileparser.Instruction, err error) {
+		diagnostic := Diagnostic{Pc: instruction.PC(), Mnemonic: instruction.Mnemonic(), Message: err.Error()}
+		var typeErr *typeError
+		if errors.As(err, &typeErr) {
+			diagnostic.Message, diagnostic.Expected, diagnostic.Actual = typeErr.message, typeErr.expected, typeErr.actual
+		}
+		result.Diagnostics = append(result.Diagnostics, diagnostic)
+	}
+
+	reachable := true
+	for _, instruction := range code.Code {
+		pc := instruction.PC()
+		if frame, ok := declared[pc]; ok {
+			if reachable {
+				if err := in.matches(in.frame, frame); err != nil {
+					report(instruction, err)
+				}
+			}
+			in.frame = frame.clone()
+			reachable = true
+		} else if !reachable {
+			continue
+		}
+		result.Frames[pc] = in.frame.clone()
+
+		if err := in.check(instruction, declared); err != nil {
+			report(instruction, err)
+			reachable = false
+			continue
+		}
+		reachable = !endsFlow(instruction)
+	}
+	if reachable {
+		last := code.Code[len(code.Code)-1]
+		report(last, errors.New("execution falls off the end of the code"))
+	}
+	return result, nil
+}
+
+// check executes instruction on the current frame and checks the frames of its exception
+// handlers and branch targets
+func (in *interpreter) check(instruction classfileparser.Instruction, declared map[int]Frame) error {
+	pc := instruction.PC()
+	for _, entry := range in.code.ExceptionTable {
+		if pc < int(entry.StartPc) || pc >= int(entry.EndPc) {
+			continue
+		}
+		handler, ok := declared[int(entry.HandlerPc)]
+		if !ok {
+			return fmt.Errorf("exception handler at pc %d has no stack map frame", entry.HandlerPc)
+		}
+		caught := ThrowableType
+		if entry.CatchClass != "" {
+			caught = ReferenceType(entry.CatchClass)
+		}
+		if err := in.matches(Frame{Locals: in.frame.Locals, Stack: []Type{caught}}, handler); err != nil {
+			return fmt.Errorf("exception handler at pc %d: %w", entry.HandlerPc, err)
+		}
+	}
+
+	if err := in.execute(instruction); err != nil {
+		return err
+	}
+	for _, target := range branchTargets(instruction) {
+		frame, ok := declared[target]
+		if !ok {
+			return fmt.Errorf("branch target %d has no stack map frame", target)
+		}
+		if err := in.matches(in.frame, frame); err != nil {
+			return fmt.Errorf("branch target %d: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// matches checks that the type state current is assignable to the declared frame
+func (in *interpreter) matches(current, declared Frame) error {
+	if len(current.Stack) != len(declared.Stack) {
+		return mismatch("stack map frame mismatch", declared, current)
+	}
+	for i, t := range declared.Locals {
+		if i >= len(current.Locals) || !in.assignable(current.Locals[i], t) {
+			return mismatch(fmt.Sprintf("local variable %d does not match the stack map frame", i), t, current.Locals[i])
+		}
+	}
+	for i, t := range declared.Stack {
+		if !in.assignable(current.Stack[i], t) {
+			return mismatch(fmt.Sprintf("stack value %d does not match the stack map frame", i), t, current.Stack[i])
+		}
+	}
+	return nil
+}
+
+// declaredFrames expands the StackMapTable of code into full frames by pc, starting from the
+// initial frame of the method
+func declaredFrames(code classfileparser.Code, initial Frame) (map[int]Frame, error) {
+	frames := map[int]Frame{}
+	var table classfileparser.StackMapTable
+	for _, attribute := range code.Attributes {
+		if t, ok := attribute.(classfileparser.StackMapTable); ok {
+			table = t
+		}
+	}
+
+	values := initial.localValues()
+	for i, entry := range table.Entries {
+		if _, ok := code.InstructionIndex(int(entry.Pc)); !ok {
+			return nil, fmt.Errorf("frame %d at pc %d is not at an instruction", i, entry.Pc)
+		}
+		var stack []Type
+		switch entry.Kind() {
+		case classfileparser.SameLocals1StackItemFrame, classfileparser.SameLocals1StackItemFrameExtended:
+			stack = stackTypes(entry.StackItems)
+		case classfileparser.ChopFrame:
+			if int(entry.Chopped) > len(values) {
+				return nil, fmt.Errorf("frame %d chops %d locals from %d", i, entry.Chopped, len(values))
+			}
+			values = values[:len(values)-int(entry.Chopped)]
+		case classfileparser.AppendFrame:
+			values = append(append([]Type(nil), values...), stackTypes(entry.Locals)...)
+		case classfileparser.FullFrame:
+			values = stackTypes(entry.Locals)
+			stack = stackTypes(entry.StackItems)
+		}
+		locals, err := slots(values, int(code.MaxLocals))
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		frames[int(entry.Pc)] = Frame{Locals: locals, Stack: stack}
+	}
+	return frames, nil
+}
+
+func stackTypes(items []classfileparser.StackItem) []Type {
+	types := make([]Type, len(items))
+	for i, item := range items {
+		types[i] = itemType(item)
+	}
+	return types
+}
+
+// branchTargets returns the pcs an instruction may jump to, besides the next instruction
+func branchTargets(instruction classfileparser.Instruction) []int {
+	switch instruction := instruction.(type) {
+	case classfileparser.Branch:
+		return []int{instruction.Target()}
+	case classfileparser.Tableswitch:
+		targets := []int{instruction.Pc + int(instruction.DefaultOffset)}
+		for _, offset := range instruction.Offsets {
+			targets = append(targets, instruction.Pc+int(offset))
+		}
+		return targets
+	case classfileparser.Lookupswitch:
+		targets := []int{instruction.Pc + int(instruction.DefaultOffset)}
+		for _, pair := range instruction.Pairs {
+			targets = append(targets, instruction.Pc+int(pair.Offset))
+		}
+		return targets
+	}
+	return nil
+}
+
+// endsFlow reports whether control never continues with the instruction following instruction
+func endsFlow(instruction classfileparser.Instruction) bool {
+	switch instruction.(type) {
+	case classfileparser.Goto, classfileparser.GotoW, classfileparser.Tableswitch, classfileparser.Lookupswitch,
+		classfileparser.Ireturn, classfileparser.Lreturn, classfileparser.Freturn, classfileparser.Dreturn,
+		classfileparser.Areturn, classfileparser.Return, classfileparser.Athrow:
+		return true
+	}
+	return false
+}
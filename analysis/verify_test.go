@@ -0,0 +1,275 @@
+package analysis
+
+import (
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// testMethod is the code of a static method of com/x/T
+type testMethod struct {
+	descriptor          string
+	maxStack, maxLocals uint16
+	code                []cf.Instruction
+	exceptions          []cf.ExceptionTableEntry
+	frames              []cf.StackMapFrame // StackMapTable entries, with Pc set
+}
+
+func (m testMethod) build() (*cf.ClassStruct, *cf.Method) {
+	code := cf.Code{MaxStack: m.maxStack, MaxLocals: m.maxLocals, Code: m.code, ExceptionTable: m.exceptions}
+	if m.frames != nil {
+		code.Attributes = []cf.Attribute{cf.StackMapTable{NumberOfEntries: uint16(len(m.frames)), Entries: m.frames}}
+	}
+	method := cf.Method{
+		Access:     cf.MethodPublic | cf.MethodStatic,
+		Name:       "m",
+		Type:       m.descriptor,
+		Descriptor: descriptor.MustParseMethod(m.descriptor),
+		Attributes: []cf.Attribute{code},
+	}
+	class := &cf.ClassStruct{ThisClass: "com/x/T", SuperClass: "java/lang/Object", Methods: []cf.Method{method}}
+	return class, &class.Methods[0]
+}
+
+// superclasses is a Hierarchy of classes without interfaces, by superclass
+type superclasses map[string]string
+
+func (h superclasses) IsAssignableFrom(target, source string) (bool, error) {
+	for name := source; name != ""; name = h[name] {
+		if name == target {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (h superclasses) IsInterface(string) bool { return false }
+
+var testHierarchy = superclasses{
+	"java/lang/Integer":          "java/lang/Number",
+	"java/lang/Number":           "java/lang/Object",
+	"java/lang/String":           "java/lang/Object",
+	"java/lang/RuntimeException": "java/lang/Exception",
+	"java/lang/Exception":        "java/lang/Throwable",
+	"java/lang/Throwable":        "java/lang/Object",
+}
+
+var (
+	intItem   = cf.StackItem{Tag: cf.ItemInteger}
+	floatItem = cf.StackItem{Tag: cf.ItemFloat}
+)
+
+// ifZero returns 1 for a non-zero argument and 0 otherwise, with a frame at the branch target
+func ifZero() testMethod {
+	return testMethod{"(I)I", 1, 1, []cf.Instruction{
+		cf.Iload0{Location: at(0)},
+		cf.Ifeq{Location: at(1), Offset: 5},
+		cf.Iconst1{Location: at(4)},
+		cf.Ireturn{Location: at(5)},
+		cf.Iconst0{Location: at(6)},
+		cf.Ireturn{Location: at(7)},
+	}, nil, []cf.StackMapFrame{{FrameType: 6, OffsetDelta: 6, Pc: 6}}}
+}
+
+func TestVerifyAccepts(t *testing.T) {
+	tests := []struct {
+		name   string
+		method testMethod
+	}{
+		{"straight line", testMethod{"(II)I", 2, 2, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Iload1{Location: at(1)},
+			cf.Iadd{Location: at(2)},
+			cf.Ireturn{Location: at(3)},
+		}, nil, nil}},
+		{"branch", ifZero()},
+		{"join with a stack item", testMethod{"(I)I", 1, 1, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Ifeq{Location: at(1), Offset: 7},
+			cf.Iconst1{Location: at(4)},
+			cf.Goto{Location: at(5), Offset: 4},
+			cf.Iconst2{Location: at(8)},
+			cf.Ireturn{Location: at(9)},
+		}, nil, []cf.StackMapFrame{
+			{FrameType: 8, OffsetDelta: 8, Pc: 8},
+			{FrameType: 64, OffsetDelta: 0, Pc: 9, StackItems: []cf.StackItem{intItem}},
+		}}},
+		{"loop", testMethod{"(I)I", 2, 2, []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Istore1{Location: at(1)},
+			cf.Iload1{Location: at(2)},
+			cf.Iload0{Location: at(3)},
+			cf.IfIcmpge{Location: at(4), Offset: 9},
+			cf.Iinc{Location: at(7), LocalIndex: 1, Const: 1},
+			cf.Goto{Location: at(10), Offset: -8},
+			cf.Iload1{Location: at(13)},
+			cf.Ireturn{Location: at(14)},
+		}, nil, []cf.StackMapFrame{
+			{FrameType: 252, OffsetDelta: 2, Pc: 2, Locals: []cf.StackItem{intItem}},
+			{FrameType: 10, OffsetDelta: 10, Pc: 13},
+		}}},
+		{"handler", testMethod{"(Ljava/lang/Object;)I", 1, 1, []cf.Instruction{
+			cf.Aload0{Location: at(0)},
+			cf.Invokevirtual{Location: at(1), Class: "java/lang/Object", Name: "hashCode", Type: "()I"},
+			cf.Ireturn{Location: at(4)},
+			cf.Pop{Location: at(5)},
+			cf.Iconst0{Location: at(6)},
+			cf.Ireturn{Location: at(7)},
+		}, []cf.ExceptionTableEntry{{StartPc: 0, EndPc: 4, HandlerPc: 5, CatchClass: "java/lang/RuntimeException"}}, []cf.StackMapFrame{
+			{FrameType: 69, OffsetDelta: 5, Pc: 5, StackItems: []cf.StackItem{{Tag: cf.ItemObject, Class: "java/lang/Exception"}}},
+		}}},
+		{"tableswitch", testMethod{"(I)I", 1, 1, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Tableswitch{Location: at(1), DefaultOffset: 25, Low: 0, High: 1, Offsets: []int32{23, 25}},
+			cf.Iconst1{Location: at(24)},
+			cf.Ireturn{Location: at(25)},
+			cf.Iconst0{Location: at(26)},
+			cf.Ireturn{Location: at(27)},
+		}, nil, []cf.StackMapFrame{
+			{FrameType: 24, OffsetDelta: 24, Pc: 24},
+			{FrameType: 1, OffsetDelta: 1, Pc: 26},
+		}}},
+		{"long and double", testMethod{"(JD)D", 4, 4, []cf.Instruction{
+			cf.Lload0{Location: at(0)},
+			cf.L2d{Location: at(1)},
+			cf.Dload2{Location: at(2)},
+			cf.Dadd{Location: at(3)},
+			cf.Dreturn{Location: at(4)},
+		}, nil, nil}},
+		{"new object", testMethod{"()Ljava/lang/Object;", 2, 0, []cf.Instruction{
+			cf.New{Location: at(0), Class: "java/lang/Object"},
+			cf.Dup{Location: at(3)},
+			cf.Invokespecial{Location: at(4), Class: "java/lang/Object", Name: "<init>", Type: "()V"},
+			cf.Areturn{Location: at(7)},
+		}, nil, nil}},
+		{"subclass", testMethod{"(Ljava/lang/Integer;)Ljava/lang/Number;", 1, 1, []cf.Instruction{
+			cf.Aload0{Location: at(0)},
+			cf.Areturn{Location: at(1)},
+		}, nil, nil}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class, m := test.method.build()
+			result, err := Verify(class, m, testHierarchy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.OK() {
+				t.Fatalf("diagnostics = %v", result.Diagnostics)
+			}
+			for _, instruction := range test.method.code {
+				if _, ok := result.Frames[instruction.PC()]; !ok {
+					t.Errorf("no frame inferred at pc %d", instruction.PC())
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		method testMethod
+		want   string
+	}{
+		{"int returned as reference", testMethod{"()Ljava/lang/Object;", 1, 0, []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Areturn{Location: at(1)},
+		}, nil, nil}, "pc 1 (areturn): operand type mismatch: expected class java/lang/Object, found int"},
+		{"reference returned as int", testMethod{"(Ljava/lang/String;)I", 1, 1, []cf.Instruction{
+			cf.Aload0{Location: at(0)},
+			cf.Ireturn{Location: at(1)},
+		}, nil, nil}, "pc 1 (ireturn): operand type mismatch: expected int, found class java/lang/String"},
+		{"long returned as int", testMethod{"()I", 2, 0, []cf.Instruction{
+			cf.Lconst0{Location: at(0)},
+			cf.Ireturn{Location: at(1)},
+		}, nil, nil}, "pc 1 (ireturn): operand type mismatch: expected int, found long"},
+		{"unrelated class", testMethod{"(Ljava/lang/String;)Ljava/lang/Number;", 1, 1, []cf.Instruction{
+			cf.Aload0{Location: at(0)},
+			cf.Areturn{Location: at(1)},
+		}, nil, nil}, "pc 1 (areturn): operand type mismatch: expected class java/lang/Number, found class java/lang/String"},
+		{"stack underflow", testMethod{"()V", 1, 0, []cf.Instruction{
+			cf.Pop{Location: at(0)},
+			cf.Return{Location: at(1)},
+		}, nil, nil}, "pc 0 (pop): operand stack underflow: expected 1 slots, found 0 slots"},
+		{"unset local", testMethod{"()V", 1, 4, []cf.Instruction{
+			cf.Iload3{Location: at(0)},
+			cf.Pop{Location: at(1)},
+			cf.Return{Location: at(2)},
+		}, nil, nil}, "pc 0 (iload_3): local variable 3 type mismatch: expected int, found top"},
+		{"local of another type", testMethod{"()V", 1, 1, []cf.Instruction{
+			cf.Iconst0{Location: at(0)},
+			cf.Istore0{Location: at(1)},
+			cf.Aload0{Location: at(2)},
+			cf.Pop{Location: at(3)},
+			cf.Return{Location: at(4)},
+		}, nil, nil}, "pc 2 (aload_0): local variable 0 type mismatch: expected reference, found int"},
+		{"branch target without frame", testMethod{"(I)V", 1, 1, []cf.Instruction{
+			cf.Iload0{Location: at(0)},
+			cf.Ifeq{Location: at(1), Offset: 3},
+			cf.Return{Location: at(4)},
+		}, nil, nil}, "pc 1 (ifeq): branch target 4 has no stack map frame"},
+		{"handler without frame", testMethod{"()V", 0, 0, []cf.Instruction{
+			cf.Nop{Location: at(0)},
+			cf.Return{Location: at(1)},
+		}, []cf.ExceptionTableEntry{{StartPc: 0, EndPc: 1, HandlerPc: 1}}, nil}, "pc 0 (nop): exception handler at pc 1 has no stack map frame"},
+		{"falls off the end", testMethod{"()V", 0, 0, []cf.Instruction{
+			cf.Nop{Location: at(0)},
+		}, nil, nil}, "pc 0 (nop): execution falls off the end of the code"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class, m := test.method.build()
+			result, err := Verify(class, m, testHierarchy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Diagnostics) != 1 || result.Diagnostics[0].String() != test.want {
+				t.Fatalf("diagnostics = %q, want %q", result.Diagnostics, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDeclaredFrames(t *testing.T) {
+	// A frame declaring a float where the argument is an int
+	method := ifZero()
+	method.frames = []cf.StackMapFrame{{FrameType: 255, OffsetDelta: 6, Pc: 6, Locals: []cf.StackItem{floatItem}}}
+	class, m := method.build()
+	result, err := Verify(class, m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Diagnostic{Pc: 1, Mnemonic: "ifeq", Message: "local variable 0 does not match the stack map frame", Expected: "float", Actual: "int"}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0] != want {
+		t.Errorf("diagnostics = %q, want %q", result.Diagnostics, want)
+	}
+	// Checking resumes at the declared frame
+	if frame, ok := result.Frames[6]; !ok || len(frame.Locals) != 1 || frame.Locals[0] != FloatType {
+		t.Errorf("frame at pc 6 = %v", frame)
+	}
+
+	tests := []struct {
+		name  string
+		frame cf.StackMapFrame
+	}{
+		{"frame inside an instruction", cf.StackMapFrame{FrameType: 3, OffsetDelta: 3, Pc: 3}},
+		{"chop of missing locals", cf.StackMapFrame{FrameType: 249, OffsetDelta: 6, Pc: 6, Chopped: 2}},
+		{"locals beyond max_locals", cf.StackMapFrame{FrameType: 252, OffsetDelta: 6, Pc: 6, Locals: []cf.StackItem{intItem}}},
+	}
+	for _, test := range tests {
+		method := ifZero()
+		method.frames = []cf.StackMapFrame{test.frame}
+		class, m := method.build()
+		if _, err := Verify(class, m, nil); err == nil {
+			t.Errorf("%s: Verify accepted the StackMapTable", test.name)
+		}
+	}
+
+	class, m = ifZero().build()
+	m.Attributes = nil
+	if _, err := Verify(class, m, nil); err == nil {
+		t.Error("Verify accepted a method without code")
+	}
+}
@@ -0,0 +1,279 @@
+package classfileparser
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// maxElementValueDepth bounds the nesting of annotations and arrays inside an element value,
+// so that a crafted attribute cannot exhaust the stack
+const maxElementValueDepth = 256
+
+// readAnnotations reads the num_annotations prefixed annotation table of a Runtime*Annotations attribute
+func readAnnotations(reader *bytes.Reader, cp ConstantPool) ([]Annotation, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	if int(count) > reader.Len() {
+		return nil, fmt.Errorf("%d annotations overrun the attribute", count)
+	}
+	annotations := make([]Annotation, count)
+	for i := range annotations {
+		annotation, err := readAnnotation(reader, cp, 0)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %d: %w", i, err)
+		}
+		annotations[i] = annotation
+	}
+	return annotations, nil
+}
+
+// readParameterAnnotations reads the body of a Runtime*ParameterAnnotations attribute
+func readParameterAnnotations(reader *bytes.Reader, cp ConstantPool) ([]ParameterAnnotation, error) {
+	var count uint8
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	parameters := make([]ParameterAnnotation, count)
+	for i := range parameters {
+		annotations, err := readAnnotations(reader, cp)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i, err)
+		}
+		parameters[i] = ParameterAnnotation{
+			NumAnnotations: uint16(len(annotations)),
+			Annotations:    annotations,
+		}
+	}
+	return parameters, nil
+}
+
+func readAnnotation(reader *bytes.Reader, cp ConstantPool, depth int) (Annotation, error) {
+	var annotation Annotation
+	if err := read(reader, &annotation.TypeIndex, &annotation.NumElementValuePairs); err != nil {
+		return annotation, err
+	}
+	annotationType, err := constant[Utf8](cp, annotation.TypeIndex)
+	if err != nil {
+		return annotation, err
+	}
+	annotation.Type = string(annotationType)
+	pairs, err := readElementValuePairs(reader, annotation.NumElementValuePairs, cp, depth)
+	if err != nil {
+		return annotation, fmt.Errorf("%s: %w", annotation.Type, err)
+	}
+	annotation.ElementValuePairs = pairs
+	return annotation, nil
+}
+
+func readElementValuePairs(reader *bytes.Reader, count uint16, cp ConstantPool, depth int) ([]ElementValuePair, error) {
+	if int(count) > reader.Len() {
+		return nil, fmt.Errorf("%d element value pairs overrun the attribute", count)
+	}
+	pairs := make([]ElementValuePair, count)
+	for i := range pairs {
+		pair := &pairs[i]
+		if err := read(reader, &pair.ElementNameIndex); err != nil {
+			return nil, err
+		}
+		name, err := constant[Utf8](cp, pair.ElementNameIndex)
+		if err != nil {
+			return nil, err
+		}
+		pair.Name = string(name)
+		if pair.Value, err = readElementValue(reader, cp, depth); err != nil {
+			return nil, fmt.Errorf("element %s: %w", pair.Name, err)
+		}
+	}
+	return pairs, nil
+}
+
+// readElementValue reads an element_value structure, resolving its constants through cp
+func readElementValue(reader *bytes.Reader, cp ConstantPool, depth int) (ElementValue, error) {
+	var value ElementValue
+	if depth > maxElementValueDepth {
+		return value, fmt.Errorf("element values nested deeper than %d levels", maxElementValueDepth)
+	}
+	if err := read(reader, &value.Tag); err != nil {
+		return value, err
+	}
+
+	var err error
+	switch value.Tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's', 'c':
+		err = read(reader, &value.Index)
+	case 'e':
+		err = read(reader, &value.Index, &value.NameIndex)
+	}
+	if err != nil {
+		return ElementValue{}, err
+	}
+
+	switch value.Tag {
+	case 'B', 'C', 'I', 'S', 'Z':
+		var v int32
+		if v, err = constant[int32](cp, value.Index); err != nil {
+			break
+		}
+		switch value.Tag {
+		case 'B':
+			value.Value = int8(v)
+		case 'C':
+			value.Value = uint16(v)
+		case 'I':
+			value.Value = v
+		case 'S':
+			value.Value = int16(v)
+		case 'Z':
+			value.Value = v != 0
+		}
+	case 'D':
+		value.Value, err = constant[float64](cp, value.Index)
+	case 'F':
+		value.Value, err = constant[float32](cp, value.Index)
+	case 'J':
+		value.Value, err = constant[int64](cp, value.Index)
+	case 's':
+		var v Utf8
+		v, err = constant[Utf8](cp, value.Index)
+		value.Value = string(v)
+	case 'e':
+		var typeName, constName Utf8
+		if typeName, err = constant[Utf8](cp, value.Index); err != nil {
+			break
+		}
+		if constName, err = constant[Utf8](cp, value.NameIndex); err != nil {
+			break
+		}
+		value.Value = EnumValue{Type: string(typeName), Name: string(constName)}
+	case 'c':
+		var v Utf8
+		v, err = constant[Utf8](cp, value.Index)
+		value.Value = ClassValue(v)
+	case '@':
+		value.Value, err = readAnnotation(reader, cp, depth+1)
+	case '[':
+		var count uint16
+		if err = read(reader, &count); err != nil {
+			break
+		}
+		if int(count) > reader.Len() {
+			err = fmt.Errorf("%d array values overrun the attribute", count)
+			break
+		}
+		values := make([]ElementValue, count)
+		for i := range values {
+			if values[i], err = readElementValue(reader, cp, depth+1); err != nil {
+				err = fmt.Errorf("array value %d: %w", i, err)
+				break
+			}
+		}
+		value.Value = values
+	default:
+		err = fmt.Errorf("unknown element value tag %q", value.Tag)
+	}
+	if err != nil {
+		return ElementValue{}, err
+	}
+	return value, nil
+}
+
+// writeAnnotation encodes annotation, allocating its constants in pool. The resolved Type, Name
+// and Value fields are used, the constant pool indexes are ignored.
+func writeAnnotation(buf *bytes.Buffer, annotation Annotation, pool *PoolBuilder) error {
+	if len(annotation.ElementValuePairs) > 0xFFFF {
+		return fmt.Errorf("too many element value pairs: %d", len(annotation.ElementValuePairs))
+	}
+	write(buf, pool.Utf8(annotation.Type), uint16(len(annotation.ElementValuePairs)))
+	for _, pair := range annotation.ElementValuePairs {
+		write(buf, pool.Utf8(pair.Name))
+		if err := writeElementValue(buf, pair.Value, pool); err != nil {
+			return fmt.Errorf("element %s: %w", pair.Name, err)
+		}
+	}
+	return nil
+}
+
+func writeElementValue(buf *bytes.Buffer, value ElementValue, pool *PoolBuilder) error {
+	write(buf, value.Tag)
+	switch v := value.Value.(type) {
+	case int8:
+		if value.Tag == 'B' {
+			write(buf, pool.Integer(int32(v)))
+			return nil
+		}
+	case uint16:
+		if value.Tag == 'C' {
+			write(buf, pool.Integer(int32(v)))
+			return nil
+		}
+	case int32:
+		if value.Tag == 'I' {
+			write(buf, pool.Integer(v))
+			return nil
+		}
+	case int16:
+		if value.Tag == 'S' {
+			write(buf, pool.Integer(int32(v)))
+			return nil
+		}
+	case bool:
+		if value.Tag == 'Z' {
+			var i int32
+			if v {
+				i = 1
+			}
+			write(buf, pool.Integer(i))
+			return nil
+		}
+	case float64:
+		if value.Tag == 'D' {
+			write(buf, pool.Double(v))
+			return nil
+		}
+	case float32:
+		if value.Tag == 'F' {
+			write(buf, pool.Float(v))
+			return nil
+		}
+	case int64:
+		if value.Tag == 'J' {
+			write(buf, pool.Long(v))
+			return nil
+		}
+	case string:
+		if value.Tag == 's' {
+			write(buf, pool.Utf8(v))
+			return nil
+		}
+	case EnumValue:
+		if value.Tag == 'e' {
+			write(buf, pool.Utf8(v.Type), pool.Utf8(v.Name))
+			return nil
+		}
+	case ClassValue:
+		if value.Tag == 'c' {
+			write(buf, pool.Utf8(string(v)))
+			return nil
+		}
+	case Annotation:
+		if value.Tag == '@' {
+			return writeAnnotation(buf, v, pool)
+		}
+	case []ElementValue:
+		if value.Tag == '[' {
+			if len(v) > 0xFFFF {
+				return fmt.Errorf("too many array values: %d", len(v))
+			}
+			write(buf, uint16(len(v)))
+			for i, element := range v {
+				if err := writeElementValue(buf, element, pool); err != nil {
+					return fmt.Errorf("array value %d: %w", i, err)
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("element value tag %q cannot hold %T", value.Tag, value.Value)
+}
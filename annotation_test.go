@@ -0,0 +1,151 @@
+package classfileparser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var annotationPool = ConstantPool{
+	1:  Utf8("LAnn;"),
+	2:  Utf8("value"),
+	3:  int32(200),
+	4:  Utf8("LE;"),
+	5:  Utf8("A"),
+	6:  Utf8("Ljava/lang/String;"),
+	7:  1.5,
+	8:  Utf8("text"),
+	9:  int64(7),
+	10: float32(2),
+	11: Utf8("V"),
+	12: Utf8("numbers"),
+	13: Utf8("enum"),
+	14: Utf8("class"),
+	15: Utf8("nested"),
+	16: Utf8("string"),
+}
+
+// annotationData is a RuntimeVisibleAnnotations body holding one annotation with every kind of element value
+var annotationData = []byte{
+	0, 1, // num_annotations
+	0, 1, 0, 6, // LAnn; with 6 pairs
+	0, 12, '[', 0, 8, 'B', 0, 3, 'C', 0, 3, 'Z', 0, 3, 'S', 0, 3, 'I', 0, 3, 'D', 0, 7, 'F', 0, 10, 'J', 0, 9,
+	0, 13, 'e', 0, 4, 0, 5,
+	0, 14, 'c', 0, 11,
+	0, 15, '@', 0, 1, 0, 1, 0, 2, '[', 0, 1, '@', 0, 1, 0, 0,
+	0, 16, 's', 0, 8,
+	0, 2, '[', 0, 0,
+}
+
+var annotationValue = Annotation{
+	TypeIndex:            1,
+	NumElementValuePairs: 6,
+	Type:                 "LAnn;",
+	ElementValuePairs: []ElementValuePair{
+		{ElementNameIndex: 12, Name: "numbers", Value: ElementValue{Tag: '[', Value: []ElementValue{
+			{Tag: 'B', Value: int8(-56), Index: 3},
+			{Tag: 'C', Value: uint16(200), Index: 3},
+			{Tag: 'Z', Value: true, Index: 3},
+			{Tag: 'S', Value: int16(200), Index: 3},
+			{Tag: 'I', Value: int32(200), Index: 3},
+			{Tag: 'D', Value: 1.5, Index: 7},
+			{Tag: 'F', Value: float32(2), Index: 10},
+			{Tag: 'J', Value: int64(7), Index: 9},
+		}}},
+		{ElementNameIndex: 13, Name: "enum", Value: ElementValue{Tag: 'e', Value: EnumValue{Type: "LE;", Name: "A"}, Index: 4, NameIndex: 5}},
+		{ElementNameIndex: 14, Name: "class", Value: ElementValue{Tag: 'c', Value: ClassValue("V"), Index: 11}},
+		{ElementNameIndex: 15, Name: "nested", Value: ElementValue{Tag: '@', Value: Annotation{
+			TypeIndex:            1,
+			NumElementValuePairs: 1,
+			Type:                 "LAnn;",
+			ElementValuePairs: []ElementValuePair{
+				{ElementNameIndex: 2, Name: "value", Value: ElementValue{Tag: '[', Value: []ElementValue{
+					{Tag: '@', Value: Annotation{TypeIndex: 1, Type: "LAnn;", ElementValuePairs: []ElementValuePair{}}},
+				}}},
+			},
+		}}},
+		{ElementNameIndex: 16, Name: "string", Value: ElementValue{Tag: 's', Value: "text", Index: 8}},
+		{ElementNameIndex: 2, Name: "value", Value: ElementValue{Tag: '[', Value: []ElementValue{}}},
+	},
+}
+
+func TestReadAnnotations(t *testing.T) {
+	annotations, err := readAnnotations(bytes.NewReader(annotationData), annotationPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 1 || !reflect.DeepEqual(annotations[0], annotationValue) {
+		t.Errorf("annotations = %#v", annotations)
+	}
+}
+
+func TestReadParameterAnnotations(t *testing.T) {
+	data := []byte{2, 0, 0, 0, 1, 0, 1, 0, 0}
+	parameters, err := readParameterAnnotations(bytes.NewReader(data), annotationPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parameters) != 2 || parameters[0].NumAnnotations != 0 || parameters[1].NumAnnotations != 1 || parameters[1].Annotations[0].Type != "LAnn;" {
+		t.Errorf("parameters = %#v", parameters)
+	}
+}
+
+func TestReadInvalidElementValue(t *testing.T) {
+	deep := bytes.Repeat([]byte{'[', 0, 1}, maxElementValueDepth+2)
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"unknown tag", []byte{'x'}, "unknown element value tag 'x'"},
+		{"wrong constant", []byte{'I', 0, 8}, "Utf8"},
+		{"missing constant", []byte{'s', 0, 99}, "#99"},
+		{"array overrun", []byte{'[', 0xFF, 0xFF}, "65535 array values overrun"},
+		{"too deep", deep, "nested deeper than"},
+		{"truncated enum", []byte{'e', 0, 4}, "EOF"},
+	}
+	for _, test := range tests {
+		_, err := readElementValue(bytes.NewReader(test.data), annotationPool, 0)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestWriteAnnotationRoundTrip(t *testing.T) {
+	pool := NewPoolBuilder(nil)
+	var buf bytes.Buffer
+	if err := writeAnnotation(&buf, annotationValue, pool); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := (&ClassFile{ConstantPool: pool.Pool()}).GetConstantPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readAnnotation(bytes.NewReader(buf.Bytes()), cp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again bytes.Buffer
+	if err := writeAnnotation(&again, decoded, NewPoolBuilder(pool.Pool())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("annotation differs after a round trip")
+	}
+	numbers := decoded.ElementValuePairs[0].Value.Value.([]ElementValue)
+	if len(numbers) != 8 {
+		t.Fatalf("numbers = %#v", numbers)
+	}
+	for i, want := range annotationValue.ElementValuePairs[0].Value.Value.([]ElementValue) {
+		if numbers[i].Tag != want.Tag || numbers[i].Value != want.Value {
+			t.Errorf("number %d = %#v", i, numbers[i])
+		}
+	}
+
+	err = writeElementValue(&bytes.Buffer{}, ElementValue{Tag: 'I', Value: "1"}, pool)
+	if err == nil || !strings.Contains(err.Error(), "tag 'I' cannot hold string") {
+		t.Errorf("error = %v", err)
+	}
+}
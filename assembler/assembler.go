@@ -0,0 +1,589 @@
+// Package assembler builds class files from a textual assembly language modelled on Jasmin, for
+// test fixtures and small hand-written classes.
+package assembler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/analysis"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// Error reports a problem in the assembly source and the line it was found on
+type Error struct {
+	Line int // Line number, starting at 1
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Assemble reads assembly source from r and returns the class file it describes, as Open would
+// read it back, ready to be written with WriteTo. The constant pool is built from the names,
+// descriptors and constants the source mentions.
+//
+// Missing .limit directives and, for class file versions 50 and above, the default being 52,
+// the StackMapTable of every method are computed by analysis.ComputeCode, merging class types
+// through resolver, which may be nil. Such methods cannot contain unreachable code, jsr or ret.
+func Assemble(r io.Reader, resolver analysis.SuperclassResolver) (*classfileparser.ClassFile, error) {
+	a := &assembler{
+		pool: classfileparser.NewPoolBuilder(nil),
+		file: classfileparser.ClassFile{Magic: 0xCAFEBABE, MajorVersion: 52},
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		a.line++
+		tokens, err := tokenize(scanner.Text())
+		if err == nil && len(tokens) > 0 {
+			err = a.parseLine(tokens)
+		}
+		var lineErr *Error
+		if err != nil && !errors.As(err, &lineErr) {
+			err = &Error{Line: a.line, Err: err}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if a.method != nil {
+		return nil, &Error{Line: a.method.line, Err: fmt.Errorf("method %s has no .end method", a.method.name)}
+	}
+	if !a.declared {
+		return nil, &Error{Line: a.line, Err: fmt.Errorf("missing .class directive")}
+	}
+	return a.finish(resolver)
+}
+
+// assembler holds the class being assembled
+type assembler struct {
+	pool     *classfileparser.PoolBuilder
+	file     classfileparser.ClassFile
+	line     int
+	declared bool // .class or .interface was read
+	this     string
+	super    *string
+
+	// attributes are those of the class or of the member declared last, which .signature adds to
+	attributes       *[]classfileparser.AttributeInfo
+	bootstrapMethods []classfileparser.MethodHandle
+	bootstrapArgs    [][]interface{}
+
+	method *method  // Method being read, nil outside .method and .end method
+	limits []limits // Limits of every method, by index
+}
+
+// method is a method being read
+type method struct {
+	line       int
+	name       string // Name and descriptor
+	access     classfileparser.MethodAccess
+	labels     map[string]int // Index of the instruction each label marks
+	code       []pendingInstruction
+	body       *switchBody // Switch whose targets are being read
+	catches    []catch
+	lines      []classfileparser.LineNumberTableEntry
+	variables  []variable
+	exceptions []string
+	limits     limits
+}
+
+// limits are the max_stack and max_locals declared for a method, -1 when they are computed
+type limits struct {
+	line      int
+	maxStack  int
+	maxLocals int
+}
+
+// catch is an exception handler declared by .catch
+type catch struct {
+	line            int
+	class           string // Empty for all exceptions
+	from, to, using string
+}
+
+// variable is a local variable declared by .var
+type variable struct {
+	line             int
+	index            uint16
+	name, descriptor string
+	from, to         string
+}
+
+// Modifiers of classes, fields and methods, as in Java source or named after the flags without one.
+var (
+	classModifiers = map[string]classfileparser.ClassAccess{
+		"public": classfileparser.ClassPublic, "final": classfileparser.ClassFinal, "super": classfileparser.ClassSuper,
+		"interface": classfileparser.ClassInterface, "abstract": classfileparser.ClassAbstract, "synthetic": classfileparser.ClassSynthetic,
+		"annotation": classfileparser.ClassAnnotation, "enum": classfileparser.ClassEnum,
+	}
+	fieldModifiers = map[string]classfileparser.FieldAccess{
+		"public": classfileparser.FieldPublic, "private": classfileparser.FieldPrivate, "protected": classfileparser.FieldProtected,
+		"static": classfileparser.FieldStatic, "final": classfileparser.FieldFinal, "volatile": classfileparser.FieldVolatile,
+		"transient": classfileparser.FieldTransient, "synthetic": classfileparser.FieldSynthetic, "enum": classfileparser.FieldEnum,
+	}
+	methodModifiers = map[string]classfileparser.MethodAccess{
+		"public": classfileparser.MethodPublic, "private": classfileparser.MethodPrivate, "protected": classfileparser.MethodProtected,
+		"static": classfileparser.MethodStatic, "final": classfileparser.MethodFinal, "synchronized": classfileparser.MethodSynchronized,
+		"bridge": classfileparser.MethodBridge, "varargs": classfileparser.MethodVarargs, "native": classfileparser.MethodNative,
+		"abstract": classfileparser.MethodAbstract, "strictfp": classfileparser.MethodStrict, "synthetic": classfileparser.MethodSynthetic,
+	}
+)
+
+// modifiers consumes the leading tokens naming flags and returns their union and the rest
+func modifiers[T ~uint16](tokens []string, names map[string]T) (T, []string) {
+	var flags T
+	for len(tokens) > 0 {
+		flag, ok := names[tokens[0]]
+		if !ok {
+			break
+		}
+		flags |= flag
+		tokens = tokens[1:]
+	}
+	return flags, tokens
+}
+
+// parseLine handles a line of source split into tokens
+func (a *assembler) parseLine(tokens []string) error {
+	if a.method != nil && a.method.body != nil {
+		if err := a.method.body.add(tokens); err != nil {
+			return err
+		}
+		if a.method.body.done {
+			a.method.body = nil
+		}
+		return nil
+	}
+	if strings.HasPrefix(tokens[0], ".") {
+		return a.directive(tokens[0], tokens[1:])
+	}
+	if a.method == nil {
+		return fmt.Errorf("instruction %s outside a method", tokens[0])
+	}
+	if label, ok := strings.CutSuffix(tokens[0], ":"); ok {
+		if label == "" {
+			return fmt.Errorf("empty label")
+		}
+		if _, ok := a.method.labels[label]; ok {
+			return fmt.Errorf("label %s is already defined", label)
+		}
+		a.method.labels[label] = len(a.method.code)
+		if tokens = tokens[1:]; len(tokens) == 0 {
+			return nil
+		}
+	}
+	pending, err := a.instruction(tokens[0], tokens[1:])
+	if err != nil {
+		return err
+	}
+	a.method.code = append(a.method.code, pending)
+	return nil
+}
+
+// directive handles a line starting with a directive
+func (a *assembler) directive(name string, args []string) error {
+	switch name {
+	case ".version", ".source", ".class", ".interface":
+	default:
+		if !a.declared {
+			return fmt.Errorf("%s before .class", name)
+		}
+	}
+	inMethod := map[string]bool{".limit": true, ".throws": true, ".catch": true, ".line": true, ".var": true, ".end": true}
+	if inMethod[name] != (a.method != nil) && name != ".signature" {
+		if a.method == nil {
+			return fmt.Errorf("%s outside a method", name)
+		}
+		return fmt.Errorf("%s inside method %s", name, a.method.name)
+	}
+
+	switch name {
+	case ".version":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf(".version takes a major and optionally a minor version")
+		}
+		major, err := unsigned(args[0], 16)
+		if err != nil {
+			return err
+		}
+		a.file.MajorVersion = uint16(major)
+		if len(args) == 2 {
+			minor, err := unsigned(args[1], 16)
+			if err != nil {
+				return err
+			}
+			a.file.MinorVersion = uint16(minor)
+		}
+	case ".class", ".interface":
+		if a.declared {
+			return fmt.Errorf("class %s is already declared", a.this)
+		}
+		access, rest := modifiers(args, classModifiers)
+		if len(rest) != 1 {
+			return fmt.Errorf("%s takes modifiers and a class name", name)
+		}
+		if name == ".interface" {
+			access |= classfileparser.ClassInterface | classfileparser.ClassAbstract
+		}
+		if !access.Has(classfileparser.ClassInterface) {
+			access |= classfileparser.ClassSuper
+		}
+		a.declared, a.this = true, rest[0]
+		a.file.AccessFlags = uint16(access)
+		a.file.ThisClass = a.pool.Class(a.this)
+		a.attributes = &a.file.Attributes
+	case ".super":
+		if len(args) != 1 {
+			return fmt.Errorf(".super takes a class name")
+		}
+		if a.super != nil {
+			return fmt.Errorf("superclass %s is already declared", *a.super)
+		}
+		a.super = &args[0]
+	case ".implements":
+		if len(args) != 1 {
+			return fmt.Errorf(".implements takes an interface name")
+		}
+		a.file.Interfaces = append(a.file.Interfaces, a.pool.Class(args[0]))
+	case ".source":
+		if len(args) != 1 {
+			return fmt.Errorf(".source takes a file name")
+		}
+		source, err := text(args[0])
+		if err != nil {
+			return err
+		}
+		a.file.Attributes = append(a.file.Attributes, a.attribute("SourceFile", a.pool.Utf8(source)))
+	case ".signature":
+		if len(args) != 1 {
+			return fmt.Errorf(".signature takes a signature")
+		}
+		signature, err := text(args[0])
+		if err != nil {
+			return err
+		}
+		*a.attributes = append(*a.attributes, a.attribute("Signature", a.pool.Utf8(signature)))
+	case ".bootstrap":
+		handle, rest, err := methodHandle(args)
+		if err != nil {
+			return err
+		}
+		var arguments []interface{}
+		for len(rest) > 0 {
+			var argument interface{}
+			if argument, rest, err = a.constant(rest, false); err != nil {
+				return err
+			}
+			arguments = append(arguments, argument)
+		}
+		a.bootstrapMethods = append(a.bootstrapMethods, handle)
+		a.bootstrapArgs = append(a.bootstrapArgs, arguments)
+	case ".field":
+		return a.field(args)
+	case ".method":
+		return a.beginMethod(args)
+	case ".limit":
+		if len(args) != 2 || (args[0] != "stack" && args[0] != "locals") {
+			return fmt.Errorf(".limit takes stack or locals and a value")
+		}
+		value, err := unsigned(args[1], 16)
+		if err != nil {
+			return err
+		}
+		if args[0] == "stack" {
+			a.method.limits.maxStack = int(value)
+		} else {
+			a.method.limits.maxLocals = int(value)
+		}
+	case ".throws":
+		if len(args) != 1 {
+			return fmt.Errorf(".throws takes a class name")
+		}
+		a.method.exceptions = append(a.method.exceptions, args[0])
+	case ".catch":
+		if len(args) != 7 || args[1] != "from" || args[3] != "to" || args[5] != "using" {
+			return fmt.Errorf(".catch is written .catch class from label to label using label")
+		}
+		handler := catch{line: a.line, class: args[0], from: args[2], to: args[4], using: args[6]}
+		if handler.class == "all" {
+			handler.class = ""
+		}
+		a.method.catches = append(a.method.catches, handler)
+	case ".line":
+		if len(args) != 1 {
+			return fmt.Errorf(".line takes a line number")
+		}
+		number, err := unsigned(args[0], 16)
+		if err != nil {
+			return err
+		}
+		entry := classfileparser.LineNumberTableEntry{StartPc: uint16(len(a.method.code)), LineNumber: uint16(number)}
+		a.method.lines = append(a.method.lines, entry)
+	case ".var":
+		if len(args) != 8 || args[1] != "is" || args[4] != "from" || args[6] != "to" {
+			return fmt.Errorf(".var is written .var index is name descriptor from label to label")
+		}
+		index, err := unsigned(args[0], 16)
+		if err != nil {
+			return err
+		}
+		a.method.variables = append(a.method.variables, variable{line: a.line, index: uint16(index), name: args[2], descriptor: args[3], from: args[5], to: args[7]})
+	case ".end":
+		if len(args) != 1 || args[0] != "method" {
+			return fmt.Errorf("expected .end method")
+		}
+		return a.endMethod()
+	default:
+		return fmt.Errorf("unknown directive %s", name)
+	}
+	return nil
+}
+
+// attribute returns an attribute made of a single constant pool index
+func (a *assembler) attribute(name string, index uint16) classfileparser.AttributeInfo {
+	return classfileparser.AttributeInfo{AttributeNameIndex: a.pool.Utf8(name), AttributeLength: 2, Info: binary.BigEndian.AppendUint16(nil, index)}
+}
+
+// field handles .field modifiers name descriptor [= value]
+func (a *assembler) field(args []string) error {
+	access, rest := modifiers(args, fieldModifiers)
+	if len(rest) != 2 && (len(rest) != 4 || rest[2] != "=") {
+		return fmt.Errorf(".field takes modifiers, a name, a descriptor and optionally = value")
+	}
+	if _, err := descriptor.ParseField(rest[1]); err != nil {
+		return err
+	}
+	info := classfileparser.FieldInfo{AccessFlags: uint16(access), NameIndex: a.pool.Utf8(rest[0]), DescriptorIndex: a.pool.Utf8(rest[1])}
+	if len(rest) == 4 {
+		value, err := fieldConstant(rest[1], rest[3])
+		if err != nil {
+			return err
+		}
+		index, err := a.pool.Constant(value)
+		if err != nil {
+			return err
+		}
+		info.Attributes = append(info.Attributes, a.attribute("ConstantValue", index))
+	}
+	a.file.Fields = append(a.file.Fields, info)
+	a.attributes = &a.file.Fields[len(a.file.Fields)-1].Attributes
+	return nil
+}
+
+// fieldConstant parses the initial value of a field of type field
+func fieldConstant(field, token string) (interface{}, error) {
+	switch field {
+	case "I", "S", "C", "B", "Z":
+		value, err := integer(token, 32)
+		return int32(value), err
+	case "J":
+		return integer(strings.TrimRight(token, "lL"), 64)
+	case "F", "D":
+		bits := map[string]int{"F": 32, "D": 64}[field]
+		value, err := strconv.ParseFloat(strings.TrimRight(token, "fFdD"), bits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %d-bit floating-point number %q", bits, token)
+		}
+		if field == "F" {
+			return float32(value), nil
+		}
+		return value, nil
+	case "Ljava/lang/String;":
+		if !strings.HasPrefix(token, `"`) {
+			return nil, fmt.Errorf("string constant %s is not quoted", token)
+		}
+		return text(token)
+	}
+	return nil, fmt.Errorf("fields of type %s cannot have a constant value", field)
+}
+
+// beginMethod handles .method modifiers name(descriptor)
+func (a *assembler) beginMethod(args []string) error {
+	access, rest := modifiers(args, methodModifiers)
+	if len(rest) != 1 {
+		return fmt.Errorf(".method takes modifiers and a name followed by a descriptor")
+	}
+	open := strings.IndexByte(rest[0], '(')
+	if open <= 0 {
+		return fmt.Errorf("method %q is not written name(descriptor)", rest[0])
+	}
+	name, desc := rest[0][:open], rest[0][open:]
+	if _, err := descriptor.ParseMethod(desc); err != nil {
+		return err
+	}
+	a.file.Methods = append(a.file.Methods, classfileparser.MethodInfo{AccessFlags: uint16(access), NameIndex: a.pool.Utf8(name), DescriptorIndex: a.pool.Utf8(desc)})
+	a.attributes = &a.file.Methods[len(a.file.Methods)-1].Attributes
+	a.method = &method{line: a.line, name: rest[0], access: access, labels: map[string]int{}, limits: limits{line: a.line, maxStack: -1, maxLocals: -1}}
+	return nil
+}
+
+// endMethod resolves the labels of the method being read and encodes its code
+func (a *assembler) endMethod() error {
+	m := a.method
+	a.method = nil
+	a.limits = append(a.limits, m.limits)
+	info := &a.file.Methods[len(a.file.Methods)-1]
+	if len(m.exceptions) > 0 {
+		body := binary.BigEndian.AppendUint16(nil, uint16(len(m.exceptions)))
+		for _, exception := range m.exceptions {
+			body = binary.BigEndian.AppendUint16(body, a.pool.Class(exception))
+		}
+		info.Attributes = append(info.Attributes, classfileparser.AttributeInfo{AttributeNameIndex: a.pool.Utf8("Exceptions"), AttributeLength: uint32(len(body)), Info: body})
+	}
+
+	bodiless := m.access&(classfileparser.MethodAbstract|classfileparser.MethodNative) != 0
+	switch {
+	case bodiless && len(m.code) > 0:
+		return fmt.Errorf("abstract or native method %s has code", m.name)
+	case bodiless:
+		return nil
+	case len(m.code) == 0:
+		return fmt.Errorf("method %s has no code", m.name)
+	}
+
+	// position resolves a label that may follow the last instruction, as the end of a range
+	// does, and target one that must mark an instruction, as a jump or handler does
+	position := func(name string) (int, error) {
+		index, ok := m.labels[name]
+		if !ok {
+			return 0, fmt.Errorf("undefined label %s", name)
+		}
+		return index, nil
+	}
+	target := func(name string) (int, error) {
+		index, err := position(name)
+		if err == nil && index == len(m.code) {
+			err = fmt.Errorf("label %s is not followed by an instruction", name)
+		}
+		return index, err
+	}
+	code := classfileparser.Code{MaxStack: uint16(max(m.limits.maxStack, 0)), MaxLocals: uint16(max(m.limits.maxLocals, 0))}
+	for i, pending := range m.code {
+		offset := func(name string) (int32, error) {
+			index, err := target(name)
+			return int32(index - i), err
+		}
+		instruction, err := pending.build(classfileparser.Location{Pc: i}, offset)
+		if err != nil {
+			return &Error{Line: pending.line, Err: err}
+		}
+		code.Code = append(code.Code, instruction)
+	}
+
+	for _, handler := range m.catches {
+		start, err := position(handler.from)
+		var end, pc int
+		if err == nil {
+			end, err = position(handler.to)
+		}
+		if err == nil {
+			pc, err = target(handler.using)
+		}
+		if err != nil {
+			return &Error{Line: handler.line, Err: err}
+		}
+		if start >= end {
+			return &Error{Line: handler.line, Err: fmt.Errorf("exception range from %s to %s is empty", handler.from, handler.to)}
+		}
+		entry := classfileparser.ExceptionTableEntry{StartPc: uint16(start), EndPc: uint16(end), HandlerPc: uint16(pc), CatchClass: handler.class}
+		if handler.class != "" {
+			entry.CatchType = a.pool.Class(handler.class)
+		}
+		code.ExceptionTable = append(code.ExceptionTable, entry)
+	}
+
+	var lines []classfileparser.LineNumberTableEntry
+	for _, entry := range m.lines {
+		if int(entry.StartPc) < len(m.code) {
+			lines = append(lines, entry)
+		}
+	}
+	if len(lines) > 0 {
+		code.Attributes = append(code.Attributes, classfileparser.LineNumberTable{LineNumberTableLength: uint16(len(lines)), LineNumberTable: lines})
+	}
+	if len(m.variables) > 0 {
+		table := classfileparser.LocalVariableTable{LocalVariableTableLength: uint16(len(m.variables))}
+		for _, v := range m.variables {
+			from, err := position(v.from)
+			if err != nil {
+				return &Error{Line: v.line, Err: err}
+			}
+			to, err := position(v.to)
+			if err != nil {
+				return &Error{Line: v.line, Err: err}
+			}
+			if to < from {
+				return &Error{Line: v.line, Err: fmt.Errorf("variable %s ends before it starts", v.name)}
+			}
+			table.LocalVariableTable = append(table.LocalVariableTable, classfileparser.LocalVariableEntry{
+				StartPc: uint16(from), Length: uint16(to - from), NameIndex: a.pool.Utf8(v.name), SignatureIndex: a.pool.Utf8(v.descriptor), Index: v.index,
+			})
+		}
+		code.Attributes = append(code.Attributes, table)
+	}
+
+	encoded, err := classfileparser.EncodeCode(code, a.pool)
+	if err != nil {
+		return fmt.Errorf("method %s: %w", m.name, err)
+	}
+	info.Attributes = append([]classfileparser.AttributeInfo{encoded}, info.Attributes...)
+	return nil
+}
+
+// finish completes the class file, computes the limits and frames of its methods and reads it
+// back
+func (a *assembler) finish(resolver analysis.SuperclassResolver) (*classfileparser.ClassFile, error) {
+	switch {
+	case a.super != nil:
+		a.file.SuperClass = a.pool.Class(*a.super)
+	case a.this != "java/lang/Object":
+		a.file.SuperClass = a.pool.Class("java/lang/Object")
+	}
+	if len(a.bootstrapMethods) > 0 {
+		body := binary.BigEndian.AppendUint16(nil, uint16(len(a.bootstrapMethods)))
+		for i, handle := range a.bootstrapMethods {
+			index, err := a.pool.MethodHandle(handle)
+			if err != nil {
+				return nil, err
+			}
+			body = binary.BigEndian.AppendUint16(body, index)
+			body = binary.BigEndian.AppendUint16(body, uint16(len(a.bootstrapArgs[i])))
+			for _, argument := range a.bootstrapArgs[i] {
+				index, err := a.pool.Constant(argument)
+				if err != nil {
+					return nil, err
+				}
+				body = binary.BigEndian.AppendUint16(body, index)
+			}
+		}
+		a.file.Attributes = append(a.file.Attributes, classfileparser.AttributeInfo{AttributeNameIndex: a.pool.Utf8("BootstrapMethods"), AttributeLength: uint32(len(body)), Info: body})
+	}
+	if err := a.pool.Err(); err != nil {
+		return nil, err
+	}
+	a.file.ConstantPool = a.pool.Pool()
+
+	file, err := analysis.ComputeCode(&a.file, resolver, func(index int) (int, int) {
+		return a.limits[index].maxStack, a.limits[index].maxLocals
+	})
+	var methodErr *analysis.MethodError
+	if errors.As(err, &methodErr) {
+		return nil, &Error{Line: a.limits[methodErr.Index].line, Err: err}
+	}
+	return file, err
+}
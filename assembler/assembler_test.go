@@ -0,0 +1,250 @@
+package assembler_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/analysis"
+	"github.com/Slummp/classfileparser/assembler"
+)
+
+// assemble assembles source and reads the class back
+func assemble(t *testing.T, source string) *classfileparser.ClassStruct {
+	t.Helper()
+	file, err := assembler.Assemble(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	class, err := file.GetClassFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return class
+}
+
+// code returns the code of the method of class called name
+func code(t *testing.T, class *classfileparser.ClassStruct, name string) classfileparser.Code {
+	t.Helper()
+	for _, m := range class.Methods {
+		if m.Name != name {
+			continue
+		}
+		for _, attribute := range m.Attributes {
+			if code, ok := attribute.(classfileparser.Code); ok {
+				return code
+			}
+		}
+	}
+	t.Fatalf("no code for method %s", name)
+	return classfileparser.Code{}
+}
+
+const hello = `.source Hello.java
+.class public com/x/Hello
+.super java/lang/Object
+.implements java/lang/Runnable
+.field private static final MAX I = 42
+.field private name Ljava/lang/String;
+
+.bootstrap invokeStatic java/lang/invoke/LambdaMetafactory/metafactory(Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodHandle;Ljava/lang/invoke/MethodType;)Ljava/lang/invoke/CallSite; methodtype ()V methodhandle invokeStatic com/x/Hello/lambda$0()V methodtype ()V
+
+.method public <init>()V
+    aload_0
+    invokespecial java/lang/Object/<init>()V
+    return
+.end method
+
+.method public run()V
+    .line 3
+Start:
+    getstatic java/lang/System/out Ljava/io/PrintStream;
+    aload_0
+    getfield com/x/Hello/name Ljava/lang/String;
+    invokevirtual java/io/PrintStream/println(Ljava/lang/String;)V
+    invokedynamic run()Ljava/lang/Runnable; 0
+    invokeinterface java/lang/Runnable/run()V
+End:
+    return
+    .var 0 is this Lcom/x/Hello; from Start to End
+.end method
+
+.method public static pick(I)I
+    iload_0
+    tableswitch 0 1
+        One
+        Two
+        default : Other
+One:
+    iconst_1
+    ireturn
+Two:
+    iconst_2
+    ireturn
+Other:
+    iconst_0
+    ireturn
+.end method
+
+.method public static safe(Ljava/lang/Object;)I
+Start:
+    aload_0
+    invokevirtual java/lang/Object/hashCode()I
+End:
+    ireturn
+Handler:
+    pop
+    iconst_m1
+    ireturn
+    .catch java/lang/RuntimeException from Start to End using Handler
+.end method
+
+.method public static count(I)I
+    iconst_0
+    istore_1
+Loop:
+    iload_1
+    iload_0
+    if_icmpge Done
+    iinc 1 1
+    goto Loop
+Done:
+    iload_1
+    ireturn
+.end method
+
+.method private static lambda$0()V
+    return
+.end method
+`
+
+func TestAssembleVerifies(t *testing.T) {
+	class := assemble(t, hello)
+	if class.ThisClass != "com/x/Hello" || class.SuperClass != "java/lang/Object" || len(class.Interfaces) != 1 {
+		t.Fatalf("class = %s extends %s implements %v", class.ThisClass, class.SuperClass, class.Interfaces)
+	}
+	if len(class.Methods) != 6 {
+		t.Fatalf("%d methods, want 6", len(class.Methods))
+	}
+	for i := range class.Methods {
+		m := &class.Methods[i]
+		t.Run(m.Name, func(t *testing.T) {
+			if err := analysis.CheckMaxs(m); err != nil {
+				t.Error(err)
+			}
+			result, err := analysis.Verify(class, m, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.OK() {
+				t.Errorf("diagnostics = %v", result.Diagnostics)
+			}
+		})
+	}
+
+	run := code(t, class, "run")
+	indy, ok := run.Code[4].(classfileparser.Invokedynamic)
+	if !ok || indy.InvokeDynamic.Bootstrap == nil || indy.InvokeDynamic.Bootstrap.Method.Name != "metafactory" {
+		t.Errorf("invokedynamic = %#v", run.Code[4])
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	const header = ".class public com/x/T\n.super java/lang/Object\n"
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		want   string
+	}{
+		{"unknown instruction", ".method static m()V\nfrobnicate\nreturn\n.end method", 4, `unknown instruction "frobnicate"`},
+		{"operand count", ".method static m()V\niload\nreturn\n.end method", 4, "iload takes 1 operands, found 0"},
+		{"undefined label", ".method static m()V\ngoto Nowhere\n.end method", 4, "undefined label Nowhere"},
+		{"label at the end", ".method static m(I)V\niload_0\nifeq End\nreturn\nEnd:\n.end method", 5, "label End is not followed by an instruction"},
+		{"duplicate label", ".method static m()V\nA:\nA:\nreturn\n.end method", 5, "label A"},
+		{"handler at the end", ".method static m()V\nA:\nreturn\nB:\n.catch all from A to B using B\n.end method", 7, "label B is not followed by an instruction"},
+		{"unreachable code", ".method static m()V\nreturn\nnop\n.end method", 3, "method m()V: "},
+		{"empty exception range", ".method static m()V\nA:\nreturn\n.catch all from A to A using A\n.end method", 6, "exception range from A to A is empty"},
+		{"no code", ".method static m()V\n.end method", 4, "method m()V has no code"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := assembler.Assemble(strings.NewReader(header+test.source), nil)
+			var asmErr *assembler.Error
+			if !errors.As(err, &asmErr) {
+				t.Fatalf("error = %v, want an *assembler.Error", err)
+			}
+			if asmErr.Line != test.line || !strings.Contains(asmErr.Err.Error(), test.want) {
+				t.Errorf("error = %v, want line %d: %s", err, test.line, test.want)
+			}
+		})
+	}
+}
+
+func TestAssembleFarJumps(t *testing.T) {
+	// 40000 nops put the labels beyond the reach of 16-bit offsets
+	nops := strings.Repeat("    nop\n", 40000)
+	class := assemble(t, `.class public com/x/Far
+.super java/lang/Object
+.method public static far(I)V
+Top:
+    iload_0
+    ifeq Bottom
+`+nops+`Bottom:
+    iload_0
+    ifne Top
+    return
+.end method
+`)
+	body := code(t, class, "far")
+	var forward, backward []string
+	for _, instruction := range body.Code {
+		if _, ok := instruction.(classfileparser.Nop); ok {
+			continue
+		}
+		if instruction.PC() < 40000 {
+			forward = append(forward, instruction.Mnemonic())
+		} else {
+			backward = append(backward, instruction.Mnemonic())
+		}
+	}
+	// Conditional jumps become the opposite condition over a goto_w
+	if got, want := fmt.Sprint(forward), "[iload_0 ifne goto_w]"; got != want {
+		t.Errorf("forward jump = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(backward), "[iload_0 ifeq goto_w return]"; got != want {
+		t.Errorf("backward jump = %s, want %s", got, want)
+	}
+	result, err := analysis.Verify(class, &class.Methods[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK() {
+		t.Errorf("diagnostics = %v", result.Diagnostics)
+	}
+}
+
+func TestAssembleWideConstants(t *testing.T) {
+	var source strings.Builder
+	source.WriteString(".class public com/x/Constants\n.super java/lang/Object\n.method public static m()V\n")
+	for i := range 300 {
+		fmt.Fprintf(&source, "    ldc \"s%d\"\n    pop\n", i)
+	}
+	source.WriteString("    return\n.end method\n")
+	class := assemble(t, source.String())
+
+	var narrow, wide int
+	for _, instruction := range code(t, class, "m").Code {
+		switch instruction.(type) {
+		case classfileparser.Ldc:
+			narrow++
+		case classfileparser.LdcW:
+			wide++
+		}
+	}
+	if narrow+wide != 300 || narrow == 0 || wide == 0 {
+		t.Errorf("%d ldc and %d ldc_w, want both making 300", narrow, wide)
+	}
+}
@@ -0,0 +1,1108 @@
+package assembler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// simpleInstructions builds the instructions without operands, by mnemonic
+var simpleInstructions = map[string]func(at classfileparser.Location) classfileparser.Instruction{
+	"nop": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Nop{Location: at}
+	},
+	"aconst_null": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.AconstNull{Location: at}
+	},
+	"iconst_m1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.IconstM1{Location: at}
+	},
+	"iconst_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iconst0{Location: at}
+	},
+	"iconst_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iconst1{Location: at}
+	},
+	"iconst_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iconst2{Location: at}
+	},
+	"iconst_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iconst3{Location: at}
+	},
+	"iconst_4": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iconst4{Location: at}
+	},
+	"iconst_5": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iconst5{Location: at}
+	},
+	"lconst_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lconst0{Location: at}
+	},
+	"lconst_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lconst1{Location: at}
+	},
+	"fconst_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fconst0{Location: at}
+	},
+	"fconst_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fconst1{Location: at}
+	},
+	"fconst_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fconst2{Location: at}
+	},
+	"dconst_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dconst0{Location: at}
+	},
+	"dconst_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dconst1{Location: at}
+	},
+	"iload_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iload0{Location: at}
+	},
+	"iload_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iload1{Location: at}
+	},
+	"iload_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iload2{Location: at}
+	},
+	"iload_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iload3{Location: at}
+	},
+	"lload_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lload0{Location: at}
+	},
+	"lload_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lload1{Location: at}
+	},
+	"lload_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lload2{Location: at}
+	},
+	"lload_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lload3{Location: at}
+	},
+	"fload_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fload0{Location: at}
+	},
+	"fload_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fload1{Location: at}
+	},
+	"fload_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fload2{Location: at}
+	},
+	"fload_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fload3{Location: at}
+	},
+	"dload_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dload0{Location: at}
+	},
+	"dload_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dload1{Location: at}
+	},
+	"dload_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dload2{Location: at}
+	},
+	"dload_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dload3{Location: at}
+	},
+	"aload_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Aload0{Location: at}
+	},
+	"aload_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Aload1{Location: at}
+	},
+	"aload_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Aload2{Location: at}
+	},
+	"aload_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Aload3{Location: at}
+	},
+	"iaload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iaload{Location: at}
+	},
+	"laload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Laload{Location: at}
+	},
+	"faload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Faload{Location: at}
+	},
+	"daload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Daload{Location: at}
+	},
+	"aaload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Aaload{Location: at}
+	},
+	"baload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Baload{Location: at}
+	},
+	"caload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Caload{Location: at}
+	},
+	"saload": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Saload{Location: at}
+	},
+	"istore_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Istore0{Location: at}
+	},
+	"istore_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Istore1{Location: at}
+	},
+	"istore_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Istore2{Location: at}
+	},
+	"istore_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Istore3{Location: at}
+	},
+	"lstore_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lstore0{Location: at}
+	},
+	"lstore_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lstore1{Location: at}
+	},
+	"lstore_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lstore2{Location: at}
+	},
+	"lstore_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lstore3{Location: at}
+	},
+	"fstore_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fstore0{Location: at}
+	},
+	"fstore_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fstore1{Location: at}
+	},
+	"fstore_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fstore2{Location: at}
+	},
+	"fstore_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fstore3{Location: at}
+	},
+	"dstore_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dstore0{Location: at}
+	},
+	"dstore_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dstore1{Location: at}
+	},
+	"dstore_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dstore2{Location: at}
+	},
+	"dstore_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dstore3{Location: at}
+	},
+	"astore_0": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Astore0{Location: at}
+	},
+	"astore_1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Astore1{Location: at}
+	},
+	"astore_2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Astore2{Location: at}
+	},
+	"astore_3": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Astore3{Location: at}
+	},
+	"iastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iastore{Location: at}
+	},
+	"lastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lastore{Location: at}
+	},
+	"fastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fastore{Location: at}
+	},
+	"dastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dastore{Location: at}
+	},
+	"aastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Aastore{Location: at}
+	},
+	"bastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Bastore{Location: at}
+	},
+	"castore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Castore{Location: at}
+	},
+	"sastore": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Sastore{Location: at}
+	},
+	"pop": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Pop{Location: at}
+	},
+	"pop2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Pop2{Location: at}
+	},
+	"dup": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dup{Location: at}
+	},
+	"dup_x1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.DupX1{Location: at}
+	},
+	"dup_x2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.DupX2{Location: at}
+	},
+	"dup2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dup2{Location: at}
+	},
+	"dup2_x1": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dup2X1{Location: at}
+	},
+	"dup2_x2": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dup2X2{Location: at}
+	},
+	"swap": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Swap{Location: at}
+	},
+	"iadd": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iadd{Location: at}
+	},
+	"ladd": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ladd{Location: at}
+	},
+	"fadd": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fadd{Location: at}
+	},
+	"dadd": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dadd{Location: at}
+	},
+	"isub": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Isub{Location: at}
+	},
+	"lsub": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lsub{Location: at}
+	},
+	"fsub": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fsub{Location: at}
+	},
+	"dsub": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dsub{Location: at}
+	},
+	"imul": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Imul{Location: at}
+	},
+	"lmul": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lmul{Location: at}
+	},
+	"fmul": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fmul{Location: at}
+	},
+	"dmul": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dmul{Location: at}
+	},
+	"idiv": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Idiv{Location: at}
+	},
+	"ldiv": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ldiv{Location: at}
+	},
+	"fdiv": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fdiv{Location: at}
+	},
+	"ddiv": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ddiv{Location: at}
+	},
+	"irem": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Irem{Location: at}
+	},
+	"lrem": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lrem{Location: at}
+	},
+	"frem": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Frem{Location: at}
+	},
+	"drem": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Drem{Location: at}
+	},
+	"ineg": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ineg{Location: at}
+	},
+	"lneg": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lneg{Location: at}
+	},
+	"fneg": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fneg{Location: at}
+	},
+	"dneg": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dneg{Location: at}
+	},
+	"ishl": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ishl{Location: at}
+	},
+	"lshl": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lshl{Location: at}
+	},
+	"ishr": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ishr{Location: at}
+	},
+	"lshr": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lshr{Location: at}
+	},
+	"iushr": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iushr{Location: at}
+	},
+	"lushr": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lushr{Location: at}
+	},
+	"iand": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Iand{Location: at}
+	},
+	"land": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Land{Location: at}
+	},
+	"ior": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ior{Location: at}
+	},
+	"lor": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lor{Location: at}
+	},
+	"ixor": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ixor{Location: at}
+	},
+	"lxor": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lxor{Location: at}
+	},
+	"i2l": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.I2l{Location: at}
+	},
+	"i2f": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.I2f{Location: at}
+	},
+	"i2d": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.I2d{Location: at}
+	},
+	"l2i": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.L2i{Location: at}
+	},
+	"l2f": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.L2f{Location: at}
+	},
+	"l2d": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.L2d{Location: at}
+	},
+	"f2i": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.F2i{Location: at}
+	},
+	"f2l": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.F2l{Location: at}
+	},
+	"f2d": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.F2d{Location: at}
+	},
+	"d2i": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.D2i{Location: at}
+	},
+	"d2l": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.D2l{Location: at}
+	},
+	"d2f": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.D2f{Location: at}
+	},
+	"i2b": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.I2b{Location: at}
+	},
+	"i2c": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.I2c{Location: at}
+	},
+	"i2s": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.I2s{Location: at}
+	},
+	"lcmp": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lcmp{Location: at}
+	},
+	"fcmpl": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fcmpl{Location: at}
+	},
+	"fcmpg": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Fcmpg{Location: at}
+	},
+	"dcmpl": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dcmpl{Location: at}
+	},
+	"dcmpg": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dcmpg{Location: at}
+	},
+	"ireturn": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Ireturn{Location: at}
+	},
+	"lreturn": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Lreturn{Location: at}
+	},
+	"freturn": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Freturn{Location: at}
+	},
+	"dreturn": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Dreturn{Location: at}
+	},
+	"areturn": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Areturn{Location: at}
+	},
+	"return": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Return{Location: at}
+	},
+	"arraylength": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Arraylength{Location: at}
+	},
+	"athrow": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Athrow{Location: at}
+	},
+	"monitorenter": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Monitorenter{Location: at}
+	},
+	"monitorexit": func(at classfileparser.Location) classfileparser.Instruction {
+		return classfileparser.Monitorexit{Location: at}
+	},
+}
+
+// localInstructions builds the instructions taking a local variable index, by mnemonic. Indexes
+// above 255 are encoded with wide, using the opcode of the instruction.
+var localInstructions = map[string]func(at classfileparser.Location, index uint8) classfileparser.Instruction{
+	"iload": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Iload{Location: at, LocalIndex: index}
+	},
+	"lload": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Lload{Location: at, LocalIndex: index}
+	},
+	"fload": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Fload{Location: at, LocalIndex: index}
+	},
+	"dload": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Dload{Location: at, LocalIndex: index}
+	},
+	"aload": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Aload{Location: at, LocalIndex: index}
+	},
+	"istore": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Istore{Location: at, LocalIndex: index}
+	},
+	"lstore": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Lstore{Location: at, LocalIndex: index}
+	},
+	"fstore": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Fstore{Location: at, LocalIndex: index}
+	},
+	"dstore": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Dstore{Location: at, LocalIndex: index}
+	},
+	"astore": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Astore{Location: at, LocalIndex: index}
+	},
+	"ret": func(at classfileparser.Location, index uint8) classfileparser.Instruction {
+		return classfileparser.Ret{Location: at, LocalIndex: index}
+	},
+}
+
+// branchInstructions builds the instructions jumping to a label with a 16-bit offset, by mnemonic
+var branchInstructions = map[string]func(at classfileparser.Location, offset int16) classfileparser.Instruction{
+	"ifeq": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifeq{Location: at, Offset: offset}
+	},
+	"ifne": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifne{Location: at, Offset: offset}
+	},
+	"iflt": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Iflt{Location: at, Offset: offset}
+	},
+	"ifge": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifge{Location: at, Offset: offset}
+	},
+	"ifgt": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifgt{Location: at, Offset: offset}
+	},
+	"ifle": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifle{Location: at, Offset: offset}
+	},
+	"if_icmpeq": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfIcmpeq{Location: at, Offset: offset}
+	},
+	"if_icmpne": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfIcmpne{Location: at, Offset: offset}
+	},
+	"if_icmplt": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfIcmplt{Location: at, Offset: offset}
+	},
+	"if_icmpge": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfIcmpge{Location: at, Offset: offset}
+	},
+	"if_icmpgt": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfIcmpgt{Location: at, Offset: offset}
+	},
+	"if_icmple": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfIcmple{Location: at, Offset: offset}
+	},
+	"if_acmpeq": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfAcmpeq{Location: at, Offset: offset}
+	},
+	"if_acmpne": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.IfAcmpne{Location: at, Offset: offset}
+	},
+	"goto": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Goto{Location: at, Offset: offset}
+	},
+	"jsr": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Jsr{Location: at, Offset: offset}
+	},
+	"ifnull": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifnull{Location: at, Offset: offset}
+	},
+	"ifnonnull": func(at classfileparser.Location, offset int16) classfileparser.Instruction {
+		return classfileparser.Ifnonnull{Location: at, Offset: offset}
+	},
+}
+
+// operandMnemonics lists the other instructions, parsed one by one by instruction
+var operandMnemonics = []string{
+	"bipush", "sipush", "ldc", "ldc_w", "ldc2_w", "iinc", "goto_w", "jsr_w", "tableswitch", "lookupswitch",
+	"getstatic", "putstatic", "getfield", "putfield", "invokevirtual", "invokespecial", "invokestatic",
+	"invokeinterface", "invokedynamic", "new", "newarray", "anewarray", "checkcast", "instanceof",
+	"multianewarray", "wide",
+}
+
+// mnemonics maps the lowercase mnemonics without underscores, which are also the lowercase
+// names of the instruction types, to the mnemonics of the JVM specification
+var mnemonics = func() map[string]string {
+	names := map[string]string{}
+	add := func(mnemonic string) { names[strings.ReplaceAll(mnemonic, "_", "")] = mnemonic }
+	for mnemonic := range simpleInstructions {
+		add(mnemonic)
+	}
+	for mnemonic := range localInstructions {
+		add(mnemonic)
+	}
+	for mnemonic := range branchInstructions {
+		add(mnemonic)
+	}
+	for _, mnemonic := range operandMnemonics {
+		add(mnemonic)
+	}
+	return names
+}()
+
+// lookupMnemonic returns the mnemonic of the instruction named name, written as in the JVM
+// specification or as its type, in any case
+func lookupMnemonic(name string) (string, bool) {
+	mnemonic, ok := mnemonics[strings.ReplaceAll(strings.ToLower(name), "_", "")]
+	return mnemonic, ok
+}
+
+// newarrayTypes maps the element types of newarray to their atype codes
+var newarrayTypes = map[string]uint8{
+	"boolean": 4, "char": 5, "float": 6, "double": 7, "byte": 8, "short": 9, "int": 10, "long": 11,
+}
+
+// resolver returns the offset from the instruction at to the instruction a label marks
+type resolver func(label string) (int32, error)
+
+// jump is a branch to the instruction at index target, whose offset only fits once encoded
+type jump struct {
+	classfileparser.Branch
+	target int
+}
+
+// Target returns the index of the instruction jumped to
+func (j jump) Target() int { return j.target }
+
+// pendingInstruction is an instruction built once the labels of its method are known
+type pendingInstruction struct {
+	line  int
+	build func(at classfileparser.Location, offset resolver) (classfileparser.Instruction, error)
+}
+
+// instruction parses an instruction with its operands. Switches only get their header here, the
+// following lines list their targets.
+func (a *assembler) instruction(name string, operands []string) (pendingInstruction, error) {
+	mnemonic, ok := lookupMnemonic(name)
+	if !ok {
+		return pendingInstruction{}, fmt.Errorf("unknown instruction %q", name)
+	}
+	pending := pendingInstruction{line: a.line}
+	count := func(n int) error {
+		if len(operands) != n {
+			return fmt.Errorf("%s takes %d operands, found %d", mnemonic, n, len(operands))
+		}
+		return nil
+	}
+
+	if newInstruction, ok := simpleInstructions[mnemonic]; ok {
+		if err := count(0); err != nil {
+			return pending, err
+		}
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			return newInstruction(at), nil
+		}
+		return pending, nil
+	}
+	if newInstruction, ok := localInstructions[mnemonic]; ok {
+		if err := count(1); err != nil {
+			return pending, err
+		}
+		index, err := unsigned(operands[0], 16)
+		if err != nil {
+			return pending, err
+		}
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			if index > math.MaxUint8 {
+				return classfileparser.Wide{Location: at, OpCode: newInstruction(at, 0).Opcode(), LocalIndex: uint16(index)}, nil
+			}
+			return newInstruction(at, uint8(index)), nil
+		}
+		return pending, nil
+	}
+	if newInstruction, ok := branchInstructions[mnemonic]; ok {
+		if err := count(1); err != nil {
+			return pending, err
+		}
+		label := operands[0]
+		pending.build = func(at classfileparser.Location, offset resolver) (classfileparser.Instruction, error) {
+			delta, err := offset(label)
+			if err != nil {
+				return nil, err
+			}
+			// The offset is left to EncodeCode, which widens the jump when the target is too far
+			return jump{Branch: newInstruction(at, 0).(classfileparser.Branch), target: at.Pc + int(delta)}, nil
+		}
+		return pending, nil
+	}
+
+	var err error
+	switch mnemonic {
+	case "bipush", "sipush":
+		if err := count(1); err != nil {
+			return pending, err
+		}
+		bits := 8
+		if mnemonic == "sipush" {
+			bits = 16
+		}
+		value, err := integer(operands[0], bits)
+		if err != nil {
+			return pending, err
+		}
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			if mnemonic == "bipush" {
+				return classfileparser.Bipush{Location: at, Byte: byte(value)}, nil
+			}
+			return classfileparser.Sipush{Location: at, Short: int16(value)}, nil
+		}
+	case "ldc", "ldc_w", "ldc2_w":
+		pending.build, err = a.ldc(mnemonic, operands)
+	case "iinc":
+		if err := count(2); err != nil {
+			return pending, err
+		}
+		index, err := unsigned(operands[0], 16)
+		if err != nil {
+			return pending, err
+		}
+		increment, err := integer(operands[1], 16)
+		if err != nil {
+			return pending, err
+		}
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			if index > math.MaxUint8 || increment < math.MinInt8 || increment > math.MaxInt8 {
+				return classfileparser.Wide{Location: at, OpCode: 0x84, LocalIndex: uint16(index), Const: int16(increment)}, nil
+			}
+			return classfileparser.Iinc{Location: at, LocalIndex: uint8(index), Const: int8(increment)}, nil
+		}
+	case "goto_w", "jsr_w":
+		if err := count(1); err != nil {
+			return pending, err
+		}
+		label := operands[0]
+		pending.build = func(at classfileparser.Location, offset resolver) (classfileparser.Instruction, error) {
+			delta, err := offset(label)
+			if err != nil {
+				return nil, err
+			}
+			if mnemonic == "goto_w" {
+				return classfileparser.GotoW{Location: at, Offset: delta}, nil
+			}
+			return classfileparser.JsrW{Location: at, Offset: delta}, nil
+		}
+	case "tableswitch", "lookupswitch":
+		pending.build, err = a.openSwitch(mnemonic, operands)
+	case "getstatic", "putstatic", "getfield", "putfield":
+		if err := count(2); err != nil {
+			return pending, err
+		}
+		class, name, err := fieldRef(operands[0])
+		if err != nil {
+			return pending, err
+		}
+		if _, err := descriptor.ParseField(operands[1]); err != nil {
+			return pending, err
+		}
+		field := operands[1]
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			switch mnemonic {
+			case "getstatic":
+				return classfileparser.Getstatic{Location: at, Class: class, Name: name, Type: field}, nil
+			case "putstatic":
+				return classfileparser.Putstatic{Location: at, Class: class, Name: name, Type: field}, nil
+			case "getfield":
+				return classfileparser.Getfield{Location: at, Class: class, Name: name, Type: field}, nil
+			}
+			return classfileparser.Putfield{Location: at, Class: class, Name: name, Type: field}, nil
+		}
+	case "invokevirtual", "invokespecial", "invokestatic", "invokeinterface":
+		pending.build, err = invoke(mnemonic, operands)
+	case "invokedynamic":
+		if err := count(2); err != nil {
+			return pending, err
+		}
+		open := strings.IndexByte(operands[0], '(')
+		if open <= 0 {
+			return pending, fmt.Errorf("call site %q is not written name(descriptor)", operands[0])
+		}
+		name, method := operands[0][:open], operands[0][open:]
+		if _, err := descriptor.ParseMethod(method); err != nil {
+			return pending, err
+		}
+		bootstrap, err := unsigned(operands[1], 16)
+		if err != nil {
+			return pending, err
+		}
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			if int(bootstrap) >= len(a.bootstrapMethods) {
+				return nil, fmt.Errorf("bootstrap method %d is not declared", bootstrap)
+			}
+			return classfileparser.Invokedynamic{Location: at, InvokeDynamic: classfileparser.InvokeDynamic{BootstrapIndex: uint16(bootstrap), Name: name, Type: method}}, nil
+		}
+	case "new", "anewarray", "checkcast", "instanceof":
+		if err := count(1); err != nil {
+			return pending, err
+		}
+		class := operands[0]
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			switch mnemonic {
+			case "new":
+				return classfileparser.New{Location: at, Class: class}, nil
+			case "anewarray":
+				return classfileparser.Anewarray{Location: at, Class: class}, nil
+			case "checkcast":
+				return classfileparser.Checkcast{Location: at, Class: class}, nil
+			}
+			return classfileparser.Instanceof{Location: at, Class: class}, nil
+		}
+	case "newarray":
+		if err := count(1); err != nil {
+			return pending, err
+		}
+		atype, ok := newarrayTypes[operands[0]]
+		if !ok {
+			return pending, fmt.Errorf("newarray of unknown type %q", operands[0])
+		}
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			return classfileparser.Newarray{Location: at, Type: atype}, nil
+		}
+	case "multianewarray":
+		if err := count(2); err != nil {
+			return pending, err
+		}
+		dimensions, err := unsigned(operands[1], 8)
+		if err != nil || dimensions == 0 {
+			return pending, fmt.Errorf("invalid dimension count %q", operands[1])
+		}
+		class := operands[0]
+		pending.build = func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+			return classfileparser.Multianewarray{Location: at, Class: class, Dimension: uint8(dimensions)}, nil
+		}
+	case "wide":
+		pending.build, err = wide(operands)
+	}
+	return pending, err
+}
+
+// ldc parses the constant loaded by ldc, ldc_w or ldc2_w
+func (a *assembler) ldc(mnemonic string, operands []string) (func(classfileparser.Location, resolver) (classfileparser.Instruction, error), error) {
+	value, rest, err := a.constant(operands, mnemonic == "ldc2_w")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected %q after the constant", strings.Join(rest, " "))
+	}
+	switch value.(type) {
+	case int64, float64:
+		if mnemonic != "ldc2_w" {
+			return nil, fmt.Errorf("%s cannot load a long or double, use ldc2_w", mnemonic)
+		}
+	default:
+		if mnemonic == "ldc2_w" {
+			return nil, fmt.Errorf("ldc2_w only loads a long or double")
+		}
+	}
+	return func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+		switch mnemonic {
+		case "ldc":
+			return classfileparser.Ldc{Location: at, Value: value}, nil
+		case "ldc_w":
+			return classfileparser.LdcW{Location: at, Value: value}, nil
+		}
+		return classfileparser.Ldc2W{Location: at, Value: value}, nil
+	}, nil
+}
+
+// invoke parses a method invocation. invokespecial and invokestatic call an interface method
+// when the reference is preceded by the keyword interface, and the count of invokeinterface
+// defaults to the argument slots of the method plus one.
+func invoke(mnemonic string, operands []string) (func(classfileparser.Location, resolver) (classfileparser.Instruction, error), error) {
+	isInterface := len(operands) > 0 && operands[0] == "interface" && (mnemonic == "invokespecial" || mnemonic == "invokestatic")
+	if isInterface {
+		operands = operands[1:]
+	}
+	if len(operands) == 0 || len(operands) > 2 || (len(operands) == 2 && mnemonic != "invokeinterface") {
+		return nil, fmt.Errorf("%s takes a method reference", mnemonic)
+	}
+	class, name, method, err := methodRef(operands[0])
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := descriptor.ParseMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	count := uint64(parsed.ArgumentSlots() + 1)
+	if len(operands) == 2 {
+		if count, err = unsigned(operands[1], 8); err != nil {
+			return nil, err
+		}
+	}
+	return func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+		switch mnemonic {
+		case "invokevirtual":
+			return classfileparser.Invokevirtual{Location: at, Class: class, Name: name, Type: method}, nil
+		case "invokespecial":
+			return classfileparser.Invokespecial{Location: at, Class: class, Name: name, Type: method, Interface: isInterface}, nil
+		case "invokestatic":
+			return classfileparser.Invokestatic{Location: at, Class: class, Name: name, Type: method, Interface: isInterface}, nil
+		}
+		ref := classfileparser.InterfaceMethodref{Class: class, Name: name, Type: method}
+		return classfileparser.Invokeinterface{Location: at, InterfaceMethodref: ref, Count: uint8(count)}, nil
+	}, nil
+}
+
+// wide parses an explicitly wide local variable instruction, e.g. wide iinc 3 1000
+func wide(operands []string) (func(classfileparser.Location, resolver) (classfileparser.Instruction, error), error) {
+	if len(operands) < 2 {
+		return nil, fmt.Errorf("wide takes an instruction and a local variable index")
+	}
+	mnemonic, _ := lookupMnemonic(operands[0])
+	newInstruction, local := localInstructions[mnemonic]
+	if !local && mnemonic != "iinc" {
+		return nil, fmt.Errorf("wide cannot modify %q", operands[0])
+	}
+	if want := map[bool]int{true: 2, false: 3}[local]; len(operands) != want {
+		return nil, fmt.Errorf("wide %s takes %d operands, found %d", mnemonic, want-1, len(operands)-1)
+	}
+	index, err := unsigned(operands[1], 16)
+	if err != nil {
+		return nil, err
+	}
+	opcode, increment := uint8(0x84), int64(0)
+	if local {
+		opcode = newInstruction(classfileparser.Location{}, 0).Opcode()
+	} else if increment, err = integer(operands[2], 16); err != nil {
+		return nil, err
+	}
+	return func(at classfileparser.Location, _ resolver) (classfileparser.Instruction, error) {
+		return classfileparser.Wide{Location: at, OpCode: opcode, LocalIndex: uint16(index), Const: int16(increment)}, nil
+	}, nil
+}
+
+// switchBody collects the targets of a tableswitch or lookupswitch, listed one per line after
+// the instruction until its default target
+type switchBody struct {
+	lookup   bool
+	low      int32
+	high     *int32 // Declared highest index of a tableswitch, nil when implied by its targets
+	keys     []int32
+	labels   []string
+	fallback string
+	done     bool
+}
+
+// openSwitch parses the header of a switch, tableswitch low [high] or lookupswitch, and starts
+// collecting its targets
+func (a *assembler) openSwitch(mnemonic string, operands []string) (func(classfileparser.Location, resolver) (classfileparser.Instruction, error), error) {
+	body := &switchBody{lookup: mnemonic == "lookupswitch"}
+	switch {
+	case body.lookup && len(operands) != 0:
+		return nil, fmt.Errorf("lookupswitch takes no operands, its targets follow on the next lines")
+	case !body.lookup && (len(operands) < 1 || len(operands) > 2):
+		return nil, fmt.Errorf("tableswitch takes its lowest index and optionally its highest")
+	case !body.lookup:
+		low, err := integer(operands[0], 32)
+		if err != nil {
+			return nil, err
+		}
+		body.low = int32(low)
+		if len(operands) == 2 {
+			high, err := integer(operands[1], 32)
+			if err != nil {
+				return nil, err
+			}
+			if high < low {
+				return nil, fmt.Errorf("tableswitch highest index %d is below its lowest %d", high, low)
+			}
+			declared := int32(high)
+			body.high = &declared
+		}
+	}
+	a.method.body = body
+	return func(at classfileparser.Location, offset resolver) (classfileparser.Instruction, error) {
+		fallback, err := offset(body.fallback)
+		if err != nil {
+			return nil, err
+		}
+		offsets := make([]int32, len(body.labels))
+		for i, label := range body.labels {
+			if offsets[i], err = offset(label); err != nil {
+				return nil, err
+			}
+		}
+		if !body.lookup {
+			return classfileparser.Tableswitch{Location: at, DefaultOffset: fallback, Low: body.low, High: body.low + int32(len(offsets)) - 1, Offsets: offsets}, nil
+		}
+		pairs := make([]classfileparser.MatchOffset, len(offsets))
+		for i := range pairs {
+			pairs[i] = classfileparser.MatchOffset{Match: body.keys[i], Offset: offsets[i]}
+		}
+		return classfileparser.Lookupswitch{Location: at, DefaultOffset: fallback, Pairs: pairs}, nil
+	}, nil
+}
+
+// add parses a line of a switch body: a label for tableswitch, key : label for lookupswitch,
+// or default : label, which ends the body
+func (body *switchBody) add(tokens []string) error {
+	key, label, found := strings.Cut(strings.Join(tokens, " "), ":")
+	key, label = strings.TrimSpace(key), strings.TrimSpace(label)
+	switch {
+	case found && key == "default":
+		if label == "" {
+			return fmt.Errorf("default target without a label")
+		}
+		if body.high != nil && int64(*body.high)-int64(body.low)+1 != int64(len(body.labels)) {
+			return fmt.Errorf("tableswitch from %d to %d has %d targets", body.low, *body.high, len(body.labels))
+		}
+		if !body.lookup && len(body.labels) == 0 {
+			return fmt.Errorf("tableswitch without targets")
+		}
+		body.fallback, body.done = label, true
+	case body.lookup:
+		if !found || label == "" {
+			return fmt.Errorf("lookupswitch targets are written key : label")
+		}
+		match, err := integer(key, 32)
+		if err != nil {
+			return err
+		}
+		if n := len(body.keys); n > 0 && int32(match) <= body.keys[n-1] {
+			return fmt.Errorf("lookupswitch keys must increase, %d follows %d", match, body.keys[n-1])
+		}
+		body.keys = append(body.keys, int32(match))
+		body.labels = append(body.labels, label)
+	default:
+		if found || len(tokens) != 1 {
+			return fmt.Errorf("tableswitch targets are written one label per line")
+		}
+		body.labels = append(body.labels, tokens[0])
+	}
+	return nil
+}
+
+// constant parses a constant starting at tokens[0] and returns the tokens following it. It is a
+// quoted string, a number, class name, methodtype descriptor or methodhandle kind reference.
+// Integers are int64 and decimals float64 when wide is set, int32 and float32 otherwise; the
+// suffixes L, F and D force a type.
+func (a *assembler) constant(tokens []string, wide bool) (interface{}, []string, error) {
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("missing constant")
+	}
+	switch token := tokens[0]; {
+	case strings.HasPrefix(token, `"`):
+		value, err := text(token)
+		return value, tokens[1:], err
+	case token == "class" || token == "methodtype":
+		if len(tokens) < 2 {
+			return nil, nil, fmt.Errorf("%s without a value", token)
+		}
+		if token == "class" {
+			return classfileparser.Class(tokens[1]), tokens[2:], nil
+		}
+		if _, err := descriptor.ParseMethod(tokens[1]); err != nil {
+			return nil, nil, err
+		}
+		return classfileparser.MethodType(tokens[1]), tokens[2:], nil
+	case token == "methodhandle":
+		return methodHandle(tokens[1:])
+	}
+	value, err := number(tokens[0], wide)
+	return value, tokens[1:], err
+}
+
+// methodHandle parses the kind and reference of a method handle, e.g.
+// invokeStatic java/lang/Integer/valueOf(I)Ljava/lang/Integer; or getStatic Foo/count I, and
+// returns the tokens following it
+func methodHandle(tokens []string) (classfileparser.MethodHandle, []string, error) {
+	var handle classfileparser.MethodHandle
+	if len(tokens) < 2 {
+		return handle, nil, fmt.Errorf("methodhandle takes a kind and a reference")
+	}
+	handle.Kind, tokens = tokens[0], tokens[1:]
+	if tokens[0] == "interface" && len(tokens) > 1 {
+		handle.Interface, tokens = true, tokens[1:]
+	}
+	switch handle.Kind {
+	case "getField", "getStatic", "putField", "putStatic":
+		if len(tokens) < 2 {
+			return handle, nil, fmt.Errorf("%s handle takes a field reference and a descriptor", handle.Kind)
+		}
+		class, name, err := fieldRef(tokens[0])
+		if err != nil {
+			return handle, nil, err
+		}
+		handle.Class, handle.Name, handle.Type = class, name, tokens[1]
+		return handle, tokens[2:], nil
+	case "invokeVirtual", "invokeStatic", "invokeSpecial", "newInvokeSpecial", "invokeInterface":
+		class, name, method, err := methodRef(tokens[0])
+		if err != nil {
+			return handle, nil, err
+		}
+		handle.Class, handle.Name, handle.Type = class, name, method
+		return handle, tokens[1:], nil
+	}
+	return handle, nil, fmt.Errorf("unknown method handle kind %q", handle.Kind)
+}
+
+// number parses an integer or decimal constant, see constant
+func number(token string, wide bool) (interface{}, error) {
+	hex := strings.HasPrefix(strings.TrimLeft(token, "+-"), "0x") || strings.HasPrefix(strings.TrimLeft(token, "+-"), "0X")
+	suffix := strings.ToUpper(token[len(token)-1:])
+	switch {
+	case suffix == "L":
+		value, err := integer(token[:len(token)-1], 64)
+		return value, err
+	case !hex && (suffix == "F" || suffix == "D"):
+		token, wide = token[:len(token)-1], suffix == "D"
+	case hex || !strings.ContainsAny(token, ".eEIN"):
+		value, err := integer(token, map[bool]int{true: 64, false: 32}[wide])
+		if wide {
+			return value, err
+		}
+		return int32(value), err
+	}
+	if wide {
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double %q", token)
+		}
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(token, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid float %q", token)
+	}
+	return float32(value), nil
+}
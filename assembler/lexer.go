@@ -0,0 +1,96 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenize splits a source line into whitespace-separated tokens. A quoted string is a single
+// token, kept with its quotes, and a token starting with ; begins a comment running to the end of
+// the line, so that the semicolons of descriptors need no escaping.
+func tokenize(line string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			return tokens, nil
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated string %s", line[i:])
+			}
+			tokens = append(tokens, line[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(line) && line[end] != ' ' && line[end] != '\t' && line[end] != '\r' && line[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, line[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// text returns the value of a token that may be quoted, such as a file name or a signature
+func text(token string) (string, error) {
+	if !strings.HasPrefix(token, `"`) {
+		return token, nil
+	}
+	value, err := strconv.Unquote(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", token)
+	}
+	return value, nil
+}
+
+// integer parses a decimal or 0x-prefixed hexadecimal integer fitting in bits bits
+func integer(token string, bits int) (int64, error) {
+	value, err := strconv.ParseInt(token, 0, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %d-bit integer %q", bits, token)
+	}
+	return value, nil
+}
+
+// unsigned parses a decimal or 0x-prefixed hexadecimal integer fitting in bits bits, without sign
+func unsigned(token string, bits int) (uint64, error) {
+	value, err := strconv.ParseUint(token, 0, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unsigned %d-bit integer %q", bits, token)
+	}
+	return value, nil
+}
+
+// methodRef splits a method reference written class/name(descriptor), e.g.
+// java/io/PrintStream/println(Ljava/lang/String;)V
+func methodRef(token string) (class, name, descriptor string, err error) {
+	open := strings.IndexByte(token, '(')
+	if open < 0 {
+		return "", "", "", fmt.Errorf("method reference %q has no descriptor", token)
+	}
+	slash := strings.LastIndexByte(token[:open], '/')
+	if slash <= 0 || slash == open-1 {
+		return "", "", "", fmt.Errorf("method reference %q is not written class/name(descriptor)", token)
+	}
+	return token[:slash], token[slash+1 : open], token[open:], nil
+}
+
+// fieldRef splits a field reference written class/name, e.g. java/lang/System/out
+func fieldRef(token string) (class, name string, err error) {
+	slash := strings.LastIndexByte(token, '/')
+	if slash <= 0 || slash == len(token)-1 {
+		return "", "", fmt.Errorf("field reference %q is not written class/name", token)
+	}
+	return token[:slash], token[slash+1:], nil
+}
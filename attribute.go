@@ -3,8 +3,10 @@ package classfileparser
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 )
 
 // Attribute represents a generic attribute found in a JVM class file.
@@ -14,14 +16,25 @@ type Attribute interface{}
 type Code struct {
 	MaxStack       uint16
 	MaxLocals      uint16
-	Code           []interface{}
+	Code           []Instruction
 	ExceptionTable []ExceptionTableEntry
 	Attributes     []Attribute
 }
 
+// InstructionIndex returns the index in Code of the instruction starting at pc,
+// so that branch offsets and exception table entries can be mapped back to instructions
+func (c Code) InstructionIndex(pc int) (int, bool) {
+	i := sort.Search(len(c.Code), func(i int) bool { return c.Code[i].PC() >= pc })
+	if i < len(c.Code) && c.Code[i].PC() == pc {
+		return i, true
+	}
+	return 0, false
+}
+
 // ConstantValue is the attribute for a constant field.
 type ConstantValue struct {
 	ConstantValueIndex uint16
+	Value              interface{} // Resolved value: int32, float32, int64, float64 or string
 }
 
 // Deprecated marks a method as obsolete.
@@ -31,6 +44,7 @@ type Deprecated struct{}
 type Exceptions struct {
 	NumberOfExceptions  uint16
 	ExceptionIndexTable []uint16
+	Exceptions          []string // Resolved internal names of the exception classes
 }
 
 // InnerClasses stores information about nested classes.
@@ -87,9 +101,27 @@ type RuntimeInvisibleParameterAnnotations struct {
 	ParameterAnnotations []ParameterAnnotation
 }
 
+// RuntimeVisibleTypeAnnotations contains runtime-visible type annotations.
+type RuntimeVisibleTypeAnnotations struct {
+	NumAnnotations uint16
+	Annotations    []TypeAnnotation
+}
+
+// RuntimeInvisibleTypeAnnotations contains runtime-invisible type annotations.
+type RuntimeInvisibleTypeAnnotations struct {
+	NumAnnotations uint16
+	Annotations    []TypeAnnotation
+}
+
+// AnnotationDefault holds the default value of an annotation interface element.
+type AnnotationDefault struct {
+	Value ElementValue
+}
+
 // SourceFile holds the source file name.
 type SourceFile struct {
 	SourcefileIndex uint16
+	Name            string // Resolved source file name
 }
 
 // SourceDebugExtension holds source debugging data.
@@ -113,6 +145,9 @@ type Synthetic struct{}
 type EnclosingMethod struct {
 	ClassIndex  uint16
 	MethodIndex uint16
+	Class       string // Resolved internal name of the enclosing class
+	MethodName  string // Resolved name of the enclosing method, empty outside a method
+	MethodType  string // Resolved descriptor of the enclosing method, empty outside a method
 }
 
 // BootstrapMethods lists bootstrap methods.
@@ -123,40 +158,59 @@ type BootstrapMethods struct {
 
 // ModuleInfo stores module metadata.
 type ModuleInfo struct {
-	NameIndex uint16
-	Flags     uint16
-	Requires  []ModuleRequire
+	NameIndex    uint16
+	Flags        ModuleAccess
+	VersionIndex uint16
+	Requires     []ModuleRequire
+	Exports      []ModuleExport
+	Opens        []ModuleExport
+	UsesIndex    []uint16
+	Provides     []ModuleProvide
+	Name         string   // Resolved module name
+	Version      string   // Resolved module version, empty when absent
+	Uses         []string // Resolved internal names of the used services
 }
 
 // ModulePackages stores the packages declared in a module.
 type ModulePackages struct {
 	NumberOfPackages uint16
 	PackageIndex     []uint16
+	Packages         []string // Resolved package names, in internal form
 }
 
 // NestHost stores the host class.
 type NestHost struct {
 	HostClassIndex uint16
+	HostClass      string // Resolved internal name of the host class
 }
 
 // NestMembers lists the member classes.
 type NestMembers struct {
 	NumberOfMembers uint16
 	ClassIndex      []uint16
+	Classes         []string // Resolved internal names of the member classes
+}
+
+// RawAttribute keeps the undecoded body of an attribute this package does not know.
+type RawAttribute struct {
+	Name string
+	Info []byte
 }
 
 // PermittedSubclasses lists the permitted subclasses.
 type PermittedSubclasses struct {
 	NumberOfSubclasses uint16
 	SubclassIndex      []uint16
+	Subclasses         []string // Resolved internal names of the permitted subclasses
 }
 
 // ExceptionTableEntry describes the additional entries needed for attributes.
 type ExceptionTableEntry struct {
-	StartPc   uint16
-	EndPc     uint16
-	HandlerPc uint16
-	CatchType uint16
+	StartPc    uint16
+	EndPc      uint16
+	HandlerPc  uint16
+	CatchType  uint16
+	CatchClass string // Resolved internal name of the caught class, empty for any exception
 }
 
 // InnerClassInfo describes an InnerClasses entry.
@@ -164,7 +218,10 @@ type InnerClassInfo struct {
 	InnerClassIndex       uint16
 	OuterClassIndex       uint16
 	InnerNameIndex        uint16
-	InnerClassAccessFlags uint16
+	InnerClassAccessFlags InnerClassAccess
+	InnerClass            string // Resolved internal name of the inner class
+	OuterClass            string // Resolved internal name of the outer class, empty for local and anonymous classes
+	InnerName             string // Resolved simple name, empty for anonymous classes
 }
 
 // LineNumberTableEntry links a bytecode position to a source line.
@@ -194,7 +251,8 @@ type LocalVariableTypeEntry struct {
 // MethodParameter describes a method parameter.
 type MethodParameter struct {
 	NameIndex   uint16
-	AccessFlags uint16
+	AccessFlags ParameterAccess
+	Name        string // Resolved parameter name, empty for a formal parameter without a name
 }
 
 // Annotation represents a JVM annotation.
@@ -202,6 +260,7 @@ type Annotation struct {
 	TypeIndex            uint16
 	NumElementValuePairs uint16
 	ElementValuePairs    []ElementValuePair
+	Type                 string // Resolved field descriptor of the annotation interface, e.g. Ljava/lang/Deprecated;
 }
 
 // ParameterAnnotation groups the annotations of a method parameter.
@@ -210,684 +269,1292 @@ type ParameterAnnotation struct {
 	Annotations    []Annotation
 }
 
+// TypeAnnotation is an annotation on a use of a type. TargetType selects which of the target
+// fields are set, see the Target constants.
+type TypeAnnotation struct {
+	TargetType           uint8
+	TypeParameterIndex   uint8                 // Type parameter targets and type parameter bound targets
+	BoundIndex           uint8                 // Type parameter bound targets
+	SupertypeIndex       uint16                // Supertype target, 65535 for the superclass, otherwise an index in the interfaces
+	FormalParameterIndex uint8                 // Formal parameter target
+	ThrowsTypeIndex      uint16                // Throws target, an index in the Exceptions attribute
+	LocalVariables       []LocalVariableTarget // Local variable and resource variable targets
+	ExceptionTableIndex  uint16                // Catch target, an index in the exception table of the Code attribute
+	Offset               uint16                // Offset targets and type argument targets, pc of the annotated instruction
+	TypeArgumentIndex    uint8                 // Type argument targets
+	TypePath             []TypePathEntry       // Location of the annotated type inside the targeted type
+	Annotation
+}
+
+// LocalVariableTarget is a range of code in which a local variable has the annotated type.
+type LocalVariableTarget struct {
+	StartPc uint16
+	Length  uint16
+	Index   uint16
+}
+
+// TypePathEntry is a step from a type to one of its parts, see the Path constants.
+type TypePathEntry struct {
+	Kind              uint8
+	TypeArgumentIndex uint8 // Index of the type argument for PathTypeArgument steps
+}
+
 // ElementValuePair associates an element name with an annotation value.
 type ElementValuePair struct {
 	ElementNameIndex uint16
 	Value            ElementValue
+	Name             string // Resolved element name
 }
 
-// ElementValue holds an annotation value.
+// ElementValue holds an annotation value. Value depends on Tag: int8 for B, uint16 for C,
+// float64 for D, float32 for F, int32 for I, int64 for J, int16 for S, bool for Z, string for s,
+// EnumValue for e, ClassValue for c, Annotation for @ and []ElementValue for [.
+// The indices are those read from the class file; writing takes the constants from Value.
 type ElementValue struct {
-	Tag   uint8
-	Value uint16
+	Tag       uint8
+	Value     interface{}
+	Index     uint16 // const_value_index, class_info_index, or type_name_index for e
+	NameIndex uint16 // const_name_index for e
+}
+
+// EnumValue is an enum constant used as an annotation value.
+type EnumValue struct {
+	Type string // Field descriptor of the enum class
+	Name string // Name of the enum constant
 }
 
+// ClassValue is a class literal used as an annotation value, as a return descriptor (V for void.class).
+type ClassValue string
+
 // StackMapFrame describes a frame of the StackMapTable.
 type StackMapFrame struct {
-	FrameType   uint8
-	OffsetDelta uint16
-	StackItems  []StackItem
+	FrameType   uint8       // Raw frame_type, see Kind
+	OffsetDelta uint16      // Offset delta, implicit in the frame type of same and same_locals_1_stack_item frames
+	Pc          uint16      // Absolute bytecode offset the frame applies to
+	Chopped     uint8       // Number of locals removed by a chop frame
+	Locals      []StackItem // Locals added by an append frame, or every local of a full frame
+	StackItems  []StackItem // Operand stack of same_locals_1_stack_item and full frames
 }
 
-// StackItem represents a stack element inside the StackMapTable.
+// StackItem is a verification_type_info entry describing a local or an operand stack element.
 type StackItem struct {
-	Tag   uint8
-	Value uint16
+	Tag    uint8  // One of the Item constants
+	Class  string // Internal name or array descriptor of an ItemObject
+	Offset uint16 // Offset of the new instruction creating an ItemUninitialized
 }
 
 // BootstrapMethod references a bootstrap method and its arguments.
 type BootstrapMethod struct {
-	MethodRefIndex uint16
-	ArgumentsCount uint16
-	Arguments      []uint16
+	MethodRefIndex  uint16
+	ArgumentsCount  uint16
+	Arguments       []uint16
+	Method          MethodHandle  // Resolved bootstrap method handle
+	StaticArguments []interface{} // Resolved static arguments, typed like ConstantPool values
 }
 
 // ModuleRequire describes a requires entry of a ModuleInfo.
 type ModuleRequire struct {
 	NameIndex    uint16
-	Flags        uint16
+	Flags        RequiresAccess
 	VersionIndex uint16
+	Name         string // Resolved name of the required module
+	Version      string // Resolved version of the required module at compile time, empty when absent
+}
+
+// ModuleExport describes an exports or opens entry of a ModuleInfo.
+type ModuleExport struct {
+	PackageIndex uint16
+	Flags        ExportsAccess
+	ToIndex      []uint16
+	Package      string   // Resolved internal name of the package
+	To           []string // Resolved names of the modules the package is restricted to, empty when unqualified
+}
+
+// ModuleProvide describes a provides entry of a ModuleInfo.
+type ModuleProvide struct {
+	ServiceIndex uint16
+	WithIndex    []uint16
+	Service      string   // Resolved internal name of the service interface
+	With         []string // Resolved internal names of the implementations
 }
 
-func parseAttributes(attributes []AttributeInfo, cp ConstantPool) []Attribute {
+func parseAttributes(attributes []AttributeInfo, cp ConstantPool) ([]Attribute, error) {
 	var attr []Attribute
 	for _, a := range attributes {
+		name, err := constant[Utf8](cp, a.AttributeNameIndex)
+		if err != nil {
+			return nil, &DecodeError{Err: fmt.Errorf("invalid attribute name: %w", err)}
+		}
 		reader := bytes.NewReader(a.Info)
-		switch cp[a.AttributeNameIndex].(Utf8) {
-		case "Code":
-			var code Code
-
-			binary.Read(reader, binary.BigEndian, &code.MaxStack)
-			binary.Read(reader, binary.BigEndian, &code.MaxLocals)
-
-			var codeLength uint32
-			binary.Read(reader, binary.BigEndian, &codeLength)
-			start := reader.Len()
-			for {
-				if reader.Len() == start-int(codeLength) {
-					break
-				}
-				var opcode uint8
-				err := binary.Read(reader, binary.BigEndian, &opcode)
-				if err != nil {
-					panic(err)
-				}
-
-				switch opcode {
-				case 0x00:
-					code.Code = append(code.Code, Nop{})
-				case 0x01:
-					code.Code = append(code.Code, AconstNull{})
-				case 0x02:
-					code.Code = append(code.Code, IconstM1{})
-				case 0x03:
-					code.Code = append(code.Code, Iconst0{})
-				case 0x04:
-					code.Code = append(code.Code, Iconst1{})
-				case 0x05:
-					code.Code = append(code.Code, Iconst2{})
-				case 0x06:
-					code.Code = append(code.Code, Iconst3{})
-				case 0x07:
-					code.Code = append(code.Code, Iconst4{})
-				case 0x08:
-					code.Code = append(code.Code, Iconst5{})
-				case 0x09:
-					code.Code = append(code.Code, Lconst0{})
-				case 0x0A:
-					code.Code = append(code.Code, Lconst1{})
-				case 0x0B:
-					code.Code = append(code.Code, Fconst0{})
-				case 0x0C:
-					code.Code = append(code.Code, Fconst1{})
-				case 0x0D:
-					code.Code = append(code.Code, Fconst2{})
-				case 0x0E:
-					code.Code = append(code.Code, Dconst0{})
-				case 0x0F:
-					code.Code = append(code.Code, Dconst1{})
-				case 0x10:
-					var instr Bipush
-					binary.Read(reader, binary.BigEndian, &instr.Byte)
-					code.Code = append(code.Code, instr)
-				case 0x11:
-					var instr Sipush
-					binary.Read(reader, binary.BigEndian, &instr.Short)
-					code.Code = append(code.Code, instr)
-				case 0x12:
-					var cpIndex uint8
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Ldc(cp[uint16(cpIndex)]))
-				case 0x13:
-					var cpIndex uint8
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, LdcW(cp[uint16(cpIndex)]))
-				case 0x14:
-					var cpIndex uint8
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Ldc2W(cp[uint16(cpIndex)]))
-				case 0x15:
-					var instr Iload
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x16:
-					var instr Lload
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x17:
-					var instr Fload
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x18:
-					var instr Dload
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x19:
-					var instr Aload
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x1A:
-					code.Code = append(code.Code, Iload0{})
-				case 0x1B:
-					code.Code = append(code.Code, Iload1{})
-				case 0x1C:
-					code.Code = append(code.Code, Iload2{})
-				case 0x1D:
-					code.Code = append(code.Code, Iload3{})
-				case 0x1E:
-					code.Code = append(code.Code, Lload0{})
-				case 0x1F:
-					code.Code = append(code.Code, Lload1{})
-				case 0x20:
-					code.Code = append(code.Code, Lload2{})
-				case 0x21:
-					code.Code = append(code.Code, Lload3{})
-				case 0x22:
-					code.Code = append(code.Code, Fload0{})
-				case 0x23:
-					code.Code = append(code.Code, Fload1{})
-				case 0x24:
-					code.Code = append(code.Code, Fload2{})
-				case 0x25:
-					code.Code = append(code.Code, Fload3{})
-				case 0x26:
-					code.Code = append(code.Code, Dload0{})
-				case 0x27:
-					code.Code = append(code.Code, Dload1{})
-				case 0x28:
-					code.Code = append(code.Code, Dload2{})
-				case 0x29:
-					code.Code = append(code.Code, Dload3{})
-				case 0x2A:
-					code.Code = append(code.Code, Aload0{})
-				case 0x2B:
-					code.Code = append(code.Code, Aload1{})
-				case 0x2C:
-					code.Code = append(code.Code, Aload2{})
-				case 0x2D:
-					code.Code = append(code.Code, Aload3{})
-				case 0x2E:
-					code.Code = append(code.Code, Iaload{})
-				case 0x2F:
-					code.Code = append(code.Code, Laload{})
-				case 0x30:
-					code.Code = append(code.Code, Faload{})
-				case 0x31:
-					code.Code = append(code.Code, Daload{})
-				case 0x32:
-					code.Code = append(code.Code, Aaload{})
-				case 0x33:
-					code.Code = append(code.Code, Baload{})
-				case 0x34:
-					code.Code = append(code.Code, Caload{})
-				case 0x35:
-					code.Code = append(code.Code, Saload{})
-				case 0x36:
-					var instr Istore
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x37:
-					var instr Lstore
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x38:
-					var instr Fstore
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x39:
-					var instr Dstore
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x3A:
-					var instr Astore
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				case 0x3B:
-					code.Code = append(code.Code, Istore0{})
-				case 0x3C:
-					code.Code = append(code.Code, Istore1{})
-				case 0x3D:
-					code.Code = append(code.Code, Istore2{})
-				case 0x3E:
-					code.Code = append(code.Code, Istore3{})
-				case 0x3F:
-					code.Code = append(code.Code, Lstore0{})
-				case 0x40:
-					code.Code = append(code.Code, Lstore1{})
-				case 0x41:
-					code.Code = append(code.Code, Lstore2{})
-				case 0x42:
-					code.Code = append(code.Code, Lstore3{})
-				case 0x43:
-					code.Code = append(code.Code, Fstore0{})
-				case 0x44:
-					code.Code = append(code.Code, Fstore1{})
-				case 0x45:
-					code.Code = append(code.Code, Fstore2{})
-				case 0x46:
-					code.Code = append(code.Code, Fstore3{})
-				case 0x47:
-					code.Code = append(code.Code, Dstore0{})
-				case 0x48:
-					code.Code = append(code.Code, Dstore1{})
-				case 0x49:
-					code.Code = append(code.Code, Dstore2{})
-				case 0x4A:
-					code.Code = append(code.Code, Dstore3{})
-				case 0x4B:
-					code.Code = append(code.Code, Astore0{})
-				case 0x4C:
-					code.Code = append(code.Code, Astore1{})
-				case 0x4D:
-					code.Code = append(code.Code, Astore2{})
-				case 0x4E:
-					code.Code = append(code.Code, Astore3{})
-				case 0x4F:
-					code.Code = append(code.Code, Iastore{})
-				case 0x50:
-					code.Code = append(code.Code, Lastore{})
-				case 0x51:
-					code.Code = append(code.Code, Fastore{})
-				case 0x52:
-					code.Code = append(code.Code, Dastore{})
-				case 0x53:
-					code.Code = append(code.Code, Aastore{})
-				case 0x54:
-					code.Code = append(code.Code, Bastore{})
-				case 0x55:
-					code.Code = append(code.Code, Castore{})
-				case 0x56:
-					code.Code = append(code.Code, Sastore{})
-				case 0x57:
-					code.Code = append(code.Code, Pop{})
-				case 0x58:
-					code.Code = append(code.Code, Pop2{})
-				case 0x59:
-					code.Code = append(code.Code, Dup{})
-				case 0x5A:
-					code.Code = append(code.Code, DupX1{})
-				case 0x5B:
-					code.Code = append(code.Code, DupX2{})
-				case 0x5C:
-					code.Code = append(code.Code, Dup2{})
-				case 0x5D:
-					code.Code = append(code.Code, Dup2X1{})
-				case 0x5E:
-					code.Code = append(code.Code, Dup2X2{})
-				case 0x5F:
-					code.Code = append(code.Code, Swap{})
-				case 0x60:
-					code.Code = append(code.Code, Iadd{})
-				case 0x61:
-					code.Code = append(code.Code, Ladd{})
-				case 0x62:
-					code.Code = append(code.Code, Fadd{})
-				case 0x63:
-					code.Code = append(code.Code, Dadd{})
-				case 0x64:
-					code.Code = append(code.Code, Isub{})
-				case 0x65:
-					code.Code = append(code.Code, Lsub{})
-				case 0x66:
-					code.Code = append(code.Code, Fsub{})
-				case 0x67:
-					code.Code = append(code.Code, Dsub{})
-				case 0x68:
-					code.Code = append(code.Code, Imul{})
-				case 0x69:
-					code.Code = append(code.Code, Lmul{})
-				case 0x6A:
-					code.Code = append(code.Code, Fmul{})
-				case 0x6B:
-					code.Code = append(code.Code, Dmul{})
-				case 0x6C:
-					code.Code = append(code.Code, Idiv{})
-				case 0x6D:
-					code.Code = append(code.Code, Ldiv{})
-				case 0x6E:
-					code.Code = append(code.Code, Fdiv{})
-				case 0x6F:
-					code.Code = append(code.Code, Ddiv{})
-				case 0x70:
-					code.Code = append(code.Code, Irem{})
-				case 0x71:
-					code.Code = append(code.Code, Lrem{})
-				case 0x72:
-					code.Code = append(code.Code, Frem{})
-				case 0x73:
-					code.Code = append(code.Code, Drem{})
-				case 0x74:
-					code.Code = append(code.Code, Ineg{})
-				case 0x75:
-					code.Code = append(code.Code, Lneg{})
-				case 0x76:
-					code.Code = append(code.Code, Fneg{})
-				case 0x77:
-					code.Code = append(code.Code, Dneg{})
-				case 0x78:
-					code.Code = append(code.Code, Ishl{})
-				case 0x79:
-					code.Code = append(code.Code, Lshl{})
-				case 0x7A:
-					code.Code = append(code.Code, Ishr{})
-				case 0x7B:
-					code.Code = append(code.Code, Lshr{})
-				case 0x7C:
-					code.Code = append(code.Code, Iushr{})
-				case 0x7D:
-					code.Code = append(code.Code, Lushr{})
-				case 0x7E:
-					code.Code = append(code.Code, Iand{})
-				case 0x7F:
-					code.Code = append(code.Code, Land{})
-				case 0x80:
-					code.Code = append(code.Code, Ior{})
-				case 0x81:
-					code.Code = append(code.Code, Lor{})
-				case 0x82:
-					code.Code = append(code.Code, Ixor{})
-				case 0x83:
-					code.Code = append(code.Code, Lxor{})
-				case 0x84:
-					var instr Iinc
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					binary.Read(reader, binary.BigEndian, &instr.Const)
-					code.Code = append(code.Code, instr)
-				case 0x85:
-					code.Code = append(code.Code, I2l{})
-				case 0x86:
-					code.Code = append(code.Code, I2f{})
-				case 0x87:
-					code.Code = append(code.Code, I2d{})
-				case 0x88:
-					code.Code = append(code.Code, L2i{})
-				case 0x89:
-					code.Code = append(code.Code, L2f{})
-				case 0x8A:
-					code.Code = append(code.Code, L2d{})
-				case 0x8B:
-					code.Code = append(code.Code, F2i{})
-				case 0x8C:
-					code.Code = append(code.Code, F2l{})
-				case 0x8D:
-					code.Code = append(code.Code, F2d{})
-				case 0x8E:
-					code.Code = append(code.Code, D2i{})
-				case 0x8F:
-					code.Code = append(code.Code, D2l{})
-				case 0x90:
-					code.Code = append(code.Code, D2f{})
-				case 0x91:
-					code.Code = append(code.Code, I2b{})
-				case 0x92:
-					code.Code = append(code.Code, I2c{})
-				case 0x93:
-					code.Code = append(code.Code, I2s{})
-				case 0x94:
-					code.Code = append(code.Code, Lcmp{})
-				case 0x95:
-					code.Code = append(code.Code, Fcmpl{})
-				case 0x96:
-					code.Code = append(code.Code, Fcmpg{})
-				case 0x97:
-					code.Code = append(code.Code, Dcmpl{})
-				case 0x98:
-					code.Code = append(code.Code, Dcmpg{})
-				case 0x99:
-					var instr Ifeq
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0x9A:
-					var instr Ifne
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0x9B:
-					var instr Iflt
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0x9C:
-					var instr Ifge
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0x9D:
-					var instr Ifgt
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0x9E:
-					var instr Ifle
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0x9F:
-					var instr IfIcmpeq
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA0:
-					var instr IfIcmpne
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA1:
-					var instr IfIcmplt
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA2:
-					var instr IfIcmpge
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA3:
-					var instr IfIcmpgt
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA4:
-					var instr IfIcmple
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA5:
-					var instr IfAcmpeq
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA6:
-					var instr IfAcmpne
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA7:
-					var instr Goto
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA8:
-					var instr Jsr
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xA9:
-					var instr Ret
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					code.Code = append(code.Code, instr)
-				// case 0xAA:
-				// 	code.Code = append(code.Code, Tableswitch{})
-				// case 0xAB:
-				// 	code.Code = append(code.Code, Lookupswitch{})
-				case 0xAC:
-					code.Code = append(code.Code, Ireturn{})
-				case 0xAD:
-					code.Code = append(code.Code, Lreturn{})
-				case 0xAE:
-					code.Code = append(code.Code, Freturn{})
-				case 0xAF:
-					code.Code = append(code.Code, Dreturn{})
-				case 0xB0:
-					code.Code = append(code.Code, Areturn{})
-				case 0xB1:
-					code.Code = append(code.Code, Return{})
-				case 0xB2:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Getstatic(cp[cpIndex].(Fieldref)))
-				case 0xB3:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Putstatic(cp[cpIndex].(Fieldref)))
-				case 0xB4:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Getfield(cp[cpIndex].(Fieldref)))
-				case 0xB5:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Putfield(cp[cpIndex].(Fieldref)))
-				case 0xB6:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Invokevirtual(cp[cpIndex].(Methodref)))
-				case 0xB7:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Invokespecial(cp[cpIndex].(Methodref)))
-				case 0xB8:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Invokestatic(cp[cpIndex].(Methodref)))
-				case 0xB9:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					instr := Invokeinterface{
-						InterfaceMethodref: cp[cpIndex].(InterfaceMethodref),
-					}
-					binary.Read(reader, binary.BigEndian, &instr.Count)
-					var void byte
-					binary.Read(reader, binary.BigEndian, &void)
-					code.Code = append(code.Code, instr)
-				case 0xBA:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					instr := Invokedynamic{
-						InvokeDynamic: cp[cpIndex].(InvokeDynamic),
-					}
-					var void byte
-					binary.Read(reader, binary.BigEndian, &void)
-					binary.Read(reader, binary.BigEndian, &void)
-					code.Code = append(code.Code, instr)
-				case 0xBB:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, New(cp[cpIndex].(Class)))
-				case 0xBC:
-					var instr Newarray
-					binary.Read(reader, binary.BigEndian, &instr.Type)
-					code.Code = append(code.Code, instr)
-				case 0xBD:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Anewarray(cp[cpIndex].(Class)))
-				case 0xBE:
-					code.Code = append(code.Code, Arraylength{})
-				case 0xBF:
-					code.Code = append(code.Code, Athrow{})
-				case 0xC0:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Checkcast(cp[cpIndex].(Class)))
-				case 0xC1:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					code.Code = append(code.Code, Instanceof(cp[cpIndex].(Class)))
-				case 0xC2:
-					code.Code = append(code.Code, Monitorenter{})
-				case 0xC3:
-					code.Code = append(code.Code, Monitorexit{})
-				case 0xC4:
-					var instr Wide
-					binary.Read(reader, binary.BigEndian, &instr.OpCode)
-					binary.Read(reader, binary.BigEndian, &instr.LocalIndex)
-					if instr.OpCode == 0x84 {
-						binary.Read(reader, binary.BigEndian, &instr.Const)
-					}
-					code.Code = append(code.Code, instr)
-				case 0xC5:
-					var cpIndex uint16
-					binary.Read(reader, binary.BigEndian, &cpIndex)
-					instr := Multianewarray{
-						Class: string(cp[cpIndex].(Class)),
-					}
-					binary.Read(reader, binary.BigEndian, &instr.Dimension)
-					code.Code = append(code.Code, instr)
-				case 0xC6:
-					var instr Ifnull
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xC7:
-					var instr Ifnonnull
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xC8:
-					var instr GotoW
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				case 0xC9:
-					var instr JsrW
-					binary.Read(reader, binary.BigEndian, &instr.Offset)
-					code.Code = append(code.Code, instr)
-				default:
-					panic(fmt.Errorf("unknown opcode: %0x", opcode))
-				}
+		decoded, err := decodeAttribute(string(name), reader, cp)
+		if err != nil {
+			decodeErr := asDecodeError(err)
+			if _, ok := err.(*DecodeError); !ok {
+				decodeErr.Offset = offset(reader)
+			}
+			if decodeErr.Attribute == "" {
+				decodeErr.Attribute = string(name)
+			} else {
+				decodeErr.Attribute = string(name) + "." + decodeErr.Attribute
 			}
+			return nil, decodeErr
+		}
+		attr = append(attr, decoded)
+	}
+
+	return attr, nil
+}
 
-			var exceptionTableLength uint16
-			binary.Read(reader, binary.BigEndian, &exceptionTableLength)
-			code.ExceptionTable = make([]ExceptionTableEntry, exceptionTableLength)
-			for i := 0; i < int(exceptionTableLength); i++ {
-				exception := &ExceptionTableEntry{}
-				binary.Read(reader, binary.BigEndian, &exception.StartPc)
-				binary.Read(reader, binary.BigEndian, &exception.EndPc)
-				binary.Read(reader, binary.BigEndian, &exception.HandlerPc)
-				binary.Read(reader, binary.BigEndian, &exception.CatchType)
-				code.ExceptionTable[i] = *exception
+// decodeAttribute decodes the body of the attribute called name. Unknown attributes are kept as a RawAttribute.
+func decodeAttribute(name string, reader *bytes.Reader, cp ConstantPool) (Attribute, error) {
+	switch name {
+	case "Code":
+		return parseCode(reader, cp)
+	case "ConstantValue":
+		var value ConstantValue
+		if err := read(reader, &value.ConstantValueIndex); err != nil {
+			return nil, err
+		}
+		switch constant := cp[value.ConstantValueIndex].(type) {
+		case int32, float32, int64, float64, string:
+			value.Value = constant
+		case nil:
+			return nil, fmt.Errorf("constant pool index #%d does not refer to an entry", value.ConstantValueIndex)
+		default:
+			return nil, fmt.Errorf("constant pool entry #%d is %T, which is not a constant value", value.ConstantValueIndex, constant)
+		}
+		return value, nil
+	case "Deprecated":
+		return Deprecated{}, nil
+	case "Exceptions":
+		indexes, names, err := readClassList(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return Exceptions{
+			NumberOfExceptions:  uint16(len(indexes)),
+			ExceptionIndexTable: indexes,
+			Exceptions:          names,
+		}, nil
+	case "InnerClasses":
+		classes, err := readInnerClasses(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return InnerClasses{
+			NumberOfClasses: uint16(len(classes)),
+			InnerClassInfo:  classes,
+		}, nil
+	case "LineNumberTable":
+		var table LineNumberTable
+		if err := read(reader, &table.LineNumberTableLength); err != nil {
+			return nil, err
+		}
+		table.LineNumberTable = make([]LineNumberTableEntry, table.LineNumberTableLength)
+		if err := read(reader, table.LineNumberTable); err != nil {
+			return nil, err
+		}
+		return table, nil
+	case "LocalVariableTable":
+		var table LocalVariableTable
+		if err := read(reader, &table.LocalVariableTableLength); err != nil {
+			return nil, err
+		}
+		table.LocalVariableTable = make([]LocalVariableEntry, table.LocalVariableTableLength)
+		if err := read(reader, table.LocalVariableTable); err != nil {
+			return nil, err
+		}
+		return table, nil
+	case "LocalVariableTypeTable":
+		var table LocalVariableTypeTable
+		if err := read(reader, &table.LocalVariableTypeTableLength); err != nil {
+			return nil, err
+		}
+		table.LocalVariableTypeTable = make([]LocalVariableTypeEntry, table.LocalVariableTypeTableLength)
+		if err := read(reader, table.LocalVariableTypeTable); err != nil {
+			return nil, err
+		}
+		return table, nil
+	case "MethodParameters":
+		parameters, err := readMethodParameters(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return MethodParameters{
+			MethodParametersCount: uint16(len(parameters)),
+			MethodParameters:      parameters,
+		}, nil
+	case "RuntimeVisibleAnnotations":
+		annotations, err := readAnnotations(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return RuntimeVisibleAnnotations{
+			NumAnnotations: uint16(len(annotations)),
+			Annotations:    annotations,
+		}, nil
+	case "RuntimeInvisibleAnnotations":
+		annotations, err := readAnnotations(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return RuntimeInvisibleAnnotations{
+			NumAnnotations: uint16(len(annotations)),
+			Annotations:    annotations,
+		}, nil
+	case "RuntimeVisibleParameterAnnotations":
+		parameters, err := readParameterAnnotations(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return RuntimeVisibleParameterAnnotations{
+			NumParameters:        uint16(len(parameters)),
+			ParameterAnnotations: parameters,
+		}, nil
+	case "RuntimeInvisibleParameterAnnotations":
+		parameters, err := readParameterAnnotations(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return RuntimeInvisibleParameterAnnotations{
+			NumParameters:        uint16(len(parameters)),
+			ParameterAnnotations: parameters,
+		}, nil
+	case "RuntimeVisibleTypeAnnotations":
+		annotations, err := readTypeAnnotations(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return RuntimeVisibleTypeAnnotations{
+			NumAnnotations: uint16(len(annotations)),
+			Annotations:    annotations,
+		}, nil
+	case "RuntimeInvisibleTypeAnnotations":
+		annotations, err := readTypeAnnotations(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return RuntimeInvisibleTypeAnnotations{
+			NumAnnotations: uint16(len(annotations)),
+			Annotations:    annotations,
+		}, nil
+	case "AnnotationDefault":
+		value, err := readElementValue(reader, cp, 0)
+		if err != nil {
+			return nil, err
+		}
+		return AnnotationDefault{Value: value}, nil
+	case "SourceFile":
+		var file SourceFile
+		if err := read(reader, &file.SourcefileIndex); err != nil {
+			return nil, err
+		}
+		name, err := constant[Utf8](cp, file.SourcefileIndex)
+		if err != nil {
+			return nil, err
+		}
+		file.Name = string(name)
+		return file, nil
+	case "SourceDebugExtension":
+		extension := make([]byte, reader.Len())
+		_, _ = reader.Read(extension)
+		return SourceDebugExtension{DebugExtension: extension}, nil
+	case "Signature":
+		signature, err := readConstant[Utf8](reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return Signature(signature), nil
+	case "StackMapTable":
+		frames, err := readStackMapFrames(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return StackMapTable{
+			NumberOfEntries: uint16(len(frames)),
+			Entries:         frames,
+		}, nil
+	case "Synthetic":
+		return Synthetic{}, nil
+	case "EnclosingMethod":
+		var enclosing EnclosingMethod
+		if err := read(reader, &enclosing.ClassIndex, &enclosing.MethodIndex); err != nil {
+			return nil, err
+		}
+		class, err := constant[Class](cp, enclosing.ClassIndex)
+		if err != nil {
+			return nil, err
+		}
+		method, err := optionalConstant[NameAndType](cp, enclosing.MethodIndex)
+		if err != nil {
+			return nil, err
+		}
+		enclosing.Class, enclosing.MethodName, enclosing.MethodType = string(class), method.Name, method.Type
+		return enclosing, nil
+	case "BootstrapMethods":
+		methods, err := readBootstrapMethods(reader)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveBootstrapMethods(methods, cp); err != nil {
+			return nil, err
+		}
+		return BootstrapMethods{
+			NumBootstrapMethods: uint16(len(methods)),
+			BootstrapMethods:    methods,
+		}, nil
+	case "Module":
+		return readModule(reader, cp)
+	case "ModulePackages":
+		var count uint16
+		if err := read(reader, &count); err != nil {
+			return nil, err
+		}
+		packages := ModulePackages{NumberOfPackages: count, PackageIndex: make([]uint16, count), Packages: make([]string, count)}
+		if err := read(reader, packages.PackageIndex); err != nil {
+			return nil, err
+		}
+		for i, index := range packages.PackageIndex {
+			name, err := constant[Package](cp, index)
+			if err != nil {
+				return nil, fmt.Errorf("package %d: %w", i, err)
 			}
+			packages.Packages[i] = string(name)
+		}
+		return packages, nil
+	case "NestHost":
+		var host NestHost
+		if err := read(reader, &host.HostClassIndex); err != nil {
+			return nil, err
+		}
+		class, err := constant[Class](cp, host.HostClassIndex)
+		if err != nil {
+			return nil, err
+		}
+		host.HostClass = string(class)
+		return host, nil
+	case "NestMembers":
+		indexes, names, err := readClassList(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return NestMembers{
+			NumberOfMembers: uint16(len(indexes)),
+			ClassIndex:      indexes,
+			Classes:         names,
+		}, nil
+	case "PermittedSubclasses":
+		indexes, names, err := readClassList(reader, cp)
+		if err != nil {
+			return nil, err
+		}
+		return PermittedSubclasses{
+			NumberOfSubclasses: uint16(len(indexes)),
+			SubclassIndex:      indexes,
+			Subclasses:         names,
+		}, nil
+	}
+	info := make([]byte, reader.Len())
+	_, _ = reader.Read(info)
+	return RawAttribute{Name: name, Info: info}, nil
+}
+
+func parseCode(reader *bytes.Reader, cp ConstantPool) (Code, error) {
+	var code Code
+
+	var codeLength uint32
+	if err := read(reader, &code.MaxStack, &code.MaxLocals, &codeLength); err != nil {
+		return code, err
+	}
+	if int64(codeLength) > int64(reader.Len()) {
+		return code, fmt.Errorf("code length %d exceeds the remaining %d bytes", codeLength, reader.Len())
+	}
+	start := offset(reader)
+	end := start + int(codeLength)
+	for offset(reader) < end {
+		position := offset(reader)
+		at := Location{Pc: position - start}
+		var opcode uint8
+		err := read(reader, &opcode)
+		if err != nil {
+			return code, err
+		}
 
-			var attributesCount uint16
-			binary.Read(reader, binary.BigEndian, &attributesCount)
-			nestedAttributes := make([]AttributeInfo, attributesCount)
-			for i := 0; i < int(attributesCount); i++ {
-				attribute := &AttributeInfo{}
-				binary.Read(reader, binary.BigEndian, &attribute.AttributeNameIndex)
-				binary.Read(reader, binary.BigEndian, &attribute.AttributeLength)
-				attribute.Info = make([]byte, attribute.AttributeLength)
-				io.ReadFull(reader, attribute.Info)
-				nestedAttributes[i] = *attribute
+		switch opcode {
+		case 0x00:
+			code.Code = append(code.Code, Nop{Location: at})
+		case 0x01:
+			code.Code = append(code.Code, AconstNull{Location: at})
+		case 0x02:
+			code.Code = append(code.Code, IconstM1{Location: at})
+		case 0x03:
+			code.Code = append(code.Code, Iconst0{Location: at})
+		case 0x04:
+			code.Code = append(code.Code, Iconst1{Location: at})
+		case 0x05:
+			code.Code = append(code.Code, Iconst2{Location: at})
+		case 0x06:
+			code.Code = append(code.Code, Iconst3{Location: at})
+		case 0x07:
+			code.Code = append(code.Code, Iconst4{Location: at})
+		case 0x08:
+			code.Code = append(code.Code, Iconst5{Location: at})
+		case 0x09:
+			code.Code = append(code.Code, Lconst0{Location: at})
+		case 0x0A:
+			code.Code = append(code.Code, Lconst1{Location: at})
+		case 0x0B:
+			code.Code = append(code.Code, Fconst0{Location: at})
+		case 0x0C:
+			code.Code = append(code.Code, Fconst1{Location: at})
+		case 0x0D:
+			code.Code = append(code.Code, Fconst2{Location: at})
+		case 0x0E:
+			code.Code = append(code.Code, Dconst0{Location: at})
+		case 0x0F:
+			code.Code = append(code.Code, Dconst1{Location: at})
+		case 0x10:
+			instr := Bipush{Location: at}
+			err = read(reader, &instr.Byte)
+			code.Code = append(code.Code, instr)
+		case 0x11:
+			instr := Sipush{Location: at}
+			err = read(reader, &instr.Short)
+			code.Code = append(code.Code, instr)
+		case 0x12:
+			var value interface{}
+			value, err = readLoadable(reader, opcode, cp)
+			code.Code = append(code.Code, Ldc{Location: at, Value: value})
+		case 0x13:
+			var value interface{}
+			value, err = readLoadable(reader, opcode, cp)
+			code.Code = append(code.Code, LdcW{Location: at, Value: value})
+		case 0x14:
+			var value interface{}
+			value, err = readLoadable(reader, opcode, cp)
+			code.Code = append(code.Code, Ldc2W{Location: at, Value: value})
+		case 0x15:
+			instr := Iload{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x16:
+			instr := Lload{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x17:
+			instr := Fload{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x18:
+			instr := Dload{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x19:
+			instr := Aload{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x1A:
+			code.Code = append(code.Code, Iload0{Location: at})
+		case 0x1B:
+			code.Code = append(code.Code, Iload1{Location: at})
+		case 0x1C:
+			code.Code = append(code.Code, Iload2{Location: at})
+		case 0x1D:
+			code.Code = append(code.Code, Iload3{Location: at})
+		case 0x1E:
+			code.Code = append(code.Code, Lload0{Location: at})
+		case 0x1F:
+			code.Code = append(code.Code, Lload1{Location: at})
+		case 0x20:
+			code.Code = append(code.Code, Lload2{Location: at})
+		case 0x21:
+			code.Code = append(code.Code, Lload3{Location: at})
+		case 0x22:
+			code.Code = append(code.Code, Fload0{Location: at})
+		case 0x23:
+			code.Code = append(code.Code, Fload1{Location: at})
+		case 0x24:
+			code.Code = append(code.Code, Fload2{Location: at})
+		case 0x25:
+			code.Code = append(code.Code, Fload3{Location: at})
+		case 0x26:
+			code.Code = append(code.Code, Dload0{Location: at})
+		case 0x27:
+			code.Code = append(code.Code, Dload1{Location: at})
+		case 0x28:
+			code.Code = append(code.Code, Dload2{Location: at})
+		case 0x29:
+			code.Code = append(code.Code, Dload3{Location: at})
+		case 0x2A:
+			code.Code = append(code.Code, Aload0{Location: at})
+		case 0x2B:
+			code.Code = append(code.Code, Aload1{Location: at})
+		case 0x2C:
+			code.Code = append(code.Code, Aload2{Location: at})
+		case 0x2D:
+			code.Code = append(code.Code, Aload3{Location: at})
+		case 0x2E:
+			code.Code = append(code.Code, Iaload{Location: at})
+		case 0x2F:
+			code.Code = append(code.Code, Laload{Location: at})
+		case 0x30:
+			code.Code = append(code.Code, Faload{Location: at})
+		case 0x31:
+			code.Code = append(code.Code, Daload{Location: at})
+		case 0x32:
+			code.Code = append(code.Code, Aaload{Location: at})
+		case 0x33:
+			code.Code = append(code.Code, Baload{Location: at})
+		case 0x34:
+			code.Code = append(code.Code, Caload{Location: at})
+		case 0x35:
+			code.Code = append(code.Code, Saload{Location: at})
+		case 0x36:
+			instr := Istore{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x37:
+			instr := Lstore{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x38:
+			instr := Fstore{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x39:
+			instr := Dstore{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x3A:
+			instr := Astore{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0x3B:
+			code.Code = append(code.Code, Istore0{Location: at})
+		case 0x3C:
+			code.Code = append(code.Code, Istore1{Location: at})
+		case 0x3D:
+			code.Code = append(code.Code, Istore2{Location: at})
+		case 0x3E:
+			code.Code = append(code.Code, Istore3{Location: at})
+		case 0x3F:
+			code.Code = append(code.Code, Lstore0{Location: at})
+		case 0x40:
+			code.Code = append(code.Code, Lstore1{Location: at})
+		case 0x41:
+			code.Code = append(code.Code, Lstore2{Location: at})
+		case 0x42:
+			code.Code = append(code.Code, Lstore3{Location: at})
+		case 0x43:
+			code.Code = append(code.Code, Fstore0{Location: at})
+		case 0x44:
+			code.Code = append(code.Code, Fstore1{Location: at})
+		case 0x45:
+			code.Code = append(code.Code, Fstore2{Location: at})
+		case 0x46:
+			code.Code = append(code.Code, Fstore3{Location: at})
+		case 0x47:
+			code.Code = append(code.Code, Dstore0{Location: at})
+		case 0x48:
+			code.Code = append(code.Code, Dstore1{Location: at})
+		case 0x49:
+			code.Code = append(code.Code, Dstore2{Location: at})
+		case 0x4A:
+			code.Code = append(code.Code, Dstore3{Location: at})
+		case 0x4B:
+			code.Code = append(code.Code, Astore0{Location: at})
+		case 0x4C:
+			code.Code = append(code.Code, Astore1{Location: at})
+		case 0x4D:
+			code.Code = append(code.Code, Astore2{Location: at})
+		case 0x4E:
+			code.Code = append(code.Code, Astore3{Location: at})
+		case 0x4F:
+			code.Code = append(code.Code, Iastore{Location: at})
+		case 0x50:
+			code.Code = append(code.Code, Lastore{Location: at})
+		case 0x51:
+			code.Code = append(code.Code, Fastore{Location: at})
+		case 0x52:
+			code.Code = append(code.Code, Dastore{Location: at})
+		case 0x53:
+			code.Code = append(code.Code, Aastore{Location: at})
+		case 0x54:
+			code.Code = append(code.Code, Bastore{Location: at})
+		case 0x55:
+			code.Code = append(code.Code, Castore{Location: at})
+		case 0x56:
+			code.Code = append(code.Code, Sastore{Location: at})
+		case 0x57:
+			code.Code = append(code.Code, Pop{Location: at})
+		case 0x58:
+			code.Code = append(code.Code, Pop2{Location: at})
+		case 0x59:
+			code.Code = append(code.Code, Dup{Location: at})
+		case 0x5A:
+			code.Code = append(code.Code, DupX1{Location: at})
+		case 0x5B:
+			code.Code = append(code.Code, DupX2{Location: at})
+		case 0x5C:
+			code.Code = append(code.Code, Dup2{Location: at})
+		case 0x5D:
+			code.Code = append(code.Code, Dup2X1{Location: at})
+		case 0x5E:
+			code.Code = append(code.Code, Dup2X2{Location: at})
+		case 0x5F:
+			code.Code = append(code.Code, Swap{Location: at})
+		case 0x60:
+			code.Code = append(code.Code, Iadd{Location: at})
+		case 0x61:
+			code.Code = append(code.Code, Ladd{Location: at})
+		case 0x62:
+			code.Code = append(code.Code, Fadd{Location: at})
+		case 0x63:
+			code.Code = append(code.Code, Dadd{Location: at})
+		case 0x64:
+			code.Code = append(code.Code, Isub{Location: at})
+		case 0x65:
+			code.Code = append(code.Code, Lsub{Location: at})
+		case 0x66:
+			code.Code = append(code.Code, Fsub{Location: at})
+		case 0x67:
+			code.Code = append(code.Code, Dsub{Location: at})
+		case 0x68:
+			code.Code = append(code.Code, Imul{Location: at})
+		case 0x69:
+			code.Code = append(code.Code, Lmul{Location: at})
+		case 0x6A:
+			code.Code = append(code.Code, Fmul{Location: at})
+		case 0x6B:
+			code.Code = append(code.Code, Dmul{Location: at})
+		case 0x6C:
+			code.Code = append(code.Code, Idiv{Location: at})
+		case 0x6D:
+			code.Code = append(code.Code, Ldiv{Location: at})
+		case 0x6E:
+			code.Code = append(code.Code, Fdiv{Location: at})
+		case 0x6F:
+			code.Code = append(code.Code, Ddiv{Location: at})
+		case 0x70:
+			code.Code = append(code.Code, Irem{Location: at})
+		case 0x71:
+			code.Code = append(code.Code, Lrem{Location: at})
+		case 0x72:
+			code.Code = append(code.Code, Frem{Location: at})
+		case 0x73:
+			code.Code = append(code.Code, Drem{Location: at})
+		case 0x74:
+			code.Code = append(code.Code, Ineg{Location: at})
+		case 0x75:
+			code.Code = append(code.Code, Lneg{Location: at})
+		case 0x76:
+			code.Code = append(code.Code, Fneg{Location: at})
+		case 0x77:
+			code.Code = append(code.Code, Dneg{Location: at})
+		case 0x78:
+			code.Code = append(code.Code, Ishl{Location: at})
+		case 0x79:
+			code.Code = append(code.Code, Lshl{Location: at})
+		case 0x7A:
+			code.Code = append(code.Code, Ishr{Location: at})
+		case 0x7B:
+			code.Code = append(code.Code, Lshr{Location: at})
+		case 0x7C:
+			code.Code = append(code.Code, Iushr{Location: at})
+		case 0x7D:
+			code.Code = append(code.Code, Lushr{Location: at})
+		case 0x7E:
+			code.Code = append(code.Code, Iand{Location: at})
+		case 0x7F:
+			code.Code = append(code.Code, Land{Location: at})
+		case 0x80:
+			code.Code = append(code.Code, Ior{Location: at})
+		case 0x81:
+			code.Code = append(code.Code, Lor{Location: at})
+		case 0x82:
+			code.Code = append(code.Code, Ixor{Location: at})
+		case 0x83:
+			code.Code = append(code.Code, Lxor{Location: at})
+		case 0x84:
+			instr := Iinc{Location: at}
+			err = read(reader, &instr.LocalIndex, &instr.Const)
+			code.Code = append(code.Code, instr)
+		case 0x85:
+			code.Code = append(code.Code, I2l{Location: at})
+		case 0x86:
+			code.Code = append(code.Code, I2f{Location: at})
+		case 0x87:
+			code.Code = append(code.Code, I2d{Location: at})
+		case 0x88:
+			code.Code = append(code.Code, L2i{Location: at})
+		case 0x89:
+			code.Code = append(code.Code, L2f{Location: at})
+		case 0x8A:
+			code.Code = append(code.Code, L2d{Location: at})
+		case 0x8B:
+			code.Code = append(code.Code, F2i{Location: at})
+		case 0x8C:
+			code.Code = append(code.Code, F2l{Location: at})
+		case 0x8D:
+			code.Code = append(code.Code, F2d{Location: at})
+		case 0x8E:
+			code.Code = append(code.Code, D2i{Location: at})
+		case 0x8F:
+			code.Code = append(code.Code, D2l{Location: at})
+		case 0x90:
+			code.Code = append(code.Code, D2f{Location: at})
+		case 0x91:
+			code.Code = append(code.Code, I2b{Location: at})
+		case 0x92:
+			code.Code = append(code.Code, I2c{Location: at})
+		case 0x93:
+			code.Code = append(code.Code, I2s{Location: at})
+		case 0x94:
+			code.Code = append(code.Code, Lcmp{Location: at})
+		case 0x95:
+			code.Code = append(code.Code, Fcmpl{Location: at})
+		case 0x96:
+			code.Code = append(code.Code, Fcmpg{Location: at})
+		case 0x97:
+			code.Code = append(code.Code, Dcmpl{Location: at})
+		case 0x98:
+			code.Code = append(code.Code, Dcmpg{Location: at})
+		case 0x99:
+			instr := Ifeq{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0x9A:
+			instr := Ifne{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0x9B:
+			instr := Iflt{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0x9C:
+			instr := Ifge{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0x9D:
+			instr := Ifgt{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0x9E:
+			instr := Ifle{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0x9F:
+			instr := IfIcmpeq{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA0:
+			instr := IfIcmpne{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA1:
+			instr := IfIcmplt{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA2:
+			instr := IfIcmpge{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA3:
+			instr := IfIcmpgt{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA4:
+			instr := IfIcmple{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA5:
+			instr := IfAcmpeq{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA6:
+			instr := IfAcmpne{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA7:
+			instr := Goto{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA8:
+			instr := Jsr{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xA9:
+			instr := Ret{Location: at}
+			err = read(reader, &instr.LocalIndex)
+			code.Code = append(code.Code, instr)
+		case 0xAA:
+			var instr Tableswitch
+			instr, err = readTableswitch(reader, at)
+			code.Code = append(code.Code, instr)
+		case 0xAB:
+			var instr Lookupswitch
+			instr, err = readLookupswitch(reader, at)
+			code.Code = append(code.Code, instr)
+		case 0xAC:
+			code.Code = append(code.Code, Ireturn{Location: at})
+		case 0xAD:
+			code.Code = append(code.Code, Lreturn{Location: at})
+		case 0xAE:
+			code.Code = append(code.Code, Freturn{Location: at})
+		case 0xAF:
+			code.Code = append(code.Code, Dreturn{Location: at})
+		case 0xB0:
+			code.Code = append(code.Code, Areturn{Location: at})
+		case 0xB1:
+			code.Code = append(code.Code, Return{Location: at})
+		case 0xB2:
+			var ref Fieldref
+			ref, err = readConstant[Fieldref](reader, cp)
+			code.Code = append(code.Code, Getstatic{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type})
+		case 0xB3:
+			var ref Fieldref
+			ref, err = readConstant[Fieldref](reader, cp)
+			code.Code = append(code.Code, Putstatic{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type})
+		case 0xB4:
+			var ref Fieldref
+			ref, err = readConstant[Fieldref](reader, cp)
+			code.Code = append(code.Code, Getfield{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type})
+		case 0xB5:
+			var ref Fieldref
+			ref, err = readConstant[Fieldref](reader, cp)
+			code.Code = append(code.Code, Putfield{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type})
+		case 0xB6:
+			var ref Methodref
+			ref, err = readConstant[Methodref](reader, cp)
+			code.Code = append(code.Code, Invokevirtual{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type})
+		case 0xB7:
+			var ref Methodref
+			var isInterface bool
+			ref, isInterface, err = readMethodref(reader, cp)
+			code.Code = append(code.Code, Invokespecial{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type, Interface: isInterface})
+		case 0xB8:
+			var ref Methodref
+			var isInterface bool
+			ref, isInterface, err = readMethodref(reader, cp)
+			code.Code = append(code.Code, Invokestatic{Location: at, Class: ref.Class, Name: ref.Name, Type: ref.Type, Interface: isInterface})
+		case 0xB9:
+			instr := Invokeinterface{Location: at}
+			instr.InterfaceMethodref, err = readConstant[InterfaceMethodref](reader, cp)
+			if err == nil {
+				var void byte
+				err = read(reader, &instr.Count, &void)
+			}
+			code.Code = append(code.Code, instr)
+		case 0xBA:
+			instr := Invokedynamic{Location: at}
+			instr.InvokeDynamic, err = readConstant[InvokeDynamic](reader, cp)
+			if err == nil {
+				var void uint16
+				err = read(reader, &void)
+			}
+			code.Code = append(code.Code, instr)
+		case 0xBB:
+			var class Class
+			class, err = readConstant[Class](reader, cp)
+			code.Code = append(code.Code, New{Location: at, Class: string(class)})
+		case 0xBC:
+			instr := Newarray{Location: at}
+			err = read(reader, &instr.Type)
+			code.Code = append(code.Code, instr)
+		case 0xBD:
+			var class Class
+			class, err = readConstant[Class](reader, cp)
+			code.Code = append(code.Code, Anewarray{Location: at, Class: string(class)})
+		case 0xBE:
+			code.Code = append(code.Code, Arraylength{Location: at})
+		case 0xBF:
+			code.Code = append(code.Code, Athrow{Location: at})
+		case 0xC0:
+			var class Class
+			class, err = readConstant[Class](reader, cp)
+			code.Code = append(code.Code, Checkcast{Location: at, Class: string(class)})
+		case 0xC1:
+			var class Class
+			class, err = readConstant[Class](reader, cp)
+			code.Code = append(code.Code, Instanceof{Location: at, Class: string(class)})
+		case 0xC2:
+			code.Code = append(code.Code, Monitorenter{Location: at})
+		case 0xC3:
+			code.Code = append(code.Code, Monitorexit{Location: at})
+		case 0xC4:
+			instr := Wide{Location: at}
+			err = read(reader, &instr.OpCode)
+			switch {
+			case err != nil:
+			case instr.OpCode < 0x15 || instr.OpCode > 0x19 && instr.OpCode < 0x36 || instr.OpCode > 0x3A && instr.OpCode != 0x84 && instr.OpCode != 0xA9:
+				err = fmt.Errorf("invalid opcode 0x%02X modified by wide", instr.OpCode)
+			default:
+				err = read(reader, &instr.LocalIndex)
+			}
+			if err == nil && instr.OpCode == 0x84 {
+				err = read(reader, &instr.Const)
+			}
+			code.Code = append(code.Code, instr)
+		case 0xC5:
+			var class Class
+			class, err = readConstant[Class](reader, cp)
+			instr := Multianewarray{
+				Location: at,
+				Class:    string(class),
+			}
+			if err == nil {
+				err = read(reader, &instr.Dimension)
 			}
-			code.Attributes = parseAttributes(nestedAttributes, cp)
-
-			attr = append(attr, code)
-		case "ConstantValue": // TODO
-			attr = append(attr, ConstantValue{})
-		case "Deprecated": // TODO
-			attr = append(attr, Deprecated{})
-		case "Exceptions": // TODO
-			attr = append(attr, Exceptions{})
-		case "InnerClasses": // TODO
-			attr = append(attr, InnerClasses{})
-		case "LineNumberTable": // TODO
-			attr = append(attr, LineNumberTable{})
-		case "LocalVariableTable": // TODO
-			attr = append(attr, LocalVariableTable{})
-		case "LocalVariableTypeTable": // TODO
-			attr = append(attr, LocalVariableTypeTable{})
-		case "MethodParameters": // TODO
-			attr = append(attr, MethodParameters{})
-		case "RuntimeVisibleAnnotations": // TODO
-			attr = append(attr, RuntimeVisibleAnnotations{})
-		case "RuntimeInvisibleAnnotations": // TODO
-			attr = append(attr, RuntimeInvisibleAnnotations{})
-		case "RuntimeVisibleParameterAnnotations": // TODO
-			attr = append(attr, RuntimeVisibleParameterAnnotations{})
-		case "RuntimeInvisibleParameterAnnotations": // TODO
-			attr = append(attr, RuntimeInvisibleParameterAnnotations{})
-		case "SourceFile": // TODO
-			attr = append(attr, SourceFile{})
-		case "SourceDebugExtension": // TODO
-			attr = append(attr, SourceDebugExtension{})
-		case "Signature":
-			var cpIndex uint16
-			binary.Read(reader, binary.BigEndian, &cpIndex)
-			attr = append(attr, Signature(cp[cpIndex].(Utf8)))
-		case "StackMapTable": // TODO
-			attr = append(attr, StackMapTable{})
-		case "Synthetic": // TODO
-			attr = append(attr, Synthetic{})
-		case "EnclosingMethod": // TODO
-			attr = append(attr, EnclosingMethod{})
-		case "BootstrapMethods": // TODO
-			attr = append(attr, BootstrapMethods{})
-		case "Module": // TODO
-			attr = append(attr, ModuleInfo{})
-		case "ModulePackages": // TODO
-			attr = append(attr, ModulePackages{})
-		case "NestHost": // TODO
-			attr = append(attr, NestHost{})
-		case "NestMembers": // TODO
-			attr = append(attr, NestMembers{})
-		case "PermittedSubclasses": // TODO
-			attr = append(attr, PermittedSubclasses{})
-		}
-	}
-
-	return attr
+			code.Code = append(code.Code, instr)
+		case 0xC6:
+			instr := Ifnull{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xC7:
+			instr := Ifnonnull{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xC8:
+			instr := GotoW{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		case 0xC9:
+			instr := JsrW{Location: at}
+			err = read(reader, &instr.Offset)
+			code.Code = append(code.Code, instr)
+		default:
+			err = errors.New("unknown opcode")
+		}
+		if err == nil && offset(reader) > end {
+			err = errors.New("instruction overruns the code array")
+		}
+		if err != nil {
+			return code, &DecodeError{Offset: position, Err: fmt.Errorf("opcode 0x%02X at pc %d: %w", opcode, at.Pc, err)}
+		}
+	}
+
+	var exceptionTableLength uint16
+	if err := read(reader, &exceptionTableLength); err != nil {
+		return code, err
+	}
+	code.ExceptionTable = make([]ExceptionTableEntry, exceptionTableLength)
+	for i := range code.ExceptionTable {
+		exception := &code.ExceptionTable[i]
+		if err := read(reader, &exception.StartPc, &exception.EndPc, &exception.HandlerPc, &exception.CatchType); err != nil {
+			return code, err
+		}
+		catchClass, err := optionalConstant[Class](cp, exception.CatchType)
+		if err != nil {
+			return code, fmt.Errorf("exception table entry %d: %w", i, err)
+		}
+		exception.CatchClass = string(catchClass)
+	}
+
+	var attributesCount uint16
+	if err := read(reader, &attributesCount); err != nil {
+		return code, err
+	}
+	nestedAttributes := make([]AttributeInfo, attributesCount)
+	for i := range nestedAttributes {
+		if err := parseAttribute(reader, &nestedAttributes[i]); err != nil {
+			return code, fmt.Errorf("failed to read nested attribute %d: %w", i, noEOF(err))
+		}
+	}
+	attributes, err := parseAttributes(nestedAttributes, cp)
+	if err != nil {
+		return code, err
+	}
+	code.Attributes = attributes
+
+	return code, nil
+}
+
+// read decodes big-endian values from reader into each of data in order
+func read(reader io.Reader, data ...interface{}) error {
+	for _, d := range data {
+		if err := binary.Read(reader, binary.BigEndian, d); err != nil {
+			return noEOF(err)
+		}
+	}
+	return nil
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF, since attribute data never ends voluntarily mid-structure
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// offset returns the position of reader from the start of its attribute data
+func offset(reader *bytes.Reader) int {
+	return int(reader.Size()) - reader.Len()
+}
+
+// constant returns the constant pool entry at index, checking its type
+func constant[T interface{}](cp ConstantPool, index uint16) (T, error) {
+	value, ok := cp[index].(T)
+	if !ok {
+		if cp[index] == nil {
+			return value, fmt.Errorf("constant pool index #%d does not refer to an entry", index)
+		}
+		return value, fmt.Errorf("constant pool entry #%d is %T, expected %T", index, cp[index], value)
+	}
+	return value, nil
+}
+
+// optionalConstant is like constant, but index 0 marks an absent entry and yields the zero value
+func optionalConstant[T interface{}](cp ConstantPool, index uint16) (T, error) {
+	if index == 0 {
+		var zero T
+		return zero, nil
+	}
+	return constant[T](cp, index)
+}
+
+// readConstant reads a u2 constant pool index from reader and returns the entry, checking its type
+func readConstant[T interface{}](reader io.Reader, cp ConstantPool) (T, error) {
+	var index uint16
+	if err := read(reader, &index); err != nil {
+		var zero T
+		return zero, err
+	}
+	return constant[T](cp, index)
+}
+
+// readMethodref reads a u2 constant pool index referring to a Methodref or an InterfaceMethodref,
+// as allowed by invokespecial and invokestatic, and reports whether it was an InterfaceMethodref
+func readMethodref(reader io.Reader, cp ConstantPool) (Methodref, bool, error) {
+	var index uint16
+	if err := read(reader, &index); err != nil {
+		return Methodref{}, false, err
+	}
+	if ref, ok := cp[index].(InterfaceMethodref); ok {
+		return Methodref(ref), true, nil
+	}
+	ref, err := constant[Methodref](cp, index)
+	return ref, false, err
+}
+
+// readLoadable reads the constant pool index of an ldc family instruction and returns the loadable
+// entry, checking that its category matches the opcode
+func readLoadable(reader io.Reader, opcode uint8, cp ConstantPool) (interface{}, error) {
+	var index uint16
+	if opcode != 0x12 {
+		if err := read(reader, &index); err != nil {
+			return nil, err
+		}
+	} else {
+		var narrow uint8
+		if err := read(reader, &narrow); err != nil {
+			return nil, err
+		}
+		index = uint16(narrow)
+	}
+	value, err := loadable(cp, index)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLoadCategory(opcode, value); err != nil {
+		return nil, fmt.Errorf("constant pool entry #%d: %w", index, err)
+	}
+	return value, nil
+}
+
+// checkLoadCategory checks that ldc and ldc_w load a category 1 constant and ldc2_w a long or double one
+func checkLoadCategory(opcode uint8, value interface{}) error {
+	wide := false
+	switch value := value.(type) {
+	case int64, float64:
+		wide = true
+	case Dynamic:
+		wide = value.Type == "J" || value.Type == "D"
+	}
+	switch {
+	case opcode == 0x14 && !wide:
+		return fmt.Errorf("ldc2_w cannot load %T, only long and double constants", value)
+	case opcode != 0x14 && wide:
+		return fmt.Errorf("%T takes two slots and is loaded with ldc2_w", value)
+	}
+	return nil
+}
+
+// loadable returns the constant pool entry at index, checking that ldc or a bootstrap method can load it
+func loadable(cp ConstantPool, index uint16) (interface{}, error) {
+	switch value := cp[index].(type) {
+	case nil:
+		return nil, fmt.Errorf("constant pool index #%d does not refer to an entry", index)
+	case Utf8, NameAndType, Fieldref, Methodref, InterfaceMethodref, InvokeDynamic, Module, Package:
+		return nil, fmt.Errorf("constant pool entry #%d is %T, which is not loadable", index, value)
+	default:
+		return value, nil
+	}
+}
+
+// readBootstrapMethods reads the raw entries of a BootstrapMethods attribute
+func readBootstrapMethods(reader *bytes.Reader) ([]BootstrapMethod, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	methods := make([]BootstrapMethod, count)
+	for i := range methods {
+		method := &methods[i]
+		if err := read(reader, &method.MethodRefIndex, &method.ArgumentsCount); err != nil {
+			return nil, err
+		}
+		method.Arguments = make([]uint16, method.ArgumentsCount)
+		if err := read(reader, method.Arguments); err != nil {
+			return nil, err
+		}
+	}
+	return methods, nil
+}
+
+// resolveBootstrapMethods fills the method handle and static arguments of methods from cp
+func resolveBootstrapMethods(methods []BootstrapMethod, cp ConstantPool) error {
+	for i := range methods {
+		method := &methods[i]
+		handle, err := constant[MethodHandle](cp, method.MethodRefIndex)
+		if err != nil {
+			return fmt.Errorf("bootstrap method %d: %w", i, err)
+		}
+		method.Method = handle
+		method.StaticArguments = make([]interface{}, len(method.Arguments))
+		for j, argument := range method.Arguments {
+			if method.StaticArguments[j], err = loadable(cp, argument); err != nil {
+				return fmt.Errorf("bootstrap method %d, argument %d: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readInnerClasses reads the entries of an InnerClasses attribute
+func readInnerClasses(reader *bytes.Reader, cp ConstantPool) ([]InnerClassInfo, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	classes := make([]InnerClassInfo, count)
+	for i := range classes {
+		class := &classes[i]
+		if err := read(reader, &class.InnerClassIndex, &class.OuterClassIndex, &class.InnerNameIndex, &class.InnerClassAccessFlags); err != nil {
+			return nil, err
+		}
+		inner, err := constant[Class](cp, class.InnerClassIndex)
+		if err != nil {
+			return nil, fmt.Errorf("inner class %d: %w", i, err)
+		}
+		outer, err := optionalConstant[Class](cp, class.OuterClassIndex)
+		if err != nil {
+			return nil, fmt.Errorf("inner class %d: %w", i, err)
+		}
+		name, err := optionalConstant[Utf8](cp, class.InnerNameIndex)
+		if err != nil {
+			return nil, fmt.Errorf("inner class %d: %w", i, err)
+		}
+		class.InnerClass, class.OuterClass, class.InnerName = string(inner), string(outer), string(name)
+	}
+	return classes, nil
+}
+
+// readClassList reads a u2 count followed by as many CONSTANT_Class indexes, and returns the
+// indexes with the class names they resolve to
+func readClassList(reader *bytes.Reader, cp ConstantPool) ([]uint16, []string, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, nil, err
+	}
+	indexes := make([]uint16, count)
+	if err := read(reader, indexes); err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, count)
+	for i, index := range indexes {
+		class, err := constant[Class](cp, index)
+		if err != nil {
+			return nil, nil, fmt.Errorf("class %d: %w", i, err)
+		}
+		names[i] = string(class)
+	}
+	return indexes, names, nil
+}
+
+// readMethodParameters reads the entries of a MethodParameters attribute
+func readMethodParameters(reader *bytes.Reader, cp ConstantPool) ([]MethodParameter, error) {
+	var count uint8
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	parameters := make([]MethodParameter, count)
+	for i := range parameters {
+		parameter := &parameters[i]
+		if err := read(reader, &parameter.NameIndex, &parameter.AccessFlags); err != nil {
+			return nil, err
+		}
+		name, err := optionalConstant[Utf8](cp, parameter.NameIndex)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %w", i, err)
+		}
+		parameter.Name = string(name)
+	}
+	return parameters, nil
+}
+
+// skipSwitchPadding skips the 0 to 3 bytes aligning the operands of the switch instruction at pc on a multiple of 4
+func skipSwitchPadding(reader *bytes.Reader, pc int) error {
+	padding := make([]byte, switchPadding(pc))
+	_, err := io.ReadFull(reader, padding)
+	return noEOF(err)
+}
+
+// switchPadding returns the number of padding bytes following the opcode of a switch instruction at pc
+func switchPadding(pc int) int {
+	return (4 - (pc+1)%4) % 4
+}
+
+func readTableswitch(reader *bytes.Reader, at Location) (Tableswitch, error) {
+	instr := Tableswitch{Location: at}
+	if err := skipSwitchPadding(reader, at.Pc); err != nil {
+		return instr, err
+	}
+	if err := read(reader, &instr.DefaultOffset, &instr.Low, &instr.High); err != nil {
+		return instr, err
+	}
+	if instr.Low > instr.High {
+		return instr, fmt.Errorf("tableswitch low %d is greater than high %d", instr.Low, instr.High)
+	}
+	count := int64(instr.High) - int64(instr.Low) + 1
+	if count*4 > int64(reader.Len()) {
+		return instr, fmt.Errorf("tableswitch with %d offsets overruns the attribute", count)
+	}
+	instr.Offsets = make([]int32, count)
+	err := read(reader, instr.Offsets)
+	return instr, err
+}
+
+func readLookupswitch(reader *bytes.Reader, at Location) (Lookupswitch, error) {
+	instr := Lookupswitch{Location: at}
+	if err := skipSwitchPadding(reader, at.Pc); err != nil {
+		return instr, err
+	}
+	var npairs int32
+	if err := read(reader, &instr.DefaultOffset, &npairs); err != nil {
+		return instr, err
+	}
+	if npairs < 0 || int64(npairs)*8 > int64(reader.Len()) {
+		return instr, fmt.Errorf("invalid lookupswitch pair count %d", npairs)
+	}
+	instr.Pairs = make([]MatchOffset, npairs)
+	if err := read(reader, instr.Pairs); err != nil {
+		return instr, err
+	}
+	for i := 1; i < len(instr.Pairs); i++ {
+		if instr.Pairs[i-1].Match >= instr.Pairs[i].Match {
+			return instr, errors.New("lookupswitch matches are not sorted in increasing order")
+		}
+	}
+	return instr, nil
 }
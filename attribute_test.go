@@ -0,0 +1,86 @@
+package classfileparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// codeAttribute returns the info of a Code attribute holding code and no exception table or attributes
+func codeAttribute(code []byte) []byte {
+	info := binary.BigEndian.AppendUint16(nil, 1)
+	info = binary.BigEndian.AppendUint16(info, 1)
+	info = binary.BigEndian.AppendUint32(info, uint32(len(code)))
+	info = append(info, code...)
+	return append(info, 0, 0, 0, 0)
+}
+
+// switchCode returns nops nops followed by a switch opcode, its padding and the operands
+func switchCode(nops int, opcode byte, operands ...int32) []byte {
+	code := append(make([]byte, nops), opcode)
+	code = append(code, make([]byte, switchPadding(nops))...)
+	for _, operand := range operands {
+		code = binary.BigEndian.AppendUint32(code, uint32(operand))
+	}
+	return code
+}
+
+func TestSwitchPadding(t *testing.T) {
+	for pc, want := range []int{3, 2, 1, 0} {
+		if got := switchPadding(pc); got != want {
+			t.Errorf("switchPadding(%d) = %d, want %d", pc, got, want)
+		}
+	}
+}
+
+func TestParseTableswitch(t *testing.T) {
+	for pc := range 4 {
+		want := Tableswitch{Location: Location{Pc: pc}, DefaultOffset: 20, Low: -1, High: 1, Offsets: []int32{8, 12, 16}}
+		code := switchCode(pc, 0xAA, 20, -1, 1, 8, 12, 16)
+		parsed, err := parseCode(bytes.NewReader(codeAttribute(code)), ConstantPool{})
+		if err != nil {
+			t.Fatalf("pc %d: %v", pc, err)
+		}
+		if len(parsed.Code) != pc+1 || !reflect.DeepEqual(parsed.Code[pc], want) {
+			t.Errorf("pc %d: code = %#v", pc, parsed.Code)
+		}
+	}
+}
+
+func TestParseLookupswitch(t *testing.T) {
+	for pc := range 4 {
+		want := Lookupswitch{Location: Location{Pc: pc}, DefaultOffset: 20, Pairs: []MatchOffset{{Match: -5, Offset: 8}, {Match: 100, Offset: 12}}}
+		code := switchCode(pc, 0xAB, 20, 2, -5, 8, 100, 12)
+		parsed, err := parseCode(bytes.NewReader(codeAttribute(code)), ConstantPool{})
+		if err != nil {
+			t.Fatalf("pc %d: %v", pc, err)
+		}
+		if len(parsed.Code) != pc+1 || !reflect.DeepEqual(parsed.Code[pc], want) {
+			t.Errorf("pc %d: code = %#v", pc, parsed.Code)
+		}
+	}
+}
+
+func TestParseInvalidSwitch(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		err  string
+	}{
+		{"tableswitch low above high", switchCode(0, 0xAA, 0, 2, 1), "low 2 is greater than high 1"},
+		{"tableswitch oversized range", switchCode(1, 0xAA, 0, -0x80000000, 0x7FFFFFFF), "overruns the attribute"},
+		{"tableswitch missing offsets", switchCode(2, 0xAA, 0, 0, 3, 4), "overruns the attribute"},
+		{"lookupswitch negative count", switchCode(3, 0xAB, 0, -1), "invalid lookupswitch pair count -1"},
+		{"lookupswitch oversized count", switchCode(0, 0xAB, 0, 0x10000000), "invalid lookupswitch pair count"},
+		{"lookupswitch unsorted", switchCode(0, 0xAB, 0, 2, 5, 0, 5, 0), "not sorted"},
+		{"truncated padding", []byte{0, 0xAA, 0}, "opcode 0xAA at pc 1"},
+	}
+	for _, test := range tests {
+		_, err := parseCode(bytes.NewReader(codeAttribute(test.code)), ConstantPool{})
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
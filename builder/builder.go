@@ -0,0 +1,240 @@
+// Package builder generates class files from Go with a fluent API, for adapter classes and other
+// small generated code.
+package builder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/analysis"
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// Class describes a class to generate. Errors are kept and reported by Build, so that calls can
+// be chained:
+//
+//	file, err := builder.NewClass("com/x/Foo").
+//		Implements("java/lang/Runnable").
+//		Field(classfileparser.FieldPrivate, "count", "I").
+//		Method(classfileparser.MethodPublic, "run", "()V", func(code *builder.Code) {
+//			code.Emit(classfileparser.Return{})
+//		}).
+//		Build()
+type Class struct {
+	name       string
+	super      string
+	interfaces []string
+	access     classfileparser.ClassAccess
+	major      uint16
+	minor      uint16
+	source     string
+	resolver   analysis.SuperclassResolver
+	fields     []field
+	methods    []method
+	bootstraps []bootstrap
+	err        error
+}
+
+type field struct {
+	access           classfileparser.FieldAccess
+	name, descriptor string
+}
+
+// bootstrap is a bootstrap method added by Bootstrap
+type bootstrap struct {
+	handle    classfileparser.MethodHandle
+	arguments []interface{}
+}
+
+type method struct {
+	access           classfileparser.MethodAccess
+	name, descriptor string
+	code             *Code // nil for abstract and native methods
+}
+
+// NewClass starts a public class named name, in internal form, extending java/lang/Object.
+// The class file version is 52 unless Version changes it.
+func NewClass(name string) *Class {
+	c := &Class{name: name, super: "java/lang/Object", access: classfileparser.ClassPublic | classfileparser.ClassSuper, major: 52}
+	if name == "java/lang/Object" {
+		c.super = ""
+	}
+	if !descriptor.ValidInternalName(name) {
+		c.fail(fmt.Errorf("invalid class name %q", name))
+	}
+	return c
+}
+
+// fail records the first error
+func (c *Class) fail(err error) *Class {
+	if c.err == nil {
+		c.err = err
+	}
+	return c
+}
+
+// Access replaces the access flags of the class, public and super by default
+func (c *Class) Access(access classfileparser.ClassAccess) *Class {
+	c.access = access
+	return c
+}
+
+// Version sets the class file version. From version 50, methods get a StackMapTable.
+func (c *Class) Version(major, minor uint16) *Class {
+	c.major, c.minor = major, minor
+	return c
+}
+
+// Extends sets the superclass
+func (c *Class) Extends(super string) *Class {
+	c.super = super
+	return c
+}
+
+// Implements adds interfaces to those the class implements
+func (c *Class) Implements(interfaces ...string) *Class {
+	c.interfaces = append(c.interfaces, interfaces...)
+	return c
+}
+
+// Source sets the name of the source file recorded in the SourceFile attribute
+func (c *Class) Source(file string) *Class {
+	c.source = file
+	return c
+}
+
+// Resolver sets how classes merge where control flow joins when computing stack map frames,
+// see analysis.ComputeFrames. Without one, distinct classes merge into java/lang/Object.
+func (c *Class) Resolver(resolver analysis.SuperclassResolver) *Class {
+	c.resolver = resolver
+	return c
+}
+
+// Bootstrap adds a bootstrap method calling handle with the static arguments, typed like the
+// values of a ConstantPool (e.g. classfileparser.MethodType or classfileparser.MethodHandle),
+// and returns its index, the BootstrapIndex of classfileparser.InvokeDynamic and
+// classfileparser.Dynamic constants
+func (c *Class) Bootstrap(handle classfileparser.MethodHandle, arguments ...interface{}) uint16 {
+	c.bootstraps = append(c.bootstraps, bootstrap{handle: handle, arguments: arguments})
+	return uint16(len(c.bootstraps) - 1)
+}
+
+// Field adds a field whose type is the field descriptor desc
+func (c *Class) Field(access classfileparser.FieldAccess, name, desc string) *Class {
+	if _, err := descriptor.ParseField(desc); err != nil {
+		return c.fail(fmt.Errorf("field %s: %w", name, err))
+	}
+	c.fields = append(c.fields, field{access: access, name: name, descriptor: desc})
+	return c
+}
+
+// Method adds a method with the method descriptor desc, whose instructions body emits. body is
+// nil for abstract and native methods.
+func (c *Class) Method(access classfileparser.MethodAccess, name, desc string, body func(code *Code)) *Class {
+	if _, err := descriptor.ParseMethod(desc); err != nil {
+		return c.fail(fmt.Errorf("method %s: %w", name, err))
+	}
+	bodiless := access&(classfileparser.MethodAbstract|classfileparser.MethodNative) != 0
+	switch {
+	case bodiless && body != nil:
+		return c.fail(fmt.Errorf("method %s%s is abstract or native but has a body", name, desc))
+	case !bodiless && body == nil:
+		return c.fail(fmt.Errorf("method %s%s has no body", name, desc))
+	}
+	m := method{access: access, name: name, descriptor: desc}
+	if body != nil {
+		m.code = &Code{}
+		body(m.code)
+	}
+	c.methods = append(c.methods, m)
+	return c
+}
+
+// Build returns the class file, as Open reads it back. The constant pool holds every name,
+// descriptor and constant the class refers to, once. MaxStack and MaxLocals and, from version
+// 50, the StackMapTable are computed with analysis.ComputeCode, so the code cannot be
+// unreachable or use jsr and ret there.
+func (c *Class) Build() (*classfileparser.ClassFile, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	pool := classfileparser.NewPoolBuilder(nil)
+	file := &classfileparser.ClassFile{
+		Magic:        0xCAFEBABE,
+		MinorVersion: c.minor,
+		MajorVersion: c.major,
+		AccessFlags:  uint16(c.access),
+		ThisClass:    pool.Class(c.name),
+	}
+	if c.super != "" {
+		file.SuperClass = pool.Class(c.super)
+	}
+	for _, name := range c.interfaces {
+		file.Interfaces = append(file.Interfaces, pool.Class(name))
+	}
+	for _, f := range c.fields {
+		file.Fields = append(file.Fields, classfileparser.FieldInfo{AccessFlags: uint16(f.access), NameIndex: pool.Utf8(f.name), DescriptorIndex: pool.Utf8(f.descriptor)})
+	}
+	for _, m := range c.methods {
+		info := classfileparser.MethodInfo{AccessFlags: uint16(m.access), NameIndex: pool.Utf8(m.name), DescriptorIndex: pool.Utf8(m.descriptor)}
+		if m.code != nil {
+			code, err := m.code.code(pool)
+			if err != nil {
+				return nil, fmt.Errorf("method %s%s: %w", m.name, m.descriptor, err)
+			}
+			encoded, err := classfileparser.EncodeCode(code, pool)
+			if err != nil {
+				return nil, fmt.Errorf("method %s%s: %w", m.name, m.descriptor, err)
+			}
+			info.Attributes = append(info.Attributes, encoded)
+		}
+		file.Methods = append(file.Methods, info)
+	}
+	if c.source != "" {
+		file.Attributes = append(file.Attributes, classfileparser.AttributeInfo{
+			AttributeNameIndex: pool.Utf8("SourceFile"),
+			AttributeLength:    2,
+			Info:               binary.BigEndian.AppendUint16(nil, pool.Utf8(c.source)),
+		})
+	}
+	if len(c.bootstraps) > 0 {
+		body := binary.BigEndian.AppendUint16(nil, uint16(len(c.bootstraps)))
+		for _, b := range c.bootstraps {
+			index, err := pool.MethodHandle(b.handle)
+			if err != nil {
+				return nil, fmt.Errorf("bootstrap method %s: %w", b.handle.Name, err)
+			}
+			body = binary.BigEndian.AppendUint16(body, index)
+			body = binary.BigEndian.AppendUint16(body, uint16(len(b.arguments)))
+			for _, argument := range b.arguments {
+				index, err := pool.Constant(argument)
+				if err != nil {
+					return nil, fmt.Errorf("bootstrap method %s: %w", b.handle.Name, err)
+				}
+				body = binary.BigEndian.AppendUint16(body, index)
+			}
+		}
+		file.Attributes = append(file.Attributes, classfileparser.AttributeInfo{AttributeNameIndex: pool.Utf8("BootstrapMethods"), AttributeLength: uint32(len(body)), Info: body})
+	}
+	if err := pool.Err(); err != nil {
+		return nil, err
+	}
+	file.ConstantPool = pool.Pool()
+
+	return analysis.ComputeCode(file, c.resolver, nil)
+}
+
+// Bytes returns the class file built by Build in the .class format
+func (c *Class) Bytes() ([]byte, error) {
+	file, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
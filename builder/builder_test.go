@@ -0,0 +1,226 @@
+package builder_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/analysis"
+	"github.com/Slummp/classfileparser/builder"
+)
+
+// build builds class and checks that each of its methods verifies
+func build(t *testing.T, class *builder.Class) *classfileparser.ClassStruct {
+	t.Helper()
+	file, err := class.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := file.GetClassFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range snapshot.Methods {
+		m := &snapshot.Methods[i]
+		if m.Access.Has(classfileparser.MethodAbstract) || m.Access.Has(classfileparser.MethodNative) {
+			continue
+		}
+		if err := analysis.CheckMaxs(m); err != nil {
+			t.Errorf("method %s: %v", m.Name, err)
+		}
+		result, err := analysis.Verify(snapshot, m, nil)
+		if err != nil {
+			t.Fatalf("method %s: %v", m.Name, err)
+		}
+		if !result.OK() {
+			t.Errorf("method %s: diagnostics = %v", m.Name, result.Diagnostics)
+		}
+	}
+	return snapshot
+}
+
+// instructions returns the instructions of the method of class called name
+func instructions(t *testing.T, class *classfileparser.ClassStruct, name string) []classfileparser.Instruction {
+	t.Helper()
+	for _, m := range class.Methods {
+		for _, attribute := range m.Attributes {
+			if code, ok := attribute.(classfileparser.Code); ok && m.Name == name {
+				return code.Code
+			}
+		}
+	}
+	t.Fatalf("no code for method %s", name)
+	return nil
+}
+
+func constructor(code *builder.Code) {
+	code.Emit(classfileparser.Aload0{},
+		classfileparser.Invokespecial{Class: "java/lang/Object", Name: "<init>", Type: "()V"},
+		classfileparser.Return{})
+}
+
+func TestBuild(t *testing.T) {
+	class := build(t, builder.NewClass("com/x/Counter").
+		Implements("java/lang/Runnable").
+		Source("Counter.java").
+		Field(classfileparser.FieldPrivate, "count", "I").
+		Method(classfileparser.MethodPublic, "<init>", "()V", constructor).
+		Method(classfileparser.MethodPublic, "run", "()V", func(code *builder.Code) {
+			done := code.NewLabel()
+			code.Line(7).
+				Emit(classfileparser.Aload0{}, classfileparser.Getfield{Class: "com/x/Counter", Name: "count", Type: "I"}).
+				Jump(classfileparser.Ifeq{}, done).
+				Emit(classfileparser.Aload0{}, classfileparser.Iconst0{},
+					classfileparser.Putfield{Class: "com/x/Counter", Name: "count", Type: "I"}).
+				Mark(done).
+				Emit(classfileparser.Return{})
+		}).
+		Method(classfileparser.MethodPublic|classfileparser.MethodStatic, "pick", "(I)I", func(code *builder.Code) {
+			one, two, other := code.NewLabel(), code.NewLabel(), code.NewLabel()
+			code.Emit(classfileparser.Iload0{}).
+				Tableswitch(1, other, one, two).
+				Mark(one).Emit(classfileparser.Iconst1{}, classfileparser.Ireturn{}).
+				Mark(two).Emit(classfileparser.Iconst2{}, classfileparser.Ireturn{}).
+				Mark(other).Emit(classfileparser.Iconst0{}, classfileparser.Ireturn{})
+		}).
+		Method(classfileparser.MethodPublic|classfileparser.MethodStatic, "sparse", "(I)I", func(code *builder.Code) {
+			hundred, other := code.NewLabel(), code.NewLabel()
+			code.Emit(classfileparser.Iload0{}).
+				Lookupswitch(other, map[int32]*builder.Label{100: hundred, -5: hundred}).
+				Mark(hundred).Emit(classfileparser.Iconst1{}, classfileparser.Ireturn{}).
+				Mark(other).Emit(classfileparser.Iconst0{}, classfileparser.Ireturn{})
+		}).
+		Method(classfileparser.MethodPublic|classfileparser.MethodStatic, "safe", "(Ljava/lang/Object;)I", func(code *builder.Code) {
+			start, end, handler := code.NewLabel(), code.NewLabel(), code.NewLabel()
+			code.Mark(start).
+				Emit(classfileparser.Aload0{}, classfileparser.Invokevirtual{Class: "java/lang/Object", Name: "hashCode", Type: "()I"}).
+				Mark(end).
+				Emit(classfileparser.Ireturn{}).
+				Mark(handler).
+				Emit(classfileparser.Pop{}, classfileparser.IconstM1{}, classfileparser.Ireturn{}).
+				TryCatch(start, end, handler, "java/lang/RuntimeException")
+		}).
+		Method(classfileparser.MethodPublic|classfileparser.MethodNative, "hash", "()I", nil))
+
+	if class.ThisClass != "com/x/Counter" || len(class.Interfaces) != 1 || len(class.Fields) != 1 || len(class.Methods) != 6 {
+		t.Fatalf("class = %+v", class)
+	}
+	lookup, ok := instructions(t, class, "sparse")[1].(classfileparser.Lookupswitch)
+	if !ok || len(lookup.Pairs) != 2 || lookup.Pairs[0].Match != -5 || lookup.Pairs[1].Match != 100 {
+		t.Errorf("lookupswitch = %#v", instructions(t, class, "sparse")[1])
+	}
+}
+
+func TestBuildFarJumps(t *testing.T) {
+	nops := make([]classfileparser.Instruction, 40000)
+	for i := range nops {
+		nops[i] = classfileparser.Nop{}
+	}
+	class := build(t, builder.NewClass("com/x/Far").
+		Method(classfileparser.MethodPublic|classfileparser.MethodStatic, "far", "(I)V", func(code *builder.Code) {
+			top, bottom := code.NewLabel(), code.NewLabel()
+			code.Mark(top).
+				Emit(classfileparser.Iload0{}).
+				Jump(classfileparser.Ifeq{}, bottom).
+				Emit(nops...).
+				Mark(bottom).
+				Emit(classfileparser.Iload0{}).
+				Jump(classfileparser.Ifne{}, top).
+				Emit(classfileparser.Return{})
+		}))
+
+	var mnemonics []string
+	for _, instruction := range instructions(t, class, "far") {
+		if _, ok := instruction.(classfileparser.Nop); !ok {
+			mnemonics = append(mnemonics, instruction.Mnemonic())
+		}
+	}
+	// Conditional jumps become the opposite condition over a goto_w
+	if got, want := fmt.Sprint(mnemonics), "[iload_0 ifne goto_w iload_0 ifeq goto_w return]"; got != want {
+		t.Errorf("instructions = %s, want %s", got, want)
+	}
+}
+
+func TestBuildBootstrap(t *testing.T) {
+	metafactory := classfileparser.MethodHandle{
+		Kind:  "invokeStatic",
+		Class: "java/lang/invoke/LambdaMetafactory",
+		Name:  "metafactory",
+		Type:  "(Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodHandle;Ljava/lang/invoke/MethodType;)Ljava/lang/invoke/CallSite;",
+	}
+	lambda := classfileparser.MethodHandle{Kind: "invokeStatic", Class: "com/x/Lambda", Name: "lambda$0", Type: "()V"}
+	c := builder.NewClass("com/x/Lambda")
+	index := c.Bootstrap(metafactory, classfileparser.MethodType("()V"), lambda, classfileparser.MethodType("()V"))
+	class := build(t, c.
+		Method(classfileparser.MethodPublic|classfileparser.MethodStatic, "make", "()Ljava/lang/Runnable;", func(code *builder.Code) {
+			code.Emit(classfileparser.Invokedynamic{InvokeDynamic: classfileparser.InvokeDynamic{BootstrapIndex: index, Name: "run", Type: "()Ljava/lang/Runnable;"}},
+				classfileparser.Areturn{})
+		}).
+		Method(classfileparser.MethodPrivate|classfileparser.MethodStatic|classfileparser.MethodSynthetic, "lambda$0", "()V", func(code *builder.Code) {
+			code.Emit(classfileparser.Return{})
+		}))
+
+	if index != 0 {
+		t.Errorf("bootstrap index = %d, want 0", index)
+	}
+	indy, ok := instructions(t, class, "make")[0].(classfileparser.Invokedynamic)
+	if !ok || indy.InvokeDynamic.Bootstrap == nil {
+		t.Fatalf("invokedynamic = %#v", instructions(t, class, "make")[0])
+	}
+	bootstrap := indy.InvokeDynamic.Bootstrap
+	if bootstrap.Method != metafactory || len(bootstrap.StaticArguments) != 3 || bootstrap.StaticArguments[1] != lambda {
+		t.Errorf("bootstrap method = %+v", bootstrap)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	static := classfileparser.MethodPublic | classfileparser.MethodStatic
+	tests := []struct {
+		name  string
+		class *builder.Class
+		want  string
+	}{
+		{"invalid class name", builder.NewClass("com.x.Dotted"), `invalid class name "com.x.Dotted"`},
+		{"invalid field descriptor", builder.NewClass("com/x/T").Field(classfileparser.FieldPublic, "f", "Q"), "field f"},
+		{"missing body", builder.NewClass("com/x/T").Method(static, "m", "()V", nil), "method m()V has no body"},
+		{"branch emitted", builder.NewClass("com/x/T").Method(static, "m", "()V", func(code *builder.Code) {
+			code.Emit(classfileparser.Goto{})
+		}), "goto jumps to a label, emit it with Jump"},
+		{"label not marked", builder.NewClass("com/x/T").Method(static, "m", "()V", func(code *builder.Code) {
+			code.Jump(classfileparser.Goto{}, code.NewLabel())
+		}), "jump to a label that is not marked"},
+		{"label at the end", builder.NewClass("com/x/T").Method(static, "m", "(I)V", func(code *builder.Code) {
+			end := code.NewLabel()
+			code.Emit(classfileparser.Iload0{}).Jump(classfileparser.Ifeq{}, end).Emit(classfileparser.Return{}).Mark(end)
+		}), "jump to a label that is not followed by an instruction"},
+		{"label marked twice", builder.NewClass("com/x/T").Method(static, "m", "()V", func(code *builder.Code) {
+			label := code.NewLabel()
+			code.Mark(label).Mark(label).Emit(classfileparser.Return{})
+		}), "label marked twice"},
+		{"empty handler range", builder.NewClass("com/x/T").Method(static, "m", "()V", func(code *builder.Code) {
+			label := code.NewLabel()
+			code.Mark(label).Emit(classfileparser.Return{}).TryCatch(label, label, label, "")
+		}), "exception handler 0 covers no instruction"},
+		{"handler at the end", builder.NewClass("com/x/T").Method(static, "m", "()V", func(code *builder.Code) {
+			start, end := code.NewLabel(), code.NewLabel()
+			code.Mark(start).Emit(classfileparser.Return{}).Mark(end).TryCatch(start, end, end, "")
+		}), "exception handler 0: jump to a label that is not followed by an instruction"},
+		{"unreachable code", builder.NewClass("com/x/T").Method(static, "m", "()V", func(code *builder.Code) {
+			code.Emit(classfileparser.Return{}, classfileparser.Nop{})
+		}), "method m()V: "},
+		{"invalid bootstrap kind", func() *builder.Class {
+			c := builder.NewClass("com/x/T")
+			c.Bootstrap(classfileparser.MethodHandle{Kind: "invokeMagic", Class: "com/x/T", Name: "bsm", Type: "()V"})
+			return c
+		}(), "bootstrap method bsm"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.class.Build()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want %s", err, test.want)
+			}
+		})
+	}
+}
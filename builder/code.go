@@ -0,0 +1,263 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// Label marks a position in the code of a method, to jump to or delimit an exception handler
+type Label struct {
+	index  int // Index of the instruction following the label
+	marked bool
+}
+
+// Code emits the instructions of a method. Errors are kept and reported by Class.Build, so that
+// calls can be chained.
+type Code struct {
+	instructions []classfileparser.Instruction
+	jumps        []jump
+	handlers     []handler
+	lines        []classfileparser.LineNumberTableEntry
+	err          error
+}
+
+// jump is an instruction whose offsets are set once its labels are marked
+type jump struct {
+	index    int
+	fallback *Label   // Target of a branch, default target of a switch
+	targets  []*Label // Targets of a switch
+	keys     []int32  // Keys of a lookupswitch
+}
+
+// resolvedBranch is a branch to the instruction at index target, whose offset only fits once encoded
+type resolvedBranch struct {
+	classfileparser.Branch
+	target int
+}
+
+// Target returns the index of the instruction jumped to
+func (b resolvedBranch) Target() int { return b.target }
+
+// handler is an exception handler added by TryCatch
+type handler struct {
+	start, end, handler *Label
+	class               string
+}
+
+// fail records the first error
+func (c *Code) fail(err error) *Code {
+	if c.err == nil {
+		c.err = fmt.Errorf("instruction %d: %w", len(c.instructions), err)
+	}
+	return c
+}
+
+// NewLabel returns a label to mark once with Mark
+func (c *Code) NewLabel() *Label {
+	return &Label{}
+}
+
+// Mark places label before the next instruction emitted
+func (c *Code) Mark(label *Label) *Code {
+	if label.marked {
+		return c.fail(errors.New("label marked twice"))
+	}
+	label.index, label.marked = len(c.instructions), true
+	return c
+}
+
+// Emit appends instructions, given as values of the instruction types with their operands,
+// e.g. classfileparser.Getfield{Class: "com/x/Foo", Name: "x", Type: "I"}. Their pcs are
+// ignored. Branches and switches go through Jump, Tableswitch and Lookupswitch, which resolve
+// their labels.
+func (c *Code) Emit(instructions ...classfileparser.Instruction) *Code {
+	for _, instruction := range instructions {
+		switch instruction.(type) {
+		case classfileparser.Branch:
+			return c.fail(fmt.Errorf("%s jumps to a label, emit it with Jump", instruction.Mnemonic()))
+		case classfileparser.Tableswitch, classfileparser.Lookupswitch:
+			return c.fail(fmt.Errorf("%s jumps to labels, emit it with Tableswitch or Lookupswitch", instruction.Mnemonic()))
+		}
+		if err := c.append(instruction); err != nil {
+			return c.fail(err)
+		}
+	}
+	return c
+}
+
+// Jump appends a branch, such as classfileparser.Ifeq{} or classfileparser.Goto{}, jumping to
+// target
+func (c *Code) Jump(branch classfileparser.Branch, target *Label) *Code {
+	c.jumps = append(c.jumps, jump{index: len(c.instructions), fallback: target})
+	if err := c.append(branch); err != nil {
+		return c.fail(err)
+	}
+	return c
+}
+
+// Tableswitch appends a tableswitch jumping to targets[i] for the index low+i, and to fallback
+// for the indexes out of range
+func (c *Code) Tableswitch(low int32, fallback *Label, targets ...*Label) *Code {
+	if len(targets) == 0 {
+		return c.fail(errors.New("tableswitch without targets"))
+	}
+	c.jumps = append(c.jumps, jump{index: len(c.instructions), fallback: fallback, targets: targets})
+	high := low + int32(len(targets)) - 1
+	if err := c.append(classfileparser.Tableswitch{Low: low, High: high}); err != nil {
+		return c.fail(err)
+	}
+	return c
+}
+
+// Lookupswitch appends a lookupswitch jumping to targets[key] for each key, and to fallback for
+// the other values
+func (c *Code) Lookupswitch(fallback *Label, targets map[int32]*Label) *Code {
+	keys := make([]int32, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	labels := make([]*Label, len(keys))
+	for i, key := range keys {
+		labels[i] = targets[key]
+	}
+	c.jumps = append(c.jumps, jump{index: len(c.instructions), fallback: fallback, targets: labels, keys: keys})
+	if err := c.append(classfileparser.Lookupswitch{}); err != nil {
+		return c.fail(err)
+	}
+	return c
+}
+
+// TryCatch adds an exception handler: exceptions of class, or of any class when class is empty,
+// thrown by the instructions from start up to end are caught by the code at target
+func (c *Code) TryCatch(start, end, target *Label, class string) *Code {
+	c.handlers = append(c.handlers, handler{start: start, end: end, handler: target, class: class})
+	return c
+}
+
+// Line records that the next instruction emitted starts the given source line
+func (c *Code) Line(number uint16) *Code {
+	c.lines = append(c.lines, classfileparser.LineNumberTableEntry{StartPc: uint16(len(c.instructions)), LineNumber: number})
+	return c
+}
+
+// append adds an instruction, numbered by its index as EncodeCode accepts
+func (c *Code) append(instruction classfileparser.Instruction) error {
+	located, err := setField(instruction, "Pc", int64(len(c.instructions)))
+	if err != nil {
+		return err
+	}
+	c.instructions = append(c.instructions, located)
+	return nil
+}
+
+// code resolves the labels and returns the Code attribute, catch types being allocated in pool
+func (c *Code) code(pool *classfileparser.PoolBuilder) (classfileparser.Code, error) {
+	if c.err != nil {
+		return classfileparser.Code{}, c.err
+	}
+	if len(c.instructions) == 0 {
+		return classfileparser.Code{}, errors.New("no instructions")
+	}
+	position := func(label *Label) (int, error) {
+		if label == nil || !label.marked {
+			return 0, errors.New("jump to a label that is not marked")
+		}
+		return label.index, nil
+	}
+	target := func(label *Label) (int, error) {
+		index, err := position(label)
+		if err == nil && index == len(c.instructions) {
+			err = errors.New("jump to a label that is not followed by an instruction")
+		}
+		return index, err
+	}
+	instructions := append([]classfileparser.Instruction(nil), c.instructions...)
+	for _, jump := range c.jumps {
+		instruction := instructions[jump.index]
+		fallback, err := target(jump.fallback)
+		if err != nil {
+			return classfileparser.Code{}, fmt.Errorf("instruction %d (%s): %w", jump.index, instruction.Mnemonic(), err)
+		}
+		offsets := make([]int32, len(jump.targets))
+		for i, label := range jump.targets {
+			index, err := target(label)
+			if err != nil {
+				return classfileparser.Code{}, fmt.Errorf("instruction %d (%s): %w", jump.index, instruction.Mnemonic(), err)
+			}
+			offsets[i] = int32(index - jump.index)
+		}
+		switch instruction := instruction.(type) {
+		case classfileparser.Tableswitch:
+			instruction.DefaultOffset, instruction.Offsets = int32(fallback-jump.index), offsets
+			instructions[jump.index] = instruction
+		case classfileparser.Lookupswitch:
+			instruction.DefaultOffset = int32(fallback - jump.index)
+			for i, key := range jump.keys {
+				instruction.Pairs = append(instruction.Pairs, classfileparser.MatchOffset{Match: key, Offset: offsets[i]})
+			}
+			instructions[jump.index] = instruction
+		case classfileparser.Branch:
+			// The offset is left to EncodeCode, which widens the jump when the target is too far
+			instructions[jump.index] = resolvedBranch{Branch: instruction, target: fallback}
+		}
+	}
+
+	code := classfileparser.Code{Code: instructions}
+	for i, h := range c.handlers {
+		start, err := position(h.start)
+		var end, pc int
+		if err == nil {
+			end, err = position(h.end)
+		}
+		if err == nil {
+			pc, err = target(h.handler)
+		}
+		if err != nil {
+			return classfileparser.Code{}, fmt.Errorf("exception handler %d: %w", i, err)
+		}
+		if start >= end {
+			return classfileparser.Code{}, fmt.Errorf("exception handler %d covers no instruction", i)
+		}
+		entry := classfileparser.ExceptionTableEntry{StartPc: uint16(start), EndPc: uint16(end), HandlerPc: uint16(pc), CatchClass: h.class}
+		if h.class != "" {
+			entry.CatchType = pool.Class(h.class)
+		}
+		code.ExceptionTable = append(code.ExceptionTable, entry)
+	}
+
+	var lines []classfileparser.LineNumberTableEntry
+	for _, entry := range c.lines {
+		if int(entry.StartPc) < len(instructions) {
+			lines = append(lines, entry)
+		}
+	}
+	if len(lines) > 0 {
+		code.Attributes = append(code.Attributes, classfileparser.LineNumberTable{LineNumberTableLength: uint16(len(lines)), LineNumberTable: lines})
+	}
+	return code, nil
+}
+
+// setField returns a copy of instruction with its integer field name, such as Pc, set to value.
+// The instruction types have no setters, their fields being promoted from Location.
+func setField(instruction classfileparser.Instruction, name string, value int64) (classfileparser.Instruction, error) {
+	original := reflect.ValueOf(instruction)
+	if original.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s must be passed by value", instruction.Mnemonic())
+	}
+	copied := reflect.New(original.Type()).Elem()
+	copied.Set(original)
+	field := copied.FieldByName(name)
+	if !field.IsValid() || !field.CanInt() {
+		return nil, fmt.Errorf("%s has no %s", instruction.Mnemonic(), name)
+	}
+	if field.OverflowInt(value) {
+		return nil, fmt.Errorf("%s cannot reach %d instructions away", instruction.Mnemonic(), value)
+	}
+	field.SetInt(value)
+	return copied.Interface().(classfileparser.Instruction), nil
+}
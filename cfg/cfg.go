@@ -0,0 +1,389 @@
+// Package cfg builds the control-flow graph of a method body from its decoded Code attribute.
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// BlockKind tells the explicit entry and exit blocks apart from the blocks holding code
+type BlockKind uint8
+
+const (
+	// CodeBlock holds a run of instructions entered only at its first one.
+	CodeBlock BlockKind = iota
+	// EntryBlock holds no instruction and precedes the first block of the method.
+	EntryBlock
+	// ExitBlock holds no instruction and follows every return and athrow.
+	ExitBlock
+)
+
+// Block is a node of the graph
+type Block struct {
+	Index        int // Position in Graph.Blocks
+	Kind         BlockKind
+	Start        int                           // Pc of the first instruction, -1 for the entry and exit blocks
+	End          int                           // Pc following the last instruction, -1 for the entry and exit blocks
+	Instructions []classfileparser.Instruction // Sub-slice of Code.Code
+	Succs        []*Edge                       // Outgoing edges, in the order they were found
+	Preds        []*Edge                       // Incoming edges
+}
+
+// Last returns the last instruction of the block, nil for the entry and exit blocks
+func (b *Block) Last() classfileparser.Instruction {
+	if len(b.Instructions) == 0 {
+		return nil
+	}
+	return b.Instructions[len(b.Instructions)-1]
+}
+
+// String names the block: entry, exit, or B followed by its index
+func (b *Block) String() string {
+	switch b.Kind {
+	case EntryBlock:
+		return "entry"
+	case ExitBlock:
+		return "exit"
+	default:
+		return fmt.Sprintf("B%d", b.Index)
+	}
+}
+
+// EdgeKind tells how control flows along an edge
+type EdgeKind uint8
+
+const (
+	// Fallthrough continues with the next instruction, or leaves the entry block.
+	Fallthrough EdgeKind = iota
+	// Jump is the taken branch of goto or of a conditional branch.
+	Jump
+	// Switch goes to a case or the default of a tableswitch or lookupswitch.
+	Switch
+	// Exception goes to an exception handler covering the block.
+	Exception
+	// Jsr enters a subroutine.
+	Jsr
+	// Ret leaves a subroutine for the instruction following one of its jsr.
+	Ret
+	// Exit leaves the method through a return or athrow instruction.
+	Exit
+)
+
+var edgeKindNames = map[EdgeKind]string{
+	Fallthrough: "fallthrough",
+	Jump:        "jump",
+	Switch:      "switch",
+	Exception:   "exception",
+	Jsr:         "jsr",
+	Ret:         "ret",
+	Exit:        "exit",
+}
+
+func (k EdgeKind) String() string {
+	if name, ok := edgeKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("EdgeKind(%d)", uint8(k))
+}
+
+// Edge is a directed edge of the graph
+type Edge struct {
+	Kind     EdgeKind
+	From, To *Block
+	Cases    []int32 // Switch edges: the keys jumping to To, sorted
+	Default  bool    // Switch edges: whether To is also the default target
+	Handler  int     // Exception edges: index of the entry in Code.ExceptionTable
+	Catch    string  // Exception edges: caught class, empty for any exception
+}
+
+// String renders the edge as its source, target and kind, e.g. B2 -> B5 (jump)
+func (e *Edge) String() string {
+	return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, e.Kind)
+}
+
+// Subroutine is the code reached by jsr instructions to the same target
+type Subroutine struct {
+	Entry   *Block   // Block starting at the jsr target
+	Blocks  []*Block // Blocks of the subroutine, Entry included, sorted by pc. Nested subroutines are not included.
+	Callers []*Block // Blocks ending with a jsr to Entry
+	Rets    []*Block // Blocks ending with the ret of the subroutine
+}
+
+// Graph is the control-flow graph of a method
+type Graph struct {
+	Entry       *Block
+	Exit        *Block
+	Blocks      []*Block // Entry, code blocks sorted by pc, then Exit
+	Subroutines []*Subroutine
+	byPc        map[int]*Block // Code blocks by start pc
+}
+
+// New builds the control-flow graph of code. Blocks are split at branch targets, after
+// instructions that transfer control and at the boundaries of exception handler ranges, so that
+// every block covered by a handler gets an Exception edge to it. Edges from instructions that
+// may throw outside a handler range are not represented; athrow goes to the exit block.
+//
+// Each ret gets a Ret edge to the instruction following every jsr of its subroutine, the
+// subroutine being the code reached from the jsr target without entering nested subroutines.
+func New(code classfileparser.Code) (*Graph, error) {
+	instructions := code.Code
+	if len(instructions) == 0 {
+		return nil, errors.New("empty code")
+	}
+	last := instructions[len(instructions)-1]
+	end := last.PC() + last.Len()
+
+	isInstruction := func(pc int) bool {
+		_, ok := code.InstructionIndex(pc)
+		return ok
+	}
+	leaders := map[int]bool{0: true}
+	for i, instruction := range instructions {
+		for _, target := range targets(instruction) {
+			if !isInstruction(target) {
+				return nil, fmt.Errorf("%s at pc %d jumps to %d, which is not an instruction", instruction.Mnemonic(), instruction.PC(), target)
+			}
+			leaders[target] = true
+		}
+		if endsBlock(instruction) && i+1 < len(instructions) {
+			leaders[instructions[i+1].PC()] = true
+		}
+	}
+	for i, entry := range code.ExceptionTable {
+		start, stop, handler := int(entry.StartPc), int(entry.EndPc), int(entry.HandlerPc)
+		if start >= stop || !isInstruction(start) || (stop != end && !isInstruction(stop)) || !isInstruction(handler) {
+			return nil, fmt.Errorf("exception table entry %d has an invalid range [%d, %d) or handler %d", i, start, stop, handler)
+		}
+		leaders[start] = true
+		leaders[handler] = true
+		if stop != end {
+			leaders[stop] = true
+		}
+	}
+
+	g := &Graph{byPc: map[int]*Block{}}
+	g.Entry = g.newBlock(EntryBlock)
+	for i, instruction := range instructions {
+		if leaders[instruction.PC()] {
+			block := g.newBlock(CodeBlock)
+			block.Start = instruction.PC()
+			block.Instructions = instructions[i : i+1]
+			g.byPc[block.Start] = block
+		} else {
+			block := g.Blocks[len(g.Blocks)-1]
+			block.Instructions = block.Instructions[:len(block.Instructions)+1]
+		}
+		block := g.Blocks[len(g.Blocks)-1]
+		block.End = instruction.PC() + instruction.Len()
+	}
+	g.Exit = g.newBlock(ExitBlock)
+
+	addEdge(Fallthrough, g.Entry, g.Blocks[1])
+	for _, block := range g.Blocks[1 : len(g.Blocks)-1] {
+		if err := g.addFlowEdges(block); err != nil {
+			return nil, err
+		}
+	}
+	for _, block := range g.Blocks[1 : len(g.Blocks)-1] {
+		for i, entry := range code.ExceptionTable {
+			if block.Start >= int(entry.StartPc) && block.Start < int(entry.EndPc) {
+				edge := addEdge(Exception, block, g.byPc[int(entry.HandlerPc)])
+				edge.Handler = i
+				edge.Catch = entry.CatchClass
+			}
+		}
+	}
+	g.linkSubroutines()
+	return g, nil
+}
+
+func (g *Graph) newBlock(kind BlockKind) *Block {
+	block := &Block{Index: len(g.Blocks), Kind: kind, Start: -1, End: -1}
+	g.Blocks = append(g.Blocks, block)
+	return block
+}
+
+// BlockAt returns the code block holding the instruction at pc
+func (g *Graph) BlockAt(pc int) (*Block, bool) {
+	blocks := g.Blocks[1 : len(g.Blocks)-1]
+	i := sort.Search(len(blocks), func(i int) bool { return blocks[i].End > pc })
+	if i < len(blocks) && blocks[i].Start <= pc {
+		return blocks[i], true
+	}
+	return nil, false
+}
+
+func addEdge(kind EdgeKind, from, to *Block) *Edge {
+	edge := &Edge{Kind: kind, From: from, To: to}
+	from.Succs = append(from.Succs, edge)
+	to.Preds = append(to.Preds, edge)
+	return edge
+}
+
+// addFlowEdges adds the edges following the last instruction of block, except Ret edges
+func (g *Graph) addFlowEdges(block *Block) error {
+	last := block.Last()
+	next, hasNext := g.byPc[block.End]
+	fallThrough := func() error {
+		if !hasNext {
+			return fmt.Errorf("%s at pc %d falls off the end of the code", last.Mnemonic(), last.PC())
+		}
+		addEdge(Fallthrough, block, next)
+		return nil
+	}
+
+	switch instruction := last.(type) {
+	case classfileparser.Tableswitch:
+		cases := map[int][]int32{}
+		for i, offset := range instruction.Offsets {
+			target := instruction.Pc + int(offset)
+			cases[target] = append(cases[target], instruction.Low+int32(i))
+		}
+		g.addSwitchEdges(block, cases, instruction.Pc+int(instruction.DefaultOffset))
+	case classfileparser.Lookupswitch:
+		cases := map[int][]int32{}
+		for _, pair := range instruction.Pairs {
+			target := instruction.Pc + int(pair.Offset)
+			cases[target] = append(cases[target], pair.Match)
+		}
+		g.addSwitchEdges(block, cases, instruction.Pc+int(instruction.DefaultOffset))
+	case classfileparser.Goto, classfileparser.GotoW:
+		addEdge(Jump, block, g.byPc[last.(classfileparser.Branch).Target()])
+	case classfileparser.Jsr, classfileparser.JsrW:
+		addEdge(Jsr, block, g.byPc[last.(classfileparser.Branch).Target()])
+	case classfileparser.Ret, classfileparser.Wide:
+		if !isRet(last) {
+			return fallThrough()
+		}
+	case classfileparser.Ireturn, classfileparser.Lreturn, classfileparser.Freturn, classfileparser.Dreturn,
+		classfileparser.Areturn, classfileparser.Return, classfileparser.Athrow:
+		addEdge(Exit, block, g.Exit)
+	case classfileparser.Branch:
+		addEdge(Jump, block, g.byPc[instruction.Target()])
+		return fallThrough()
+	default:
+		return fallThrough()
+	}
+	return nil
+}
+
+func (g *Graph) addSwitchEdges(block *Block, cases map[int][]int32, defaultTarget int) {
+	if _, ok := cases[defaultTarget]; !ok {
+		cases[defaultTarget] = nil
+	}
+	pcs := make([]int, 0, len(cases))
+	for pc := range cases {
+		pcs = append(pcs, pc)
+	}
+	sort.Ints(pcs)
+	for _, pc := range pcs {
+		edge := addEdge(Switch, block, g.byPc[pc])
+		edge.Cases = cases[pc]
+		sort.Slice(edge.Cases, func(i, j int) bool { return edge.Cases[i] < edge.Cases[j] })
+		edge.Default = pc == defaultTarget
+	}
+}
+
+// linkSubroutines finds the subroutines entered by jsr and adds the Ret edges of their ret
+func (g *Graph) linkSubroutines() {
+	subroutines := map[*Block]*Subroutine{}
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			if edge.Kind != Jsr {
+				continue
+			}
+			subroutine, ok := subroutines[edge.To]
+			if !ok {
+				subroutine = &Subroutine{Entry: edge.To}
+				subroutines[edge.To] = subroutine
+				g.Subroutines = append(g.Subroutines, subroutine)
+			}
+			subroutine.Callers = append(subroutine.Callers, block)
+		}
+	}
+
+	for _, subroutine := range g.Subroutines {
+		seen := map[*Block]bool{subroutine.Entry: true}
+		stack := []*Block{subroutine.Entry}
+		for len(stack) > 0 {
+			block := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			subroutine.Blocks = append(subroutine.Blocks, block)
+			if isRet(block.Last()) {
+				subroutine.Rets = append(subroutine.Rets, block)
+				continue
+			}
+			for _, edge := range block.Succs {
+				to := edge.To
+				switch edge.Kind {
+				case Exception, Exit:
+					continue
+				case Jsr:
+					// Skip the nested subroutine, assuming it returns
+					if to = g.byPc[block.End]; to == nil {
+						continue
+					}
+				}
+				if !seen[to] {
+					seen[to] = true
+					stack = append(stack, to)
+				}
+			}
+		}
+		sort.Slice(subroutine.Blocks, func(i, j int) bool { return subroutine.Blocks[i].Start < subroutine.Blocks[j].Start })
+
+		for _, ret := range subroutine.Rets {
+			for _, caller := range subroutine.Callers {
+				if next, ok := g.byPc[caller.End]; ok {
+					addEdge(Ret, ret, next)
+				}
+			}
+		}
+	}
+}
+
+// targets returns the pcs an instruction may jump to, besides the next instruction
+func targets(instruction classfileparser.Instruction) []int {
+	switch instruction := instruction.(type) {
+	case classfileparser.Branch:
+		return []int{instruction.Target()}
+	case classfileparser.Tableswitch:
+		pcs := []int{instruction.Pc + int(instruction.DefaultOffset)}
+		for _, offset := range instruction.Offsets {
+			pcs = append(pcs, instruction.Pc+int(offset))
+		}
+		return pcs
+	case classfileparser.Lookupswitch:
+		pcs := []int{instruction.Pc + int(instruction.DefaultOffset)}
+		for _, pair := range instruction.Pairs {
+			pcs = append(pcs, instruction.Pc+int(pair.Offset))
+		}
+		return pcs
+	}
+	return nil
+}
+
+// endsBlock reports whether the instruction following instruction starts a new block
+func endsBlock(instruction classfileparser.Instruction) bool {
+	switch instruction.(type) {
+	case classfileparser.Branch, classfileparser.Tableswitch, classfileparser.Lookupswitch,
+		classfileparser.Ireturn, classfileparser.Lreturn, classfileparser.Freturn, classfileparser.Dreturn,
+		classfileparser.Areturn, classfileparser.Return, classfileparser.Athrow:
+		return true
+	}
+	return isRet(instruction)
+}
+
+// isRet reports whether instruction is ret or wide ret
+func isRet(instruction classfileparser.Instruction) bool {
+	switch instruction := instruction.(type) {
+	case classfileparser.Ret:
+		return true
+	case classfileparser.Wide:
+		return instruction.OpCode == 0xA9
+	}
+	return false
+}
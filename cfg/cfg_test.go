@@ -0,0 +1,359 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+)
+
+// ifElse is
+//
+//	0: iconst_0
+//	1: ifeq 8
+//	4: iconst_1
+//	5: goto 9
+//	8: iconst_0
+//	9: ireturn
+func ifElse() cf.Code {
+	return cf.Code{Code: []cf.Instruction{
+		cf.Iconst0{Location: cf.Location{Pc: 0}},
+		cf.Ifeq{Location: cf.Location{Pc: 1}, Offset: 7},
+		cf.Iconst1{Location: cf.Location{Pc: 4}},
+		cf.Goto{Location: cf.Location{Pc: 5}, Offset: 4},
+		cf.Iconst0{Location: cf.Location{Pc: 8}},
+		cf.Ireturn{Location: cf.Location{Pc: 9}},
+	}}
+}
+
+// blocks describes the code blocks of g as "B1 [0, 4)"
+func blocks(g *Graph) []string {
+	var described []string
+	for _, block := range g.Blocks {
+		if block.Kind == CodeBlock {
+			described = append(described, fmt.Sprintf("%s [%d, %d)", block, block.Start, block.End))
+		}
+	}
+	return described
+}
+
+// edges describes the edges of g, in the order of their source block and of discovery, as
+// "B1 jump B3", followed by the keys of switch edges and the handler of exception edges
+func edges(g *Graph) []string {
+	var described []string
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			if edge.From != block {
+				described = append(described, "edge with a wrong source")
+			}
+			s := fmt.Sprintf("%s %s %s", edge.From, edge.Kind, edge.To)
+			switch edge.Kind {
+			case Switch:
+				s += fmt.Sprintf(" %v", edge.Cases)
+				if edge.Default {
+					s += " default"
+				}
+			case Exception:
+				s += fmt.Sprintf(" #%d %q", edge.Handler, edge.Catch)
+			}
+			described = append(described, s)
+		}
+	}
+	return described
+}
+
+// checkGraph compares the blocks and edges of g, and checks that Preds mirrors Succs
+func checkGraph(t *testing.T, g *Graph, wantBlocks, wantEdges []string) {
+	t.Helper()
+	if got := blocks(g); !reflect.DeepEqual(got, wantBlocks) {
+		t.Errorf("blocks:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantBlocks, "\n"))
+	}
+	if got := edges(g); !reflect.DeepEqual(got, wantEdges) {
+		t.Errorf("edges:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantEdges, "\n"))
+	}
+	preds := 0
+	for _, block := range g.Blocks {
+		for _, edge := range block.Preds {
+			if edge.To != block {
+				t.Errorf("%s has a predecessor edge to %s", block, edge.To)
+			}
+		}
+		preds += len(block.Preds)
+	}
+	if succs := len(edges(g)); preds != succs {
+		t.Errorf("%d predecessor edges for %d successor edges", preds, succs)
+	}
+}
+
+func TestLeaders(t *testing.T) {
+	g, err := New(ifElse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGraph(t, g,
+		[]string{"B1 [0, 4)", "B2 [4, 8)", "B3 [8, 9)", "B4 [9, 10)"},
+		[]string{
+			"entry fallthrough B1",
+			"B1 jump B3",
+			"B1 fallthrough B2",
+			"B2 jump B4",
+			"B3 fallthrough B4",
+			"B4 exit exit",
+		})
+	if g.Entry != g.Blocks[0] || g.Exit != g.Blocks[len(g.Blocks)-1] || g.Entry.Last() != nil {
+		t.Error("entry and exit are not the first and last blocks")
+	}
+	if _, ok := g.Blocks[1].Last().(cf.Ifeq); !ok || len(g.Blocks[1].Instructions) != 2 {
+		t.Errorf("B1 instructions = %v", g.Blocks[1].Instructions)
+	}
+
+	for pc, want := range map[int]string{0: "B1", 3: "B1", 5: "B2", 7: "B2", 8: "B3", 9: "B4", 10: "", -1: ""} {
+		block, ok := g.BlockAt(pc)
+		if want == "" {
+			if ok {
+				t.Errorf("BlockAt(%d) = %s, want none", pc, block)
+			}
+		} else if !ok || block.String() != want {
+			t.Errorf("BlockAt(%d) = %v, want %s", pc, block, want)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		code cf.Code
+		err  string
+	}{
+		{"empty", cf.Code{}, "empty code"},
+		{"jump inside an instruction", cf.Code{Code: []cf.Instruction{
+			cf.Goto{Location: cf.Location{Pc: 0}, Offset: 1},
+			cf.Return{Location: cf.Location{Pc: 3}},
+		}}, "goto at pc 0 jumps to 1, which is not an instruction"},
+		{"falls off the end", cf.Code{Code: []cf.Instruction{
+			cf.Iconst0{Location: cf.Location{Pc: 0}},
+			cf.Ifeq{Location: cf.Location{Pc: 1}, Offset: 3},
+			cf.Return{Location: cf.Location{Pc: 4}},
+			cf.Nop{Location: cf.Location{Pc: 5}},
+		}}, "nop at pc 5 falls off the end of the code"},
+		{"empty handler range", cf.Code{Code: []cf.Instruction{cf.Return{Location: cf.Location{Pc: 0}}},
+			ExceptionTable: []cf.ExceptionTableEntry{{StartPc: 0, EndPc: 0, HandlerPc: 0}}}, "exception table entry 0 has an invalid range [0, 0)"},
+		{"handler outside the code", cf.Code{Code: []cf.Instruction{cf.Return{Location: cf.Location{Pc: 0}}},
+			ExceptionTable: []cf.ExceptionTableEntry{{StartPc: 0, EndPc: 1, HandlerPc: 1}}}, "or handler 1"},
+	}
+	for _, test := range tests {
+		if _, err := New(test.code); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestExceptionEdges(t *testing.T) {
+	// The handler at 3 catches Exception in [0, 2) and anything in [1, 3)
+	code := cf.Code{
+		Code: []cf.Instruction{
+			cf.Nop{Location: cf.Location{Pc: 0}},
+			cf.Nop{Location: cf.Location{Pc: 1}},
+			cf.Return{Location: cf.Location{Pc: 2}},
+			cf.Pop{Location: cf.Location{Pc: 3}},
+			cf.Return{Location: cf.Location{Pc: 4}},
+		},
+		ExceptionTable: []cf.ExceptionTableEntry{
+			{StartPc: 0, EndPc: 2, HandlerPc: 3, CatchType: 7, CatchClass: "java/lang/Exception"},
+			{StartPc: 1, EndPc: 3, HandlerPc: 3},
+		},
+	}
+	g, err := New(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGraph(t, g,
+		[]string{"B1 [0, 1)", "B2 [1, 2)", "B3 [2, 3)", "B4 [3, 5)"},
+		[]string{
+			"entry fallthrough B1",
+			"B1 fallthrough B2",
+			`B1 exception B4 #0 "java/lang/Exception"`,
+			"B2 fallthrough B3",
+			`B2 exception B4 #0 "java/lang/Exception"`,
+			`B2 exception B4 #1 ""`,
+			"B3 exit exit",
+			`B3 exception B4 #1 ""`,
+			"B4 exit exit",
+		})
+}
+
+func TestSwitchEdges(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   cf.Code
+		blocks []string
+		edges  []string
+	}{
+		{"tableswitch", cf.Code{Code: []cf.Instruction{
+			// Keys 1 and 3 go to 28, 2 to 29, anything else to 30
+			cf.Tableswitch{Location: cf.Location{Pc: 0}, DefaultOffset: 30, Low: 1, High: 3, Offsets: []int32{28, 29, 28}},
+			cf.Return{Location: cf.Location{Pc: 28}},
+			cf.Return{Location: cf.Location{Pc: 29}},
+			cf.Return{Location: cf.Location{Pc: 30}},
+		}}, []string{"B1 [0, 28)", "B2 [28, 29)", "B3 [29, 30)", "B4 [30, 31)"}, []string{
+			"entry fallthrough B1",
+			"B1 switch B2 [1 3]",
+			"B1 switch B3 [2]",
+			"B1 switch B4 [] default",
+			"B2 exit exit",
+			"B3 exit exit",
+			"B4 exit exit",
+		}},
+		{"lookupswitch", cf.Code{Code: []cf.Instruction{
+			// Padded to 4 from pc 1, key 10 goes to 29, -5 and anything else to 28
+			cf.Nop{Location: cf.Location{Pc: 0}},
+			cf.Lookupswitch{Location: cf.Location{Pc: 1}, DefaultOffset: 27, Pairs: []cf.MatchOffset{{Match: -5, Offset: 27}, {Match: 10, Offset: 28}}},
+			cf.Return{Location: cf.Location{Pc: 28}},
+			cf.Return{Location: cf.Location{Pc: 29}},
+		}}, []string{"B1 [0, 28)", "B2 [28, 29)", "B3 [29, 30)"}, []string{
+			"entry fallthrough B1",
+			"B1 switch B2 [-5] default",
+			"B1 switch B3 [10]",
+			"B2 exit exit",
+			"B3 exit exit",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := New(test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGraph(t, g, test.blocks, test.edges)
+		})
+	}
+}
+
+func TestSubroutines(t *testing.T) {
+	tests := []struct {
+		name string
+		ret  cf.Instruction
+	}{
+		{"ret", cf.Ret{Location: cf.Location{Pc: 10}, LocalIndex: 1}},
+		{"wide ret", cf.Wide{Location: cf.Location{Pc: 10}, OpCode: 0xA9, LocalIndex: 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Two jsr calling the subroutine at 8, which stores its return address and returns
+			g, err := New(cf.Code{Code: []cf.Instruction{
+				cf.Jsr{Location: cf.Location{Pc: 0}, Offset: 8},
+				cf.Jsr{Location: cf.Location{Pc: 3}, Offset: 5},
+				cf.Nop{Location: cf.Location{Pc: 6}},
+				cf.Return{Location: cf.Location{Pc: 7}},
+				cf.Astore{Location: cf.Location{Pc: 8}, LocalIndex: 1},
+				test.ret,
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			end := 10 + test.ret.Len()
+			checkGraph(t, g,
+				[]string{"B1 [0, 3)", "B2 [3, 6)", "B3 [6, 8)", fmt.Sprintf("B4 [8, %d)", end)},
+				[]string{
+					"entry fallthrough B1",
+					"B1 jsr B4",
+					"B2 jsr B4",
+					"B3 exit exit",
+					"B4 ret B2",
+					"B4 ret B3",
+				})
+			if len(g.Subroutines) != 1 {
+				t.Fatalf("%d subroutines", len(g.Subroutines))
+			}
+			subroutine := g.Subroutines[0]
+			names := func(blocks []*Block) string { return fmt.Sprint(blocks) }
+			if subroutine.Entry.String() != "B4" || names(subroutine.Blocks) != "[B4]" ||
+				names(subroutine.Callers) != "[B1 B2]" || names(subroutine.Rets) != "[B4]" {
+				t.Errorf("subroutine = %+v", subroutine)
+			}
+		})
+	}
+}
+
+func TestNestedSubroutine(t *testing.T) {
+	// The subroutine at 5 calls the one at 12 before returning, and both return to their callers
+	g, err := New(cf.Code{Code: []cf.Instruction{
+		cf.Jsr{Location: cf.Location{Pc: 0}, Offset: 5},
+		cf.Nop{Location: cf.Location{Pc: 3}},
+		cf.Return{Location: cf.Location{Pc: 4}},
+		cf.Astore{Location: cf.Location{Pc: 5}, LocalIndex: 1},
+		cf.Jsr{Location: cf.Location{Pc: 7}, Offset: 5},
+		cf.Ret{Location: cf.Location{Pc: 10}, LocalIndex: 1},
+		cf.Astore{Location: cf.Location{Pc: 12}, LocalIndex: 2},
+		cf.Ret{Location: cf.Location{Pc: 14}, LocalIndex: 2},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGraph(t, g,
+		[]string{"B1 [0, 3)", "B2 [3, 5)", "B3 [5, 10)", "B4 [10, 12)", "B5 [12, 16)"},
+		[]string{
+			"entry fallthrough B1",
+			"B1 jsr B3",
+			"B2 exit exit",
+			"B3 jsr B5",
+			"B4 ret B2",
+			"B5 ret B4",
+		})
+	var got []string
+	for _, subroutine := range g.Subroutines {
+		got = append(got, fmt.Sprintf("%s %v %v %v", subroutine.Entry, subroutine.Blocks, subroutine.Callers, subroutine.Rets))
+	}
+	// The nested subroutine is skipped, from its jsr to the instruction following it
+	if want := []string{"B3 [B3 B4] [B1] [B4]", "B5 [B5] [B3] [B5]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subroutines = %q, want %q", got, want)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	code := ifElse()
+	code.ExceptionTable = []cf.ExceptionTableEntry{{StartPc: 4, EndPc: 8, HandlerPc: 9, CatchClass: `a/"Quoted"`}}
+	g, err := New(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := g.WriteDOT(&out, "ifElse"); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph "ifElse" {
+	node [shape=box, fontname=monospace];
+	"entry" [shape=oval];
+	"B1" [label="B1 [0, 4)\l0: iconst_0\l1: ifeq 8\l"];
+	"B2" [label="B2 [4, 8)\l4: iconst_1\l5: goto 9\l"];
+	"B3" [label="B3 [8, 9)\l8: iconst_0\l"];
+	"B4" [label="B4 [9, 10)\l9: ireturn\l"];
+	"exit" [shape=oval];
+	"entry" -> "B1";
+	"B1" -> "B3" [label="jump"];
+	"B1" -> "B2";
+	"B2" -> "B4" [label="jump"];
+	"B2" -> "B4" [style=dashed, color=red, label="a/\"Quoted\""];
+	"B3" -> "B4";
+	"B4" -> "exit" [color=gray];
+}
+`
+	if out.String() != want {
+		t.Errorf("WriteDOT:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	g, err = New(cf.Code{Code: []cf.Instruction{
+		cf.Tableswitch{Location: cf.Location{Pc: 0}, DefaultOffset: 16, Low: 0, High: 0, Offsets: []int32{16}},
+		cf.Return{Location: cf.Location{Pc: 16}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	g.WriteDOT(&out, "switch")
+	if !strings.Contains(out.String(), `"B1" -> "B2" [label="0, default"];`) {
+		t.Errorf("switch edge missing from:\n%s", out.String())
+	}
+}
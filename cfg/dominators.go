@@ -0,0 +1,162 @@
+package cfg
+
+// DominatorTree records the immediate dominator of every block reachable from its root. The tree
+// of Graph.Dominators is rooted at the entry block and follows edges forward; the tree of
+// Graph.PostDominators is rooted at the exit block and follows them backward.
+type DominatorTree struct {
+	root     *Block
+	idom     []*Block   // Immediate dominators by block index, nil for the root and unreached blocks
+	children [][]*Block // Dominated blocks by block index, sorted by index
+	in, out  []int      // Preorder and postorder numbers in the tree, 0 for unreached blocks
+}
+
+// Dominators returns the dominator tree of the graph. Exception, jsr and ret edges are followed
+// like any other edge.
+func (g *Graph) Dominators() *DominatorTree {
+	return newDominatorTree(g, g.Entry, successors, predecessors)
+}
+
+// PostDominators returns the post-dominator tree of the graph, rooted at the exit block. Blocks
+// that cannot reach the exit, such as the blocks of an infinite loop, are not in the tree.
+func (g *Graph) PostDominators() *DominatorTree {
+	return newDominatorTree(g, g.Exit, predecessors, successors)
+}
+
+func successors(block *Block) []*Block {
+	blocks := make([]*Block, len(block.Succs))
+	for i, edge := range block.Succs {
+		blocks[i] = edge.To
+	}
+	return blocks
+}
+
+func predecessors(block *Block) []*Block {
+	blocks := make([]*Block, len(block.Preds))
+	for i, edge := range block.Preds {
+		blocks[i] = edge.From
+	}
+	return blocks
+}
+
+// newDominatorTree computes the tree with the iterative algorithm of Cooper, Harvey and Kennedy,
+// walking from root along next and merging along prev
+func newDominatorTree(g *Graph, root *Block, next, prev func(*Block) []*Block) *DominatorTree {
+	n := len(g.Blocks)
+	order := postorder(root, n, next)
+	number := make([]int, n) // Postorder number plus one, 0 for unreached blocks
+	for i, block := range order {
+		number[block.Index] = i + 1
+	}
+
+	idom := make([]*Block, n)
+	idom[root.Index] = root
+	intersect := func(a, b *Block) *Block {
+		for a != b {
+			for number[a.Index] < number[b.Index] {
+				a = idom[a.Index]
+			}
+			for number[b.Index] < number[a.Index] {
+				b = idom[b.Index]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 2; i >= 0; i-- {
+			block := order[i]
+			var dominator *Block
+			for _, p := range prev(block) {
+				if idom[p.Index] == nil {
+					continue
+				}
+				if dominator == nil {
+					dominator = p
+				} else {
+					dominator = intersect(p, dominator)
+				}
+			}
+			if idom[block.Index] != dominator {
+				idom[block.Index] = dominator
+				changed = true
+			}
+		}
+	}
+	idom[root.Index] = nil
+
+	t := &DominatorTree{root: root, idom: idom, children: make([][]*Block, n), in: make([]int, n), out: make([]int, n)}
+	for _, block := range g.Blocks {
+		if dominator := idom[block.Index]; dominator != nil {
+			t.children[dominator.Index] = append(t.children[dominator.Index], block)
+		}
+	}
+	clock := 0
+	var walk func(*Block)
+	walk = func(block *Block) {
+		clock++
+		t.in[block.Index] = clock
+		for _, child := range t.children[block.Index] {
+			walk(child)
+		}
+		clock++
+		t.out[block.Index] = clock
+	}
+	walk(root)
+	return t
+}
+
+// postorder returns the blocks reached from root along next in depth-first postorder
+func postorder(root *Block, n int, next func(*Block) []*Block) []*Block {
+	visited := make([]bool, n)
+	var order []*Block
+	var visit func(*Block)
+	visit = func(block *Block) {
+		visited[block.Index] = true
+		for _, successor := range next(block) {
+			if !visited[successor.Index] {
+				visit(successor)
+			}
+		}
+		order = append(order, block)
+	}
+	visit(root)
+	return order
+}
+
+// Root returns the root of the tree: the entry block for dominators, the exit block for
+// post-dominators
+func (t *DominatorTree) Root() *Block { return t.root }
+
+// Contains reports whether block is reached from the root, and thus part of the tree
+func (t *DominatorTree) Contains(block *Block) bool { return t.in[block.Index] != 0 }
+
+// Idom returns the immediate dominator of block, nil for the root and blocks outside the tree
+func (t *DominatorTree) Idom(block *Block) *Block { return t.idom[block.Index] }
+
+// Children returns the blocks whose immediate dominator is block
+func (t *DominatorTree) Children(block *Block) []*Block { return t.children[block.Index] }
+
+// Dominates reports whether every path from the root to b goes through a. A block dominates
+// itself. Blocks outside the tree neither dominate nor are dominated.
+func (t *DominatorTree) Dominates(a, b *Block) bool {
+	if !t.Contains(a) || !t.Contains(b) {
+		return false
+	}
+	return t.in[a.Index] <= t.in[b.Index] && t.out[b.Index] <= t.out[a.Index]
+}
+
+// Unreachable returns the code blocks that cannot be reached from the entry block, following
+// every kind of edge, sorted by pc. Their instructions are dead code.
+func (g *Graph) Unreachable() []*Block {
+	visited := make([]bool, len(g.Blocks))
+	for _, block := range postorder(g.Entry, len(g.Blocks), successors) {
+		visited[block.Index] = true
+	}
+	var unreachable []*Block
+	for _, block := range g.Blocks {
+		if block.Kind == CodeBlock && !visited[block.Index] {
+			unreachable = append(unreachable, block)
+		}
+	}
+	return unreachable
+}
@@ -0,0 +1,133 @@
+package cfg
+
+import (
+	"fmt"
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+)
+
+// nestedLoops builds
+//
+//	B1  0: nop
+//	B2  1: iconst_0         outer loop header
+//	    2: ifeq 16
+//	B3  5: iconst_0         inner loop header
+//	    6: ifeq 12
+//	B4  9: goto 5
+//	B5 12: iconst_0
+//	   13: ifne 1
+//	B6 16: return
+//	B7 17: nop              dead code
+//	   18: return
+func nestedLoops(t *testing.T) *Graph {
+	t.Helper()
+	g, err := New(cf.Code{Code: []cf.Instruction{
+		cf.Nop{Location: cf.Location{Pc: 0}},
+		cf.Iconst0{Location: cf.Location{Pc: 1}},
+		cf.Ifeq{Location: cf.Location{Pc: 2}, Offset: 14},
+		cf.Iconst0{Location: cf.Location{Pc: 5}},
+		cf.Ifeq{Location: cf.Location{Pc: 6}, Offset: 6},
+		cf.Goto{Location: cf.Location{Pc: 9}, Offset: -4},
+		cf.Iconst0{Location: cf.Location{Pc: 12}},
+		cf.Ifne{Location: cf.Location{Pc: 13}, Offset: -12},
+		cf.Return{Location: cf.Location{Pc: 16}},
+		cf.Nop{Location: cf.Location{Pc: 17}},
+		cf.Return{Location: cf.Location{Pc: 18}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(blocks(g)); got != "[B1 [0, 1) B2 [1, 5) B3 [5, 9) B4 [9, 12) B5 [12, 16) B6 [16, 17) B7 [17, 19)]" {
+		t.Fatalf("blocks = %s", got)
+	}
+	return g
+}
+
+// block returns the block of g named name
+func block(g *Graph, name string) *Block {
+	for _, block := range g.Blocks {
+		if block.String() == name {
+			return block
+		}
+	}
+	panic("no block " + name)
+}
+
+// idoms describes the immediate dominator of each block of g in the tree as "B3:B2"
+func idoms(g *Graph, tree *DominatorTree) string {
+	var described []string
+	for _, block := range g.Blocks {
+		if block == tree.Root() || !tree.Contains(block) {
+			continue
+		}
+		described = append(described, fmt.Sprintf("%s:%s", block, tree.Idom(block)))
+	}
+	return fmt.Sprint(described)
+}
+
+func TestDominators(t *testing.T) {
+	g := nestedLoops(t)
+	tree := g.Dominators()
+	if tree.Root() != g.Entry {
+		t.Errorf("root = %s", tree.Root())
+	}
+	if got, want := idoms(g, tree), "[B1:entry B2:B1 B3:B2 B4:B3 B5:B3 B6:B2 exit:B6]"; got != want {
+		t.Errorf("idoms = %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(tree.Children(block(g, "B3"))); got != "[B4 B5]" {
+		t.Errorf("children of B3 = %s", got)
+	}
+
+	dead := block(g, "B7")
+	if tree.Contains(dead) || tree.Idom(dead) != nil {
+		t.Error("the unreachable block is in the tree")
+	}
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"B2", "B2", true},
+		{"entry", "exit", true},
+		{"B2", "B5", true},
+		{"B3", "B5", true},
+		{"B4", "B5", false},
+		{"B5", "B6", false},
+		{"B6", "B2", false},
+		{"B1", "B7", false},
+		{"B7", "B7", false},
+	}
+	for _, test := range tests {
+		if got := tree.Dominates(block(g, test.a), block(g, test.b)); got != test.want {
+			t.Errorf("Dominates(%s, %s) = %t", test.a, test.b, got)
+		}
+	}
+	if got := fmt.Sprint(g.Unreachable()); got != "[B7]" {
+		t.Errorf("Unreachable() = %s", got)
+	}
+}
+
+func TestPostDominators(t *testing.T) {
+	g := nestedLoops(t)
+	tree := g.PostDominators()
+	if tree.Root() != g.Exit {
+		t.Errorf("root = %s", tree.Root())
+	}
+	// B7 reaches the exit, even though the entry does not reach it
+	if got, want := idoms(g, tree), "[entry:B1 B1:B2 B2:B6 B3:B5 B4:B3 B5:B6 B6:exit B7:exit]"; got != want {
+		t.Errorf("post-idoms = %s, want %s", got, want)
+	}
+	if !tree.Dominates(block(g, "B5"), block(g, "B4")) || tree.Dominates(block(g, "B3"), block(g, "B2")) {
+		t.Error("Dominates")
+	}
+
+	// An infinite loop never reaches the exit
+	g, err := New(cf.Code{Code: []cf.Instruction{cf.Goto{Location: cf.Location{Pc: 0}, Offset: 0}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree = g.PostDominators()
+	if tree.Contains(g.Blocks[1]) || tree.Contains(g.Entry) || !tree.Contains(g.Exit) {
+		t.Error("the infinite loop is in the post-dominator tree")
+	}
+}
@@ -0,0 +1,84 @@
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language as a digraph called name. Code blocks
+// list their instructions, and edges are styled by kind: exception edges are dashed and labelled
+// with the caught class, switch edges with their keys, subroutine edges are dotted.
+func (g *Graph) WriteDOT(w io.Writer, name string) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %s {\n", quote(name))
+	fmt.Fprintln(out, "\tnode [shape=box, fontname=monospace];")
+	for _, block := range g.Blocks {
+		switch block.Kind {
+		case EntryBlock, ExitBlock:
+			fmt.Fprintf(out, "\t%s [shape=oval];\n", quote(block.String()))
+		default:
+			var label strings.Builder
+			fmt.Fprintf(&label, "%s [%d, %d)\\l", block, block.Start, block.End)
+			for _, instruction := range block.Instructions {
+				fmt.Fprintf(&label, "%s\\l", escape(instructionText(instruction)))
+			}
+			fmt.Fprintf(out, "\t%s [label=\"%s\"];\n", quote(block.String()), label.String())
+		}
+	}
+	for _, block := range g.Blocks {
+		for _, edge := range block.Succs {
+			fmt.Fprintf(out, "\t%s -> %s%s;\n", quote(edge.From.String()), quote(edge.To.String()), edgeAttributes(edge))
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+func edgeAttributes(edge *Edge) string {
+	switch edge.Kind {
+	case Jump:
+		return ` [label="jump"]`
+	case Switch:
+		var keys []string
+		for _, key := range edge.Cases {
+			keys = append(keys, fmt.Sprint(key))
+		}
+		if edge.Default {
+			keys = append(keys, "default")
+		}
+		return fmt.Sprintf(" [label=%s]", quote(strings.Join(keys, ", ")))
+	case Exception:
+		catch := edge.Catch
+		if catch == "" {
+			catch = "any"
+		}
+		return fmt.Sprintf(" [style=dashed, color=red, label=%s]", quote(catch))
+	case Jsr, Ret:
+		return fmt.Sprintf(" [style=dotted, label=%s]", quote(edge.Kind.String()))
+	case Exit:
+		return " [color=gray]"
+	}
+	return ""
+}
+
+// instructionText renders an instruction as its pc and mnemonic, followed by its target for branches
+func instructionText(instruction classfileparser.Instruction) string {
+	text := fmt.Sprintf("%d: %s", instruction.PC(), instruction.Mnemonic())
+	if branch, ok := instruction.(classfileparser.Branch); ok {
+		text += fmt.Sprintf(" %d", branch.Target())
+	}
+	return text
+}
+
+// quote returns s as a DOT double-quoted string
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
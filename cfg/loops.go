@@ -0,0 +1,136 @@
+package cfg
+
+import "sort"
+
+// Loop is a natural loop: a header dominating the sources of its back edges, and the blocks that
+// reach them without going through the header
+type Loop struct {
+	Header    *Block
+	Blocks    []*Block // Blocks of the loop, header and nested loops included, sorted by pc
+	BackEdges []*Edge  // Edges from the loop to its header
+	Parent    *Loop    // Innermost enclosing loop, nil for an outermost loop
+	Children  []*Loop  // Loops directly nested in this one, sorted by header pc
+	Depth     int      // Nesting depth, 1 for an outermost loop
+}
+
+// Contains reports whether block belongs to the loop
+func (l *Loop) Contains(block *Block) bool {
+	i := sort.Search(len(l.Blocks), func(i int) bool { return l.Blocks[i].Start >= block.Start })
+	return i < len(l.Blocks) && l.Blocks[i] == block
+}
+
+// LoopNest holds the natural loops of a graph and the edges that make it irreducible
+type LoopNest struct {
+	Loops []*Loop // Every loop, sorted by header pc
+	// Irreducible lists the edges closing a cycle whose target does not dominate their
+	// source: cycles with several entries, such as those produced by obfuscators. The cycles
+	// they close are not reported as loops.
+	Irreducible []*Edge
+	innermost   map[*Block]*Loop
+}
+
+// Loops finds the natural loops of the graph reachable from its entry, merging loops that
+// share a header, and nests them
+func (g *Graph) Loops() *LoopNest {
+	dominators := g.Dominators()
+	nest := &LoopNest{innermost: map[*Block]*Loop{}}
+
+	// A depth-first search finds the edges going back to a block still being visited
+	byHeader := map[*Block]*Loop{}
+	state := make([]uint8, len(g.Blocks)) // 0 unvisited, 1 on the stack, 2 done
+	var visit func(*Block)
+	visit = func(block *Block) {
+		state[block.Index] = 1
+		for _, edge := range block.Succs {
+			switch state[edge.To.Index] {
+			case 0:
+				visit(edge.To)
+			case 1:
+				if !dominators.Dominates(edge.To, block) {
+					nest.Irreducible = append(nest.Irreducible, edge)
+					continue
+				}
+				loop, ok := byHeader[edge.To]
+				if !ok {
+					loop = &Loop{Header: edge.To}
+					byHeader[edge.To] = loop
+					nest.Loops = append(nest.Loops, loop)
+				}
+				loop.BackEdges = append(loop.BackEdges, edge)
+			}
+		}
+		state[block.Index] = 2
+	}
+	visit(g.Entry)
+
+	for _, loop := range nest.Loops {
+		in := map[*Block]bool{loop.Header: true}
+		loop.Blocks = []*Block{loop.Header}
+		var stack []*Block
+		for _, edge := range loop.BackEdges {
+			if !in[edge.From] {
+				in[edge.From] = true
+				loop.Blocks = append(loop.Blocks, edge.From)
+				stack = append(stack, edge.From)
+			}
+		}
+		for len(stack) > 0 {
+			block := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, edge := range block.Preds {
+				if !in[edge.From] && dominators.Contains(edge.From) {
+					in[edge.From] = true
+					loop.Blocks = append(loop.Blocks, edge.From)
+					stack = append(stack, edge.From)
+				}
+			}
+		}
+		sort.Slice(loop.Blocks, func(i, j int) bool { return loop.Blocks[i].Start < loop.Blocks[j].Start })
+	}
+
+	// Natural loops with distinct headers are disjoint or nested: visiting the largest first,
+	// the innermost loop recorded for a header is the parent of the loop it starts
+	sort.Slice(nest.Loops, func(i, j int) bool { return len(nest.Loops[i].Blocks) > len(nest.Loops[j].Blocks) })
+	for _, loop := range nest.Loops {
+		if parent := nest.innermost[loop.Header]; parent != nil {
+			loop.Parent = parent
+			loop.Depth = parent.Depth + 1
+			parent.Children = append(parent.Children, loop)
+		} else {
+			loop.Depth = 1
+		}
+		for _, block := range loop.Blocks {
+			nest.innermost[block] = loop
+		}
+	}
+	sort.Slice(nest.Loops, func(i, j int) bool { return nest.Loops[i].Header.Start < nest.Loops[j].Header.Start })
+	for _, loop := range nest.Loops {
+		sort.Slice(loop.Children, func(i, j int) bool { return loop.Children[i].Header.Start < loop.Children[j].Header.Start })
+	}
+	return nest
+}
+
+// IsReducible reports whether every cycle of the graph is a natural loop
+func (n *LoopNest) IsReducible() bool { return len(n.Irreducible) == 0 }
+
+// Innermost returns the innermost loop containing block, nil outside loops
+func (n *LoopNest) Innermost(block *Block) *Loop { return n.innermost[block] }
+
+// Depth returns the loop nesting depth of block, 0 outside loops
+func (n *LoopNest) Depth(block *Block) int {
+	if loop := n.innermost[block]; loop != nil {
+		return loop.Depth
+	}
+	return 0
+}
+
+// CyclomaticComplexity returns the McCabe complexity of the method, edges minus blocks plus two,
+// counted over the blocks reachable from the entry block. Exception edges count as decisions.
+func (g *Graph) CyclomaticComplexity() int {
+	reachable := postorder(g.Entry, len(g.Blocks), successors)
+	edges := 0
+	for _, block := range reachable {
+		edges += len(block.Succs)
+	}
+	return edges - len(reachable) + 2
+}
@@ -0,0 +1,104 @@
+package cfg
+
+import (
+	"fmt"
+	"testing"
+
+	cf "github.com/Slummp/classfileparser"
+)
+
+// loops describes each loop of the nest with its blocks, depth, parent header and back edges
+func loops(nest *LoopNest) []string {
+	var described []string
+	for _, loop := range nest.Loops {
+		parent := "none"
+		if loop.Parent != nil {
+			parent = loop.Parent.Header.String()
+		}
+		described = append(described, fmt.Sprintf("%s %v depth %d parent %s back %v", loop.Header, loop.Blocks, loop.Depth, parent, loop.BackEdges))
+	}
+	return described
+}
+
+func TestLoops(t *testing.T) {
+	g := nestedLoops(t)
+	nest := g.Loops()
+	want := "[B2 [B2 B3 B4 B5] depth 1 parent none back [B5 -> B2 (jump)] B3 [B3 B4] depth 2 parent B2 back [B4 -> B3 (jump)]]"
+	if got := fmt.Sprint(loops(nest)); got != want {
+		t.Errorf("loops = %s, want %s", got, want)
+	}
+	outer, inner := nest.Loops[0], nest.Loops[1]
+	if len(outer.Children) != 1 || outer.Children[0] != inner || len(inner.Children) != 0 {
+		t.Errorf("children of %s = %v", outer.Header, outer.Children)
+	}
+	if !outer.Contains(block(g, "B4")) || outer.Contains(block(g, "B6")) || inner.Contains(block(g, "B5")) {
+		t.Error("Contains")
+	}
+
+	tests := []struct {
+		block     string
+		innermost *Loop
+		depth     int
+	}{
+		{"B1", nil, 0},
+		{"B2", outer, 1},
+		{"B3", inner, 2},
+		{"B4", inner, 2},
+		{"B5", outer, 1},
+		{"B6", nil, 0},
+		{"B7", nil, 0},
+	}
+	for _, test := range tests {
+		b := block(g, test.block)
+		if nest.Innermost(b) != test.innermost || nest.Depth(b) != test.depth {
+			t.Errorf("%s: innermost %v, depth %d, want depth %d", test.block, nest.Innermost(b), nest.Depth(b), test.depth)
+		}
+	}
+	if !nest.IsReducible() {
+		t.Errorf("irreducible edges %v", nest.Irreducible)
+	}
+	// Three conditional branches
+	if got := g.CyclomaticComplexity(); got != 4 {
+		t.Errorf("CyclomaticComplexity() = %d", got)
+	}
+}
+
+func TestLoopsSharedHeader(t *testing.T) {
+	// Both branches of the body at 1 go back to the header at 0, as a continue does
+	g, err := New(cf.Code{Code: []cf.Instruction{
+		cf.Nop{Location: cf.Location{Pc: 0}},
+		cf.Iconst0{Location: cf.Location{Pc: 1}},
+		cf.Ifeq{Location: cf.Location{Pc: 2}, Offset: -2},
+		cf.Goto{Location: cf.Location{Pc: 5}, Offset: -5},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[B1 [B1 B2] depth 1 parent none back [B1 -> B1 (jump) B2 -> B1 (jump)]]"
+	if got := fmt.Sprint(loops(g.Loops())); got != want {
+		t.Errorf("loops = %s, want %s", got, want)
+	}
+}
+
+func TestIrreducible(t *testing.T) {
+	// The cycle between 4 and 8 is entered at both blocks from 0
+	g, err := New(cf.Code{Code: []cf.Instruction{
+		cf.Iconst0{Location: cf.Location{Pc: 0}},
+		cf.Ifeq{Location: cf.Location{Pc: 1}, Offset: 7},
+		cf.Nop{Location: cf.Location{Pc: 4}},
+		cf.Goto{Location: cf.Location{Pc: 5}, Offset: 3},
+		cf.Iconst0{Location: cf.Location{Pc: 8}},
+		cf.Ifeq{Location: cf.Location{Pc: 9}, Offset: -5},
+		cf.Return{Location: cf.Location{Pc: 12}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nest := g.Loops()
+	if nest.IsReducible() || fmt.Sprint(nest.Irreducible) != "[B2 -> B3 (jump)]" {
+		t.Errorf("irreducible edges = %v", nest.Irreducible)
+	}
+	if len(nest.Loops) != 0 {
+		t.Errorf("loops = %s", loops(nest))
+	}
+}
@@ -1,7 +1,10 @@
 package classfileparser
 
 import (
-	"regexp"
+	"fmt"
+
+	"github.com/Slummp/classfileparser/descriptor"
+	"github.com/Slummp/classfileparser/signature"
 )
 
 // ClassStruct represents the entire structure of a .class file
@@ -10,10 +13,12 @@ type ClassStruct struct {
 		MinorVersion uint16 // Minor version
 		MajorVersion uint16 // Major version
 	}
-	Access     []string // Access flags (e.g., public, final)
-	ThisClass  string   // Current class
-	SuperClass string   // Superclass
-	Interfaces []string // Interfaces
+	Access       ClassAccess               // Access flags (e.g., public, final)
+	ThisClass    string                    // Current class
+	SuperClass   string                    // Superclass
+	Interfaces   []string                  // Interfaces
+	Signature    *signature.ClassSignature // Generic signature, nil without a valid Signature attribute
+	SignatureErr error                     // Why the Signature attribute could not be parsed, Signature being nil
 
 	Fields     []Field     // Field structures
 	Methods    []Method    // Method structures
@@ -22,56 +27,142 @@ type ClassStruct struct {
 
 // Field represents a field in the class
 type Field struct {
-	Access     []string    // Access flags for the field
-	Name       string      // Name of the field
-	Type       string      // Type of the field
-	Attributes []Attribute // Field attributes
+	Access       FieldAccess     // Access flags for the field
+	Name         string          // Name of the field
+	Type         string          // Raw descriptor of the field
+	Descriptor   descriptor.Type // Parsed type of the field
+	Signature    signature.Type  // Generic type of the field, nil without a valid Signature attribute
+	SignatureErr error           // Why the Signature attribute could not be parsed, Signature being nil
+	Attributes   []Attribute     // Field attributes
 }
 
 // Method represents a method in the class
 type Method struct {
-	Access      []string    // Access flags for the method
-	Name        string      // Name of the method
-	ReturnType  string      // Return type of the method
-	ParamsTypes []string    // Type of the params of the method
-	Attributes  []Attribute // Method attributes
+	Access       MethodAccess               // Access flags for the method
+	Name         string                     // Name of the method
+	Type         string                     // Raw descriptor of the method
+	Descriptor   descriptor.Method          // Parsed descriptor of the method
+	Signature    *signature.MethodSignature // Generic signature of the method, nil without a valid Signature attribute
+	SignatureErr error                      // Why the Signature attribute could not be parsed, Signature being nil
+	ReturnType   string                     // Return type of the method
+	ParamsTypes  []string                   // Type of the params of the method
+	Attributes   []Attribute                // Method attributes
 }
 
-// GetClassFile converts the parsed binary data into a structured ClassStruct snapshot
+// GetClassFile converts the parsed binary data into a structured ClassStruct snapshot. The JVM
+// never validates Signature attributes, so a malformed one does not fail the class: its
+// signature is left nil and the error is reported in SignatureErr.
 func (cf *ClassFile) GetClassFile() (*ClassStruct, error) {
-	cp, err := cf.GetConstantPool()
+	cp, bootstrapMethods, err := cf.constantPool()
+	if err != nil {
+		return nil, asDecodeError(err)
+	}
+
+	thisClass, err := constant[Class](cp, cf.ThisClass)
 	if err != nil {
-		return nil, err
+		return nil, &DecodeError{Err: fmt.Errorf("invalid this_class: %w", err)}
+	}
+	className := string(thisClass)
+
+	// Only java/lang/Object and module-info have no superclass
+	var superClass Class
+	if cf.SuperClass != 0 {
+		superClass, err = constant[Class](cp, cf.SuperClass)
+		if err != nil {
+			return nil, &DecodeError{Class: className, Err: fmt.Errorf("invalid super_class: %w", err)}
+		}
 	}
 
 	interfaces := []string{}
 	for _, i := range cf.Interfaces {
-		interfaces = append(interfaces, string(cp[i].(Class)))
+		iface, err := constant[Class](cp, i)
+		if err != nil {
+			return nil, &DecodeError{Class: className, Err: fmt.Errorf("invalid interface: %w", err)}
+		}
+		interfaces = append(interfaces, string(iface))
 	}
 
 	fields := []Field{}
-	for _, f := range cf.Fields {
-		fields = append(fields, Field{
-			Access:     findFlags(FieldT, f.AccessFlags),
-			Name:       string(cp[f.NameIndex].(Utf8)),
-			Type:       string(cp[f.DescriptorIndex].(Utf8)),
-			Attributes: parseAttributes(f.Attributes, cp),
-		})
+	for i, f := range cf.Fields {
+		name, err := constant[Utf8](cp, f.NameIndex)
+		if err != nil {
+			return nil, &DecodeError{Class: className, Err: fmt.Errorf("invalid name of field %d: %w", i, err)}
+		}
+		rawType, err := constant[Utf8](cp, f.DescriptorIndex)
+		if err != nil {
+			return nil, &DecodeError{Class: className, Member: string(name), Err: fmt.Errorf("invalid descriptor: %w", err)}
+		}
+		fieldType, err := descriptor.ParseField(string(rawType))
+		if err != nil {
+			return nil, &DecodeError{Class: className, Member: string(name), Err: err}
+		}
+		attributes, err := parseAttributes(f.Attributes, cp)
+		if err != nil {
+			return nil, withContext(err, className, string(name))
+		}
+		field := Field{
+			Access:     FieldAccess(f.AccessFlags),
+			Name:       string(name),
+			Type:       string(rawType),
+			Descriptor: fieldType,
+			Attributes: attributes,
+		}
+		if raw, ok := findSignature(attributes); ok {
+			if field.Signature, err = signature.ParseField(raw); err != nil {
+				field.Signature, field.SignatureErr = nil, signatureError(className, field.Name, err)
+			}
+		}
+		fields = append(fields, field)
 	}
 
 	methods := []Method{}
-	for _, m := range cf.Methods {
-		paramsTypes, returnType := readSignature(string(cp[m.DescriptorIndex].(Utf8)))
-		methods = append(methods, Method{
-			Access:      findFlags(MethodT, m.AccessFlags),
-			Name:        string(cp[m.NameIndex].(Utf8)),
-			ReturnType:  returnType,
+	for i, m := range cf.Methods {
+		name, err := constant[Utf8](cp, m.NameIndex)
+		if err != nil {
+			return nil, &DecodeError{Class: className, Err: fmt.Errorf("invalid name of method %d: %w", i, err)}
+		}
+		rawType, err := constant[Utf8](cp, m.DescriptorIndex)
+		if err != nil {
+			return nil, &DecodeError{Class: className, Member: string(name), Err: fmt.Errorf("invalid descriptor: %w", err)}
+		}
+		member := string(name) + string(rawType)
+		methodType, err := descriptor.ParseMethod(string(rawType))
+		if err != nil {
+			return nil, &DecodeError{Class: className, Member: member, Err: err}
+		}
+		paramsTypes := make([]string, len(methodType.Params))
+		for j, param := range methodType.Params {
+			paramsTypes[j] = param.Descriptor()
+		}
+		attributes, err := parseAttributes(m.Attributes, cp)
+		if err != nil {
+			return nil, withContext(err, className, member)
+		}
+		method := Method{
+			Access:      MethodAccess(m.AccessFlags),
+			Name:        string(name),
+			Type:        string(rawType),
+			Descriptor:  methodType,
+			ReturnType:  methodType.Return.Descriptor(),
 			ParamsTypes: paramsTypes,
-			Attributes:  parseAttributes(m.Attributes, cp),
-		})
+			Attributes:  attributes,
+		}
+		if raw, ok := findSignature(attributes); ok {
+			if parsed, err := signature.ParseMethod(raw); err != nil {
+				method.SignatureErr = signatureError(className, member, err)
+			} else {
+				method.Signature = &parsed
+			}
+		}
+		methods = append(methods, method)
 	}
 
-	return &ClassStruct{
+	attributes, err := parseAttributes(cf.Attributes, cp)
+	if err != nil {
+		return nil, withContext(err, className, "")
+	}
+	shareBootstrapMethods(attributes, bootstrapMethods)
+	class := &ClassStruct{
 		Version: struct {
 			MinorVersion uint16
 			MajorVersion uint16
@@ -79,23 +170,46 @@ func (cf *ClassFile) GetClassFile() (*ClassStruct, error) {
 			MinorVersion: cf.MinorVersion,
 			MajorVersion: cf.MajorVersion,
 		},
-		Access:     findFlags(ClassT, cf.AccessFlags),
-		ThisClass:  string(cp[cf.ThisClass].(Class)),
-		SuperClass: string(cp[cf.SuperClass].(Class)),
+		Access:     ClassAccess(cf.AccessFlags),
+		ThisClass:  className,
+		SuperClass: string(superClass),
 		Interfaces: interfaces,
 		Fields:     fields,
 		Methods:    methods,
-		Attributes: parseAttributes(cf.Attributes, cp),
-	}, nil
+		Attributes: attributes,
+	}
+	if raw, ok := findSignature(attributes); ok {
+		if parsed, err := signature.ParseClass(raw); err != nil {
+			class.SignatureErr = signatureError(className, "", err)
+		} else {
+			class.Signature = &parsed
+		}
+	}
+	return class, nil
 }
 
-func readSignature(signature string) ([]string, string) {
-	re := regexp.MustCompile(`\((.*?)\)(.*)`)
-	matches := re.FindStringSubmatch(signature)
-	paramString, returnType := matches[1], matches[2]
+// signatureError locates the error of a Signature attribute that could not be parsed
+func signatureError(class, member string, err error) error {
+	return &DecodeError{Class: class, Member: member, Attribute: "Signature", Err: err}
+}
 
-	re = regexp.MustCompile(`(\[?(?:L.+?;|.))`)
-	params := re.FindAllString(paramString, -1)
+// findSignature returns the value of the Signature attribute among attributes
+func findSignature(attributes []Attribute) (string, bool) {
+	for _, attribute := range attributes {
+		if value, ok := attribute.(Signature); ok {
+			return string(value), true
+		}
+	}
+	return "", false
+}
 
-	return params, returnType
+// shareBootstrapMethods replaces the entries of the BootstrapMethods attribute among attributes
+// with methods, those the Bootstrap fields of the constant pool point to
+func shareBootstrapMethods(attributes []Attribute, methods []BootstrapMethod) {
+	for i, attribute := range attributes {
+		if _, ok := attribute.(BootstrapMethods); ok {
+			attributes[i] = BootstrapMethods{NumBootstrapMethods: uint16(len(methods)), BootstrapMethods: methods}
+			return
+		}
+	}
 }
@@ -0,0 +1,79 @@
+package classfileparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestGetClassFileSignatures(t *testing.T) {
+	cf, err := Open(bytes.NewReader(readTestdata(t)["Sample.class"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	class, err := cf.GetClassFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class.Signature == nil || class.SignatureErr != nil {
+		t.Fatalf("class signature = %v, %v", class.Signature, class.SignatureErr)
+	}
+	if got, want := class.Signature.String(), "<T::Ljava/lang/Comparable<TT;>;>Ljava/lang/Object;Ljava/lang/Runnable;"; got != want {
+		t.Errorf("class signature = %s, want %s", got, want)
+	}
+	for _, field := range class.Fields {
+		if field.Name == "items" && (field.Signature == nil || field.Signature.JavaName() != "java.util.List<T>") {
+			t.Errorf("field signature = %v", field.Signature)
+		}
+	}
+}
+
+func TestGetClassFileMalformedSignatures(t *testing.T) {
+	cf, err := Open(bytes.NewReader(readTestdata(t)["Sample.class"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewPoolBuilder(cf.ConstantPool)
+	junk := binary.BigEndian.AppendUint16(nil, pool.Utf8("<T:junk"))
+	signature := AttributeInfo{AttributeNameIndex: pool.Utf8("Signature"), AttributeLength: 2, Info: junk}
+	cf.ConstantPool = pool.Pool()
+	replace := func(attributes []AttributeInfo) []AttributeInfo {
+		for i, attribute := range attributes {
+			if attribute.AttributeNameIndex == signature.AttributeNameIndex {
+				attributes[i] = signature
+				return attributes
+			}
+		}
+		return append(attributes, signature)
+	}
+	cf.Attributes = replace(cf.Attributes)
+	for i := range cf.Fields {
+		cf.Fields[i].Attributes = replace(cf.Fields[i].Attributes)
+	}
+	for i := range cf.Methods {
+		cf.Methods[i].Attributes = replace(cf.Methods[i].Attributes)
+	}
+
+	class, err := cf.GetClassFile()
+	if err != nil {
+		t.Fatalf("a malformed signature failed the class: %v", err)
+	}
+	check := func(what string, parsed bool, err error, attributes []Attribute) {
+		t.Helper()
+		var decodeErr *DecodeError
+		if parsed || !errors.As(err, &decodeErr) || decodeErr.Attribute != "Signature" {
+			t.Errorf("%s: parsed %v, error %v", what, parsed, err)
+		}
+		if raw, ok := findSignature(attributes); !ok || raw != "<T:junk" {
+			t.Errorf("%s: raw signature %q", what, raw)
+		}
+	}
+	check("class", class.Signature != nil, class.SignatureErr, class.Attributes)
+	for _, field := range class.Fields {
+		check(field.Name, field.Signature != nil, field.SignatureErr, field.Attributes)
+	}
+	for _, method := range class.Methods {
+		check(method.Name, method.Signature != nil, method.SignatureErr, method.Attributes)
+	}
+}
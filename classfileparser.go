@@ -1,6 +1,7 @@
 package classfileparser
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -216,7 +217,7 @@ func getCpInfoLength(tag uint8, file io.Reader) (int, error) {
 	case 16: // CONSTANT_MethodType
 		return 2, nil
 
-	case 18: // CONSTANT_InvokeDynamic
+	case 17, 18: // CONSTANT_Dynamic, CONSTANT_InvokeDynamic
 		return 4, nil
 
 	case 19, 20: // CONSTANT_Module, CONSTANT_Package (Java 9+)
@@ -278,9 +279,38 @@ func parseAttribute(file io.Reader, attr *AttributeInfo) error {
 	if err := binary.Read(file, binary.BigEndian, &attr.AttributeLength); err != nil {
 		return err
 	}
-	attr.Info = make([]byte, attr.AttributeLength)
-	if _, err := io.ReadFull(file, attr.Info); err != nil {
+	info, err := readInfo(file, attr.AttributeLength)
+	if err != nil {
 		return err
 	}
+	attr.Info = info
 	return nil
 }
+
+// maxInfoPrealloc is the largest attribute read into a buffer allocated upfront. Longer ones grow
+// their buffer as the input is read.
+const maxInfoPrealloc = 1 << 20
+
+// readInfo reads the length bytes of an attribute's info. A corrupt length cannot make it
+// allocate more memory than the input holds: readers reporting the bytes left, such as
+// bytes.Reader, are checked first, and other readers fill a buffer growing with the data read.
+func readInfo(file io.Reader, length uint32) ([]byte, error) {
+	if r, ok := file.(interface{ Len() int }); ok && int64(length) > int64(r.Len()) {
+		return nil, fmt.Errorf("attribute length %d exceeds the %d bytes left", length, r.Len())
+	}
+	if length <= maxInfoPrealloc {
+		info := make([]byte, length)
+		if _, err := io.ReadFull(file, info); err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+	var buf bytes.Buffer
+	if n, err := io.CopyN(&buf, file, int64(length)); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("attribute length %d exceeds the %d bytes left", length, n)
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
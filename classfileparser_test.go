@@ -0,0 +1,78 @@
+package classfileparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// minimalClass returns the bytes of a class named A without members, with the given encoded
+// class attributes
+func minimalClass(attributes ...[]byte) []byte {
+	var buf bytes.Buffer
+	write := func(values ...any) {
+		for _, value := range values {
+			binary.Write(&buf, binary.BigEndian, value)
+		}
+	}
+	write(uint32(0xCAFEBABE), uint16(0), uint16(52), uint16(3))
+	write(uint8(1), uint16(1), []byte("A"))
+	write(uint8(7), uint16(1))
+	write(uint16(0x21), uint16(2), uint16(0), uint16(0), uint16(0), uint16(0))
+	write(uint16(len(attributes)))
+	for _, attribute := range attributes {
+		buf.Write(attribute)
+	}
+	return buf.Bytes()
+}
+
+// attribute returns an attribute named by constant #1 declaring length bytes, followed by info
+func attribute(length uint32, info []byte) []byte {
+	buf := binary.BigEndian.AppendUint16(nil, 1)
+	buf = binary.BigEndian.AppendUint32(buf, length)
+	return append(buf, info...)
+}
+
+func TestOpenMinimalClass(t *testing.T) {
+	cf, err := Open(bytes.NewReader(minimalClass(attribute(3, []byte{1, 2, 3}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cf.Attributes) != 1 || !bytes.Equal(cf.Attributes[0].Info, []byte{1, 2, 3}) {
+		t.Errorf("attributes = %+v", cf.Attributes)
+	}
+}
+
+func TestOpenRejectsOversizedAttribute(t *testing.T) {
+	data := minimalClass(attribute(0xFFFFFFF0, []byte{1, 2, 3}))
+	readers := map[string]func() io.Reader{
+		"bytes.Reader": func() io.Reader { return bytes.NewReader(data) },
+		// io.MultiReader hides the length left, so the buffer has to grow with the input
+		"plain reader": func() io.Reader { return io.MultiReader(bytes.NewReader(data)) },
+	}
+	for name, reader := range readers {
+		t.Run(name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := Open(reader())
+			runtime.ReadMemStats(&after)
+			if err == nil {
+				t.Fatal("Open accepted an attribute longer than the input")
+			}
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+				t.Errorf("Open allocated %d bytes for a %d-byte input", allocated, len(data))
+			}
+		})
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	data := minimalClass(attribute(3, []byte{1, 2, 3}))
+	for n := 0; n < len(data); n++ {
+		if _, err := Open(bytes.NewReader(data[:n])); err == nil {
+			t.Errorf("Open accepted the first %d of %d bytes", n, len(data))
+		}
+	}
+}
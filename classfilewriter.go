@@ -0,0 +1,111 @@
+package classfileparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteTo serializes the class file to w in the .class format. Counts and lengths are taken
+// from the slices rather than from the count fields, so entries can be added or removed
+// without updating them by hand. Writing an unmodified ClassFile returned by Open
+// reproduces its input byte for byte.
+func (cf *ClassFile) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if err := cf.encode(&buf); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func (cf *ClassFile) encode(buf *bytes.Buffer) error {
+	write(buf, cf.Magic, cf.MinorVersion, cf.MajorVersion)
+
+	if len(cf.ConstantPool)+1 > 0xFFFF {
+		return fmt.Errorf("too many constant pool entries: %d", len(cf.ConstantPool))
+	}
+	write(buf, uint16(len(cf.ConstantPool)+1))
+	for i, entry := range cf.ConstantPool {
+		if entry.Tag == 0 {
+			// Second slot of a CONSTANT_Long or CONSTANT_Double
+			continue
+		}
+		if err := writeCpInfo(buf, entry); err != nil {
+			return fmt.Errorf("failed to write constant pool entry #%d: %w", i+1, err)
+		}
+	}
+
+	write(buf, cf.AccessFlags, cf.ThisClass, cf.SuperClass)
+	if len(cf.Interfaces) > 0xFFFF {
+		return fmt.Errorf("too many interfaces: %d", len(cf.Interfaces))
+	}
+	write(buf, uint16(len(cf.Interfaces)), cf.Interfaces)
+
+	if len(cf.Fields) > 0xFFFF {
+		return fmt.Errorf("too many fields: %d", len(cf.Fields))
+	}
+	write(buf, uint16(len(cf.Fields)))
+	for i, field := range cf.Fields {
+		write(buf, field.AccessFlags, field.NameIndex, field.DescriptorIndex)
+		if err := writeAttributes(buf, field.Attributes); err != nil {
+			return fmt.Errorf("failed to write field %d: %w", i, err)
+		}
+	}
+
+	if len(cf.Methods) > 0xFFFF {
+		return fmt.Errorf("too many methods: %d", len(cf.Methods))
+	}
+	write(buf, uint16(len(cf.Methods)))
+	for i, method := range cf.Methods {
+		write(buf, method.AccessFlags, method.NameIndex, method.DescriptorIndex)
+		if err := writeAttributes(buf, method.Attributes); err != nil {
+			return fmt.Errorf("failed to write method %d: %w", i, err)
+		}
+	}
+
+	if err := writeAttributes(buf, cf.Attributes); err != nil {
+		return fmt.Errorf("failed to write attributes: %w", err)
+	}
+	return nil
+}
+
+func writeCpInfo(buf *bytes.Buffer, entry CpInfo) error {
+	write(buf, entry.Tag)
+	if entry.Tag == 1 {
+		if len(entry.Info) > 0xFFFF {
+			return fmt.Errorf("CONSTANT_Utf8 of %d bytes is too long", len(entry.Info))
+		}
+		write(buf, uint16(len(entry.Info)))
+	} else if length, err := getCpInfoLength(entry.Tag, nil); err != nil {
+		return err
+	} else if length != len(entry.Info) {
+		return fmt.Errorf("tag %d requires %d bytes of data, got %d", entry.Tag, length, len(entry.Info))
+	}
+	buf.Write(entry.Info)
+	return nil
+}
+
+func writeAttributes(buf *bytes.Buffer, attributes []AttributeInfo) error {
+	if len(attributes) > 0xFFFF {
+		return fmt.Errorf("too many attributes: %d", len(attributes))
+	}
+	write(buf, uint16(len(attributes)))
+	for _, attribute := range attributes {
+		if uint64(len(attribute.Info)) > 0xFFFFFFFF {
+			return fmt.Errorf("attribute of %d bytes is too long", len(attribute.Info))
+		}
+		write(buf, attribute.AttributeNameIndex, uint32(len(attribute.Info)))
+		buf.Write(attribute.Info)
+	}
+	return nil
+}
+
+// write encodes data into buf in big-endian order, mirroring read
+func write(buf *bytes.Buffer, data ...interface{}) {
+	for _, d := range data {
+		// Writing fixed-size values to a bytes.Buffer cannot fail
+		_ = binary.Write(buf, binary.BigEndian, d)
+	}
+}
@@ -0,0 +1,215 @@
+package classfileparser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata holds Hello.class, laid out as javac 17 compiles a hello world, and Sample.class, a
+// version 52 class with a lambda, both switches, an exception handler, stack map frames, generic
+// signatures, annotations, two-slot constants, modified UTF-8 and an unknown attribute
+func readTestdata(t *testing.T) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.class"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no class in testdata: %v", err)
+	}
+	classes := map[string][]byte{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes[filepath.Base(path)] = data
+	}
+	return classes
+}
+
+func writeBytes(t *testing.T, cf *ClassFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	n, err := cf.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTripTestdata(t *testing.T) {
+	for name, data := range readTestdata(t) {
+		t.Run(name, func(t *testing.T) {
+			cf, err := Open(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written := writeBytes(t, cf); !bytes.Equal(written, data) {
+				t.Errorf("WriteTo wrote %d bytes differing from the %d read", len(written), len(data))
+			}
+			if _, err := cf.GetClassFile(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// synthetic returns a class file with its counts matching its slices, as Open returns them
+func synthetic(pool []CpInfo, fields []FieldInfo, methods []MethodInfo, attributes []AttributeInfo) *ClassFile {
+	count := func(attributes []AttributeInfo) []AttributeInfo {
+		for i := range attributes {
+			attributes[i].AttributeLength = uint32(len(attributes[i].Info))
+		}
+		return attributes
+	}
+	for i := range fields {
+		fields[i].AttributesCount = uint16(len(fields[i].Attributes))
+		count(fields[i].Attributes)
+	}
+	for i := range methods {
+		methods[i].AttributesCount = uint16(len(methods[i].Attributes))
+		count(methods[i].Attributes)
+	}
+	return &ClassFile{
+		Magic:             0xCAFEBABE,
+		MinorVersion:      3,
+		MajorVersion:      45,
+		ConstantPoolCount: uint16(len(pool) + 1),
+		ConstantPool:      pool,
+		AccessFlags:       0x0601,
+		ThisClass:         2,
+		SuperClass:        0,
+		Interfaces:        []uint16{2, 2},
+		InterfacesCount:   2,
+		FieldsCount:       uint16(len(fields)),
+		Fields:            fields,
+		MethodsCount:      uint16(len(methods)),
+		Methods:           methods,
+		AttributesCount:   uint16(len(attributes)),
+		Attributes:        count(attributes),
+	}
+}
+
+func TestRoundTripSynthetic(t *testing.T) {
+	pool := []CpInfo{
+		utf8Entry("A"),
+		entry(7, 0, 1),
+		entry(5, 0x80, 0, 0, 0, 0, 0, 0, 1), {}, // Long and its unusable second slot
+		entry(6, 0x7F, 0xF8, 0, 0, 0, 0, 0, 0), {}, // Double NaN
+		utf8Entry(""),
+		entry(1, 0xC0, 0x80, 0xED, 0xA0, 0xBD), // Modified UTF-8, kept as is
+		entry(3, 0xFF, 0xFF, 0xFF, 0xFF),
+		entry(4, 0x7F, 0x80, 0, 0),
+	}
+	classes := map[string]*ClassFile{
+		"empty": synthetic(pool, nil, nil, nil),
+		"members": synthetic(pool,
+			[]FieldInfo{{AccessFlags: 0x0019, NameIndex: 1, DescriptorIndex: 7}},
+			[]MethodInfo{
+				{AccessFlags: 0x0401, NameIndex: 7, DescriptorIndex: 8, Attributes: []AttributeInfo{{AttributeNameIndex: 7}}},
+				{AccessFlags: 0xFFFF, NameIndex: 1, DescriptorIndex: 1},
+			},
+			nil),
+		"unknown attributes": synthetic(pool, nil, nil, []AttributeInfo{
+			{AttributeNameIndex: 1, Info: []byte{}},
+			{AttributeNameIndex: 7, Info: []byte{0xCA, 0xFE}},
+			{AttributeNameIndex: 1, Info: bytes.Repeat([]byte{0xAB}, maxInfoPrealloc+1)},
+		}),
+	}
+	for name, cf := range classes {
+		t.Run(name, func(t *testing.T) {
+			data := writeBytes(t, cf)
+			opened, err := Open(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(normalize(opened), normalize(cf)) {
+				t.Errorf("Open returned\n%+v\nwant\n%+v", opened, cf)
+			}
+			if written := writeBytes(t, opened); !bytes.Equal(written, data) {
+				t.Error("writing the class read back changed its bytes")
+			}
+		})
+	}
+}
+
+// normalize makes empty slices nil, Open allocating them for zero counts
+func normalize(cf *ClassFile) *ClassFile {
+	copied := *cf
+	attributes := func(attributes []AttributeInfo) []AttributeInfo {
+		if len(attributes) == 0 {
+			return nil
+		}
+		normalized := append([]AttributeInfo(nil), attributes...)
+		for i := range normalized {
+			if len(normalized[i].Info) == 0 {
+				normalized[i].Info = nil
+			}
+		}
+		return normalized
+	}
+	if len(copied.Interfaces) == 0 {
+		copied.Interfaces = nil
+	}
+	copied.Fields, copied.Methods = nil, nil
+	for _, f := range cf.Fields {
+		f.Attributes = attributes(f.Attributes)
+		copied.Fields = append(copied.Fields, f)
+	}
+	for _, m := range cf.Methods {
+		m.Attributes = attributes(m.Attributes)
+		copied.Methods = append(copied.Methods, m)
+	}
+	copied.Attributes = attributes(cf.Attributes)
+	return &copied
+}
+
+func TestWriteToRecountsSlices(t *testing.T) {
+	cf, err := Open(bytes.NewReader(readTestdata(t)["Hello.class"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := FieldInfo{AccessFlags: 0x0009, NameIndex: cf.Methods[1].NameIndex}
+	for i, entry := range cf.ConstantPool {
+		if entry.Tag == 1 && string(entry.Info) == "Ljava/io/PrintStream;" {
+			field.DescriptorIndex = uint16(i + 1)
+		}
+	}
+	cf.Fields = append(cf.Fields, field)
+	cf.Methods = cf.Methods[:1]
+	cf.Attributes = nil
+
+	reopened, err := Open(bytes.NewReader(writeBytes(t, cf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	class, err := reopened.GetClassFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.FieldsCount != 1 || reopened.MethodsCount != 1 || reopened.AttributesCount != 0 {
+		t.Errorf("counts = %d fields, %d methods, %d attributes", reopened.FieldsCount, reopened.MethodsCount, reopened.AttributesCount)
+	}
+	if field := class.Fields[0]; field.Name != "main" || field.Type != "Ljava/io/PrintStream;" {
+		t.Errorf("field = %s %s", field.Name, field.Type)
+	}
+}
+
+func TestWriteToErrors(t *testing.T) {
+	tests := map[string]CpInfo{
+		"CONSTANT_Utf8 of 65536 bytes is too long": utf8Entry(strings.Repeat("x", 0x10000)),
+		"tag 7 requires 2 bytes of data, got 3":    entry(7, 0, 1, 2),
+		"unknown constant pool tag: 2":             entry(2),
+	}
+	for want, bad := range tests {
+		cf := synthetic([]CpInfo{utf8Entry("A"), entry(7, 0, 1), bad}, nil, nil, nil)
+		if _, err := cf.WriteTo(&bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package classpath finds class files by internal name across directories and jars, the way
+// the JVM searches its class path.
+package classpath
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/descriptor"
+	"github.com/Slummp/classfileparser/jar"
+)
+
+// ErrNotFound is returned, wrapped, when no entry of the class path holds a class
+var ErrNotFound = errors.New("class not found")
+
+// NestedSeparator separates the path of a jar from the name of a jar stored inside it, as in
+// app.jar!/BOOT-INF/lib/library.jar
+const NestedSeparator = "!/"
+
+// LatestRelease makes multi-release jars resolve every class to its most recent variant
+const LatestRelease = math.MaxInt
+
+// source is an entry of the class path
+type source interface {
+	// read returns the content of the class file of the internal name, if the source has it
+	read(name string) ([]byte, bool, error)
+	String() string
+}
+
+// ClassPath is an ordered list of directories and jars. When several entries hold a class, the
+// first one wins. Classes are parsed on first lookup and cached. A ClassPath is safe for
+// concurrent use.
+type ClassPath struct {
+	release  int // Java release multi-release jars are resolved for
+	mu       sync.Mutex
+	sources  []source
+	archives []*jar.Archive
+	classes  map[string]*class
+}
+
+// class is a cache entry. The entry is added under the lock of the class path, but the class is
+// looked up and parsed outside of it, once.
+type class struct {
+	sources []source // Entries of the class path when the lookup started
+	once    sync.Once
+	done    atomic.Bool // Set once file and err are
+	file    *classfileparser.ClassFile
+	err     error
+
+	snapshotOnce sync.Once
+	snapshot     *classfileparser.ClassStruct
+	snapshotErr  error
+}
+
+// New returns a class path made of entries, see Add. Multi-release jars are resolved for
+// LatestRelease.
+func New(entries ...string) (*ClassPath, error) {
+	return NewRelease(LatestRelease, entries...)
+}
+
+// NewRelease is like New, but resolves multi-release jars the way a Java release runtime does,
+// see jar.Archive.ReleaseEntries
+func NewRelease(release int, entries ...string) (*ClassPath, error) {
+	cp := &ClassPath{release: release, classes: map[string]*class{}}
+	for _, entry := range entries {
+		if err := cp.Add(entry); err != nil {
+			cp.Close()
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+// Parse returns the class path listed in s, separated like the CLASSPATH environment variable
+// (: on Unix, ; on Windows)
+func Parse(s string) (*ClassPath, error) {
+	var entries []string
+	for _, entry := range filepath.SplitList(s) {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return New(entries...)
+}
+
+// Add appends an entry to the class path: a directory, a jar or zip file, or a jar nested in
+// a jar, written with NestedSeparator (outer.jar!/lib/inner.jar, possibly on several levels).
+// Jars are opened right away and kept open until Close.
+func (cp *ClassPath) Add(entry string) error {
+	parts := strings.Split(entry, NestedSeparator)
+	info, err := os.Stat(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid class path entry %s: %w", entry, err)
+	}
+	if info.IsDir() {
+		if len(parts) > 1 {
+			return fmt.Errorf("invalid class path entry %s: %s is a directory", entry, parts[0])
+		}
+		cp.addSource(dirSource(parts[0]))
+		return nil
+	}
+
+	archive, err := jar.Open(parts[0])
+	if err != nil {
+		return err
+	}
+	cp.mu.Lock()
+	cp.archives = append(cp.archives, archive)
+	cp.mu.Unlock()
+	for i, name := range parts[1:] {
+		nested, ok := archive.Entry(name)
+		if !ok {
+			return fmt.Errorf("invalid class path entry %s: no entry %s in %s", entry, name, strings.Join(parts[:i+1], NestedSeparator))
+		}
+		data, err := nested.ReadAll()
+		if err != nil {
+			return err
+		}
+		if archive, err = jar.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+			return fmt.Errorf("invalid class path entry %s: %w", entry, err)
+		}
+	}
+	cp.addSource(newJarSource(entry, archive, cp.release))
+	return nil
+}
+
+// AddArchive appends an already opened archive to the class path. Close does not close it.
+func (cp *ClassPath) AddArchive(name string, archive *jar.Archive) {
+	cp.addSource(newJarSource(name, archive, cp.release))
+}
+
+func (cp *ClassPath) addSource(s source) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.sources = append(cp.sources, s)
+}
+
+// Close closes the jars opened by the class path
+func (cp *ClassPath) Close() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	var errs []error
+	for _, archive := range cp.archives {
+		errs = append(errs, archive.Close())
+	}
+	cp.archives = nil
+	return errors.Join(errs...)
+}
+
+// Entries returns the entries of the class path, in search order
+func (cp *ClassPath) Entries() []string {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	entries := make([]string, len(cp.sources))
+	for i, s := range cp.sources {
+		entries[i] = s.String()
+	}
+	return entries
+}
+
+// Lookup returns the class file of the class with the internal name, e.g. java/util/List,
+// taken from the first entry holding it. The error wraps ErrNotFound if no entry does.
+func (cp *ClassPath) Lookup(name string) (*classfileparser.ClassFile, error) {
+	c := cp.lookup(name)
+	return c.file, c.err
+}
+
+// Class is like Lookup, but returns the snapshot of the class built by GetClassFile
+func (cp *ClassPath) Class(name string) (*classfileparser.ClassStruct, error) {
+	c := cp.lookup(name)
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.snapshotOnce.Do(func() {
+		c.snapshot, c.snapshotErr = c.file.GetClassFile()
+	})
+	return c.snapshot, c.snapshotErr
+}
+
+func (cp *ClassPath) lookup(name string) *class {
+	cp.mu.Lock()
+	c, ok := cp.classes[name]
+	// A class missing from the entries searched may be in an entry added since
+	if !ok || c.done.Load() && errors.Is(c.err, ErrNotFound) && len(c.sources) < len(cp.sources) {
+		c = &class{sources: cp.sources}
+		cp.classes[name] = c
+	}
+	cp.mu.Unlock()
+
+	c.once.Do(func() {
+		c.file, c.err = find(c.sources, name)
+		c.done.Store(true)
+	})
+	return c
+}
+
+// find reads and parses the class file of the internal name from the first source holding it
+func find(sources []source, name string) (*classfileparser.ClassFile, error) {
+	if !descriptor.ValidInternalName(name) {
+		return nil, fmt.Errorf("invalid class name %q", name)
+	}
+	for _, s := range sources {
+		data, ok, err := s.read(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", name, s, err)
+		}
+		if !ok {
+			continue
+		}
+		file, err := classfileparser.Open(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s from %s: %w", name, s, err)
+		}
+		return file, nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+}
+
+// dirSource is a directory holding class files in package directories
+type dirSource string
+
+func (d dirSource) read(name string) ([]byte, bool, error) {
+	data, err := os.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)+".class"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	return data, err == nil, err
+}
+
+func (d dirSource) String() string {
+	return string(d)
+}
+
+// jarSource is an archive, indexed by the path of the entries a Java release sees
+type jarSource struct {
+	name    string
+	entries map[string]jar.Entry
+}
+
+func newJarSource(name string, archive *jar.Archive, release int) *jarSource {
+	s := &jarSource{name: name, entries: map[string]jar.Entry{}}
+	for _, entry := range archive.ReleaseEntries(release) {
+		if entry.IsClass() {
+			s.entries[entry.Path] = entry
+		}
+	}
+	return s
+}
+
+func (s *jarSource) read(name string) ([]byte, bool, error) {
+	entry, ok := s.entries[name+".class"]
+	if !ok {
+		return nil, false, nil
+	}
+	data, err := entry.ReadAll()
+	return data, err == nil, err
+}
+
+func (s *jarSource) String() string {
+	return s.name
+}
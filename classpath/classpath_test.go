@@ -0,0 +1,213 @@
+package classpath
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// zipData returns an archive holding files, stored in the order of names
+func zipData(t *testing.T, names []string, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		file, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(files[name])
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// thisClass returns the name of the class file found for name
+func thisClass(t *testing.T, cp *ClassPath, name string) string {
+	t.Helper()
+	class, err := cp.Class(name)
+	if err != nil {
+		t.Fatalf("Class(%s): %v", name, err)
+	}
+	return class.ThisClass
+}
+
+func TestLookupShadowing(t *testing.T) {
+	hello, sample := readTestdata(t, "Hello.class"), readTestdata(t, "Sample.class")
+	root := t.TempDir()
+	dir := filepath.Join(root, "classes")
+	writeFile(t, filepath.Join(dir, "a", "X.class"), hello)
+	jarPath := writeFile(t, filepath.Join(root, "lib.jar"), zipData(t, []string{"a/X.class", "a/Y.class"}, map[string][]byte{
+		"a/X.class": sample,
+		"a/Y.class": sample,
+	}))
+
+	cp, err := New(dir, jarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if got := thisClass(t, cp, "a/X"); got != "Hello" {
+		t.Errorf("a/X comes from %s, want the directory", got)
+	}
+	if got := thisClass(t, cp, "a/Y"); got != "com/x/Sample" {
+		t.Errorf("a/Y comes from %s, want the jar", got)
+	}
+
+	cp, err = Parse(jarPath + string(filepath.ListSeparator) + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if got := thisClass(t, cp, "a/X"); got != "com/x/Sample" {
+		t.Errorf("a/X comes from %s, want the jar", got)
+	}
+	if entries := cp.Entries(); len(entries) != 2 || entries[0] != jarPath || entries[1] != dir {
+		t.Errorf("Entries() = %q", entries)
+	}
+}
+
+func TestLookupNested(t *testing.T) {
+	hello := readTestdata(t, "Hello.class")
+	inner := zipData(t, []string{"a/Inner.class"}, map[string][]byte{"a/Inner.class": hello})
+	middle := zipData(t, []string{"lib/inner.jar"}, map[string][]byte{"lib/inner.jar": inner})
+	outer := writeFile(t, filepath.Join(t.TempDir(), "app.jar"), zipData(t, []string{"BOOT-INF/lib/middle.jar"}, map[string][]byte{
+		"BOOT-INF/lib/middle.jar": middle,
+	}))
+
+	entry := outer + "!/BOOT-INF/lib/middle.jar!/lib/inner.jar"
+	cp, err := New(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if got := thisClass(t, cp, "a/Inner"); got != "Hello" {
+		t.Errorf("a/Inner = %s", got)
+	}
+	if entries := cp.Entries(); len(entries) != 1 || entries[0] != entry {
+		t.Errorf("Entries() = %q", entries)
+	}
+
+	for _, entry := range []string{outer + "!/missing.jar", outer + "!/BOOT-INF/lib/middle.jar!/lib/missing.jar"} {
+		if _, err := New(entry); err == nil || !strings.Contains(err.Error(), "no entry") {
+			t.Errorf("%s: error = %v", entry, err)
+		}
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	dir := t.TempDir()
+	cp, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	if _, err := cp.Lookup("a/Missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup(a/Missing) error = %v", err)
+	}
+	if _, err := cp.Lookup("a//Invalid"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup(a//Invalid) error = %v", err)
+	}
+
+	// A class missing from the entries is looked up again in an entry added later
+	other := t.TempDir()
+	writeFile(t, filepath.Join(other, "a", "Missing.class"), readTestdata(t, "Hello.class"))
+	if err := cp.Add(other); err != nil {
+		t.Fatal(err)
+	}
+	if got := thisClass(t, cp, "a/Missing"); got != "Hello" {
+		t.Errorf("a/Missing = %s", got)
+	}
+
+	writeFile(t, filepath.Join(dir, "a", "Junk.class"), []byte("junk"))
+	if _, err := cp.Lookup("a/Junk"); err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "failed to parse a/Junk") {
+		t.Errorf("Lookup(a/Junk) error = %v", err)
+	}
+	if _, err := New(filepath.Join(dir, "missing.jar")); err == nil {
+		t.Error("a missing entry is accepted")
+	}
+}
+
+func TestLookupMultiRelease(t *testing.T) {
+	hello, sample := readTestdata(t, "Hello.class"), readTestdata(t, "Sample.class")
+	path := writeFile(t, filepath.Join(t.TempDir(), "mr.jar"), zipData(t,
+		[]string{"META-INF/MANIFEST.MF", "a/X.class", "META-INF/versions/11/a/X.class"},
+		map[string][]byte{
+			"META-INF/MANIFEST.MF":           []byte("Manifest-Version: 1.0\nMulti-Release: true\n"),
+			"a/X.class":                      hello,
+			"META-INF/versions/11/a/X.class": sample,
+		}))
+	tests := []struct {
+		release int
+		want    string
+	}{
+		{8, "Hello"},
+		{11, "com/x/Sample"},
+		{LatestRelease, "com/x/Sample"},
+	}
+	for _, test := range tests {
+		cp, err := NewRelease(test.release, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := thisClass(t, cp, "a/X"); got != test.want {
+			t.Errorf("release %d: a/X = %s, want %s", test.release, got, test.want)
+		}
+		cp.Close()
+	}
+}
+
+func TestLookupConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a", "X.class"), readTestdata(t, "Hello.class"))
+	cp, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	classes := make([]any, 8)
+	for i := range classes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			class, err := cp.Class("a/X")
+			if err != nil {
+				t.Error(err)
+			}
+			classes[i] = class
+		}()
+	}
+	wg.Wait()
+	for _, class := range classes[1:] {
+		if class != classes[0] {
+			t.Fatal("concurrent lookups return different classes")
+		}
+	}
+}
@@ -0,0 +1,558 @@
+package classfileparser
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// EncodeCode serializes code into a Code attribute, allocating or reusing in pool the constant
+// pool entries its instructions refer to. Indexes already stored in code, such as exception
+// catch types or local variable names, must belong to pool.
+//
+// The PC of every instruction is read as its position in the layout that branch and switch
+// offsets, the exception table and the nested LineNumberTable, LocalVariableTable and
+// LocalVariableTypeTable attributes were computed for. These positions are resolved to
+// instructions and rewritten for the new layout, so instructions can be inserted, removed or
+// replaced: a position resolves to the first instruction carrying it. Decoded code satisfies
+// this as it is, and so does code numbered by instruction index. The end of an exception range or
+// of a local variable range may also be the end of the code, the position following the last
+// instruction: its pc plus its length, or plus one when the code is numbered by instruction index.
+// Debug entries whose instructions were removed are dropped.
+//
+// ldc or ldc_w, goto or goto_w and jsr or jsr_w are picked from the final constant pool indexes
+// and offsets, and a conditional branch that cannot reach its target with a 16-bit offset is
+// replaced by the opposite condition jumping over a goto_w. Switch padding is recomputed.
+// Branches are encoded from their Opcode and Target alone, so code generators can pass their own
+// Branch implementations to jump farther than the Offset fields of the branch types reach.
+//
+// MaxStack and MaxLocals are written as they are. StackMapTable frames are moved along with
+// their instructions and frames of removed instructions are dropped, but the frames themselves
+// are kept: code whose types or branch targets change needs its frames updated by the caller,
+// for instance with analysis.ComputeFrames on the code read back.
+func EncodeCode(code Code, pool *PoolBuilder) (AttributeInfo, error) {
+	instructions, err := resolveInstructions(code.Code, pool)
+	if err != nil {
+		return AttributeInfo{}, err
+	}
+	end := layoutInstructions(instructions)
+	if end > 0xFFFF {
+		return AttributeInfo{}, fmt.Errorf("code of %d bytes exceeds the 65535 bytes limit", end)
+	}
+
+	var buf bytes.Buffer
+	write(&buf, code.MaxStack, code.MaxLocals, uint32(end))
+	for i := range instructions {
+		if err := instructions[i].encode(&buf, instructions, end); err != nil {
+			return AttributeInfo{}, fmt.Errorf("instruction %d (%s): %w", i, instructions[i].Mnemonic(), err)
+		}
+	}
+
+	pcs := pcMap{positions: newPositions(code.Code), instructions: instructions, length: end}
+	write(&buf, uint16(len(code.ExceptionTable)))
+	for i, entry := range code.ExceptionTable {
+		start, okStart := pcs.start(int(entry.StartPc))
+		stop, okStop := pcs.end(int(entry.EndPc))
+		handler, okHandler := pcs.start(int(entry.HandlerPc))
+		if !okStart || !okStop || !okHandler {
+			return AttributeInfo{}, fmt.Errorf("exception table entry %d refers to a removed instruction", i)
+		}
+		write(&buf, start, stop, handler, entry.CatchType)
+	}
+
+	var nested []AttributeInfo
+	for _, attribute := range code.Attributes {
+		encoded, ok, err := encodeCodeAttribute(attribute, pool, pcs)
+		if err != nil {
+			return AttributeInfo{}, err
+		}
+		if ok {
+			nested = append(nested, encoded)
+		}
+	}
+	if err := writeAttributes(&buf, nested); err != nil {
+		return AttributeInfo{}, err
+	}
+
+	name := pool.Utf8("Code")
+	if err := pool.Err(); err != nil {
+		return AttributeInfo{}, err
+	}
+	return AttributeInfo{
+		AttributeNameIndex: name,
+		AttributeLength:    uint32(buf.Len()),
+		Info:               buf.Bytes(),
+	}, nil
+}
+
+// positions maps the PCs of the input layout to instruction indexes
+type positions struct {
+	first  map[int]int
+	ends   [2]int // Positions following the last instruction, by its length and by one
+	length int
+}
+
+func newPositions(code []Instruction) positions {
+	p := positions{first: map[int]int{}, length: len(code)}
+	byIndex := true
+	last := -1
+	for i, instr := range code {
+		if _, ok := p.first[instr.PC()]; !ok {
+			p.first[instr.PC()] = i
+		}
+		byIndex = byIndex && instr.PC() == i
+		if last < 0 || instr.PC() > code[last].PC() {
+			last = i
+		}
+	}
+	if last >= 0 {
+		p.ends[0] = code[last].PC() + code[last].Len()
+	}
+	p.ends[1] = p.ends[0]
+	if byIndex {
+		// Decoded code whose instructions but the last take one byte is numbered by index too,
+		// so both ends are accepted
+		p.ends[1] = len(code)
+	}
+	return p
+}
+
+// resolve returns the index of the instruction at pc
+func (p positions) resolve(pc int) (int, bool) {
+	index, ok := p.first[pc]
+	return index, ok
+}
+
+// resolveEnd returns the index of the instruction at pc, or the number of instructions when pc
+// is the end of the code
+func (p positions) resolveEnd(pc int) (int, bool) {
+	if pc == p.ends[0] || pc == p.ends[1] {
+		return p.length, true
+	}
+	return p.resolve(pc)
+}
+
+// pcMap moves the PCs of the input layout to the new layout
+type pcMap struct {
+	positions    positions
+	instructions []encodedInstruction
+	length       int // Code length in the new layout
+}
+
+// start returns the new position of the instruction at pc
+func (m pcMap) start(pc int) (uint16, bool) {
+	index, ok := m.positions.resolve(pc)
+	if !ok {
+		return 0, false
+	}
+	return uint16(m.instructions[index].pc), true
+}
+
+// end returns the new position of the end of a range ending at pc, which may be the end of the code
+func (m pcMap) end(pc int) (uint16, bool) {
+	index, ok := m.positions.resolveEnd(pc)
+	if !ok {
+		return 0, false
+	}
+	return uint16(targetPc(m.instructions, index, m.length)), true
+}
+
+// encodedInstruction is an instruction with its operands resolved for the new layout
+type encodedInstruction struct {
+	Instruction
+	index   uint16 // constant pool operand
+	targets []int  // instruction indexes jumped to, the default target first for switches
+	wide    bool   // the branch needs a 32-bit offset
+	pc      int    // position in the new layout
+}
+
+func resolveInstructions(code []Instruction, pool *PoolBuilder) ([]encodedInstruction, error) {
+	positions := newPositions(code)
+	target := func(instr Instruction, offset int) (int, error) {
+		index, ok := positions.resolve(instr.PC() + offset)
+		if !ok {
+			return 0, fmt.Errorf("%s at pc %d jumps to pc %d, which does not start an instruction", instr.Mnemonic(), instr.PC(), instr.PC()+offset)
+		}
+		return index, nil
+	}
+
+	instructions := make([]encodedInstruction, len(code))
+	for i, instr := range code {
+		encoded := encodedInstruction{Instruction: instr}
+		var err error
+		switch instr := instr.(type) {
+		case Ldc:
+			encoded.index, err = loadConstant(instr, instr.Value, pool)
+		case LdcW:
+			encoded.index, err = loadConstant(instr, instr.Value, pool)
+		case Ldc2W:
+			encoded.index, err = loadConstant(instr, instr.Value, pool)
+		case Getstatic:
+			encoded.index = pool.Fieldref(instr.Class, instr.Name, instr.Type)
+		case Putstatic:
+			encoded.index = pool.Fieldref(instr.Class, instr.Name, instr.Type)
+		case Getfield:
+			encoded.index = pool.Fieldref(instr.Class, instr.Name, instr.Type)
+		case Putfield:
+			encoded.index = pool.Fieldref(instr.Class, instr.Name, instr.Type)
+		case Invokevirtual:
+			encoded.index = pool.Methodref(instr.Class, instr.Name, instr.Type)
+		case Invokespecial:
+			encoded.index = methodref(pool, instr.Class, instr.Name, instr.Type, instr.Interface)
+		case Invokestatic:
+			encoded.index = methodref(pool, instr.Class, instr.Name, instr.Type, instr.Interface)
+		case Invokeinterface:
+			ref := instr.InterfaceMethodref
+			encoded.index = pool.InterfaceMethodref(ref.Class, ref.Name, ref.Type)
+		case Invokedynamic:
+			ref := instr.InvokeDynamic
+			encoded.index = pool.InvokeDynamic(ref.BootstrapIndex, ref.Name, ref.Type)
+		case New:
+			encoded.index = pool.Class(instr.Class)
+		case Anewarray:
+			encoded.index = pool.Class(instr.Class)
+		case Checkcast:
+			encoded.index = pool.Class(instr.Class)
+		case Instanceof:
+			encoded.index = pool.Class(instr.Class)
+		case Multianewarray:
+			encoded.index = pool.Class(instr.Class)
+		case Branch:
+			var index int
+			index, err = target(instr, instr.Target()-instr.PC())
+			encoded.targets = []int{index}
+		case Tableswitch:
+			encoded.targets, err = switchTargets(instr, instr.DefaultOffset, instr.Offsets, target)
+		case Lookupswitch:
+			offsets := make([]int32, len(instr.Pairs))
+			for j, pair := range instr.Pairs {
+				offsets[j] = pair.Offset
+			}
+			encoded.targets, err = switchTargets(instr, instr.DefaultOffset, offsets, target)
+		}
+		if err != nil {
+			return nil, err
+		}
+		instructions[i] = encoded
+	}
+	if err := pool.Err(); err != nil {
+		return nil, err
+	}
+	return instructions, nil
+}
+
+func methodref(pool *PoolBuilder, class, name, descriptor string, isInterface bool) uint16 {
+	if isInterface {
+		return pool.InterfaceMethodref(class, name, descriptor)
+	}
+	return pool.Methodref(class, name, descriptor)
+}
+
+func switchTargets(instr Instruction, defaultOffset int32, offsets []int32, target func(Instruction, int) (int, error)) ([]int, error) {
+	targets := make([]int, 0, len(offsets)+1)
+	for _, offset := range append([]int32{defaultOffset}, offsets...) {
+		index, err := target(instr, int(offset))
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, index)
+	}
+	return targets, nil
+}
+
+// layoutInstructions assigns the new positions, widening branches until every offset fits,
+// and returns the code length
+func layoutInstructions(instructions []encodedInstruction) int {
+	for {
+		pc := 0
+		for i := range instructions {
+			instructions[i].pc = pc
+			pc += instructions[i].size()
+		}
+		end := pc
+
+		widened := false
+		for i := range instructions {
+			instr := &instructions[i]
+			if _, ok := instr.Instruction.(Branch); !ok || instr.wide {
+				continue
+			}
+			offset := targetPc(instructions, instr.targets[0], end) - instr.pc
+			if offset < math.MinInt16 || offset > math.MaxInt16 {
+				instr.wide = true
+				widened = true
+			}
+		}
+		if !widened {
+			return end
+		}
+	}
+}
+
+// loadConstant adds the constant of an ldc family instruction to pool, checking that its category
+// matches the instruction
+func loadConstant(instr Instruction, value interface{}, pool *PoolBuilder) (uint16, error) {
+	if err := checkLoadCategory(instr.Opcode(), value); err != nil {
+		return 0, fmt.Errorf("%s at pc %d: %w", instr.Mnemonic(), instr.PC(), err)
+	}
+	return pool.Constant(value)
+}
+
+func targetPc(instructions []encodedInstruction, index, end int) int {
+	if index == len(instructions) {
+		return end
+	}
+	return instructions[index].pc
+}
+
+// size returns the encoded length of the instruction at its current position
+func (e *encodedInstruction) size() int {
+	switch instr := e.Instruction.(type) {
+	case Ldc, LdcW:
+		if e.index <= 0xFF {
+			return 2
+		}
+		return 3
+	case Branch:
+		switch {
+		case !e.wide:
+			return 3
+		case unconditional(instr.Opcode()):
+			return 5
+		default:
+			return 8
+		}
+	case Tableswitch:
+		return 1 + switchPadding(e.pc) + 12 + 4*len(instr.Offsets)
+	case Lookupswitch:
+		return 1 + switchPadding(e.pc) + 8 + 8*len(instr.Pairs)
+	default:
+		return instr.Len()
+	}
+}
+
+func (e *encodedInstruction) encode(buf *bytes.Buffer, instructions []encodedInstruction, end int) error {
+	offset := func(target int) int32 {
+		return int32(targetPc(instructions, e.targets[target], end) - e.pc)
+	}
+
+	switch instr := e.Instruction.(type) {
+	case Bipush:
+		write(buf, instr.Opcode(), instr.Byte)
+	case Sipush:
+		write(buf, instr.Opcode(), instr.Short)
+	case Ldc, LdcW:
+		if e.index <= 0xFF {
+			write(buf, uint8(0x12), uint8(e.index))
+		} else {
+			write(buf, uint8(0x13), e.index)
+		}
+	case Iload:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Lload:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Fload:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Dload:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Aload:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Istore:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Lstore:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Fstore:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Dstore:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Astore:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Ret:
+		write(buf, instr.Opcode(), instr.LocalIndex)
+	case Iinc:
+		write(buf, instr.Opcode(), instr.LocalIndex, instr.Const)
+	case Branch:
+		opcode := instr.Opcode()
+		switch {
+		case opcode == 0xA7 || opcode == 0xC8: // goto and goto_w
+			e.encodeJump(buf, 0xA7, 0xC8, offset(0))
+		case opcode == 0xA8 || opcode == 0xC9: // jsr and jsr_w
+			e.encodeJump(buf, 0xA8, 0xC9, offset(0))
+		case e.wide:
+			// Jump over the goto_w when the opposite condition holds
+			write(buf, invertedCondition(opcode), int16(8), uint8(0xC8), offset(0)-3)
+		default:
+			write(buf, opcode, int16(offset(0)))
+		}
+	case Tableswitch:
+		write(buf, instr.Opcode(), make([]byte, switchPadding(e.pc)), offset(0), instr.Low, instr.High)
+		if int64(instr.High)-int64(instr.Low)+1 != int64(len(instr.Offsets)) {
+			return fmt.Errorf("%d offsets for indexes %d to %d", len(instr.Offsets), instr.Low, instr.High)
+		}
+		for i := range instr.Offsets {
+			write(buf, offset(i+1))
+		}
+	case Lookupswitch:
+		write(buf, instr.Opcode(), make([]byte, switchPadding(e.pc)), offset(0), int32(len(instr.Pairs)))
+		for i, pair := range instr.Pairs {
+			if i > 0 && instr.Pairs[i-1].Match >= pair.Match {
+				return fmt.Errorf("matches are not sorted in increasing order")
+			}
+			write(buf, pair.Match, offset(i+1))
+		}
+	case Ldc2W, Getstatic, Putstatic, Getfield, Putfield, Invokevirtual, Invokespecial, Invokestatic,
+		New, Anewarray, Checkcast, Instanceof:
+		write(buf, instr.Opcode(), e.index)
+	case Invokeinterface:
+		count := instr.Count
+		if count == 0 {
+			method, err := descriptor.ParseMethod(instr.InterfaceMethodref.Type)
+			if err != nil {
+				return err
+			}
+			count = uint8(method.ArgumentSlots() + 1)
+		}
+		write(buf, instr.Opcode(), e.index, count, uint8(0))
+	case Invokedynamic:
+		write(buf, instr.Opcode(), e.index, uint16(0))
+	case Newarray:
+		write(buf, instr.Opcode(), instr.Type)
+	case Wide:
+		write(buf, instr.Opcode(), instr.OpCode, instr.LocalIndex)
+		if instr.OpCode == 0x84 {
+			write(buf, instr.Const)
+		}
+	case Multianewarray:
+		write(buf, instr.Opcode(), e.index, instr.Dimension)
+	default:
+		if instr.Len() != 1 {
+			return fmt.Errorf("cannot encode %T", instr)
+		}
+		write(buf, instr.Opcode())
+	}
+	return nil
+}
+
+// encodeJump writes a goto or a jsr, with its 16-bit opcode short or its 32-bit opcode long
+func (e *encodedInstruction) encodeJump(buf *bytes.Buffer, short, long uint8, offset int32) {
+	if e.wide {
+		write(buf, long, offset)
+	} else {
+		write(buf, short, int16(offset))
+	}
+}
+
+// unconditional reports whether opcode is goto, jsr or their wide forms
+func unconditional(opcode uint8) bool {
+	return opcode == 0xA7 || opcode == 0xC8 || opcode == 0xA8 || opcode == 0xC9
+}
+
+// invertedCondition returns the conditional branch opcode testing the opposite condition
+func invertedCondition(opcode uint8) uint8 {
+	if opcode >= 0xC6 {
+		// ifnull and ifnonnull
+		return opcode ^ 1
+	}
+	return (opcode - 0x99) ^ 1 + 0x99
+}
+
+// encodeCodeAttribute encodes an attribute nested in a Code attribute, moving its pcs with pcs.
+// It reports false for attributes that are dropped.
+func encodeCodeAttribute(attribute Attribute, pool *PoolBuilder, pcs pcMap) (AttributeInfo, bool, error) {
+	var name string
+	var buf bytes.Buffer
+	switch attribute := attribute.(type) {
+	case LineNumberTable:
+		name = "LineNumberTable"
+		var entries []LineNumberTableEntry
+		for _, entry := range attribute.LineNumberTable {
+			if pc, ok := pcs.start(int(entry.StartPc)); ok {
+				entries = append(entries, LineNumberTableEntry{StartPc: pc, LineNumber: entry.LineNumber})
+			}
+		}
+		write(&buf, uint16(len(entries)), entries)
+	case LocalVariableTable:
+		name = "LocalVariableTable"
+		var entries []LocalVariableEntry
+		for _, entry := range attribute.LocalVariableTable {
+			start, okStart := pcs.start(int(entry.StartPc))
+			end, okEnd := pcs.end(int(entry.StartPc) + int(entry.Length))
+			if okStart && okEnd {
+				entry.StartPc, entry.Length = start, end-start
+				entries = append(entries, entry)
+			}
+		}
+		write(&buf, uint16(len(entries)), entries)
+	case LocalVariableTypeTable:
+		name = "LocalVariableTypeTable"
+		var entries []LocalVariableTypeEntry
+		for _, entry := range attribute.LocalVariableTypeTable {
+			start, okStart := pcs.start(int(entry.StartPc))
+			end, okEnd := pcs.end(int(entry.StartPc) + int(entry.Length))
+			if okStart && okEnd {
+				entry.StartPc, entry.Length = start, end-start
+				entries = append(entries, entry)
+			}
+		}
+		write(&buf, uint16(len(entries)), entries)
+	case StackMapTable:
+		name = "StackMapTable"
+		var frames []StackMapFrame
+		for _, frame := range attribute.Entries {
+			pc, ok := pcs.start(int(frame.Pc))
+			if !ok {
+				continue
+			}
+			frame.Pc = pc
+			var err error
+			if frame.Locals, err = moveStackItems(frame.Locals, pcs); err != nil {
+				return AttributeInfo{}, false, err
+			}
+			if frame.StackItems, err = moveStackItems(frame.StackItems, pcs); err != nil {
+				return AttributeInfo{}, false, err
+			}
+			frames = append(frames, frame)
+		}
+		if err := writeStackMapFrames(&buf, frames, pool); err != nil {
+			return AttributeInfo{}, false, err
+		}
+	case RuntimeVisibleTypeAnnotations:
+		name = "RuntimeVisibleTypeAnnotations"
+		if err := writeTypeAnnotations(&buf, moveTypeAnnotations(attribute.Annotations, pcs), pool); err != nil {
+			return AttributeInfo{}, false, err
+		}
+	case RuntimeInvisibleTypeAnnotations:
+		name = "RuntimeInvisibleTypeAnnotations"
+		if err := writeTypeAnnotations(&buf, moveTypeAnnotations(attribute.Annotations, pcs), pool); err != nil {
+			return AttributeInfo{}, false, err
+		}
+	case RawAttribute:
+		name = attribute.Name
+		buf.Write(attribute.Info)
+	default:
+		return AttributeInfo{}, false, fmt.Errorf("cannot encode %T inside a Code attribute", attribute)
+	}
+	return AttributeInfo{
+		AttributeNameIndex: pool.Utf8(name),
+		AttributeLength:    uint32(buf.Len()),
+		Info:               buf.Bytes(),
+	}, true, nil
+}
+
+// moveStackItems returns items with the offsets of their uninitialized types moved with pcs
+func moveStackItems(items []StackItem, pcs pcMap) ([]StackItem, error) {
+	moved := append([]StackItem(nil), items...)
+	for i, item := range moved {
+		if item.Tag != ItemUninitialized {
+			continue
+		}
+		pc, ok := pcs.start(int(item.Offset))
+		if !ok {
+			return nil, fmt.Errorf("uninitialized type refers to the removed new at pc %d", item.Offset)
+		}
+		moved[i].Offset = pc
+	}
+	return moved, nil
+}
@@ -0,0 +1,355 @@
+package classfileparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// decodeCode reads back a Code attribute encoded with pool
+func decodeCode(t *testing.T, attribute AttributeInfo, pool *PoolBuilder) Code {
+	t.Helper()
+	cp, err := (&ClassFile{ConstantPool: pool.Pool()}).GetConstantPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := parseCode(bytes.NewReader(attribute.Info), cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// bytecode returns the code array of an encoded Code attribute
+func bytecode(attribute AttributeInfo) []byte {
+	length := binary.BigEndian.Uint32(attribute.Info[4:8])
+	return attribute.Info[8 : 8+length]
+}
+
+// repeat returns count copies of instruction numbered by index from first
+func repeat(first, count int, instruction func(Location) Instruction) []Instruction {
+	instructions := make([]Instruction, count)
+	for i := range instructions {
+		instructions[i] = instruction(Location{first + i})
+	}
+	return instructions
+}
+
+func TestEncodeCodeRoundTrip(t *testing.T) {
+	for name, data := range readTestdata(t) {
+		cf, err := Open(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		class, err := cf.GetClassFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, method := range class.Methods {
+			for j, attribute := range method.Attributes {
+				code, ok := attribute.(Code)
+				if !ok {
+					continue
+				}
+				pool := NewPoolBuilder(cf.ConstantPool)
+				encoded, err := EncodeCode(code, pool)
+				if err != nil {
+					t.Fatalf("%s %s: %v", name, method.Name, err)
+				}
+				if !bytes.Equal(encoded.Info, cf.Methods[i].Attributes[j].Info) {
+					t.Errorf("%s %s: re-encoded code differs", name, method.Name)
+				}
+				if len(pool.Pool()) != len(cf.ConstantPool) {
+					t.Errorf("%s %s: %d constants added", name, method.Name, len(pool.Pool())-len(cf.ConstantPool))
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeCodeLdc(t *testing.T) {
+	pool := NewPoolBuilder(nil)
+	for i := int32(0); i < 300; i++ {
+		pool.Integer(i)
+	}
+	code := Code{Code: []Instruction{
+		Ldc{Location: Location{0}, Value: int32(299)},
+		LdcW{Location: Location{1}, Value: int32(7)},
+		Ldc{Location: Location{2}, Value: "new"},
+		Return{Location{3}},
+	}}
+	encoded, err := EncodeCode(code, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x13, 0x01, 0x2C, 0x12, 0x08, 0x13, 0x01, 0x2E, 0xB1}
+	if got := bytecode(encoded); !bytes.Equal(got, want) {
+		t.Errorf("code = % X, want % X", got, want)
+	}
+}
+
+func TestEncodeCodeLdcCategory(t *testing.T) {
+	tests := []struct {
+		instr Instruction
+		err   string
+	}{
+		{Ldc{Value: int64(1)}, "ldc at pc 0: int64 takes two slots"},
+		{LdcW{Value: 1.5}, "ldc_w at pc 0: float64 takes two slots"},
+		{LdcW{Value: Dynamic{Name: "c", Type: "J"}}, "Dynamic takes two slots"},
+		{Ldc2W{Value: int32(1)}, "ldc2_w at pc 0: ldc2_w cannot load int32"},
+		{Ldc2W{Value: "s"}, "ldc2_w cannot load string"},
+		{Ldc2W{Value: Class("A")}, "ldc2_w cannot load classfileparser.Class"},
+	}
+	for _, test := range tests {
+		_, err := EncodeCode(Code{Code: []Instruction{test.instr, Return{Location{1}}}}, NewPoolBuilder(nil))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s %v: error = %v, want %q", test.instr.Mnemonic(), test.instr, err, test.err)
+		}
+	}
+
+	code := Code{Code: []Instruction{
+		Ldc2W{Location: Location{0}, Value: int64(1)},
+		Ldc2W{Location: Location{1}, Value: 2.5},
+		Return{Location{2}},
+	}}
+	if _, err := EncodeCode(code, NewPoolBuilder(nil)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeLdcCategory(t *testing.T) {
+	cp := ConstantPool{1: int32(1), 2: int64(2), 4: float32(3), 5: 4.0}
+	tests := []struct {
+		code []byte
+		err  string
+	}{
+		{[]byte{0x12, 1, 0xB1}, ""},
+		{[]byte{0x12, 2, 0xB1}, "entry #2: int64 takes two slots"},
+		{[]byte{0x13, 0, 5, 0xB1}, "entry #5: float64 takes two slots"},
+		{[]byte{0x14, 0, 2, 0xB1}, ""},
+		{[]byte{0x14, 0, 5, 0xB1}, ""},
+		{[]byte{0x14, 0, 1, 0xB1}, "entry #1: ldc2_w cannot load int32"},
+		{[]byte{0x14, 0, 4, 0xB1}, "entry #4: ldc2_w cannot load float32"},
+	}
+	for _, test := range tests {
+		_, err := parseCode(bytes.NewReader(codeAttribute(test.code)), cp)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("% X: error = %v, want %q", test.code, err, test.err)
+		}
+	}
+}
+
+func TestEncodeCodeBranches(t *testing.T) {
+	sipush := func(at Location) Instruction { return Sipush{Location: at, Short: 1} }
+	nop := func(at Location) Instruction { return Nop{at} }
+	tests := []struct {
+		name  string
+		code  []Instruction
+		check func(t *testing.T, code []byte)
+	}{
+		{
+			name: "goto",
+			code: []Instruction{Goto{Location{0}, 2}, Nop{Location{1}}, Return{Location{2}}},
+			check: func(t *testing.T, code []byte) {
+				if !bytes.Equal(code, []byte{0xA7, 0, 4, 0, 0xB1}) {
+					t.Errorf("code = % X", code)
+				}
+			},
+		},
+		{
+			name: "goto_w shrinks",
+			code: []Instruction{GotoW{Location{0}, 2}, Nop{Location{1}}, Return{Location{2}}},
+			check: func(t *testing.T, code []byte) {
+				if !bytes.Equal(code, []byte{0xA7, 0, 4, 0, 0xB1}) {
+					t.Errorf("code = % X", code)
+				}
+			},
+		},
+		{
+			name: "goto widens",
+			code: append(append([]Instruction{Goto{Location{0}, 11001}}, repeat(1, 11000, sipush)...), Return{Location{11001}}),
+			check: func(t *testing.T, code []byte) {
+				if code[0] != 0xC8 || int32(binary.BigEndian.Uint32(code[1:5])) != 5+33000 {
+					t.Errorf("code starts with % X", code[:5])
+				}
+			},
+		},
+		{
+			name: "backward goto widens",
+			code: append(append(repeat(0, 11000, sipush), Goto{Location{11000}, -11000}), Return{Location{11001}}),
+			check: func(t *testing.T, code []byte) {
+				if code[33000] != 0xC8 || int32(binary.BigEndian.Uint32(code[33001:33005])) != -33000 {
+					t.Errorf("code at 33000 is % X", code[33000:33005])
+				}
+			},
+		},
+		{
+			name: "conditional branch inverted over goto_w",
+			code: append(append([]Instruction{Iconst0{Location{0}}, Ifeq{Location{1}, 11001}}, repeat(2, 11000, sipush)...), Return{Location{11002}}),
+			check: func(t *testing.T, code []byte) {
+				want := []byte{0x03, 0x9A, 0, 8, 0xC8}
+				want = binary.BigEndian.AppendUint32(want, 5+33000)
+				if !bytes.Equal(code[:9], want) {
+					t.Errorf("code starts with % X, want % X", code[:9], want)
+				}
+			},
+		},
+		{
+			name: "ifnull inverted",
+			code: append(append([]Instruction{AconstNull{Location{0}}, Ifnull{Location{1}, 11001}}, repeat(2, 11000, sipush)...), Return{Location{11002}}),
+			check: func(t *testing.T, code []byte) {
+				if code[1] != 0xC7 || code[4] != 0xC8 {
+					t.Errorf("code starts with % X", code[:9])
+				}
+			},
+		},
+		{
+			name: "tableswitch padding",
+			code: []Instruction{
+				Nop{Location{0}}, Iconst0{Location{1}},
+				Tableswitch{Location: Location{2}, DefaultOffset: 1, Low: 0, High: 0, Offsets: []int32{1}},
+				Return{Location{3}},
+			},
+			check: func(t *testing.T, code []byte) {
+				// The tableswitch at pc 2 is padded to pc 4 and jumps to the return at pc 20
+				want := []byte{0x00, 0x03, 0xAA, 0, 0, 0, 0, 18, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 18, 0xB1}
+				if !bytes.Equal(code, want) {
+					t.Errorf("code = % X, want % X", code, want)
+				}
+			},
+		},
+		{
+			name: "many nops",
+			code: append(append([]Instruction{Iconst0{Location{0}}, Ifeq{Location{1}, 32000}}, repeat(2, 31999, nop)...), Return{Location{32001}}),
+			check: func(t *testing.T, code []byte) {
+				if code[1] != 0x99 || int16(binary.BigEndian.Uint16(code[2:4])) != 32002 {
+					t.Errorf("code starts with % X", code[:4])
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := NewPoolBuilder(nil)
+			encoded, err := EncodeCode(Code{Code: test.code}, pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.check(t, bytecode(encoded))
+			decodeCode(t, encoded, pool)
+		})
+	}
+}
+
+// farBranch is a branch whose target is out of reach of the Offset fields, as code generators
+// numbering instructions by index pass them to EncodeCode
+type farBranch struct {
+	Branch
+	pc, target int
+}
+
+func (b farBranch) PC() int     { return b.pc }
+func (b farBranch) Target() int { return b.target }
+
+func TestEncodeCodeFarBranches(t *testing.T) {
+	nop := func(at Location) Instruction { return Nop{at} }
+	tests := []struct {
+		branch Branch
+		want   []byte
+	}{
+		{Ifeq{}, []byte{0x03, 0x9A, 0, 8, 0xC8, 0, 0, 0x9C, 0x45}},
+		{Goto{}, []byte{0x03, 0xC8, 0, 0, 0x9C, 0x45}},
+		{GotoW{}, []byte{0x03, 0xC8, 0, 0, 0x9C, 0x45}},
+		{Jsr{}, []byte{0x03, 0xC9, 0, 0, 0x9C, 0x45}},
+	}
+	for _, test := range tests {
+		t.Run(test.branch.Mnemonic(), func(t *testing.T) {
+			code := append([]Instruction{Iconst0{Location{0}}, farBranch{test.branch, 1, 40002}}, repeat(2, 40000, nop)...)
+			code = append(code, Return{Location{40002}})
+			encoded, err := EncodeCode(Code{Code: code}, NewPoolBuilder(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytecode(encoded)[:len(test.want)]; !bytes.Equal(got, test.want) {
+				t.Errorf("code starts with % X, want % X", got, test.want)
+			}
+		})
+	}
+
+	// A near target is encoded with a 16-bit offset whatever the branch type
+	code := []Instruction{Iconst0{Location{0}}, farBranch{Ifne{}, 1, 2}, Return{Location{2}}}
+	encoded, err := EncodeCode(Code{Code: code}, NewPoolBuilder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bytecode(encoded), []byte{0x03, 0x9A, 0, 3, 0xB1}; !bytes.Equal(got, want) {
+		t.Errorf("code = % X, want % X", got, want)
+	}
+}
+
+func TestEncodeCodeRanges(t *testing.T) {
+	code := func(extra ...Instruction) Code {
+		return Code{
+			Code: append([]Instruction{
+				Iconst0{Location{0}}, Ifeq{Location{1}, 2}, Nop{Location{2}}, Return{Location{3}},
+			}, extra...),
+			ExceptionTable: []ExceptionTableEntry{{StartPc: 0, EndPc: 4, HandlerPc: 3}},
+			Attributes: []Attribute{LocalVariableTable{LocalVariableTable: []LocalVariableEntry{
+				{StartPc: 2, Length: 2, NameIndex: 1, SignatureIndex: 1},
+			}}},
+		}
+	}
+	pool := NewPoolBuilder(nil)
+	pool.Utf8("I")
+	encoded, err := EncodeCode(code(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := decodeCode(t, encoded, pool)
+	if entry := decoded.ExceptionTable[0]; entry.StartPc != 0 || entry.EndPc != 6 || entry.HandlerPc != 5 {
+		t.Errorf("exception table entry = %+v", entry)
+	}
+	variables := decoded.Attributes[0].(LocalVariableTable).LocalVariableTable
+	if variables[0].StartPc != 4 || variables[0].Length != 2 {
+		t.Errorf("local variable = %+v", variables[0])
+	}
+
+	// Decoded code ends after the length of its last instruction
+	if _, err := EncodeCode(decoded, NewPoolBuilder(pool.Pool())); err != nil {
+		t.Errorf("re-encoding: %v", err)
+	}
+
+	errors := []struct {
+		code Code
+		want string
+	}{
+		{
+			code: Code{Code: []Instruction{Goto{Location{0}, 2}, Return{Location{1}}}},
+			want: "jumps to pc 2, which does not start an instruction",
+		},
+		{
+			code: Code{Code: []Instruction{Goto{Location{0}, 4}, Return{Location{1}}}},
+			want: "jumps to pc 4, which does not start an instruction",
+		},
+		{
+			code: Code{
+				Code:           []Instruction{Nop{Location{0}}, Return{Location{1}}},
+				ExceptionTable: []ExceptionTableEntry{{StartPc: 0, EndPc: 3, HandlerPc: 1}},
+			},
+			want: "exception table entry 0 refers to a removed instruction",
+		},
+		{
+			code: Code{
+				Code:           []Instruction{Nop{Location{0}}, Return{Location{1}}},
+				ExceptionTable: []ExceptionTableEntry{{StartPc: 0, EndPc: 2, HandlerPc: 2}},
+			},
+			want: "exception table entry 0 refers to a removed instruction",
+		},
+	}
+	for _, test := range errors {
+		if _, err := EncodeCode(test.code, NewPoolBuilder(nil)); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("error = %v, want %q", err, test.want)
+		}
+	}
+}
@@ -1,613 +1,658 @@
 package classfileparser
 
 // Nop - nop (0x00) : Do nothing
-type Nop struct{}
+type Nop struct{ Location }
 
 // AconstNull - aconst_null (0x01) : Push null
-type AconstNull struct{}
+type AconstNull struct{ Location }
 
 // IconstM1 - iconst_m1 (0x02) : Push int constant
-type IconstM1 struct{}
+type IconstM1 struct{ Location }
 
 // Iconst0 - iconst_0 (0x03) : Push int constant
-type Iconst0 struct{}
+type Iconst0 struct{ Location }
 
 // Iconst1 - iconst_1 (0x04) : Push int constant
-type Iconst1 struct{}
+type Iconst1 struct{ Location }
 
 // Iconst2 - iconst_2 (0x05) : Push int constant
-type Iconst2 struct{}
+type Iconst2 struct{ Location }
 
 // Iconst3 - iconst_3 (0x06) : Push int constant
-type Iconst3 struct{}
+type Iconst3 struct{ Location }
 
 // Iconst4 - iconst_4 (0x07) : Push int constant
-type Iconst4 struct{}
+type Iconst4 struct{ Location }
 
 // Iconst5 - iconst_5 (0x08) : Push int constant
-type Iconst5 struct{}
+type Iconst5 struct{ Location }
 
 // Lconst0 - lconst_0 (0x09) : Push long constant
-type Lconst0 struct{}
+type Lconst0 struct{ Location }
 
 // Lconst1 - lconst_1 (0x0A) : Push long constant
-type Lconst1 struct{}
+type Lconst1 struct{ Location }
 
 // Fconst0 - fconst_0 (0x0B) : Push float
-type Fconst0 struct{}
+type Fconst0 struct{ Location }
 
 // Fconst1 - fconst_1 (0x0C) : Push float
-type Fconst1 struct{}
+type Fconst1 struct{ Location }
 
 // Fconst2 - fconst_2 (0x0D) : Push float
-type Fconst2 struct{}
+type Fconst2 struct{ Location }
 
 // Dconst0 - dconst_0 (0x0E) : Push double
-type Dconst0 struct{}
+type Dconst0 struct{ Location }
 
 // Dconst1 - dconst_1 (0x0F) : Push double
-type Dconst1 struct{}
+type Dconst1 struct{ Location }
 
 // Bipush - bipush (0x10) : Push byte
 type Bipush struct {
+	Location
 	Byte byte
 }
 
 // Sipush - sipush (0x11) : Push short
 type Sipush struct {
+	Location
 	Short int16
 }
 
 // Ldc - ldc (0x12) : Push item from run-time constant pool
-type Ldc interface{} // index in constant pool (not long/double)
+type Ldc struct {
+	Location
+	Value interface{} // constant pool entry (not long/double)
+}
 
 // LdcW - ldc_w (0x13) : Push item from run-time constant pool (wide index)
-type LdcW interface{} // index in constant pool (not long/double)
+type LdcW struct {
+	Location
+	Value interface{} // constant pool entry (not long/double)
+}
 
 // Ldc2W - ldc2_w (0x14) : Push long or double from run-time constant pool (wide index)
-type Ldc2W interface{} // index in constant pool (not int/float/string)
+type Ldc2W struct {
+	Location
+	Value interface{} // constant pool entry (not int/float/string)
+}
 
 // Iload - iload (0x15) : Load int from local variable
 type Iload struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Lload - lload (0x16) : Load long from local variable
 type Lload struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Fload - fload (0x17) : Load float from local variable
 type Fload struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Dload - dload (0x18) : Load double from local variable
 type Dload struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Aload - aload (0x19) : Load reference from local variable
 type Aload struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Iload0 - iload_0 (0x1A) : Load int from local variable
-type Iload0 struct{}
+type Iload0 struct{ Location }
 
 // Iload1 - iload_1 (0x1B) : Load int from local variable
-type Iload1 struct{}
+type Iload1 struct{ Location }
 
 // Iload2 - iload_2 (0x1C) : Load int from local variable
-type Iload2 struct{}
+type Iload2 struct{ Location }
 
 // Iload3 - iload_3 (0x1D) : Load int from local variable
-type Iload3 struct{}
+type Iload3 struct{ Location }
 
 // Lload0 - lload_0 (0x1E) : Load long from local variable
-type Lload0 struct{}
+type Lload0 struct{ Location }
 
 // Lload1 - lload_1 (0x1F) : Load long from local variable
-type Lload1 struct{}
+type Lload1 struct{ Location }
 
 // Lload2 - lload_2 (0x20) : Load long from local variable
-type Lload2 struct{}
+type Lload2 struct{ Location }
 
 // Lload3 - lload_3 (0x21) : Load long from local variable
-type Lload3 struct{}
+type Lload3 struct{ Location }
 
 // Fload0 - fload_0 (0x22) : Load float from local variable
-type Fload0 struct{}
+type Fload0 struct{ Location }
 
 // Fload1 - fload_1 (0x23) : Load float from local variable
-type Fload1 struct{}
+type Fload1 struct{ Location }
 
 // Fload2 - fload_2 (0x24) : Load float from local variable
-type Fload2 struct{}
+type Fload2 struct{ Location }
 
 // Fload3 - fload_3 (0x25) : Load float from local variable
-type Fload3 struct{}
+type Fload3 struct{ Location }
 
 // Dload0 - dload_0 (0x26) : Load double from local variable
-type Dload0 struct{}
+type Dload0 struct{ Location }
 
 // Dload1 - dload_1 (0x27) : Load double from local variable
-type Dload1 struct{}
+type Dload1 struct{ Location }
 
 // Dload2 - dload_2 (0x28) : Load double from local variable
-type Dload2 struct{}
+type Dload2 struct{ Location }
 
 // Dload3 - dload_3 (0x29) : Load double from local variable
-type Dload3 struct{}
+type Dload3 struct{ Location }
 
 // Aload0 - aload_0 (0x2A) : Load reference from local variable
-type Aload0 struct{}
+type Aload0 struct{ Location }
 
 // Aload1 - aload_1 (0x2B) : Load reference from local variable
-type Aload1 struct{}
+type Aload1 struct{ Location }
 
 // Aload2 - aload_2 (0x2C) : Load reference from local variable
-type Aload2 struct{}
+type Aload2 struct{ Location }
 
 // Aload3 - aload_3 (0x2D) : Load reference from local variable
-type Aload3 struct{}
+type Aload3 struct{ Location }
 
 // Iaload - iaload (0x2E) : Load int from array
-type Iaload struct{}
+type Iaload struct{ Location }
 
 // Laload - laload (0x2F) : Load long from array
-type Laload struct{}
+type Laload struct{ Location }
 
 // Faload - faload (0x30) : Load float from array
-type Faload struct{}
+type Faload struct{ Location }
 
 // Daload - daload (0x31) : Load double from array
-type Daload struct{}
+type Daload struct{ Location }
 
 // Aaload - aaload (0x32) : Load reference from array
-type Aaload struct{}
+type Aaload struct{ Location }
 
 // Baload - baload (0x33) : Load byte or boolean from array
-type Baload struct{}
+type Baload struct{ Location }
 
 // Caload - caload (0x34) : Load char from array
-type Caload struct{}
+type Caload struct{ Location }
 
 // Saload - saload (0x35) : Load short from array
-type Saload struct{}
+type Saload struct{ Location }
 
 // Istore - istore (0x36) : Store int into local variable
 type Istore struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Lstore - lstore (0x37) : Store long into local variable
 type Lstore struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Fstore - fstore (0x38) : Store float into local variable
 type Fstore struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Dstore - dstore (0x39) : Store double into local variable
 type Dstore struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Astore - astore (0x3A) : Store reference into local variable
 type Astore struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
 // Istore0 - istore_0 (0x3B) : Store int into local variable
-type Istore0 struct{}
+type Istore0 struct{ Location }
 
 // Istore1 - istore_1 (0x3C) : Store int into local variable
-type Istore1 struct{}
+type Istore1 struct{ Location }
 
 // Istore2 - istore_2 (0x3D) : Store int into local variable
-type Istore2 struct{}
+type Istore2 struct{ Location }
 
 // Istore3 - istore_3 (0x3E) : Store int into local variable
-type Istore3 struct{}
+type Istore3 struct{ Location }
 
 // Lstore0 - lstore_0 (0x3F) : Store long into local variable
-type Lstore0 struct{}
+type Lstore0 struct{ Location }
 
 // Lstore1 - lstore_1 (0x40) : Store long into local variable
-type Lstore1 struct{}
+type Lstore1 struct{ Location }
 
 // Lstore2 - lstore_2 (0x41) : Store long into local variable
-type Lstore2 struct{}
+type Lstore2 struct{ Location }
 
 // Lstore3 - lstore_3 (0x42) : Store long into local variable
-type Lstore3 struct{}
+type Lstore3 struct{ Location }
 
 // Fstore0 - fstore_0 (0x43) : Store float into local variable
-type Fstore0 struct{}
+type Fstore0 struct{ Location }
 
 // Fstore1 - fstore_1 (0x44) : Store float into local variable
-type Fstore1 struct{}
+type Fstore1 struct{ Location }
 
 // Fstore2 - fstore_2 (0x45) : Store float into local variable
-type Fstore2 struct{}
+type Fstore2 struct{ Location }
 
 // Fstore3 - fstore_3 (0x46) : Store float into local variable
-type Fstore3 struct{}
+type Fstore3 struct{ Location }
 
 // Dstore0 - dstore_0 (0x47) : Store double into local variable
-type Dstore0 struct{}
+type Dstore0 struct{ Location }
 
 // Dstore1 - dstore_1 (0x48) : Store double into local variable
-type Dstore1 struct{}
+type Dstore1 struct{ Location }
 
 // Dstore2 - dstore_2 (0x49) : Store double into local variable
-type Dstore2 struct{}
+type Dstore2 struct{ Location }
 
 // Dstore3 - dstore_3 (0x4A) : Store double into local variable
-type Dstore3 struct{}
+type Dstore3 struct{ Location }
 
 // Astore0 - astore_0 (0x4B) : Store reference into local variable
-type Astore0 struct{}
+type Astore0 struct{ Location }
 
 // Astore1 - astore_1 (0x4C) : Store reference into local variable
-type Astore1 struct{}
+type Astore1 struct{ Location }
 
 // Astore2 - astore_2 (0x4D) : Store reference into local variable
-type Astore2 struct{}
+type Astore2 struct{ Location }
 
 // Astore3 - astore_3 (0x4E) : Store reference into local variable
-type Astore3 struct{}
+type Astore3 struct{ Location }
 
 // Iastore - iastore (0x4F) : Store into int array
-type Iastore struct{}
+type Iastore struct{ Location }
 
 // Lastore - lastore (0x50) : Store into long array
-type Lastore struct{}
+type Lastore struct{ Location }
 
 // Fastore - fastore (0x51) : Store into float array
-type Fastore struct{}
+type Fastore struct{ Location }
 
 // Dastore - dastore (0x52) : Store into double array
-type Dastore struct{}
+type Dastore struct{ Location }
 
 // Aastore - aastore (0x53) : Store into reference array
-type Aastore struct{}
+type Aastore struct{ Location }
 
 // Bastore - bastore (0x54) : Store into byte or boolean array
-type Bastore struct{}
+type Bastore struct{ Location }
 
 // Castore - castore (0x55) : Store into char array
-type Castore struct{}
+type Castore struct{ Location }
 
 // Sastore - sastore (0x56) : Store into short array
-type Sastore struct{}
+type Sastore struct{ Location }
 
 // Pop - pop (0x57) : Pop the top operand stack value
-type Pop struct{}
+type Pop struct{ Location }
 
 // Pop2 - pop2 (0x58) : Pop the top one or two operand stack values
-type Pop2 struct{}
+type Pop2 struct{ Location }
 
 // Dup - dup (0x59) : Duplicate the top operand stack value
-type Dup struct{}
+type Dup struct{ Location }
 
 // DupX1 - dup_x1 (0x5A) : Duplicate the top operand stack value and insert two values down
-type DupX1 struct{}
+type DupX1 struct{ Location }
 
 // DupX2 - dup_x2 (0x5B) : Duplicate the top operand stack value and insert two or three values down
-type DupX2 struct{}
+type DupX2 struct{ Location }
 
 // Dup2 - dup2 (0x5C) : Duplicate the top one or two operand stack values
-type Dup2 struct{}
+type Dup2 struct{ Location }
 
 // Dup2X1 - dup2_x1 (0x5D) : Duplicate the top one or two operand stack values and insert two or three values down
-type Dup2X1 struct{}
+type Dup2X1 struct{ Location }
 
 // Dup2X2 - dup2_x2 (0x5E) : Duplicate the top one or two operand stack values and insert two three or four values down
-type Dup2X2 struct{}
+type Dup2X2 struct{ Location }
 
 // Swap - swap (0x5F) : Swap the top two operand stack values
-type Swap struct{}
+type Swap struct{ Location }
 
 // Iadd - iadd (0x60) : Add int
-type Iadd struct{}
+type Iadd struct{ Location }
 
 // Ladd - ladd (0x61) : Add long
-type Ladd struct{}
+type Ladd struct{ Location }
 
 // Fadd - fadd (0x62) : Add float
-type Fadd struct{}
+type Fadd struct{ Location }
 
 // Dadd - dadd (0x63) : Add double
-type Dadd struct{}
+type Dadd struct{ Location }
 
 // Isub - isub (0x64) : Subtract int
-type Isub struct{}
+type Isub struct{ Location }
 
 // Lsub - lsub (0x65) : Subtract long
-type Lsub struct{}
+type Lsub struct{ Location }
 
 // Fsub - fsub (0x66) : Subtract float
-type Fsub struct{}
+type Fsub struct{ Location }
 
 // Dsub - dsub (0x67) : Subtract double
-type Dsub struct{}
+type Dsub struct{ Location }
 
 // Imul - imul (0x68) : Multiply int
-type Imul struct{}
+type Imul struct{ Location }
 
 // Lmul - lmul (0x69) : Multiply long
-type Lmul struct{}
+type Lmul struct{ Location }
 
 // Fmul - fmul (0x6A) : Multiply float
-type Fmul struct{}
+type Fmul struct{ Location }
 
 // Dmul - dmul (0x6B) : Multiply double
-type Dmul struct{}
+type Dmul struct{ Location }
 
 // Idiv - idiv (0x6C) : Divide int
-type Idiv struct{}
+type Idiv struct{ Location }
 
 // Ldiv - ldiv (0x6D) : Divide long
-type Ldiv struct{}
+type Ldiv struct{ Location }
 
 // Fdiv - fdiv (0x6E) : Divide float
-type Fdiv struct{}
+type Fdiv struct{ Location }
 
 // Ddiv - ddiv (0x6F) : Divide double
-type Ddiv struct{}
+type Ddiv struct{ Location }
 
 // Irem - irem (0x70) : Remainder int
-type Irem struct{}
+type Irem struct{ Location }
 
 // Lrem - lrem (0x71) : Remainder long
-type Lrem struct{}
+type Lrem struct{ Location }
 
 // Frem - frem (0x72) : Remainder float
-type Frem struct{}
+type Frem struct{ Location }
 
 // Drem - drem (0x73) : Remainder double
-type Drem struct{}
+type Drem struct{ Location }
 
 // Ineg - ineg (0x74) : Negate int
-type Ineg struct{}
+type Ineg struct{ Location }
 
 // Lneg - lneg (0x75) : Negate long
-type Lneg struct{}
+type Lneg struct{ Location }
 
 // Fneg - fneg (0x76) : Negate float
-type Fneg struct{}
+type Fneg struct{ Location }
 
 // Dneg - dneg (0x77) : Negate double
-type Dneg struct{}
+type Dneg struct{ Location }
 
 // Ishl - ishl (0x78) : Shift left int
-type Ishl struct{}
+type Ishl struct{ Location }
 
 // Lshl - lshl (0x79) : Shift left long
-type Lshl struct{}
+type Lshl struct{ Location }
 
 // Ishr - ishr (0x7A) : Arithmetic shift right int
-type Ishr struct{}
+type Ishr struct{ Location }
 
 // Lshr - lshr (0x7B) : Arithmetic shift right long
-type Lshr struct{}
+type Lshr struct{ Location }
 
 // Iushr - iushr (0x7C) : Logical shift right int
-type Iushr struct{}
+type Iushr struct{ Location }
 
 // Lushr - lushr (0x7D) : Logical shift right long
-type Lushr struct{}
+type Lushr struct{ Location }
 
 // Iand - iand (0x7E) : Boolean AND int
-type Iand struct{}
+type Iand struct{ Location }
 
 // Land - land (0x7F) : Boolean AND long
-type Land struct{}
+type Land struct{ Location }
 
 // Ior - ior (0x80) : Boolean OR int
-type Ior struct{}
+type Ior struct{ Location }
 
 // Lor - lor (0x81) : Boolean OR long
-type Lor struct{}
+type Lor struct{ Location }
 
 // Ixor - ixor (0x82) : Boolean XOR int
-type Ixor struct{}
+type Ixor struct{ Location }
 
 // Lxor - lxor (0x83) : Boolean XOR long
-type Lxor struct{}
+type Lxor struct{ Location }
 
 // Iinc - iinc (0x84) : Increment local variable by constant
 type Iinc struct {
+	Location
 	LocalIndex uint8 // local variable index
 	Const      int8  // const value
 }
 
 // I2l - i2l (0x85) : Convert int to long
-type I2l struct{}
+type I2l struct{ Location }
 
 // I2f - i2f (0x86) : Convert int to float
-type I2f struct{}
+type I2f struct{ Location }
 
 // I2d - i2d (0x87) : Convert int to double
-type I2d struct{}
+type I2d struct{ Location }
 
 // L2i - l2i (0x88) : Convert long to int
-type L2i struct{}
+type L2i struct{ Location }
 
 // L2f - l2f (0x89) : Convert long to float
-type L2f struct{}
+type L2f struct{ Location }
 
 // L2d - l2d (0x8A) : Convert long to double
-type L2d struct{}
+type L2d struct{ Location }
 
 // F2i - f2i (0x8B) : Convert float to int
-type F2i struct{}
+type F2i struct{ Location }
 
 // F2l - f2l (0x8C) : Convert float to long
-type F2l struct{}
+type F2l struct{ Location }
 
 // F2d - f2d (0x8D) : Convert float to double
-type F2d struct{}
+type F2d struct{ Location }
 
 // D2i - d2i (0x8E) : Convert double to int
-type D2i struct{}
+type D2i struct{ Location }
 
 // D2l - d2l (0x8F) : Convert double to long
-type D2l struct{}
+type D2l struct{ Location }
 
 // D2f - d2f (0x90) : Convert double to float
-type D2f struct{}
+type D2f struct{ Location }
 
 // I2b - i2b (0x91) : Convert int to byte
-type I2b struct{}
+type I2b struct{ Location }
 
 // I2c - i2c (0x92) : Convert int to char
-type I2c struct{}
+type I2c struct{ Location }
 
 // I2s - i2s (0x93) : Convert int to short
-type I2s struct{}
+type I2s struct{ Location }
 
 // Lcmp - lcmp (0x94) : Compare long
-type Lcmp struct{}
+type Lcmp struct{ Location }
 
 // Fcmpl - fcmpl (0x95) : Compare float
-type Fcmpl struct{}
+type Fcmpl struct{ Location }
 
 // Fcmpg - fcmpg (0x96) : Compare float
-type Fcmpg struct{}
+type Fcmpg struct{ Location }
 
 // Dcmpl - dcmpl (0x97) : Compare double
-type Dcmpl struct{}
+type Dcmpl struct{ Location }
 
 // Dcmpg - dcmpg (0x98) : Compare double
-type Dcmpg struct{}
+type Dcmpg struct{ Location }
 
 // Ifeq - ifeq (0x99) : Branch if int comparison with zero succeeds
 type Ifeq struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Ifne - ifne (0x9A) : Branch if int comparison with zero succeeds
 type Ifne struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Iflt - iflt (0x9B) : Branch if int comparison with zero succeeds
 type Iflt struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Ifge - ifge (0x9C) : Branch if int comparison with zero succeeds
 type Ifge struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Ifgt - ifgt (0x9D) : Branch if int comparison with zero succeeds
 type Ifgt struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Ifle - ifle (0x9E) : Branch if int comparison with zero succeeds
 type Ifle struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfIcmpeq - if_icmpeq (0x9F) : Branch if int comparison succeeds
 type IfIcmpeq struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfIcmpne - if_icmpne (0xA0) : Branch if int comparison succeeds
 type IfIcmpne struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfIcmplt - if_icmplt (0xA1) : Branch if int comparison succeeds
 type IfIcmplt struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfIcmpge - if_icmpge (0xA2) : Branch if int comparison succeeds
 type IfIcmpge struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfIcmpgt - if_icmpgt (0xA3) : Branch if int comparison succeeds
 type IfIcmpgt struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfIcmple - if_icmple (0xA4) : Branch if int comparison succeeds
 type IfIcmple struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfAcmpeq - if_acmpeq (0xA5) : Branch if reference comparison succeeds
 type IfAcmpeq struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // IfAcmpne - if_acmpne (0xA6) : Branch if reference comparison succeeds
 type IfAcmpne struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Goto - goto (0xA7) : Branch always
 type Goto struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Jsr - jsr (0xA8) : Jump subroutine
 type Jsr struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Ret - ret (0xA9) : Return from subroutine
 type Ret struct {
+	Location
 	LocalIndex uint8 // local variable index
 }
 
-// // Tableswitch - tableswitch (0xAA) : Access jump table by index and jump // TODO
-// type Tableswitch struct {
-//   [0] // 0 to 3 empty bytes for padding
-//   int32 // default offset
-//   int32 // low (<= high)
-//   int32 // high (>= low)
-//   [int32] // high - low + 1 offsets
-// }
+// Tableswitch - tableswitch (0xAA) : Access jump table by index and jump
+type Tableswitch struct {
+	Location
+	DefaultOffset int32   // jump offset (branch) when the index is out of range
+	Low           int32   // lowest index (<= high)
+	High          int32   // highest index (>= low)
+	Offsets       []int32 // high - low + 1 jump offsets (branch)
+}
 
-// // Lookupswitch - lookupswitch (0xAB) : Access jump table by key match and jump // TODO
-// type Lookupswitch struct {
-//   [0] // 0 to 3 empty bytes for padding
-//   int32 // default offset
-//   int32 // pairs length >= 0
-//   [int32 int32] // pairs (match - offset)
-// }
+// Lookupswitch - lookupswitch (0xAB) : Access jump table by key match and jump
+type Lookupswitch struct {
+	Location
+	DefaultOffset int32         // jump offset (branch) when no key matches
+	Pairs         []MatchOffset // match - offset pairs sorted by match
+}
+
+// MatchOffset is a key and jump offset pair of a lookupswitch
+type MatchOffset struct {
+	Match  int32 // key
+	Offset int32 // jump offset (branch)
+}
 
 // Ireturn - ireturn (0xAC) : Return int from method
-type Ireturn struct{}
+type Ireturn struct{ Location }
 
 // Lreturn - lreturn (0xAD) : Return long from method
-type Lreturn struct{}
+type Lreturn struct{ Location }
 
 // Freturn - freturn (0xAE) : Return float from method
-type Freturn struct{}
+type Freturn struct{ Location }
 
 // Dreturn - dreturn (0xAF) : Return double from method
-type Dreturn struct{}
+type Dreturn struct{ Location }
 
 // Areturn - areturn (0xB0) : Return reference from method
-type Areturn struct{}
+type Areturn struct{ Location }
 
 // Return - return (0xB1) : Return void from method
-type Return struct{}
+type Return struct{ Location }
 
 // Getstatic - getstatic (0xB2) : Get static field from class
 type Getstatic struct {
+	Location
 	Class string
 	Name  string
 	Type  string
@@ -615,6 +660,7 @@ type Getstatic struct {
 
 // Putstatic - putstatic (0xB3) : Set static field in class
 type Putstatic struct {
+	Location
 	Class string
 	Name  string
 	Type  string
@@ -622,6 +668,7 @@ type Putstatic struct {
 
 // Getfield - getfield (0xB4) : Fetch field from object
 type Getfield struct {
+	Location
 	Class string
 	Name  string
 	Type  string
@@ -629,6 +676,7 @@ type Getfield struct {
 
 // Putfield - putfield (0xB5) : Set field in object
 type Putfield struct {
+	Location
 	Class string
 	Name  string
 	Type  string
@@ -636,6 +684,7 @@ type Putfield struct {
 
 // Invokevirtual - invokevirtual (0xB6) : Invoke instance method; dispatch based on class
 type Invokevirtual struct {
+	Location
 	Class string
 	Name  string
 	Type  string
@@ -643,87 +692,112 @@ type Invokevirtual struct {
 
 // Invokespecial - invokespecial (0xB7) : Invoke instance method;  direct invocation of instance initialization methods and methods of the current class and its supertypes
 type Invokespecial struct {
-	Class string
-	Name  string
-	Type  string
+	Location
+	Class     string
+	Name      string
+	Type      string
+	Interface bool // the method is declared by an interface (CONSTANT_InterfaceMethodref)
 }
 
 // Invokestatic - invokestatic (0xB8) : Invoke a class (static) method
 type Invokestatic struct {
-	Class string
-	Name  string
-	Type  string
+	Location
+	Class     string
+	Name      string
+	Type      string
+	Interface bool // the method is declared by an interface (CONSTANT_InterfaceMethodref)
 }
 
-// Invokeinterface - invokeinterface (0xB9) : Invoke interface method // TODO
+// Invokeinterface - invokeinterface (0xB9) : Invoke interface method
 type Invokeinterface struct {
+	Location
 	InterfaceMethodref InterfaceMethodref
 	Count              uint8
 }
 
-// Invokedynamic - invokedynamic (0xBA) : Invoke a dynamically-computed call site // TODO
+// Invokedynamic - invokedynamic (0xBA) : Invoke a dynamically-computed call site
 type Invokedynamic struct {
+	Location
 	InvokeDynamic InvokeDynamic
 }
 
 // New - new (0xBB) : Create new object
-type New string
+type New struct {
+	Location
+	Class string
+}
 
-// Newarray - newarray (0xBC) : Create new array // TODO
+// Newarray - newarray (0xBC) : Create new array
 type Newarray struct {
+	Location
 	Type uint8 // type -> T_BOOLEAN=4 ; T_CHAR=5 ; T_FLOAT=6 ; T_DOUBLE=7 ; T_BYTE=8 ; T_SHORT=9 ; T_INT=10 ; T_LONG=11
 }
 
 // Anewarray - anewarray (0xBD) : Create new array of reference
-type Anewarray string
+type Anewarray struct {
+	Location
+	Class string
+}
 
 // Arraylength - arraylength (0xBE) : Get length of array
-type Arraylength struct{}
+type Arraylength struct{ Location }
 
 // Athrow - athrow (0xBF) : Throw exception or error
-type Athrow struct{}
+type Athrow struct{ Location }
 
 // Checkcast - checkcast (0xC0) : Check whether object is of given type
-type Checkcast string
+type Checkcast struct {
+	Location
+	Class string
+}
 
 // Instanceof - instanceof (0xC1) : Determine if object is of given type
-type Instanceof string
+type Instanceof struct {
+	Location
+	Class string
+}
 
 // Monitorenter - monitorenter (0xC2) : Enter monitor for object
-type Monitorenter struct{}
+type Monitorenter struct{ Location }
 
 // Monitorexit - monitorexit (0xC3) : Exit monitor for object
-type Monitorexit struct{}
+type Monitorexit struct{ Location }
 
-// Wide - wide (0xC4) : Extend local variable index by additional bytes // TODO
+// Wide - wide (0xC4) : Extend local variable index by additional bytes
 type Wide struct {
+	Location
 	OpCode     uint8  // opcode in iload fload aload lload dload istore fstore astore lstore dstore ret iinc
 	LocalIndex uint16 // local variable index
-	Const      uint16 // const value ONLY in iinc case
+	Const      int16  // const value ONLY in iinc case
 }
 
 // Multianewarray - multianewarray (0xC5) : Create new multidimensional array
 type Multianewarray struct {
+	Location
 	Class     string // index in constant pool (Class)
 	Dimension uint8  // dimension >= 1
 }
 
 // Ifnull - ifnull (0xC6) : Branch if reference is null
 type Ifnull struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // Ifnonnull - ifnonnull (0xC7) : Branch if reference not null
 type Ifnonnull struct {
+	Location
 	Offset int16 // jump offset (branch)
 }
 
 // GotoW - goto_w (0xC8) : Branch always (wide index)
 type GotoW struct {
+	Location
 	Offset int32 // jump offset (branch)
 }
 
 // JsrW - jsr_w (0xC9) : Jump subroutine (wide index)
 type JsrW struct {
+	Location
 	Offset int32 // jump offset (branch)
 }
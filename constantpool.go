@@ -1,6 +1,7 @@
 package classfileparser
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math"
@@ -53,129 +54,281 @@ type NameAndType struct {
 
 // MethodHandle holds the decoded data of a CONSTANT_MethodHandle entry
 type MethodHandle struct {
-	Kind  string
-	Class string
-	Name  string
-	Type  string
+	Kind      string
+	Class     string
+	Name      string
+	Type      string
+	Interface bool // The reference is a CONSTANT_InterfaceMethodref
 }
 
-// Dynamic represents a CONSTANT_Dynamic entry (bootstrap handling TODO)
+// referenceKinds names the reference_kind values of CONSTANT_MethodHandle entries
+var referenceKinds = map[byte]string{
+	1: "getField",
+	2: "getStatic",
+	3: "putField",
+	4: "putStatic",
+	5: "invokeVirtual",
+	6: "invokeStatic",
+	7: "invokeSpecial",
+	8: "newInvokeSpecial",
+	9: "invokeInterface",
+}
+
+// Dynamic represents a CONSTANT_Dynamic entry
 type Dynamic struct {
 	Name           string
 	Type           string
 	BootstrapIndex uint16
+	Bootstrap      *BootstrapMethod // Entry BootstrapIndex of the class BootstrapMethods attribute
 }
 
-// InvokeDynamic represents a CONSTANT_InvokeDynamic entry (bootstrap handling TODO)
+// InvokeDynamic represents a CONSTANT_InvokeDynamic entry
 type InvokeDynamic struct {
 	BootstrapIndex uint16
 	Name           string
 	Type           string
+	Bootstrap      *BootstrapMethod // Entry BootstrapIndex of the class BootstrapMethods attribute
 }
 
 // GetConstantPool resolves the raw constant pool entries into typed Go values for easier access
 func (cf *ClassFile) GetConstantPool() (ConstantPool, error) {
+	cp, _, err := cf.constantPool()
+	return cp, err
+}
+
+// constantPool resolves the constant pool and returns the entries of the BootstrapMethods
+// attribute its dynamic constants are linked to
+func (cf *ClassFile) constantPool() (ConstantPool, []BootstrapMethod, error) {
 	cp := ConstantPool{}
 	for i, cpItem := range cf.ConstantPool {
+		index := uint16(i + 1)
+		var err error
 		switch cpItem.Tag {
 		case 0:
 			continue
 		case 1:
-			cp[uint16(i+1)] = Utf8(cpItem.Info)
+			cp[index] = Utf8(cpItem.Info)
 		case 3:
-			cp[uint16(i+1)] = int32(binary.BigEndian.Uint32(cpItem.Info))
+			cp[index] = int32(binary.BigEndian.Uint32(cpItem.Info))
 		case 4:
-			cp[uint16(i+1)] = math.Float32frombits(binary.BigEndian.Uint32(cpItem.Info))
+			cp[index] = math.Float32frombits(binary.BigEndian.Uint32(cpItem.Info))
 		case 5:
-			cp[uint16(i+1)] = int64(binary.BigEndian.Uint64(cpItem.Info))
+			cp[index] = int64(binary.BigEndian.Uint64(cpItem.Info))
 		case 6:
-			cp[uint16(i+1)] = math.Float64frombits(binary.BigEndian.Uint64(cpItem.Info))
+			cp[index] = math.Float64frombits(binary.BigEndian.Uint64(cpItem.Info))
 		case 7:
-			cp[uint16(i+1)] = Class(getString(cpItem.Info, cf.ConstantPool))
+			var name string
+			name, err = getString(cpItem.Info, cf.ConstantPool)
+			cp[index] = Class(name)
 		case 8:
-			cp[uint16(i+1)] = getString(cpItem.Info, cf.ConstantPool)
+			cp[index], err = getString(cpItem.Info, cf.ConstantPool)
 		case 9:
-			cp[uint16(i+1)] = Fieldref(getClassNameType(cpItem.Info, cf.ConstantPool))
+			var ref classNameType
+			ref, err = getClassNameType(cpItem.Info, cf.ConstantPool)
+			cp[index] = Fieldref(ref)
 		case 10:
-			cp[uint16(i+1)] = Methodref(getClassNameType(cpItem.Info, cf.ConstantPool))
+			var ref classNameType
+			ref, err = getClassNameType(cpItem.Info, cf.ConstantPool)
+			cp[index] = Methodref(ref)
 		case 11:
-			cp[uint16(i+1)] = InterfaceMethodref(getClassNameType(cpItem.Info, cf.ConstantPool))
+			var ref classNameType
+			ref, err = getClassNameType(cpItem.Info, cf.ConstantPool)
+			cp[index] = InterfaceMethodref(ref)
 		case 12:
-			cp[uint16(i+1)] =
-				getNameType(cpItem.Info, cf.ConstantPool)
+			cp[index], err = getNameType(cpItem.Info, cf.ConstantPool)
 		case 15:
-			item := cp[uint16(binary.BigEndian.Uint16(cpItem.Info[1:3]))]
-			var cnt struct {
-				Class string
-				Name  string
-				Type  string
-			}
-			switch itemTyped := item.(type) {
-			case Fieldref:
-				cnt = itemTyped
-			case Methodref:
-				cnt = itemTyped
-			case InterfaceMethodref:
-				cnt = itemTyped
+			var cnt classNameType
+			var tag uint8
+			kind, ok := referenceKinds[cpItem.Info[0]]
+			if !ok {
+				err = fmt.Errorf("invalid reference kind %d", cpItem.Info[0])
+				break
 			}
-			cp[uint16(i+1)] = MethodHandle{
-				Kind: map[byte]string{
-					1: "getField",
-					2: "getStatic",
-					3: "putField",
-					4: "putStatic",
-					5: "invokeVirtual",
-					6: "invokeStatic",
-					7: "invokeSpecial",
-					8: "newInvokeSpecial",
-					9: "invokeInterface",
-				}[cpItem.Info[0]],
-				Class: cnt.Class,
-				Name:  cnt.Name,
-				Type:  cnt.Type,
+			cnt, tag, err = getMemberRef(cpItem.Info[1:3], cf.ConstantPool)
+			cp[index] = MethodHandle{
+				Kind:      kind,
+				Class:     cnt.Class,
+				Name:      cnt.Name,
+				Type:      cnt.Type,
+				Interface: tag == 11,
 			}
 		case 16:
-			cp[uint16(i+1)] = MethodType(getString(cpItem.Info, cf.ConstantPool))
+			var descriptor string
+			descriptor, err = getString(cpItem.Info, cf.ConstantPool)
+			cp[index] = MethodType(descriptor)
 		case 17:
-			cp[uint16(i+1)] = Dynamic{} // TODO
+			var nt NameAndType
+			nt, err = getDynamicNameType(cpItem.Info, cf.ConstantPool)
+			cp[index] = Dynamic{
+				Name:           nt.Name,
+				Type:           nt.Type,
+				BootstrapIndex: binary.BigEndian.Uint16(cpItem.Info[0:2]),
+			}
 		case 18:
-			cp[uint16(i+1)] = InvokeDynamic{} // TODO
+			var nt NameAndType
+			nt, err = getDynamicNameType(cpItem.Info, cf.ConstantPool)
+			cp[index] = InvokeDynamic{
+				BootstrapIndex: binary.BigEndian.Uint16(cpItem.Info[0:2]),
+				Name:           nt.Name,
+				Type:           nt.Type,
+			}
 		case 19:
-			cp[uint16(i+1)] = Module(getString(cpItem.Info, cf.ConstantPool))
+			var name string
+			name, err = getString(cpItem.Info, cf.ConstantPool)
+			cp[index] = Module(name)
 		case 20:
-			cp[uint16(i+1)] = Package(getString(cpItem.Info, cf.ConstantPool))
+			var name string
+			name, err = getString(cpItem.Info, cf.ConstantPool)
+			cp[index] = Package(name)
 		default:
-			return nil, fmt.Errorf("unknown constant pool tag: %d", cpItem.Tag)
+			return nil, nil, fmt.Errorf("unknown constant pool tag: %d", cpItem.Tag)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid constant pool entry #%d: %w", index, err)
 		}
 	}
-	return cp, nil
+	methods, err := cf.linkBootstrapMethods(cp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cp, methods, nil
 }
 
-func getString(i []byte, cp []CpInfo) string {
-	index := int16(binary.BigEndian.Uint16(i))
-	return string(cp[index-1].Info)
+// linkBootstrapMethods decodes the BootstrapMethods attribute, points every Dynamic and
+// InvokeDynamic entry of cp to the bootstrap method it uses and returns the decoded entries
+func (cf *ClassFile) linkBootstrapMethods(cp ConstantPool) ([]BootstrapMethod, error) {
+	var methods []BootstrapMethod
+	found := false
+	for _, a := range cf.Attributes {
+		if name, ok := cp[a.AttributeNameIndex].(Utf8); !ok || name != "BootstrapMethods" {
+			continue
+		}
+		var err error
+		reader := bytes.NewReader(a.Info)
+		if methods, err = readBootstrapMethods(reader); err != nil {
+			return nil, &DecodeError{Attribute: "BootstrapMethods", Offset: offset(reader), Err: err}
+		}
+		found = true
+		break
+	}
+
+	// Link first, so that dynamic constants used as static arguments carry their own bootstrap method
+	for index, entry := range cp {
+		switch entry := entry.(type) {
+		case Dynamic:
+			if int(entry.BootstrapIndex) >= len(methods) {
+				return nil, missingBootstrapMethod(index, entry.BootstrapIndex, found)
+			}
+			entry.Bootstrap = &methods[entry.BootstrapIndex]
+			cp[index] = entry
+		case InvokeDynamic:
+			if int(entry.BootstrapIndex) >= len(methods) {
+				return nil, missingBootstrapMethod(index, entry.BootstrapIndex, found)
+			}
+			entry.Bootstrap = &methods[entry.BootstrapIndex]
+			cp[index] = entry
+		}
+	}
+
+	if err := resolveBootstrapMethods(methods, cp); err != nil {
+		return nil, &DecodeError{Attribute: "BootstrapMethods", Err: err}
+	}
+	return methods, nil
 }
 
-func getNameType(i []byte, cp []CpInfo) NameAndType {
-	return NameAndType{
-		Name: getString(i[0:2], cp),
-		Type: getString(i[2:4], cp),
+func missingBootstrapMethod(index, bootstrapIndex uint16, found bool) error {
+	if !found {
+		return fmt.Errorf("invalid constant pool entry #%d: the class has no BootstrapMethods attribute", index)
 	}
+	return fmt.Errorf("invalid constant pool entry #%d: bootstrap method %d does not exist", index, bootstrapIndex)
 }
 
-func getClassNameType(i []byte, cp []CpInfo) struct {
+// classNameType is the shape shared by Fieldref, Methodref and InterfaceMethodref
+type classNameType struct {
 	Class string
 	Name  string
 	Type  string
-} {
-	indexNameAndType := cp[int16(binary.BigEndian.Uint16(i[2:4]))-1].Info
-	return struct {
-		Class string
-		Name  string
-		Type  string
-	}{
-		Class: getString(cp[int16(binary.BigEndian.Uint16(i[0:2]))-1].Info, cp),
-		Name:  getString(indexNameAndType[0:2], cp),
-		Type:  getString(indexNameAndType[2:4], cp),
+}
+
+// getEntry returns the raw entry referenced by the u2 index at the start of i, checking its tag
+func getEntry(i []byte, cp []CpInfo, tag uint8) (CpInfo, error) {
+	index := binary.BigEndian.Uint16(i)
+	if index == 0 || int(index) > len(cp) {
+		return CpInfo{}, fmt.Errorf("index #%d is out of range", index)
+	}
+	if cp[index-1].Tag != tag {
+		return CpInfo{}, fmt.Errorf("entry #%d has tag %d, expected %d", index, cp[index-1].Tag, tag)
+	}
+	return cp[index-1], nil
+}
+
+func getString(i []byte, cp []CpInfo) (string, error) {
+	entry, err := getEntry(i, cp, 1)
+	if err != nil {
+		return "", err
+	}
+	return string(entry.Info), nil
+}
+
+// getDynamicNameType resolves the NameAndType referenced by a CONSTANT_Dynamic or CONSTANT_InvokeDynamic entry
+func getDynamicNameType(i []byte, cp []CpInfo) (NameAndType, error) {
+	nameAndType, err := getEntry(i[2:4], cp, 12)
+	if err != nil {
+		return NameAndType{}, err
+	}
+	return getNameType(nameAndType.Info, cp)
+}
+
+func getNameType(i []byte, cp []CpInfo) (NameAndType, error) {
+	name, err := getString(i[0:2], cp)
+	if err != nil {
+		return NameAndType{}, err
+	}
+	descriptor, err := getString(i[2:4], cp)
+	if err != nil {
+		return NameAndType{}, err
+	}
+	return NameAndType{
+		Name: name,
+		Type: descriptor,
+	}, nil
+}
+
+// getMemberRef resolves the Fieldref, Methodref or InterfaceMethodref referenced by the u2 index at the start of i
+// and returns its tag
+func getMemberRef(i []byte, cp []CpInfo) (classNameType, uint8, error) {
+	index := binary.BigEndian.Uint16(i)
+	if index == 0 || int(index) > len(cp) {
+		return classNameType{}, 0, fmt.Errorf("index #%d is out of range", index)
+	}
+	tag := cp[index-1].Tag
+	if tag < 9 || tag > 11 {
+		return classNameType{}, 0, fmt.Errorf("entry #%d has tag %d, expected a member reference", index, tag)
+	}
+	ref, err := getClassNameType(cp[index-1].Info, cp)
+	return ref, tag, err
+}
+
+func getClassNameType(i []byte, cp []CpInfo) (classNameType, error) {
+	class, err := getEntry(i[0:2], cp, 7)
+	if err != nil {
+		return classNameType{}, err
+	}
+	className, err := getString(class.Info, cp)
+	if err != nil {
+		return classNameType{}, err
+	}
+	nameAndType, err := getEntry(i[2:4], cp, 12)
+	if err != nil {
+		return classNameType{}, err
+	}
+	nt, err := getNameType(nameAndType.Info, cp)
+	if err != nil {
+		return classNameType{}, err
 	}
+	return classNameType{
+		Class: className,
+		Name:  nt.Name,
+		Type:  nt.Type,
+	}, nil
 }
@@ -0,0 +1,97 @@
+package classfileparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// entry returns a constant pool entry with the given tag and big-endian info
+func entry(tag uint8, info ...byte) CpInfo {
+	return CpInfo{Tag: tag, Info: info}
+}
+
+func utf8Entry(value string) CpInfo {
+	return entry(1, []byte(value)...)
+}
+
+// indyClass returns a class A whose static method m()V runs invokedynamic m()V, bootstrapped by
+// the static method A.m()V with a method handle of the given reference kind
+func indyClass(kind byte) *ClassFile {
+	return &ClassFile{
+		Magic:        0xCAFEBABE,
+		MajorVersion: 52,
+		ConstantPool: []CpInfo{
+			utf8Entry("BootstrapMethods"), // #1
+			utf8Entry("A"),                // #2
+			entry(7, 0, 2),                // #3 Class A
+			utf8Entry("m"),                // #4
+			utf8Entry("()V"),              // #5
+			entry(12, 0, 4, 0, 5),         // #6 NameAndType m()V
+			entry(10, 0, 3, 0, 6),         // #7 Methodref A.m()V
+			entry(15, kind, 0, 7),         // #8 MethodHandle
+			entry(18, 0, 0, 0, 6),         // #9 InvokeDynamic 0 m()V
+			utf8Entry("Code"),             // #10
+		},
+		ThisClass: 3,
+		Methods: []MethodInfo{{
+			AccessFlags:     0x0009,
+			NameIndex:       4,
+			DescriptorIndex: 5,
+			Attributes: []AttributeInfo{{
+				AttributeNameIndex: 10,
+				Info:               []byte{0, 0, 0, 0, 0, 0, 0, 6, 0xBA, 0, 9, 0, 0, 0xB1, 0, 0, 0, 0},
+			}},
+		}},
+		Attributes: []AttributeInfo{{AttributeNameIndex: 1, Info: []byte{0, 1, 0, 8, 0, 0}}},
+	}
+}
+
+func TestMethodHandleKinds(t *testing.T) {
+	cp, err := indyClass(6).GetConstantPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MethodHandle{Kind: "invokeStatic", Class: "A", Name: "m", Type: "()V"}
+	if handle := cp[8].(MethodHandle); handle != want {
+		t.Errorf("method handle = %+v, want %+v", handle, want)
+	}
+
+	for _, kind := range []byte{0, 10, 255} {
+		_, err := indyClass(kind).GetConstantPool()
+		if err == nil || !strings.Contains(err.Error(), "invalid reference kind") {
+			t.Errorf("reference kind %d: error = %v", kind, err)
+		}
+	}
+}
+
+func TestBootstrapMethodsAreShared(t *testing.T) {
+	class, err := indyClass(6).GetClassFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attribute BootstrapMethods
+	for _, a := range class.Attributes {
+		if methods, ok := a.(BootstrapMethods); ok {
+			attribute = methods
+		}
+	}
+	if len(attribute.BootstrapMethods) != 1 {
+		t.Fatalf("BootstrapMethods = %+v", attribute)
+	}
+	code := class.Methods[0].Attributes[0].(Code)
+	indy := code.Code[0].(Invokedynamic)
+	if indy.InvokeDynamic.Bootstrap != &attribute.BootstrapMethods[0] {
+		t.Error("invokedynamic does not point to the entry of the BootstrapMethods attribute")
+	}
+	if indy.InvokeDynamic.Bootstrap.Method.Name != "m" {
+		t.Errorf("bootstrap method = %+v", indy.InvokeDynamic.Bootstrap.Method)
+	}
+}
+
+func TestMissingBootstrapMethods(t *testing.T) {
+	cf := indyClass(6)
+	cf.Attributes = nil
+	if _, err := cf.GetConstantPool(); err == nil || !strings.Contains(err.Error(), "no BootstrapMethods attribute") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+// Package descriptor parses JVM field and method descriptors into typed values,
+// which render back as descriptors or as Java source names.
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is a field type parsed from a descriptor: a Base, an Object or an Array
+type Type interface {
+	// Descriptor returns the JVM descriptor of the type, e.g. [Ljava/lang/String;
+	Descriptor() string
+	// JavaName returns the type as written in Java source, e.g. java.lang.String[]
+	JavaName() string
+	// Slots returns the number of local variable or operand stack slots taken by a value of the type
+	Slots() int
+}
+
+// Base is a primitive type, or void in the return type of a method
+type Base byte
+
+// Base types, named after their descriptor character
+const (
+	Byte    Base = 'B'
+	Char    Base = 'C'
+	Double  Base = 'D'
+	Float   Base = 'F'
+	Int     Base = 'I'
+	Long    Base = 'J'
+	Short   Base = 'S'
+	Boolean Base = 'Z'
+	Void    Base = 'V'
+)
+
+var baseNames = map[Base]string{
+	Byte:    "byte",
+	Char:    "char",
+	Double:  "double",
+	Float:   "float",
+	Int:     "int",
+	Long:    "long",
+	Short:   "short",
+	Boolean: "boolean",
+	Void:    "void",
+}
+
+// Descriptor returns the descriptor character of the base type
+func (b Base) Descriptor() string {
+	return string(rune(b))
+}
+
+// JavaName returns the Java keyword of the base type
+func (b Base) JavaName() string {
+	return baseNames[b]
+}
+
+// Slots returns 2 for long and double, 0 for void and 1 otherwise
+func (b Base) Slots() int {
+	switch b {
+	case Long, Double:
+		return 2
+	case Void:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func (b Base) String() string {
+	return b.Descriptor()
+}
+
+// Object is a class or interface type
+type Object struct {
+	Name string // Internal name, e.g. java/lang/String
+}
+
+// Descriptor returns the descriptor of the object type, e.g. Ljava/lang/String;
+func (o Object) Descriptor() string {
+	return "L" + o.Name + ";"
+}
+
+// JavaName returns the binary name of the class, e.g. java.lang.String or java.util.Map$Entry
+func (o Object) JavaName() string {
+	return strings.ReplaceAll(o.Name, "/", ".")
+}
+
+// Slots returns 1
+func (o Object) Slots() int {
+	return 1
+}
+
+func (o Object) String() string {
+	return o.Descriptor()
+}
+
+// Array is an array type
+type Array struct {
+	Dimensions int  // Number of dimensions, from 1 to 255
+	Element    Type // Element type, a Base or an Object
+}
+
+// Descriptor returns the descriptor of the array type, e.g. [[I
+func (a Array) Descriptor() string {
+	return strings.Repeat("[", a.Dimensions) + a.Element.Descriptor()
+}
+
+// JavaName returns the array type as written in Java source, e.g. int[][]
+func (a Array) JavaName() string {
+	return a.Element.JavaName() + strings.Repeat("[]", a.Dimensions)
+}
+
+// Slots returns 1
+func (a Array) Slots() int {
+	return 1
+}
+
+func (a Array) String() string {
+	return a.Descriptor()
+}
+
+// Method is a parsed method descriptor
+type Method struct {
+	Params []Type
+	Return Type // Void for methods returning nothing
+}
+
+// Descriptor returns the JVM descriptor of the method, e.g. ([Ljava/lang/String;)V
+func (m Method) Descriptor() string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, param := range m.Params {
+		b.WriteString(param.Descriptor())
+	}
+	b.WriteByte(')')
+	b.WriteString(m.Return.Descriptor())
+	return b.String()
+}
+
+// JavaDeclaration returns the declaration of a method called name with this descriptor as written
+// in Java source, e.g. void main(java.lang.String[])
+func (m Method) JavaDeclaration(name string) string {
+	params := make([]string, len(m.Params))
+	for i, param := range m.Params {
+		params[i] = param.JavaName()
+	}
+	return m.Return.JavaName() + " " + name + "(" + strings.Join(params, ", ") + ")"
+}
+
+// ArgumentSlots returns the number of local variable slots taken by the parameters, not counting this
+func (m Method) ArgumentSlots() int {
+	slots := 0
+	for _, param := range m.Params {
+		slots += param.Slots()
+	}
+	return slots
+}
+
+func (m Method) String() string {
+	return m.Descriptor()
+}
+
+// ParseField parses a field descriptor such as I, Ljava/lang/String; or [[D
+func ParseField(s string) (Type, error) {
+	p := parser{s: s}
+	t, err := p.fieldType()
+	if err == nil && p.pos != len(s) {
+		err = p.errorf("unexpected trailing characters")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseMethod parses a method descriptor such as (ILjava/lang/String;)V
+func ParseMethod(s string) (Method, error) {
+	p := parser{s: s}
+	var m Method
+	if !p.consume('(') {
+		return m, p.errorf("expected '('")
+	}
+	m.Params = []Type{}
+	for !p.consume(')') {
+		param, err := p.fieldType()
+		if err != nil {
+			return Method{}, err
+		}
+		m.Params = append(m.Params, param)
+	}
+	if p.consume('V') {
+		m.Return = Void
+	} else {
+		ret, err := p.fieldType()
+		if err != nil {
+			return Method{}, err
+		}
+		m.Return = ret
+	}
+	if p.pos != len(s) {
+		return Method{}, p.errorf("unexpected trailing characters")
+	}
+	return m, nil
+}
+
+// MustParseField is like ParseField but panics if s is malformed, for descriptors known at compile time
+func MustParseField(s string) Type {
+	t, err := ParseField(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// MustParseMethod is like ParseMethod but panics if s is malformed, for descriptors known at compile time
+func MustParseMethod(s string) Method {
+	m, err := ParseMethod(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("malformed descriptor %q at %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) fieldType() (Type, error) {
+	dimensions := 0
+	for p.consume('[') {
+		dimensions++
+	}
+	if dimensions > 255 {
+		return nil, p.errorf("array of %d dimensions exceeds the limit of 255", dimensions)
+	}
+	element, err := p.elementType()
+	if err != nil {
+		return nil, err
+	}
+	if dimensions > 0 {
+		return Array{Dimensions: dimensions, Element: element}, nil
+	}
+	return element, nil
+}
+
+func (p *parser) elementType() (Type, error) {
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("unexpected end of descriptor")
+	}
+	c := p.s[p.pos]
+	if c == 'L' {
+		end := strings.IndexByte(p.s[p.pos:], ';')
+		if end < 0 {
+			return nil, p.errorf("unterminated class name")
+		}
+		name := p.s[p.pos+1 : p.pos+end]
+		if !ValidInternalName(name) {
+			return nil, p.errorf("invalid class name %q", name)
+		}
+		p.pos += end + 1
+		return Object{Name: name}, nil
+	}
+	if _, ok := baseNames[Base(c)]; !ok || Base(c) == Void {
+		return nil, p.errorf("unexpected %q", c)
+	}
+	p.pos++
+	return Base(c), nil
+}
+
+// ValidInternalName reports whether name is a valid internal class name: non-empty slash separated
+// identifiers that contain none of . ; [ /
+func ValidInternalName(name string) bool {
+	for _, identifier := range strings.Split(name, "/") {
+		if identifier == "" || strings.ContainsAny(identifier, ".;[") {
+			return false
+		}
+	}
+	return true
+}
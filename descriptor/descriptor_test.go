@@ -0,0 +1,147 @@
+package descriptor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		want       Type
+		javaName   string
+		slots      int
+	}{
+		{"I", Int, "int", 1},
+		{"J", Long, "long", 2},
+		{"D", Double, "double", 2},
+		{"Z", Boolean, "boolean", 1},
+		{"Ljava/lang/String;", Object{Name: "java/lang/String"}, "java.lang.String", 1},
+		{"Ljava/util/Map$Entry;", Object{Name: "java/util/Map$Entry"}, "java.util.Map$Entry", 1},
+		{"LA;", Object{Name: "A"}, "A", 1},
+		{"[J", Array{Dimensions: 1, Element: Long}, "long[]", 1},
+		{"[[Ljava/lang/Object;", Array{Dimensions: 2, Element: Object{Name: "java/lang/Object"}}, "java.lang.Object[][]", 1},
+		{strings.Repeat("[", 255) + "B", Array{Dimensions: 255, Element: Byte}, "byte" + strings.Repeat("[]", 255), 1},
+	}
+	for _, test := range tests {
+		got, err := ParseField(test.descriptor)
+		if err != nil {
+			t.Errorf("ParseField(%q): %v", test.descriptor, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseField(%q) = %#v, want %#v", test.descriptor, got, test.want)
+		}
+		if got.Descriptor() != test.descriptor {
+			t.Errorf("ParseField(%q).Descriptor() = %q", test.descriptor, got.Descriptor())
+		}
+		if got.JavaName() != test.javaName {
+			t.Errorf("ParseField(%q).JavaName() = %q, want %q", test.descriptor, got.JavaName(), test.javaName)
+		}
+		if got.Slots() != test.slots {
+			t.Errorf("ParseField(%q).Slots() = %d, want %d", test.descriptor, got.Slots(), test.slots)
+		}
+	}
+}
+
+func TestParseFieldInvalid(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		err        string
+	}{
+		{"", "unexpected end of descriptor"},
+		{"V", "unexpected 'V'"},
+		{"[V", "unexpected 'V'"},
+		{"Q", "unexpected 'Q'"},
+		{"II", "unexpected trailing characters"},
+		{"[", "unexpected end of descriptor"},
+		{"Ljava/lang/String", "unterminated class name"},
+		{"L;", "invalid class name"},
+		{"Ljava//String;", "invalid class name"},
+		{"Ljava.lang.String;", "invalid class name"},
+		{"L[I;", "invalid class name"},
+		{strings.Repeat("[", 256) + "I", "256 dimensions exceeds the limit"},
+	}
+	for _, test := range tests {
+		_, err := ParseField(test.descriptor)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseField(%q): error = %v, want %q", test.descriptor, err, test.err)
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		descriptor  string
+		want        Method
+		declaration string
+		slots       int
+	}{
+		{"()V", Method{Params: []Type{}, Return: Void}, "void m()", 0},
+		{"(IJ)D", Method{Params: []Type{Int, Long}, Return: Double}, "double m(int, long)", 3},
+		{
+			"([Ljava/lang/String;)V",
+			Method{Params: []Type{Array{Dimensions: 1, Element: Object{Name: "java/lang/String"}}}, Return: Void},
+			"void m(java.lang.String[])",
+			1,
+		},
+		{
+			"(DLjava/util/Map$Entry;[[J)[I",
+			Method{Params: []Type{Double, Object{Name: "java/util/Map$Entry"}, Array{Dimensions: 2, Element: Long}}, Return: Array{Dimensions: 1, Element: Int}},
+			"int[] m(double, java.util.Map$Entry, long[][])",
+			4,
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseMethod(test.descriptor)
+		if err != nil {
+			t.Errorf("ParseMethod(%q): %v", test.descriptor, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseMethod(%q) = %#v, want %#v", test.descriptor, got, test.want)
+		}
+		if got.Descriptor() != test.descriptor {
+			t.Errorf("ParseMethod(%q).Descriptor() = %q", test.descriptor, got.Descriptor())
+		}
+		if got.JavaDeclaration("m") != test.declaration {
+			t.Errorf("ParseMethod(%q).JavaDeclaration() = %q, want %q", test.descriptor, got.JavaDeclaration("m"), test.declaration)
+		}
+		if got.ArgumentSlots() != test.slots {
+			t.Errorf("ParseMethod(%q).ArgumentSlots() = %d, want %d", test.descriptor, got.ArgumentSlots(), test.slots)
+		}
+	}
+}
+
+func TestParseMethodInvalid(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		err        string
+	}{
+		{"", "expected '('"},
+		{"V", "expected '('"},
+		{"(", "unexpected end of descriptor"},
+		{"()", "unexpected end of descriptor"},
+		{"(V)V", "unexpected 'V'"},
+		{"(I)VV", "unexpected trailing characters"},
+		{"(Ljava/lang/Object)V", "unterminated class name"},
+		{"(L;)V", "invalid class name"},
+		{"(I)[V", "unexpected 'V'"},
+	}
+	for _, test := range tests {
+		_, err := ParseMethod(test.descriptor)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseMethod(%q): error = %v, want %q", test.descriptor, err, test.err)
+		}
+	}
+}
+
+func TestMustParsePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParseMethod did not panic")
+		}
+	}()
+	MustParseMethod("(")
+}
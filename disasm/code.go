@@ -0,0 +1,284 @@
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// newarrayTypes names the element types of newarray
+var newarrayTypes = map[uint8]string{4: "boolean", 5: "char", 6: "float", 7: "double", 8: "byte", 9: "short", 10: "int", 11: "long"}
+
+// wideMnemonics names the instructions a wide prefix modifies, which javap suffixes with _w
+var wideMnemonics = map[uint8]string{
+	0x15: "iload", 0x16: "lload", 0x17: "fload", 0x18: "dload", 0x19: "aload",
+	0x36: "istore", 0x37: "lstore", 0x38: "fstore", 0x39: "dstore", 0x3A: "astore",
+	0x84: "iinc", 0xA9: "ret",
+}
+
+// frameKinds names the StackMapTable frame kinds as javap does
+var frameKinds = map[classfileparser.FrameKind]string{
+	classfileparser.SameFrame:                         "same",
+	classfileparser.SameLocals1StackItemFrame:         "same_locals_1_stack_item",
+	classfileparser.SameLocals1StackItemFrameExtended: "same_locals_1_stack_item_frame_extended",
+	classfileparser.ChopFrame:                         "chop",
+	classfileparser.SameFrameExtended:                 "same_frame_extended",
+	classfileparser.AppendFrame:                       "append",
+	classfileparser.FullFrame:                         "full_frame",
+}
+
+// printCode prints the Code attribute of method. raw holds the bytecode the instructions were
+// decoded from, where the constant pool indexes of their operands are read.
+func (p *printer) printCode(method *classfileparser.Method, code classfileparser.Code, raw []byte) {
+	p.line(4, "Code:")
+	if p.opts.Verbose {
+		args := method.Descriptor.ArgumentSlots()
+		if !method.Access.Has(classfileparser.MethodStatic) {
+			args++
+		}
+		p.line(6, "stack=%d, locals=%d, args_size=%d", code.MaxStack, code.MaxLocals, args)
+	}
+	for _, instruction := range code.Code {
+		p.printInstruction(instruction, raw)
+	}
+	if len(code.ExceptionTable) > 0 {
+		p.line(6, "Exception table:")
+		p.line(8, " from    to  target type")
+		for _, entry := range code.ExceptionTable {
+			catch := "any"
+			if entry.CatchClass != "" {
+				catch = "Class " + entry.CatchClass
+			}
+			p.line(8, " %5d %5d %5d   %s", entry.StartPc, entry.EndPc, entry.HandlerPc, catch)
+		}
+	}
+	for _, attribute := range code.Attributes {
+		switch attribute := attribute.(type) {
+		case classfileparser.LineNumberTable, classfileparser.LocalVariableTable, classfileparser.LocalVariableTypeTable:
+			if p.opts.Lines || p.opts.Verbose {
+				p.printDebugTable(6, attribute)
+			}
+		case classfileparser.StackMapTable:
+			if p.opts.Verbose {
+				p.printStackMapTable(attribute)
+			}
+		default:
+			if p.opts.Verbose {
+				p.printAttribute(6, attribute, nil)
+			}
+		}
+	}
+}
+
+// printDebugTable prints a LineNumberTable, LocalVariableTable or LocalVariableTypeTable
+func (p *printer) printDebugTable(indent int, attribute classfileparser.Attribute) {
+	switch attribute := attribute.(type) {
+	case classfileparser.LineNumberTable:
+		p.line(indent, "LineNumberTable:")
+		for _, entry := range attribute.LineNumberTable {
+			p.line(indent+2, "line %d: %d", entry.LineNumber, entry.StartPc)
+		}
+	case classfileparser.LocalVariableTable:
+		p.line(indent, "LocalVariableTable:")
+		p.line(indent+2, "Start  Length  Slot  Name   Signature")
+		for _, entry := range attribute.LocalVariableTable {
+			p.line(indent+2, "%5d %7d %5d %5s   %s", entry.StartPc, entry.Length, entry.Index, p.utf8(entry.NameIndex), p.utf8(entry.SignatureIndex))
+		}
+	case classfileparser.LocalVariableTypeTable:
+		p.line(indent, "LocalVariableTypeTable:")
+		p.line(indent+2, "Start  Length  Slot  Name   Signature")
+		for _, entry := range attribute.LocalVariableTypeTable {
+			p.line(indent+2, "%5d %7d %5d %5s   %s", entry.StartPc, entry.Length, entry.Index, p.utf8(entry.NameIndex), p.utf8(entry.SignatureIndex))
+		}
+	}
+}
+
+// printInstruction prints an instruction with its pc, its operands and, for constant pool
+// operands, what they resolve to
+func (p *printer) printInstruction(instruction classfileparser.Instruction, raw []byte) {
+	pc := instruction.PC()
+	mnemonic := instruction.Mnemonic()
+	u1 := func() int {
+		if pc+1 < len(raw) {
+			return int(raw[pc+1])
+		}
+		return 0
+	}
+	u2 := func() int {
+		if pc+3 <= len(raw) {
+			return int(binary.BigEndian.Uint16(raw[pc+1:]))
+		}
+		return 0
+	}
+	operands := func(format string, args ...interface{}) {
+		p.line(6, "%4d: %-13s %s", pc, mnemonic, fmt.Sprintf(format, args...))
+	}
+	constant := func(index int, comment string) {
+		p.commented(6, fmt.Sprintf("%4d: %-13s #%d", pc, mnemonic, index), comment)
+	}
+
+	switch instruction := instruction.(type) {
+	case classfileparser.Bipush:
+		operands("%d", int8(instruction.Byte))
+	case classfileparser.Sipush:
+		operands("%d", instruction.Short)
+	case classfileparser.Ldc:
+		constant(u1(), typedConstant(instruction.Value))
+	case classfileparser.LdcW:
+		constant(u2(), typedConstant(instruction.Value))
+	case classfileparser.Ldc2W:
+		constant(u2(), typedConstant(instruction.Value))
+	case classfileparser.Iload:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Lload:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Fload:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Dload:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Aload:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Istore:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Lstore:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Fstore:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Dstore:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Astore:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Ret:
+		operands("%d", instruction.LocalIndex)
+	case classfileparser.Iinc:
+		operands("%d, %d", instruction.LocalIndex, instruction.Const)
+	case classfileparser.Wide:
+		mnemonic = wideMnemonics[instruction.OpCode] + "_w"
+		if instruction.OpCode == 0x84 {
+			operands("%d, %d", instruction.LocalIndex, instruction.Const)
+		} else {
+			operands("%d", instruction.LocalIndex)
+		}
+	case classfileparser.Branch:
+		operands("%d", instruction.Target())
+	case classfileparser.Tableswitch:
+		operands("{ // %d to %d", instruction.Low, instruction.High)
+		for i, offset := range instruction.Offsets {
+			p.line(6, "%18d: %d", instruction.Low+int32(i), pc+int(offset))
+		}
+		p.line(6, "%18s: %d", "default", pc+int(instruction.DefaultOffset))
+		p.line(6, "      }")
+	case classfileparser.Lookupswitch:
+		operands("{ // %d", len(instruction.Pairs))
+		for _, pair := range instruction.Pairs {
+			p.line(6, "%18d: %d", pair.Match, pc+int(pair.Offset))
+		}
+		p.line(6, "%18s: %d", "default", pc+int(instruction.DefaultOffset))
+		p.line(6, "      }")
+	case classfileparser.Getstatic:
+		constant(u2(), "Field "+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Putstatic:
+		constant(u2(), "Field "+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Getfield:
+		constant(u2(), "Field "+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Putfield:
+		constant(u2(), "Field "+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Invokevirtual:
+		constant(u2(), "Method "+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Invokespecial:
+		constant(u2(), methodKind(instruction.Interface)+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Invokestatic:
+		constant(u2(), methodKind(instruction.Interface)+p.member(instruction.Class, instruction.Name, instruction.Type))
+	case classfileparser.Invokeinterface:
+		ref := instruction.InterfaceMethodref
+		p.commented(6, fmt.Sprintf("%4d: %-13s #%d,  %d", pc, mnemonic, u2(), instruction.Count), "InterfaceMethod "+p.member(ref.Class, ref.Name, ref.Type))
+	case classfileparser.Invokedynamic:
+		call := instruction.InvokeDynamic
+		p.commented(6, fmt.Sprintf("%4d: %-13s #%d,  0", pc, mnemonic, u2()), fmt.Sprintf("InvokeDynamic #%d:%s:%s", call.BootstrapIndex, quoteName(call.Name), call.Type))
+	case classfileparser.New:
+		constant(u2(), "class "+className(instruction.Class))
+	case classfileparser.Anewarray:
+		constant(u2(), "class "+className(instruction.Class))
+	case classfileparser.Checkcast:
+		constant(u2(), "class "+className(instruction.Class))
+	case classfileparser.Instanceof:
+		constant(u2(), "class "+className(instruction.Class))
+	case classfileparser.Multianewarray:
+		p.commented(6, fmt.Sprintf("%4d: %-13s #%d,  %d", pc, mnemonic, u2(), instruction.Dimension), "class "+className(instruction.Class))
+	case classfileparser.Newarray:
+		operands("%s", newarrayTypes[instruction.Type])
+	default:
+		p.line(6, "%4d: %s", pc, mnemonic)
+	}
+}
+
+func methodKind(isInterface bool) string {
+	if isInterface {
+		return "InterfaceMethod "
+	}
+	return "Method "
+}
+
+// member renders a member reference in an instruction comment, leaving out the class for
+// members of the disassembled class
+func (p *printer) member(class, name, descriptor string) string {
+	if class == p.class.ThisClass {
+		return quoteName(name) + ":" + descriptor
+	}
+	return memberName(class, name, descriptor)
+}
+
+// printStackMapTable prints the frames of a StackMapTable as they are encoded
+func (p *printer) printStackMapTable(table classfileparser.StackMapTable) {
+	p.line(6, "StackMapTable: number_of_entries = %d", len(table.Entries))
+	for _, frame := range table.Entries {
+		kind := frame.Kind()
+		p.line(8, "frame_type = %d /* %s */", frame.FrameType, frameKinds[kind])
+		if kind != classfileparser.SameFrame && kind != classfileparser.SameLocals1StackItemFrame {
+			p.line(10, "offset_delta = %d", frame.OffsetDelta)
+		}
+		switch kind {
+		case classfileparser.AppendFrame, classfileparser.FullFrame:
+			p.line(10, "locals = %s", stackItems(frame.Locals))
+		}
+		switch kind {
+		case classfileparser.SameLocals1StackItemFrame, classfileparser.SameLocals1StackItemFrameExtended, classfileparser.FullFrame:
+			p.line(10, "stack = %s", stackItems(frame.StackItems))
+		}
+	}
+}
+
+// stackItems renders verification types as javap does, e.g. [ int, class java/lang/String ]
+func stackItems(items []classfileparser.StackItem) string {
+	if len(items) == 0 {
+		return "[]"
+	}
+	types := make([]string, len(items))
+	for i, item := range items {
+		switch item.Tag {
+		case classfileparser.ItemTop:
+			types[i] = "top"
+		case classfileparser.ItemInteger:
+			types[i] = "int"
+		case classfileparser.ItemFloat:
+			types[i] = "float"
+		case classfileparser.ItemDouble:
+			types[i] = "double"
+		case classfileparser.ItemLong:
+			types[i] = "long"
+		case classfileparser.ItemNull:
+			types[i] = "null"
+		case classfileparser.ItemUninitializedThis:
+			types[i] = "this"
+		case classfileparser.ItemObject:
+			types[i] = "class " + className(item.Class)
+		case classfileparser.ItemUninitialized:
+			types[i] = fmt.Sprintf("uninitialized %d", item.Offset)
+		default:
+			types[i] = fmt.Sprintf("tag %d", item.Tag)
+		}
+	}
+	return "[ " + strings.Join(types, ", ") + " ]"
+}
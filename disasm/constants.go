@@ -0,0 +1,306 @@
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// constantKinds names the constant pool tags as javap does
+var constantKinds = map[uint8]string{
+	1:  "Utf8",
+	3:  "Integer",
+	4:  "Float",
+	5:  "Long",
+	6:  "Double",
+	7:  "Class",
+	8:  "String",
+	9:  "Fieldref",
+	10: "Methodref",
+	11: "InterfaceMethodref",
+	12: "NameAndType",
+	15: "MethodHandle",
+	16: "MethodType",
+	17: "Dynamic",
+	18: "InvokeDynamic",
+	19: "Module",
+	20: "Package",
+}
+
+// printConstantPool lists the constant pool with the raw indexes of each entry and what they
+// resolve to
+func (p *printer) printConstantPool() {
+	p.line(0, "Constant pool:")
+	width := len(fmt.Sprintf("#%d", len(p.file.ConstantPool))) + 2
+	for i, entry := range p.file.ConstantPool {
+		if entry.Tag == 0 {
+			continue // Second slot of a long or double
+		}
+		index := uint16(i + 1)
+		number, args, comment := fmt.Sprintf("#%d", index), p.constantOperands(index, entry), p.constantComment(index)
+		if comment == "" {
+			p.line(0, "%*s = %-18s %s", width, number, constantKinds[entry.Tag], args)
+		} else {
+			p.line(0, "%*s = %-18s %-14s // %s", width, number, constantKinds[entry.Tag], args, comment)
+		}
+	}
+}
+
+// constantOperands renders the body of a raw constant pool entry: the indexes it refers to, or
+// its value for numbers and Utf8
+func (p *printer) constantOperands(index uint16, entry classfileparser.CpInfo) string {
+	info := entry.Info
+	u2 := func(offset int) uint16 {
+		if offset+2 > len(info) {
+			return 0
+		}
+		return binary.BigEndian.Uint16(info[offset:])
+	}
+	switch entry.Tag {
+	case 1:
+		return escape(string(info))
+	case 3, 4, 5, 6:
+		return constantValue(p.cp[index])
+	case 7, 8, 16, 19, 20:
+		return fmt.Sprintf("#%d", u2(0))
+	case 9, 10, 11:
+		return fmt.Sprintf("#%d.#%d", u2(0), u2(2))
+	case 12:
+		return fmt.Sprintf("#%d:#%d", u2(0), u2(2))
+	case 15:
+		if len(info) < 3 {
+			return ""
+		}
+		return fmt.Sprintf("%d:#%d", info[0], binary.BigEndian.Uint16(info[1:]))
+	case 17, 18:
+		return fmt.Sprintf("#%d:#%d", u2(0), u2(2))
+	}
+	return ""
+}
+
+// constantComment renders what the entry at index resolves to, empty for entries holding their
+// value
+func (p *printer) constantComment(index uint16) string {
+	switch value := p.cp[index].(type) {
+	case classfileparser.Class:
+		return className(string(value))
+	case string:
+		return escape(value)
+	case classfileparser.Fieldref:
+		return memberName(value.Class, value.Name, value.Type)
+	case classfileparser.Methodref:
+		return memberName(value.Class, value.Name, value.Type)
+	case classfileparser.InterfaceMethodref:
+		return memberName(value.Class, value.Name, value.Type)
+	case classfileparser.NameAndType:
+		return quoteName(value.Name) + ":" + value.Type
+	case classfileparser.MethodHandle:
+		return methodHandle(value)
+	case classfileparser.MethodType:
+		return " " + string(value)
+	case classfileparser.Dynamic:
+		return fmt.Sprintf("#%d:%s:%s", value.BootstrapIndex, quoteName(value.Name), value.Type)
+	case classfileparser.InvokeDynamic:
+		return fmt.Sprintf("#%d:%s:%s", value.BootstrapIndex, quoteName(value.Name), value.Type)
+	case classfileparser.Module:
+		return string(value)
+	case classfileparser.Package:
+		return string(value)
+	}
+	return ""
+}
+
+// constantValue renders a loadable constant as javap lists it, e.g. 42, 1.5f, 10l or a class name
+func constantValue(value interface{}) string {
+	switch value := value.(type) {
+	case int32:
+		return strconv.Itoa(int(value))
+	case float32:
+		return javaFloat(float64(value), 32) + "f"
+	case int64:
+		return strconv.FormatInt(value, 10) + "l"
+	case float64:
+		return javaFloat(value, 64) + "d"
+	case string:
+		return escape(value)
+	case classfileparser.Class:
+		return className(string(value))
+	case classfileparser.MethodType:
+		return string(value)
+	case classfileparser.MethodHandle:
+		return methodHandle(value)
+	case classfileparser.Dynamic:
+		return fmt.Sprintf("#%d:%s:%s", value.BootstrapIndex, quoteName(value.Name), value.Type)
+	}
+	return fmt.Sprint(value)
+}
+
+// typedConstant renders a loadable constant preceded by its kind, as in the comments of ldc
+// and in ConstantValue attributes, e.g. int 42 or String hello
+func typedConstant(value interface{}) string {
+	var kind string
+	switch value.(type) {
+	case int32:
+		kind = "int"
+	case float32:
+		kind = "float"
+	case int64:
+		kind = "long"
+	case float64:
+		kind = "double"
+	case string:
+		kind = "String"
+	case classfileparser.Class:
+		kind = "class"
+	case classfileparser.MethodType:
+		kind = "MethodType"
+	case classfileparser.MethodHandle:
+		kind = "MethodHandle"
+	case classfileparser.Dynamic:
+		kind = "Dynamic"
+	}
+	return kind + " " + constantValue(value)
+}
+
+// javaFloat formats a float like Java's Float.toString and Double.toString: plain notation
+// with at least one fractional digit between 10^-3 and 10^7, computerized scientific notation
+// outside
+func javaFloat(value float64, bits int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Infinity"
+	case math.IsInf(value, -1):
+		return "-Infinity"
+	}
+	if abs := math.Abs(value); abs == 0 || (abs >= 1e-3 && abs < 1e7) {
+		s := strconv.FormatFloat(value, 'f', -1, bits)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(value, 'e', -1, bits)
+	mantissa, exponent, _ := strings.Cut(s, "e")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(n)
+}
+
+// methodHandle renders a method handle, e.g. REF_invokeStatic java/lang/Integer.valueOf:(I)Ljava/lang/Integer;
+func methodHandle(handle classfileparser.MethodHandle) string {
+	return "REF_" + handle.Kind + " " + memberName(handle.Class, handle.Name, handle.Type)
+}
+
+// memberName renders a field or method reference, e.g. java/lang/Object."<init>":()V
+func memberName(class, name, descriptor string) string {
+	return className(class) + "." + quoteName(name) + ":" + descriptor
+}
+
+// className renders a class as javap does, quoting array descriptors
+func className(name string) string {
+	if strings.HasPrefix(name, "[") {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// quoteName quotes member names that are not Java identifiers, such as <init>
+func quoteName(name string) string {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$' {
+			return `"` + name + `"`
+		}
+	}
+	return name
+}
+
+// escape decodes s, the modified UTF-8 of a CONSTANT_Utf8 entry, and renders its control and
+// non-ASCII characters as Java escapes, supplementary characters as their two surrogates
+func escape(s string) string {
+	return escapeQuoted(s, 0)
+}
+
+// escapeQuoted is escape that also escapes backslashes and the quote character of a Java
+// literal, unless quote is 0
+func escapeQuoted(s string, quote rune) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		r, size := decodeModifiedUTF8(s)
+		s = s[size:]
+		if r > 0xFFFF {
+			writeEscaped(&b, 0xD800+(r-0x10000)>>10, quote)
+			writeEscaped(&b, 0xDC00+(r-0x10000)&0x3FF, quote)
+		} else {
+			writeEscaped(&b, r, quote)
+		}
+	}
+	return b.String()
+}
+
+// decodeModifiedUTF8 decodes the first character of s and returns it with its length in bytes.
+// The two- and three-byte forms, which encode NUL as C0 80 and supplementary characters as two
+// surrogates, decode to UTF-16 code units; a four-byte UTF-8 sequence, which class files written
+// from Go strings may hold, decodes to its supplementary character. Any other byte decodes to
+// itself.
+func decodeModifiedUTF8(s string) (rune, int) {
+	continuation := func(n int) bool {
+		if len(s) <= n {
+			return false
+		}
+		for _, c := range []byte(s[1 : n+1]) {
+			if c&0xC0 != 0x80 {
+				return false
+			}
+		}
+		return true
+	}
+	c := rune(s[0])
+	switch {
+	case c < 0x80:
+		return c, 1
+	case c&0xE0 == 0xC0 && continuation(1):
+		return (c&0x1F)<<6 | rune(s[1]&0x3F), 2
+	case c&0xF0 == 0xE0 && continuation(2):
+		return (c&0x0F)<<12 | rune(s[1]&0x3F)<<6 | rune(s[2]&0x3F), 3
+	case c&0xF8 == 0xF0 && continuation(3):
+		return (c&0x07)<<18 | rune(s[1]&0x3F)<<12 | rune(s[2]&0x3F)<<6 | rune(s[3]&0x3F), 4
+	}
+	return c, 1
+}
+
+// writeEscaped writes the UTF-16 code unit r to b, escaped as in a Java literal
+func writeEscaped(b *strings.Builder, r, quote rune) {
+	switch {
+	case r == '\t':
+		b.WriteString(`\t`)
+	case r == '\n':
+		b.WriteString(`\n`)
+	case r == '\r':
+		b.WriteString(`\r`)
+	case r == '\b':
+		b.WriteString(`\b`)
+	case r == '\f':
+		b.WriteString(`\f`)
+	case quote != 0 && (r == quote || r == '\\'):
+		b.WriteRune('\\')
+		b.WriteRune(r)
+	case r < 0x20 || r >= 0x7F:
+		fmt.Fprintf(b, `\u%04x`, r)
+	default:
+		b.WriteRune(r)
+	}
+}
+
+// javaName converts an internal class name to the dotted form of Java source
+func javaName(name string) string {
+	return strings.ReplaceAll(name, "/", ".")
+}
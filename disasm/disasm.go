@@ -0,0 +1,569 @@
+// Package disasm prints class files in the text format of javap, without needing a JDK.
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// Options selects what Disassemble prints, like the flags of javap
+type Options struct {
+	Code    bool // -c: instructions and exception tables
+	Lines   bool // -l: line number and local variable tables
+	Private bool // -p: private members as well
+	// Verbose (-v) prints the version, flags and constant pool of the class, the descriptor,
+	// flags and attributes of every member, stack map frames and the class attributes. It
+	// implies Code and Lines.
+	Verbose bool
+}
+
+// Disassemble writes the class file cf to w in the format of javap. With Code, Private and
+// Verbose set, the output follows javap -c -v -p, except that the file name, modification time
+// and checksum javap reads from the file system are left out.
+func Disassemble(cf *classfileparser.ClassFile, w io.Writer, opts Options) error {
+	cp, err := cf.GetConstantPool()
+	if err != nil {
+		return err
+	}
+	class, err := cf.GetClassFile()
+	if err != nil {
+		return err
+	}
+	if opts.Verbose {
+		opts.Code, opts.Lines = true, true
+	}
+	p := &printer{w: w, opts: opts, file: cf, cp: cp, class: class}
+	p.printClass()
+	return p.err
+}
+
+// printer writes the disassembly of a class, keeping the first write error
+type printer struct {
+	w     io.Writer
+	err   error
+	opts  Options
+	file  *classfileparser.ClassFile
+	cp    classfileparser.ConstantPool
+	class *classfileparser.ClassStruct
+}
+
+// line writes a line indented by indent spaces
+func (p *printer) line(indent int, format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, "%s%s\n", strings.Repeat(" ", indent), fmt.Sprintf(format, args...))
+}
+
+// commented writes text followed by a comment aligned on the column javap uses
+func (p *printer) commented(indent int, text, comment string) {
+	p.line(indent, "%-39s // %s", text, comment)
+}
+
+// utf8 returns the Utf8 constant at index, empty when there is none
+func (p *printer) utf8(index uint16) string {
+	value, _ := p.cp[index].(classfileparser.Utf8)
+	return string(value)
+}
+
+func (p *printer) printClass() {
+	class := p.class
+	var source string
+	for _, attribute := range class.Attributes {
+		if file, ok := attribute.(classfileparser.SourceFile); ok {
+			source = file.Name
+		}
+	}
+	if source != "" {
+		p.line(0, "Compiled from %q", source)
+	}
+
+	if !p.opts.Verbose {
+		p.line(0, "%s {", p.declaration())
+	} else {
+		p.line(0, "%s", p.declaration())
+		p.line(2, "minor version: %d", class.Version.MinorVersion)
+		p.line(2, "major version: %d", class.Version.MajorVersion)
+		p.line(2, "flags: (0x%04x) %s", uint16(class.Access), class.Access)
+		p.commented(2, fmt.Sprintf("this_class: #%d", p.file.ThisClass), className(class.ThisClass))
+		if p.file.SuperClass != 0 {
+			p.commented(2, fmt.Sprintf("super_class: #%d", p.file.SuperClass), className(class.SuperClass))
+		} else {
+			p.line(2, "super_class: #0")
+		}
+		p.line(2, "interfaces: %d, fields: %d, methods: %d, attributes: %d", len(class.Interfaces), len(class.Fields), len(class.Methods), len(class.Attributes))
+		p.printConstantPool()
+		p.line(0, "{")
+	}
+
+	first := true
+	separate := func() {
+		if !first && (p.opts.Code || p.opts.Lines) {
+			p.line(0, "")
+		}
+		first = false
+	}
+	for i := range class.Fields {
+		field := &class.Fields[i]
+		if field.Access.Has(classfileparser.FieldPrivate) && !p.opts.Private {
+			continue
+		}
+		separate()
+		p.printField(field, p.file.Fields[i].Attributes)
+	}
+	for i := range class.Methods {
+		method := &class.Methods[i]
+		if method.Access.Has(classfileparser.MethodPrivate) && !p.opts.Private {
+			continue
+		}
+		separate()
+		p.printMethod(method, p.file.Methods[i].Attributes)
+	}
+	p.line(0, "}")
+
+	if p.opts.Verbose {
+		for i, attribute := range class.Attributes {
+			p.printAttribute(0, attribute, &p.file.Attributes[i])
+		}
+	}
+}
+
+// declaration renders the class declaration, e.g. public class com.x.Foo<T> extends com.x.Bar implements java.lang.Runnable
+func (p *printer) declaration() string {
+	class := p.class
+	var b strings.Builder
+	if keywords := class.Access.Keywords(); keywords != "" {
+		b.WriteString(keywords + " ")
+	}
+	if class.Access.Has(classfileparser.ClassModule) {
+		for _, attribute := range class.Attributes {
+			if module, ok := attribute.(classfileparser.ModuleInfo); ok {
+				b.WriteString("module " + module.Name)
+				if module.Version != "" {
+					b.WriteString("@" + module.Version)
+				}
+				return b.String()
+			}
+		}
+		return b.String() + "module " + javaName(class.ThisClass)
+	}
+	if class.Access.Has(classfileparser.ClassInterface) {
+		b.WriteString("interface ")
+	} else {
+		b.WriteString("class ")
+	}
+	b.WriteString(javaName(class.ThisClass))
+
+	super := javaName(class.SuperClass)
+	interfaces := make([]string, len(class.Interfaces))
+	for i, iface := range class.Interfaces {
+		interfaces[i] = javaName(iface)
+	}
+	if signature := class.Signature; signature != nil {
+		if len(signature.TypeParameters) > 0 {
+			parameters := make([]string, len(signature.TypeParameters))
+			for i, parameter := range signature.TypeParameters {
+				parameters[i] = parameter.JavaName()
+			}
+			b.WriteString("<" + strings.Join(parameters, ", ") + ">")
+		}
+		super = signature.Superclass.JavaName()
+		interfaces = make([]string, len(signature.Interfaces))
+		for i, iface := range signature.Interfaces {
+			interfaces[i] = iface.JavaName()
+		}
+	}
+	if class.Access.Has(classfileparser.ClassInterface) {
+		if len(interfaces) > 0 {
+			b.WriteString(" extends " + strings.Join(interfaces, ", "))
+		}
+		return b.String()
+	}
+	if super != "" && super != "java.lang.Object" {
+		b.WriteString(" extends " + super)
+	}
+	if len(interfaces) > 0 {
+		b.WriteString(" implements " + strings.Join(interfaces, ", "))
+	}
+	return b.String()
+}
+
+func (p *printer) printField(field *classfileparser.Field, raw []classfileparser.AttributeInfo) {
+	declaration := field.Descriptor.JavaName()
+	if field.Signature != nil {
+		declaration = field.Signature.JavaName()
+	}
+	if keywords := field.Access.Keywords(); keywords != "" {
+		declaration = keywords + " " + declaration
+	}
+	p.line(2, "%s %s;", declaration, field.Name)
+	if !p.opts.Verbose {
+		return
+	}
+	p.line(4, "descriptor: %s", field.Type)
+	p.line(4, "flags: (0x%04x) %s", uint16(field.Access), field.Access)
+	for i, attribute := range field.Attributes {
+		p.printAttribute(4, attribute, &raw[i])
+	}
+}
+
+func (p *printer) printMethod(method *classfileparser.Method, raw []classfileparser.AttributeInfo) {
+	p.line(2, "%s;", p.methodDeclaration(method))
+	if p.opts.Verbose {
+		p.line(4, "descriptor: %s", method.Type)
+		p.line(4, "flags: (0x%04x) %s", uint16(method.Access), method.Access)
+	}
+	for i, attribute := range method.Attributes {
+		code, ok := attribute.(classfileparser.Code)
+		switch {
+		case ok && p.opts.Code:
+			p.printCode(method, code, codeBytes(raw[i]))
+		case ok && p.opts.Lines:
+			for _, nested := range code.Attributes {
+				p.printDebugTable(4, nested)
+			}
+		case !ok && p.opts.Verbose:
+			p.printAttribute(4, attribute, &raw[i])
+		}
+	}
+}
+
+// codeBytes returns the bytecode held by a raw Code attribute
+func codeBytes(attribute classfileparser.AttributeInfo) []byte {
+	info := attribute.Info
+	if len(info) < 8 {
+		return nil
+	}
+	length := int(binary.BigEndian.Uint32(info[4:]))
+	if 8+length > len(info) {
+		return nil
+	}
+	return info[8 : 8+length]
+}
+
+// methodDeclaration renders a method as javap declares it, e.g. public static void main(java.lang.String...) throws java.io.IOException
+func (p *printer) methodDeclaration(method *classfileparser.Method) string {
+	name := method.Name
+	switch name {
+	case "<clinit>":
+		return "static {}"
+	case "<init>":
+		name = javaName(p.class.ThisClass)
+	}
+
+	var declaration string
+	var throws []string
+	if method.Signature != nil {
+		declaration = method.Signature.JavaDeclaration(name)
+	} else {
+		declaration = method.Descriptor.JavaDeclaration(name)
+	}
+	if method.Signature == nil || len(method.Signature.Throws) == 0 {
+		for _, attribute := range method.Attributes {
+			if exceptions, ok := attribute.(classfileparser.Exceptions); ok {
+				for _, exception := range exceptions.Exceptions {
+					throws = append(throws, javaName(exception))
+				}
+			}
+		}
+	}
+	if len(throws) > 0 {
+		declaration += " throws " + strings.Join(throws, ", ")
+	}
+	if method.Name == "<init>" {
+		declaration = strings.Replace(declaration, "void "+name+"(", name+"(", 1)
+	}
+	if method.Access.Has(classfileparser.MethodVarargs) {
+		if end := strings.Index(declaration, ")"); end >= 2 && declaration[end-2:end] == "[]" {
+			declaration = declaration[:end-2] + "..." + declaration[end:]
+		}
+	}
+	if keywords := method.Access.Keywords(); keywords != "" {
+		declaration = keywords + " " + declaration
+	}
+	return declaration
+}
+
+// printAttribute prints an attribute in verbose mode. raw is the undecoded attribute, used for
+// the constant pool indexes javap shows, or nil for the attributes nested in Code.
+func (p *printer) printAttribute(indent int, attribute classfileparser.Attribute, raw *classfileparser.AttributeInfo) {
+	switch attribute := attribute.(type) {
+	case classfileparser.ConstantValue:
+		p.line(indent, "ConstantValue: %s", typedConstant(attribute.Value))
+	case classfileparser.Deprecated:
+		p.line(indent, "Deprecated: true")
+	case classfileparser.Synthetic:
+		p.line(indent, "Synthetic: true")
+	case classfileparser.Exceptions:
+		p.line(indent, "Exceptions:")
+		names := make([]string, len(attribute.Exceptions))
+		for i, exception := range attribute.Exceptions {
+			names[i] = javaName(exception)
+		}
+		p.line(indent+2, "throws %s", strings.Join(names, ", "))
+	case classfileparser.Signature:
+		if raw != nil && len(raw.Info) >= 2 {
+			p.commented(indent, fmt.Sprintf("Signature: #%d", binary.BigEndian.Uint16(raw.Info)), string(attribute))
+		} else {
+			p.line(indent, "Signature: %s", string(attribute))
+		}
+	case classfileparser.SourceFile:
+		p.line(indent, "SourceFile: %q", attribute.Name)
+	case classfileparser.SourceDebugExtension:
+		p.line(indent, "SourceDebugExtension:")
+		for _, line := range strings.Split(strings.TrimRight(string(attribute.DebugExtension), "\n"), "\n") {
+			p.line(indent+2, "%s", line)
+		}
+	case classfileparser.InnerClasses:
+		p.printInnerClasses(indent, attribute)
+	case classfileparser.EnclosingMethod:
+		comment := attribute.Class
+		if attribute.MethodName != "" {
+			comment += "." + quoteName(attribute.MethodName)
+		}
+		p.commented(indent, fmt.Sprintf("EnclosingMethod: #%d.#%d", attribute.ClassIndex, attribute.MethodIndex), comment)
+	case classfileparser.NestHost:
+		p.line(indent, "NestHost: class %s", attribute.HostClass)
+	case classfileparser.NestMembers:
+		p.line(indent, "NestMembers:")
+		for _, member := range attribute.Classes {
+			p.line(indent+2, "%s", member)
+		}
+	case classfileparser.PermittedSubclasses:
+		p.line(indent, "PermittedSubclasses:")
+		for _, subclass := range attribute.Subclasses {
+			p.line(indent+2, "%s", subclass)
+		}
+	case classfileparser.BootstrapMethods:
+		p.line(indent, "BootstrapMethods:")
+		for i, method := range attribute.BootstrapMethods {
+			p.line(indent+2, "%d: #%d %s", i, method.MethodRefIndex, methodHandle(method.Method))
+			p.line(indent+4, "Method arguments:")
+			for j, argument := range method.StaticArguments {
+				p.line(indent+6, "#%d %s", method.Arguments[j], constantValue(argument))
+			}
+		}
+	case classfileparser.MethodParameters:
+		p.line(indent, "MethodParameters:")
+		p.line(indent+2, "%-30s %s", "Name", "Flags")
+		for _, parameter := range attribute.MethodParameters {
+			name := parameter.Name
+			if name == "" {
+				name = "<no name>"
+			}
+			p.line(indent+2, "%-30s %s", name, parameter.AccessFlags.Keywords())
+		}
+	case classfileparser.ModulePackages:
+		p.line(indent, "ModulePackages:")
+		for i, name := range attribute.Packages {
+			p.commented(indent+2, fmt.Sprintf("#%d", attribute.PackageIndex[i]), name)
+		}
+	case classfileparser.ModuleInfo:
+		p.printModule(indent, attribute)
+	case classfileparser.RuntimeVisibleAnnotations:
+		p.printAnnotations(indent, "RuntimeVisibleAnnotations", attribute.Annotations)
+	case classfileparser.RuntimeInvisibleAnnotations:
+		p.printAnnotations(indent, "RuntimeInvisibleAnnotations", attribute.Annotations)
+	case classfileparser.RuntimeVisibleParameterAnnotations:
+		p.printParameterAnnotations(indent, "RuntimeVisibleParameterAnnotations", attribute.ParameterAnnotations)
+	case classfileparser.RuntimeInvisibleParameterAnnotations:
+		p.printParameterAnnotations(indent, "RuntimeInvisibleParameterAnnotations", attribute.ParameterAnnotations)
+	case classfileparser.RuntimeVisibleTypeAnnotations:
+		p.printTypeAnnotations(indent, "RuntimeVisibleTypeAnnotations", attribute.Annotations)
+	case classfileparser.RuntimeInvisibleTypeAnnotations:
+		p.printTypeAnnotations(indent, "RuntimeInvisibleTypeAnnotations", attribute.Annotations)
+	case classfileparser.AnnotationDefault:
+		p.line(indent, "AnnotationDefault:")
+		p.line(indent+2, "default_value: %s", elementValueIndices(attribute.Value))
+		p.source(indent+4, elementValue(attribute.Value))
+	case classfileparser.LineNumberTable, classfileparser.LocalVariableTable, classfileparser.LocalVariableTypeTable:
+		p.printDebugTable(indent, attribute)
+	case classfileparser.RawAttribute:
+		p.line(indent, "%s: length = 0x%x", attribute.Name, len(attribute.Info))
+		for start := 0; start < len(attribute.Info); start += 16 {
+			end := min(start+16, len(attribute.Info))
+			bytes := make([]string, 0, 16)
+			for _, b := range attribute.Info[start:end] {
+				bytes = append(bytes, fmt.Sprintf("%02x", b))
+			}
+			p.line(indent+1, "%s", strings.Join(bytes, " "))
+		}
+	}
+}
+
+func (p *printer) printInnerClasses(indent int, attribute classfileparser.InnerClasses) {
+	p.line(indent, "InnerClasses:")
+	for _, inner := range attribute.InnerClassInfo {
+		var text, comment strings.Builder
+		if keywords := inner.InnerClassAccessFlags.Keywords(); keywords != "" {
+			text.WriteString(keywords + " ")
+		}
+		if inner.InnerNameIndex != 0 {
+			fmt.Fprintf(&text, "#%d= ", inner.InnerNameIndex)
+			comment.WriteString(inner.InnerName + "=")
+		}
+		fmt.Fprintf(&text, "#%d", inner.InnerClassIndex)
+		comment.WriteString("class " + className(inner.InnerClass))
+		if inner.OuterClassIndex != 0 {
+			fmt.Fprintf(&text, " of #%d", inner.OuterClassIndex)
+			comment.WriteString(" of class " + className(inner.OuterClass))
+		}
+		text.WriteString(";")
+		p.commented(indent+2, text.String(), comment.String())
+	}
+}
+
+func (p *printer) printModule(indent int, module classfileparser.ModuleInfo) {
+	p.line(indent, "Module:")
+	name := module.Name
+	if module.Version != "" {
+		name += "@" + module.Version
+	}
+	p.commented(indent+2, fmt.Sprintf("#%d,%x", module.NameIndex, uint16(module.Flags)), fmt.Sprintf("%s %s", name, module.Flags))
+	for _, require := range module.Requires {
+		p.commented(indent+2, fmt.Sprintf("requires #%d,%x", require.NameIndex, uint16(require.Flags)), strings.TrimSpace(require.Name+" "+require.Flags.String()))
+	}
+	for _, export := range module.Exports {
+		p.commented(indent+2, fmt.Sprintf("exports #%d,%x", export.PackageIndex, uint16(export.Flags)), strings.TrimSpace(export.Package+" "+strings.Join(export.To, ", ")))
+	}
+	for _, open := range module.Opens {
+		p.commented(indent+2, fmt.Sprintf("opens #%d,%x", open.PackageIndex, uint16(open.Flags)), strings.TrimSpace(open.Package+" "+strings.Join(open.To, ", ")))
+	}
+	for i, use := range module.Uses {
+		p.commented(indent+2, fmt.Sprintf("uses #%d", module.UsesIndex[i]), use)
+	}
+	for _, provide := range module.Provides {
+		p.commented(indent+2, fmt.Sprintf("provides #%d", provide.ServiceIndex), provide.Service+" with "+strings.Join(provide.With, ", "))
+	}
+}
+
+// printAnnotations prints annotations as javap -v does, each as its constant pool indices
+// followed by its source form
+func (p *printer) printAnnotations(indent int, name string, annotations []classfileparser.Annotation) {
+	p.line(indent, "%s:", name)
+	for i, annotation := range annotations {
+		p.line(indent+2, "%d: %s", i, annotationIndices(annotation))
+		p.source(indent+4, annotationSource(annotation))
+	}
+}
+
+func (p *printer) printParameterAnnotations(indent int, name string, parameters []classfileparser.ParameterAnnotation) {
+	p.line(indent, "%s:", name)
+	for i, parameter := range parameters {
+		p.line(indent+2, "parameter %d:", i)
+		for j, annotation := range parameter.Annotations {
+			p.line(indent+4, "%d: %s", j, annotationIndices(annotation))
+			p.source(indent+6, annotationSource(annotation))
+		}
+	}
+}
+
+func (p *printer) printTypeAnnotations(indent int, name string, annotations []classfileparser.TypeAnnotation) {
+	p.line(indent, "%s:", name)
+	for i, annotation := range annotations {
+		p.commented(indent+2, fmt.Sprintf("%d: %s", i, annotationIndices(annotation.Annotation)), fmt.Sprintf("target_type = 0x%02x", annotation.TargetType))
+		p.source(indent+4, annotationSource(annotation.Annotation))
+	}
+}
+
+// source prints the lines of a source form indented by indent spaces
+func (p *printer) source(indent int, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		p.line(indent, "%s", line)
+	}
+}
+
+// annotationIndices renders an annotation by its constant pool indices, e.g. #83(#84=s#85)
+func annotationIndices(annotation classfileparser.Annotation) string {
+	pairs := make([]string, len(annotation.ElementValuePairs))
+	for i, pair := range annotation.ElementValuePairs {
+		pairs[i] = fmt.Sprintf("#%d=%s", pair.ElementNameIndex, elementValueIndices(pair.Value))
+	}
+	return fmt.Sprintf("#%d(%s)", annotation.TypeIndex, strings.Join(pairs, ","))
+}
+
+// elementValueIndices renders an annotation element value by its tag and constant pool indices
+func elementValueIndices(value classfileparser.ElementValue) string {
+	switch v := value.Value.(type) {
+	case classfileparser.EnumValue:
+		return fmt.Sprintf("e#%d.#%d", value.Index, value.NameIndex)
+	case classfileparser.Annotation:
+		return "@" + annotationIndices(v)
+	case []classfileparser.ElementValue:
+		values := make([]string, len(v))
+		for i, element := range v {
+			values[i] = elementValueIndices(element)
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+	return fmt.Sprintf("%c#%d", value.Tag, value.Index)
+}
+
+// annotationSource renders an annotation as javap writes it in Java source form, one element per
+// line, e.g.
+//
+//	java.lang.Deprecated(
+//	  since="9"
+//	)
+func annotationSource(annotation classfileparser.Annotation) string {
+	name := descriptorName(annotation.Type)
+	if len(annotation.ElementValuePairs) == 0 {
+		return name
+	}
+	lines := []string{name + "("}
+	for _, pair := range annotation.ElementValuePairs {
+		for _, line := range strings.Split(pair.Name+"="+elementValue(pair.Value), "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return strings.Join(append(lines, ")"), "\n")
+}
+
+// elementValue renders an annotation element value as javap writes it in Java source form
+func elementValue(value classfileparser.ElementValue) string {
+	switch v := value.Value.(type) {
+	case int8:
+		return fmt.Sprintf("(byte) %d", v)
+	case int16:
+		return fmt.Sprintf("(short) %d", v)
+	case uint16:
+		var b strings.Builder
+		writeEscaped(&b, rune(v), '\'')
+		return "'" + b.String() + "'"
+	case float32, float64, int64:
+		return constantValue(v)
+	case string:
+		return `"` + escapeQuoted(v, '"') + `"`
+	case classfileparser.EnumValue:
+		return descriptorName(v.Type) + "." + v.Name
+	case classfileparser.ClassValue:
+		return descriptorName(string(v)) + ".class"
+	case classfileparser.Annotation:
+		return "@" + annotationSource(v)
+	case []classfileparser.ElementValue:
+		values := make([]string, len(v))
+		for i, element := range v {
+			values[i] = elementValue(element)
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+	return fmt.Sprint(value.Value)
+}
+
+// descriptorName renders a field descriptor as a Java type, e.g. Ljava/lang/String; as java.lang.String
+func descriptorName(descriptor string) string {
+	dimensions := strings.Count(descriptor, "[")
+	element := strings.TrimLeft(descriptor, "[")
+	var name string
+	switch {
+	case strings.HasPrefix(element, "L") && strings.HasSuffix(element, ";"):
+		name = javaName(element[1 : len(element)-1])
+	default:
+		name = map[string]string{"B": "byte", "C": "char", "D": "double", "F": "float", "I": "int", "J": "long", "S": "short", "Z": "boolean", "V": "void"}[element]
+	}
+	return name + strings.Repeat("[]", dimensions)
+}
@@ -0,0 +1,95 @@
+package disasm
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Slummp/classfileparser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestDisassembleGolden disassembles each class of the repository testdata with javap -c -v -p
+// options and compares the output with testdata/<class>.javap
+func TestDisassembleGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "*.class"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no class in testdata: %v", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".class")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := classfileparser.Open(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := Disassemble(file, &out, Options{Code: true, Private: true, Verbose: true}); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", name+".javap")
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != string(want) {
+				t.Errorf("output differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"tab\there\n", `tab\there\n`},
+		{"\xc3\xa9\xc0\x80\xed\xa0\xbd\xed\xb8\x80", `\u00e9\u0000\ud83d\ude00`},
+		{"\U0001F600", `\ud83d\ude00`},
+		{"\xff\xc3", `\u00ff\u00c3`},
+	}
+	for _, test := range tests {
+		if got := escape(test.in); got != test.want {
+			t.Errorf("escape(%q) = %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestElementValue(t *testing.T) {
+	tests := []struct {
+		value classfileparser.ElementValue
+		want  string
+	}{
+		{classfileparser.ElementValue{Tag: 'B', Value: int8(-1)}, "(byte) -1"},
+		{classfileparser.ElementValue{Tag: 'S', Value: int16(300)}, "(short) 300"},
+		{classfileparser.ElementValue{Tag: 'C', Value: uint16('\'')}, `'\''`},
+		{classfileparser.ElementValue{Tag: 'C', Value: uint16(0xe9)}, `'\u00e9'`},
+		{classfileparser.ElementValue{Tag: 'J', Value: int64(7)}, "7l"},
+		{classfileparser.ElementValue{Tag: 'F', Value: float32(2)}, "2.0f"},
+		{classfileparser.ElementValue{Tag: 's', Value: "a\"b\\c\xc0\x80\xed\xa0\xbd\xed\xb8\x80"}, `"a\"b\\c\u0000\ud83d\ude00"`},
+		{classfileparser.ElementValue{Tag: '[', Value: []classfileparser.ElementValue{
+			{Tag: 'e', Value: classfileparser.EnumValue{Type: "Ljava/lang/annotation/ElementType;", Name: "TYPE"}},
+			{Tag: 'c', Value: classfileparser.ClassValue("[Ljava/lang/String;")},
+		}}, "[java.lang.annotation.ElementType.TYPE,java.lang.String[].class]"},
+	}
+	for _, test := range tests {
+		if got := elementValue(test.value); got != test.want {
+			t.Errorf("elementValue(%v) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
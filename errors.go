@@ -0,0 +1,54 @@
+package classfileparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecodeError reports which part of a class file could not be decoded
+type DecodeError struct {
+	Class     string // Internal name of the class, when known
+	Member    string // Field name or method name and descriptor, empty for class-level data
+	Attribute string // Attribute name, nested attributes are joined with dots (e.g. Code.LineNumberTable)
+	Offset    int    // Byte offset inside the innermost attribute's info where decoding failed
+	Err       error  // Underlying cause
+}
+
+func (e *DecodeError) Error() string {
+	var location []string
+	if e.Class != "" {
+		location = append(location, "class "+e.Class)
+	}
+	if e.Member != "" {
+		location = append(location, "member "+e.Member)
+	}
+	if e.Attribute != "" {
+		location = append(location, fmt.Sprintf("attribute %s at offset %d", e.Attribute, e.Offset))
+	}
+	if len(location) == 0 {
+		return fmt.Sprintf("failed to decode class file: %v", e.Err)
+	}
+	return fmt.Sprintf("failed to decode %s: %v", strings.Join(location, ", "), e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// withContext returns err located in the class and member. A *DecodeError is copied rather than
+// modified, so that errors already returned keep their values, and other errors are wrapped.
+func withContext(err error, class, member string) error {
+	decodeErr := asDecodeError(err)
+	decodeErr.Class = class
+	decodeErr.Member = member
+	return decodeErr
+}
+
+// asDecodeError returns a copy of err if it is a *DecodeError, or err wrapped in a new one
+func asDecodeError(err error) *DecodeError {
+	if decodeErr, ok := err.(*DecodeError); ok {
+		copied := *decodeErr
+		return &copied
+	}
+	return &DecodeError{Err: err}
+}
@@ -0,0 +1,50 @@
+package classfileparser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWithContextCopies(t *testing.T) {
+	inner := &DecodeError{Attribute: "Code", Offset: 3, Err: io.ErrUnexpectedEOF}
+	err := withContext(inner, "A", "m()V")
+
+	if inner.Class != "" || inner.Member != "" {
+		t.Errorf("withContext modified the error it was given: %+v", inner)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Class != "A" || decodeErr.Member != "m()V" || decodeErr.Attribute != "Code" || decodeErr.Offset != 3 {
+		t.Fatalf("error = %#v", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("the cause is not in the chain")
+	}
+
+	wrapped := withContext(io.EOF, "A", "")
+	if !errors.As(wrapped, &decodeErr) || decodeErr.Class != "A" || !errors.Is(wrapped, io.EOF) {
+		t.Errorf("error = %#v", wrapped)
+	}
+}
+
+func TestGetClassFileErrorContext(t *testing.T) {
+	// The class attribute is named by constant #2, a CONSTANT_Class
+	data := minimalClass([]byte{0, 2, 0, 0, 0, 0})
+	cf, err := Open(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cf.GetClassFile()
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Class != "A" {
+		t.Fatalf("error = %v", err)
+	}
+	if !strings.Contains(err.Error(), "class A") || !strings.Contains(err.Error(), "invalid attribute name") {
+		t.Errorf("error = %v", err)
+	}
+	if errors.Unwrap(err) == nil || errors.As(errors.Unwrap(err), &decodeErr) {
+		t.Errorf("error is wrapped in several DecodeErrors: %#v", err)
+	}
+}
@@ -0,0 +1,374 @@
+// Package hierarchy links a set of classes through their superclasses and interfaces and
+// answers subtype queries over them.
+package hierarchy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/classpath"
+)
+
+// Object is the root of the class hierarchy
+const Object = "java/lang/Object"
+
+// MissingError reports a class that is referenced as a supertype but was not added to the hierarchy
+type MissingError struct {
+	Name string // Internal name of the missing class
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("class %s is missing from the hierarchy", e.Name)
+}
+
+// Hierarchy is a graph of classes linked to their superclass and interfaces
+type Hierarchy struct {
+	classes      map[string]*classfileparser.ClassStruct
+	subclasses   map[string][]string // Classes by direct superclass
+	implementers map[string][]string // Classes and interfaces by direct superinterface
+}
+
+// New returns the hierarchy of classes
+func New(classes ...*classfileparser.ClassStruct) *Hierarchy {
+	h := &Hierarchy{
+		classes:      map[string]*classfileparser.ClassStruct{},
+		subclasses:   map[string][]string{},
+		implementers: map[string][]string{},
+	}
+	for _, class := range classes {
+		h.Add(class)
+	}
+	return h
+}
+
+// Add adds class to the hierarchy. A class already present with the same name is kept.
+func (h *Hierarchy) Add(class *classfileparser.ClassStruct) {
+	if _, ok := h.classes[class.ThisClass]; ok {
+		return
+	}
+	h.classes[class.ThisClass] = class
+	if class.SuperClass != "" {
+		h.subclasses[class.SuperClass] = append(h.subclasses[class.SuperClass], class.ThisClass)
+	}
+	for _, iface := range class.Interfaces {
+		h.implementers[iface] = append(h.implementers[iface], class.ThisClass)
+	}
+}
+
+// Load adds the classes called names and, transitively, their supertypes, taking them from cp.
+// Classes that are not on the class path are left missing, other errors are returned.
+func (h *Hierarchy) Load(cp *classpath.ClassPath, names ...string) error {
+	queue := append([]string(nil), names...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := h.classes[name]; ok || strings.HasPrefix(name, "[") {
+			continue
+		}
+		class, err := cp.Class(name)
+		if errors.Is(err, classpath.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.Add(class)
+		if class.SuperClass != "" {
+			queue = append(queue, class.SuperClass)
+		}
+		queue = append(queue, class.Interfaces...)
+	}
+	return nil
+}
+
+// Class returns the class called name
+func (h *Hierarchy) Class(name string) (*classfileparser.ClassStruct, bool) {
+	class, ok := h.classes[name]
+	return class, ok
+}
+
+// Classes returns the names of the classes of the hierarchy, sorted
+func (h *Hierarchy) Classes() []string {
+	names := make([]string, 0, len(h.classes))
+	for name := range h.classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Missing returns the classes referenced as a superclass or an interface that are not in the
+// hierarchy, sorted
+func (h *Hierarchy) Missing() []string {
+	missing := map[string]bool{}
+	for _, class := range h.classes {
+		for _, super := range append([]string{class.SuperClass}, class.Interfaces...) {
+			if _, ok := h.classes[super]; super != "" && !ok {
+				missing[super] = true
+			}
+		}
+	}
+	return sortedKeys(missing)
+}
+
+// IsInterface reports whether name is an interface of the hierarchy
+func (h *Hierarchy) IsInterface(name string) bool {
+	class, ok := h.classes[name]
+	return ok && class.Access.Has(classfileparser.ClassInterface)
+}
+
+// Superclasses returns the superclass chain of name, from its direct superclass up to
+// java/lang/Object. If a class of the chain is missing, the chain found so far is returned
+// along with a *MissingError.
+func (h *Hierarchy) Superclasses(name string) ([]string, error) {
+	class, ok := h.classes[name]
+	if !ok {
+		return nil, &MissingError{Name: name}
+	}
+	var chain []string
+	for class.SuperClass != "" {
+		chain = append(chain, class.SuperClass)
+		if class, ok = h.classes[class.SuperClass]; !ok {
+			return chain, &MissingError{Name: chain[len(chain)-1]}
+		}
+		if len(chain) > len(h.classes) {
+			return chain, fmt.Errorf("circular superclass chain for %s", name)
+		}
+	}
+	return chain, nil
+}
+
+// Supertypes returns every superclass and superinterface of name, direct or not, in breadth-first
+// order. If some supertypes are missing, the ones found are returned along with a *MissingError
+// for the first missing class.
+func (h *Hierarchy) Supertypes(name string) ([]string, error) {
+	class, ok := h.classes[name]
+	if !ok {
+		return nil, &MissingError{Name: name}
+	}
+	var supertypes []string
+	var missing error
+	seen := map[string]bool{name: true}
+	queue := []*classfileparser.ClassStruct{class}
+	for len(queue) > 0 {
+		class := queue[0]
+		queue = queue[1:]
+		for _, super := range append([]string{class.SuperClass}, class.Interfaces...) {
+			if super == "" || seen[super] {
+				continue
+			}
+			seen[super] = true
+			supertypes = append(supertypes, super)
+			if next, ok := h.classes[super]; ok {
+				queue = append(queue, next)
+			} else if missing == nil {
+				missing = &MissingError{Name: super}
+			}
+		}
+	}
+	return supertypes, missing
+}
+
+// DirectSubclasses returns the classes of the hierarchy whose superclass is name, sorted
+func (h *Hierarchy) DirectSubclasses(name string) []string {
+	subclasses := append([]string(nil), h.subclasses[name]...)
+	sort.Strings(subclasses)
+	return subclasses
+}
+
+// Subclasses returns the classes of the hierarchy that extend name directly or not, sorted
+func (h *Hierarchy) Subclasses(name string) []string {
+	found := map[string]bool{}
+	h.collectSubclasses(name, found)
+	return sortedKeys(found)
+}
+
+func (h *Hierarchy) collectSubclasses(name string, found map[string]bool) {
+	for _, subclass := range h.subclasses[name] {
+		if !found[subclass] {
+			found[subclass] = true
+			h.collectSubclasses(subclass, found)
+		}
+	}
+}
+
+// DirectImplementors returns the classes and interfaces of the hierarchy that list the interface
+// name among their interfaces, sorted
+func (h *Hierarchy) DirectImplementors(name string) []string {
+	implementers := append([]string(nil), h.implementers[name]...)
+	sort.Strings(implementers)
+	return implementers
+}
+
+// Implementors returns the classes of the hierarchy that implement the interface name, directly,
+// through a superclass or through a subinterface, sorted. Interfaces are not included.
+func (h *Hierarchy) Implementors(name string) []string {
+	found := map[string]bool{}
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		iface := queue[0]
+		queue = queue[1:]
+		for _, implementer := range h.implementers[iface] {
+			if seen[implementer] {
+				continue
+			}
+			seen[implementer] = true
+			if h.IsInterface(implementer) {
+				queue = append(queue, implementer)
+				continue
+			}
+			found[implementer] = true
+			h.collectSubclasses(implementer, found)
+		}
+	}
+	return sortedKeys(found)
+}
+
+// IsAssignableFrom reports whether a value of type source can be assigned to type target, that
+// is whether target is source or one of its supertypes. Both are internal names, or array
+// descriptors such as [Ljava/lang/String; as found in CONSTANT_Class entries. When the answer
+// depends on classes missing from the hierarchy, false is returned along with a *MissingError.
+func (h *Hierarchy) IsAssignableFrom(target, source string) (bool, error) {
+	if target == source || target == Object {
+		return true, nil
+	}
+	if strings.HasPrefix(source, "[") {
+		if target == "java/lang/Cloneable" || target == "java/io/Serializable" {
+			return true, nil
+		}
+		if !strings.HasPrefix(target, "[") {
+			return false, nil
+		}
+		sourceElement, sourceRef := arrayComponent(source)
+		targetElement, targetRef := arrayComponent(target)
+		if !sourceRef || !targetRef {
+			// Arrays of primitives are only assignable to the identical array type
+			return false, nil
+		}
+		return h.IsAssignableFrom(targetElement, sourceElement)
+	}
+	if strings.HasPrefix(target, "[") {
+		return false, nil
+	}
+
+	supertypes, err := h.Supertypes(source)
+	for _, super := range supertypes {
+		if super == target {
+			return true, nil
+		}
+	}
+	return false, err
+}
+
+// CommonSuperclass returns the most specific class that both a and b are or extend, the way
+// class types merge in StackMapTable frames: java/lang/Object when either is an interface.
+// Two arrays of references merge element-wise into an array of the common superclass of their
+// components, and any other pair involving an array merges into java/lang/Object.
+// A *MissingError is returned when a superclass chain cannot be followed to java/lang/Object.
+func (h *Hierarchy) CommonSuperclass(a, b string) (string, error) {
+	if name, ok, err := commonArray(a, b, h.CommonSuperclass); ok {
+		return name, err
+	}
+	if h.IsInterface(a) || h.IsInterface(b) {
+		return Object, nil
+	}
+	chainA, err := h.Superclasses(a)
+	if err != nil {
+		return "", err
+	}
+	chainB, err := h.Superclasses(b)
+	if err != nil {
+		return "", err
+	}
+	ancestors := map[string]bool{a: true}
+	for _, super := range chainA {
+		ancestors[super] = true
+	}
+	for _, super := range append([]string{b}, chainB...) {
+		if ancestors[super] {
+			return super, nil
+		}
+	}
+	return Object, nil
+}
+
+// Resolver computes common superclasses over a hierarchy, loading the classes it lacks from a
+// class path
+type Resolver struct {
+	hierarchy *Hierarchy
+	classPath *classpath.ClassPath
+}
+
+// NewResolver returns a resolver over h that loads missing classes and their supertypes from cp
+// into h. cp may be nil to use h alone.
+func NewResolver(h *Hierarchy, cp *classpath.ClassPath) *Resolver {
+	return &Resolver{hierarchy: h, classPath: cp}
+}
+
+// CommonSuperclass returns the most specific class that both a and b are or extend, see
+// Hierarchy.CommonSuperclass
+func (r *Resolver) CommonSuperclass(a, b string) (string, error) {
+	if name, ok, err := commonArray(a, b, r.CommonSuperclass); ok {
+		return name, err
+	}
+	if r.classPath != nil {
+		if err := r.hierarchy.Load(r.classPath, a, b); err != nil {
+			return "", err
+		}
+	}
+	return r.hierarchy.CommonSuperclass(a, b)
+}
+
+// commonArray merges a and b when they are equal or either is an array, using merge for the
+// components of two arrays of references, and reports whether it did
+func commonArray(a, b string, merge func(a, b string) (string, error)) (string, bool, error) {
+	aArray, bArray := strings.HasPrefix(a, "["), strings.HasPrefix(b, "[")
+	switch {
+	case a == b:
+		return a, true, nil
+	case !aArray && !bArray:
+		return "", false, nil
+	case !aArray || !bArray:
+		return Object, true, nil
+	}
+	aComponent, aReference := arrayComponent(a)
+	bComponent, bReference := arrayComponent(b)
+	if !aReference || !bReference {
+		return Object, true, nil
+	}
+	component, err := merge(aComponent, bComponent)
+	if err != nil {
+		return "", true, err
+	}
+	if strings.HasPrefix(component, "[") {
+		return "[" + component, true, nil
+	}
+	return "[L" + component + ";", true, nil
+}
+
+// arrayComponent returns the component type of an array descriptor as a name usable with
+// IsAssignableFrom, and whether it is a reference type
+func arrayComponent(array string) (string, bool) {
+	component := array[1:]
+	switch {
+	case strings.HasPrefix(component, "["):
+		return component, true
+	case strings.HasPrefix(component, "L") && strings.HasSuffix(component, ";"):
+		return component[1 : len(component)-1], true
+	default:
+		return component, false
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,251 @@
+package hierarchy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Slummp/classfileparser"
+	"github.com/Slummp/classfileparser/classpath"
+)
+
+func class(name, super string, interfaces ...string) *classfileparser.ClassStruct {
+	return &classfileparser.ClassStruct{ThisClass: name, SuperClass: super, Interfaces: interfaces, Access: classfileparser.ClassPublic}
+}
+
+func iface(name string, interfaces ...string) *classfileparser.ClassStruct {
+	c := class(name, Object, interfaces...)
+	c.Access |= classfileparser.ClassInterface | classfileparser.ClassAbstract
+	return c
+}
+
+// testHierarchy is
+//
+//	Object
+//	├── A implements I
+//	│   ├── B
+//	│   │   └── D
+//	│   └── C implements K
+//	└── E extends Missing
+//	J extends I, K extends J
+func testHierarchy() *Hierarchy {
+	return New(
+		class(Object, ""),
+		iface("I"),
+		iface("J", "I"),
+		iface("K", "J"),
+		class("A", Object, "I"),
+		class("B", "A"),
+		class("C", "A", "K"),
+		class("D", "B"),
+		class("E", "Missing"),
+		class("F", Object, "Unknown"),
+	)
+}
+
+func TestSuperclasses(t *testing.T) {
+	h := testHierarchy()
+	tests := []struct {
+		name    string
+		want    []string
+		missing string
+	}{
+		{Object, nil, ""},
+		{"A", []string{Object}, ""},
+		{"D", []string{"B", "A", Object}, ""},
+		{"E", []string{"Missing"}, "Missing"},
+		{"Unknown", nil, "Unknown"},
+	}
+	for _, test := range tests {
+		got, err := h.Superclasses(test.name)
+		if !reflect.DeepEqual(got, test.want) || missingName(err) != test.missing {
+			t.Errorf("Superclasses(%s) = %q, %v, want %q missing %q", test.name, got, err, test.want, test.missing)
+		}
+	}
+}
+
+func TestSupertypes(t *testing.T) {
+	h := testHierarchy()
+	tests := []struct {
+		name    string
+		want    []string
+		missing string
+	}{
+		{"D", []string{"B", "A", Object, "I"}, ""},
+		{"C", []string{"A", "K", Object, "I", "J"}, ""},
+		{"K", []string{Object, "J", "I"}, ""},
+		{"F", []string{Object, "Unknown"}, "Unknown"},
+	}
+	for _, test := range tests {
+		got, err := h.Supertypes(test.name)
+		if !reflect.DeepEqual(got, test.want) || missingName(err) != test.missing {
+			t.Errorf("Supertypes(%s) = %q, %v, want %q missing %q", test.name, got, err, test.want, test.missing)
+		}
+	}
+}
+
+func TestSubtypes(t *testing.T) {
+	h := testHierarchy()
+	tests := []struct {
+		query string
+		got   []string
+		want  []string
+	}{
+		{"DirectSubclasses(A)", h.DirectSubclasses("A"), []string{"B", "C"}},
+		{"Subclasses(A)", h.Subclasses("A"), []string{"B", "C", "D"}},
+		{"Subclasses(D)", h.Subclasses("D"), []string{}},
+		{"DirectImplementors(I)", h.DirectImplementors("I"), []string{"A", "J"}},
+		// Through subclasses of A, and C through K extends J extends I
+		{"Implementors(I)", h.Implementors("I"), []string{"A", "B", "C", "D"}},
+		{"Implementors(J)", h.Implementors("J"), []string{"C"}},
+		{"Implementors(K)", h.Implementors("K"), []string{"C"}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s = %q, want %q", test.query, test.got, test.want)
+		}
+	}
+	if !h.IsInterface("J") || h.IsInterface("A") || h.IsInterface("Missing") {
+		t.Error("IsInterface")
+	}
+}
+
+func TestIsAssignableFrom(t *testing.T) {
+	h := testHierarchy()
+	tests := []struct {
+		target, source string
+		want           bool
+		missing        string
+	}{
+		{"A", "A", true, ""},
+		{Object, "Missing", true, ""},
+		{"A", "D", true, ""},
+		{"D", "A", false, ""},
+		{"I", "D", true, ""},
+		{"J", "C", true, ""},
+		{"J", "B", false, ""},
+		{"C", "B", false, ""},
+		{"I", "E", false, "Missing"},
+		{"A", "Unknown", false, "Unknown"},
+		{"[LA;", "[LD;", true, ""},
+		{"[LD;", "[LA;", false, ""},
+		{"[[LI;", "[[LB;", true, ""},
+		{"[LI;", "[[LB;", false, ""},
+		{"[Ljava/lang/Object;", "[[I", true, ""},
+		{"[I", "[I", true, ""},
+		{"[J", "[I", false, ""},
+		{"[Ljava/lang/Object;", "[I", false, ""},
+		{"java/lang/Cloneable", "[I", true, ""},
+		{"java/io/Serializable", "[LA;", true, ""},
+		{"A", "[LA;", false, ""},
+		{"[LA;", "A", false, ""},
+	}
+	for _, test := range tests {
+		got, err := h.IsAssignableFrom(test.target, test.source)
+		if got != test.want || missingName(err) != test.missing {
+			t.Errorf("IsAssignableFrom(%s, %s) = %t, %v, want %t missing %q", test.target, test.source, got, err, test.want, test.missing)
+		}
+	}
+}
+
+func TestCommonSuperclass(t *testing.T) {
+	h := testHierarchy()
+	tests := []struct {
+		a, b    string
+		want    string
+		missing string
+	}{
+		{"A", "A", "A", ""},
+		{"B", "C", "A", ""},
+		{"D", "C", "A", ""},
+		{"D", "B", "B", ""},
+		{"A", "I", Object, ""},
+		{"A", "E", "", "Missing"},
+		{"A", "Unknown", "", "Unknown"},
+		{"[LD;", "[LC;", "[LA;", ""},
+		{"[[LD;", "[[LB;", "[[LB;", ""},
+		{"[[LD;", "[LB;", "[Ljava/lang/Object;", ""},
+		{"[LD;", "[LI;", "[Ljava/lang/Object;", ""},
+		{"[I", "[I", "[I", ""},
+		{"[I", "[J", Object, ""},
+		{"[I", "[LA;", Object, ""},
+		{"[LA;", "A", Object, ""},
+		{"A", "[[I", Object, ""},
+		{"[LA;", "[LUnknown;", "", "Unknown"},
+	}
+	for _, test := range tests {
+		got, err := h.CommonSuperclass(test.a, test.b)
+		if got != test.want || missingName(err) != test.missing {
+			t.Errorf("CommonSuperclass(%s, %s) = %s, %v, want %s missing %q", test.a, test.b, got, err, test.want, test.missing)
+		}
+	}
+}
+
+func TestResolverLoadsComponents(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "Sample.class"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "com", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "com", "x", "Sample.class"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := classpath.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+
+	h := testHierarchy()
+	r := NewResolver(h, cp)
+	got, err := r.CommonSuperclass("[[Lcom/x/Sample;", "[[LA;")
+	if err != nil || got != "[[Ljava/lang/Object;" {
+		t.Errorf("CommonSuperclass = %s, %v", got, err)
+	}
+	if _, ok := h.Class("com/x/Sample"); !ok {
+		t.Error("the component class was not loaded")
+	}
+	if _, ok := h.Class("[[Lcom/x/Sample;"); ok {
+		t.Error("the array class was loaded")
+	}
+}
+
+func TestMissing(t *testing.T) {
+	h := testHierarchy()
+	if got := h.Missing(); !reflect.DeepEqual(got, []string{"Missing", "Unknown"}) {
+		t.Errorf("Missing() = %q", got)
+	}
+	h.Add(class("Missing", Object))
+	h.Add(class("A", "B")) // Ignored, A is already present
+	if got := h.Missing(); !reflect.DeepEqual(got, []string{"Unknown"}) {
+		t.Errorf("Missing() after Add = %q", got)
+	}
+	if got, err := h.Superclasses("E"); err != nil || !reflect.DeepEqual(got, []string{"Missing", Object}) {
+		t.Errorf("Superclasses(E) = %q, %v", got, err)
+	}
+	if got, _ := h.Superclasses("B"); !reflect.DeepEqual(got, []string{"A", Object}) {
+		t.Errorf("Superclasses(B) = %q", got)
+	}
+	if got := h.Classes(); len(got) != 11 || got[0] != "A" {
+		t.Errorf("Classes() = %q", got)
+	}
+
+	cycle := New(class("X", "Y"), class("Y", "X"))
+	if _, err := cycle.Superclasses("X"); err == nil || missingName(err) != "" {
+		t.Errorf("circular chain error = %v", err)
+	}
+}
+
+// missingName returns the class reported by a *MissingError, empty for other errors
+func missingName(err error) string {
+	var missing *MissingError
+	if errors.As(err, &missing) {
+		return missing.Name
+	}
+	return ""
+}
@@ -0,0 +1,459 @@
+package hierarchy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/Slummp/classfileparser"
+)
+
+var (
+	// ErrNoSuchMethod is returned, wrapped, when method resolution fails, like NoSuchMethodError
+	ErrNoSuchMethod = errors.New("no such method")
+	// ErrIncompatibleClassChange is returned, wrapped, when a method reference does not match the
+	// kind of its class, like IncompatibleClassChangeError
+	ErrIncompatibleClassChange = errors.New("incompatible class change")
+	// ErrAbstractMethod is returned, wrapped, when no concrete method can be selected, like AbstractMethodError
+	ErrAbstractMethod = errors.New("abstract method")
+)
+
+// Declaration is a method together with the class declaring it
+type Declaration struct {
+	Class  *classfileparser.ClassStruct
+	Method *classfileparser.Method
+}
+
+// String returns the declaration as class.name descriptor, e.g. java/util/List.size()I
+func (d Declaration) String() string {
+	return d.Class.ThisClass + "." + d.Method.Name + d.Method.Type
+}
+
+func (d Declaration) is(access classfileparser.MethodAccess) bool {
+	return d.Method.Access.Has(access)
+}
+
+// declared returns the method of class with the name and descriptor
+func declared(class *classfileparser.ClassStruct, name, descriptor string) (Declaration, bool) {
+	for i := range class.Methods {
+		if method := &class.Methods[i]; method.Name == name && method.Type == descriptor {
+			return Declaration{Class: class, Method: method}, true
+		}
+	}
+	return Declaration{}, false
+}
+
+// signaturePolymorphic returns the signature polymorphic method called name of class, such as
+// MethodHandle.invokeExact, which matches any descriptor (JVMS §2.9.3)
+func signaturePolymorphic(class *classfileparser.ClassStruct, name string) (Declaration, bool) {
+	if class.ThisClass != "java/lang/invoke/MethodHandle" && class.ThisClass != "java/lang/invoke/VarHandle" {
+		return Declaration{}, false
+	}
+	var found []Declaration
+	for i := range class.Methods {
+		if method := &class.Methods[i]; method.Name == name {
+			found = append(found, Declaration{Class: class, Method: method})
+		}
+	}
+	if len(found) != 1 {
+		return Declaration{}, false
+	}
+	method := found[0].Method
+	if len(method.ParamsTypes) != 1 || method.ParamsTypes[0] != "[Ljava/lang/Object;" ||
+		!method.Access.Has(classfileparser.MethodVarargs|classfileparser.MethodNative) {
+		return Declaration{}, false
+	}
+	return found[0], true
+}
+
+func noSuchMethod(class, name, descriptor string, missing error) error {
+	if missing != nil {
+		return fmt.Errorf("cannot resolve %s.%s%s: %w", class, name, descriptor, missing)
+	}
+	return fmt.Errorf("%s.%s%s: %w", class, name, descriptor, ErrNoSuchMethod)
+}
+
+// ResolveMethod resolves a Methodref as described in JVMS §5.4.3.3: the method is looked up in
+// the class and its superclasses, then among the maximally-specific superinterface methods.
+// Access control is not checked.
+func (h *Hierarchy) ResolveMethod(ref classfileparser.Methodref) (Declaration, error) {
+	class, ok := h.classes[ref.Class]
+	if !ok {
+		return Declaration{}, &MissingError{Name: ref.Class}
+	}
+	if class.Access.Has(classfileparser.ClassInterface) {
+		return Declaration{}, fmt.Errorf("%s is an interface: %w", ref.Class, ErrIncompatibleClassChange)
+	}
+
+	// Method lookup in the class and its superclasses
+	var missing error
+	for current := class; ; {
+		if declaration, ok := signaturePolymorphic(current, ref.Name); ok {
+			return declaration, nil
+		}
+		if declaration, ok := declared(current, ref.Name, ref.Type); ok {
+			return declaration, nil
+		}
+		super := current.SuperClass
+		if super == "" {
+			break
+		}
+		if current, ok = h.classes[super]; !ok {
+			missing = &MissingError{Name: super}
+			break
+		}
+	}
+
+	declaration, ok, err := h.superinterfaceMethod(ref.Class, ref.Name, ref.Type)
+	if ok {
+		return declaration, nil
+	}
+	if missing == nil {
+		missing = err
+	}
+	return Declaration{}, noSuchMethod(ref.Class, ref.Name, ref.Type, missing)
+}
+
+// ResolveInterfaceMethod resolves an InterfaceMethodref as described in JVMS §5.4.3.4: the method
+// is looked up in the interface, then among the public instance methods of java/lang/Object, then
+// among the maximally-specific superinterface methods. Access control is not checked.
+func (h *Hierarchy) ResolveInterfaceMethod(ref classfileparser.InterfaceMethodref) (Declaration, error) {
+	class, ok := h.classes[ref.Class]
+	if !ok {
+		return Declaration{}, &MissingError{Name: ref.Class}
+	}
+	if !class.Access.Has(classfileparser.ClassInterface) {
+		return Declaration{}, fmt.Errorf("%s is not an interface: %w", ref.Class, ErrIncompatibleClassChange)
+	}
+	if declaration, ok := declared(class, ref.Name, ref.Type); ok {
+		return declaration, nil
+	}
+
+	var missing error
+	if object, ok := h.classes[Object]; ok {
+		if declaration, ok := declared(object, ref.Name, ref.Type); ok &&
+			declaration.is(classfileparser.MethodPublic) && !declaration.is(classfileparser.MethodStatic) {
+			return declaration, nil
+		}
+	} else {
+		missing = &MissingError{Name: Object}
+	}
+
+	declaration, ok, err := h.superinterfaceMethod(ref.Class, ref.Name, ref.Type)
+	if ok {
+		return declaration, nil
+	}
+	if missing == nil {
+		missing = err
+	}
+	return Declaration{}, noSuchMethod(ref.Class, ref.Name, ref.Type, missing)
+}
+
+// superinterfaceMethod picks the method resolved among the superinterfaces of class: the only
+// non-abstract maximally-specific superinterface method if there is one, otherwise any of them.
+// The error reports missing supertypes.
+func (h *Hierarchy) superinterfaceMethod(class, name, descriptor string) (Declaration, bool, error) {
+	candidates, err := h.maximallySpecific(class, name, descriptor)
+	var concrete []Declaration
+	for _, candidate := range candidates {
+		if !candidate.is(classfileparser.MethodAbstract) {
+			concrete = append(concrete, candidate)
+		}
+	}
+	if len(concrete) == 1 {
+		return concrete[0], true, nil
+	}
+	if len(candidates) > 0 {
+		return candidates[0], true, nil
+	}
+	return Declaration{}, false, err
+}
+
+// maximallySpecific returns the maximally-specific superinterface methods of class with the name
+// and descriptor (JVMS §5.4.3.3), in the breadth-first order of Supertypes
+func (h *Hierarchy) maximallySpecific(class, name, descriptor string) ([]Declaration, error) {
+	supertypes, err := h.Supertypes(class)
+	var candidates []Declaration
+	for _, super := range supertypes {
+		iface, ok := h.classes[super]
+		if !ok || !iface.Access.Has(classfileparser.ClassInterface) {
+			continue
+		}
+		declaration, ok := declared(iface, name, descriptor)
+		if ok && !declaration.is(classfileparser.MethodPrivate) && !declaration.is(classfileparser.MethodStatic) {
+			candidates = append(candidates, declaration)
+		}
+	}
+
+	var specific []Declaration
+	for _, candidate := range candidates {
+		overridden := false
+		for _, other := range candidates {
+			if other.Class == candidate.Class {
+				continue
+			}
+			if ok, _ := h.IsAssignableFrom(candidate.Class.ThisClass, other.Class.ThisClass); ok {
+				// other is declared in a subinterface of the interface declaring candidate
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			specific = append(specific, candidate)
+		}
+	}
+	return specific, err
+}
+
+// packageOf returns the package of an internal class name
+func packageOf(class string) string {
+	if dir := path.Dir(class); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// CanOverride reports whether the instance method overrider can override the method overridden,
+// following JVMS §5.4.5: both have the same name and descriptor, overrider is not private,
+// and overridden is public, protected, or package-private and declared in the package of
+// overrider or overridden through an intermediate method. Static and private methods never
+// override nor are overridden. overrider.Class must be a subtype of overridden.Class.
+func (h *Hierarchy) CanOverride(overrider, overridden Declaration) bool {
+	if overrider.Method.Name != overridden.Method.Name || overrider.Method.Type != overridden.Method.Type {
+		return false
+	}
+	for _, d := range []Declaration{overrider, overridden} {
+		if d.is(classfileparser.MethodPrivate) || d.is(classfileparser.MethodStatic) {
+			return false
+		}
+	}
+	if overridden.is(classfileparser.MethodPublic) || overridden.is(classfileparser.MethodProtected) {
+		return true
+	}
+	if packageOf(overrider.Class.ThisClass) == packageOf(overridden.Class.ThisClass) {
+		return true
+	}
+
+	// Package-private method overridden through a method of an intermediate class
+	chain, _ := h.Superclasses(overrider.Class.ThisClass)
+	for _, name := range chain {
+		if name == overridden.Class.ThisClass {
+			break
+		}
+		class, ok := h.classes[name]
+		if !ok {
+			break
+		}
+		intermediate, ok := declared(class, overridden.Method.Name, overridden.Method.Type)
+		if ok && h.CanOverride(overrider, intermediate) && h.CanOverride(intermediate, overridden) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overridden returns the methods that the method declared by class overrides in its
+// superclasses or implements from its superinterfaces, superclasses first. Bridge and synthetic
+// methods take part like any other method, as they do in the JVM. If supertypes are missing,
+// the methods found are returned along with a *MissingError.
+func (h *Hierarchy) Overridden(declaration Declaration) ([]Declaration, error) {
+	if declaration.is(classfileparser.MethodPrivate) || declaration.is(classfileparser.MethodStatic) ||
+		declaration.Method.Name == "<init>" || declaration.Method.Name == "<clinit>" {
+		return nil, nil
+	}
+	supertypes, err := h.Supertypes(declaration.Class.ThisClass)
+	var classes, interfaces []Declaration
+	for _, super := range supertypes {
+		class, ok := h.classes[super]
+		if !ok {
+			continue
+		}
+		candidate, ok := declared(class, declaration.Method.Name, declaration.Method.Type)
+		if !ok || !h.CanOverride(declaration, candidate) {
+			continue
+		}
+		if class.Access.Has(classfileparser.ClassInterface) {
+			interfaces = append(interfaces, candidate)
+		} else {
+			classes = append(classes, candidate)
+		}
+	}
+	return append(classes, interfaces...), err
+}
+
+// Overriders returns the methods of the hierarchy that override or implement the method
+// declaration, sorted by class
+func (h *Hierarchy) Overriders(declaration Declaration) []Declaration {
+	if declaration.is(classfileparser.MethodPrivate) || declaration.is(classfileparser.MethodStatic) {
+		return nil
+	}
+	var subtypes []string
+	if declaration.Class.Access.Has(classfileparser.ClassInterface) {
+		subtypes = h.subinterfaces(declaration.Class.ThisClass)
+		subtypes = append(subtypes, h.Implementors(declaration.Class.ThisClass)...)
+	} else {
+		subtypes = h.Subclasses(declaration.Class.ThisClass)
+	}
+	var overriders []Declaration
+	for _, name := range subtypes {
+		candidate, ok := declared(h.classes[name], declaration.Method.Name, declaration.Method.Type)
+		if ok && h.CanOverride(candidate, declaration) {
+			overriders = append(overriders, candidate)
+		}
+	}
+	sort.Slice(overriders, func(i, j int) bool { return overriders[i].Class.ThisClass < overriders[j].Class.ThisClass })
+	return overriders
+}
+
+// subinterfaces returns the interfaces extending the interface name, directly or not
+func (h *Hierarchy) subinterfaces(name string) []string {
+	var subinterfaces []string
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		for _, implementer := range h.implementers[queue[0]] {
+			if !seen[implementer] && h.IsInterface(implementer) {
+				seen[implementer] = true
+				subinterfaces = append(subinterfaces, implementer)
+				queue = append(queue, implementer)
+			}
+		}
+		queue = queue[1:]
+	}
+	return subinterfaces
+}
+
+// SelectMethod returns the method invoked by invokevirtual or invokeinterface on an instance of
+// the class receiver when the method reference resolved to resolved, as described in JVMS §5.4.6
+func (h *Hierarchy) SelectMethod(receiver string, resolved Declaration) (Declaration, error) {
+	if resolved.is(classfileparser.MethodPrivate) {
+		return resolved, nil
+	}
+	for name := receiver; name != ""; {
+		class, ok := h.classes[name]
+		if !ok {
+			return Declaration{}, &MissingError{Name: name}
+		}
+		if candidate, ok := declared(class, resolved.Method.Name, resolved.Method.Type); ok &&
+			(candidate == resolved || h.CanOverride(candidate, resolved)) {
+			return candidate, nil
+		}
+		name = class.SuperClass
+	}
+
+	candidates, err := h.maximallySpecific(receiver, resolved.Method.Name, resolved.Method.Type)
+	var concrete []Declaration
+	for _, candidate := range candidates {
+		if !candidate.is(classfileparser.MethodAbstract) {
+			concrete = append(concrete, candidate)
+		}
+	}
+	switch {
+	case len(concrete) == 1:
+		return concrete[0], nil
+	case err != nil:
+		return Declaration{}, err
+	case len(concrete) > 1:
+		return Declaration{}, fmt.Errorf("%s has several default methods for %s%s: %w", receiver, resolved.Method.Name, resolved.Method.Type, ErrIncompatibleClassChange)
+	default:
+		return Declaration{}, fmt.Errorf("%s has no implementation of %s: %w", receiver, resolved, ErrAbstractMethod)
+	}
+}
+
+// Targets returns the methods an invokevirtual or invokeinterface of the resolved method can
+// dispatch to when the static type of the receiver is class: the method selected for class
+// and each of its concrete subtypes in the hierarchy, without duplicates and sorted by class.
+// This gives the call graph edges of class hierarchy analysis. Bridge methods are kept, as the
+// JVM runs them; SourceTargets replaces them with the methods they bridge to. Receivers for which
+// no method can be selected are skipped, and the first error is returned with the targets found.
+func (h *Hierarchy) Targets(class string, resolved Declaration) ([]Declaration, error) {
+	return h.targets(class, resolved, false)
+}
+
+// SourceTargets returns the Targets of the resolved method with each bridge method replaced by
+// the method it bridges to, as call edges read from the source would go. A bridge whose target
+// cannot be found is kept.
+func (h *Hierarchy) SourceTargets(class string, resolved Declaration) ([]Declaration, error) {
+	return h.targets(class, resolved, true)
+}
+
+func (h *Hierarchy) targets(class string, resolved Declaration, collapse bool) ([]Declaration, error) {
+	if resolved.is(classfileparser.MethodPrivate) || resolved.is(classfileparser.MethodStatic) {
+		return []Declaration{resolved}, nil
+	}
+	receivers := []string{class}
+	if h.IsInterface(class) {
+		receivers = h.Implementors(class)
+	} else {
+		receivers = append(receivers, h.Subclasses(class)...)
+	}
+
+	var targets []Declaration
+	var firstErr error
+	seen := map[*classfileparser.Method]bool{}
+	for _, receiver := range receivers {
+		if c, ok := h.classes[receiver]; ok && c.Access.Has(classfileparser.ClassAbstract) {
+			continue
+		}
+		target, err := h.SelectMethod(receiver, resolved)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if collapse {
+			if bridged, ok := h.Bridged(target); ok {
+				// The bridge invokevirtuals its target, which the receiver may override in turn
+				if selected, err := h.SelectMethod(receiver, bridged); err == nil {
+					bridged = selected
+				}
+				target = bridged
+			}
+		}
+		if !seen[target.Method] {
+			seen[target.Method] = true
+			targets = append(targets, target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Class.ThisClass < targets[j].Class.ThisClass })
+	return targets, firstErr
+}
+
+// Bridged returns the method the bridge method bridge forwards to: the method of the same name
+// and another descriptor that its code invokes, as compilers generate for covariant returns and
+// erased generic parameters. It reports false if bridge is not a bridge method or the method it
+// invokes cannot be resolved.
+func (h *Hierarchy) Bridged(bridge Declaration) (Declaration, bool) {
+	if !bridge.is(classfileparser.MethodBridge) {
+		return Declaration{}, false
+	}
+	for _, attribute := range bridge.Method.Attributes {
+		code, ok := attribute.(classfileparser.Code)
+		if !ok {
+			continue
+		}
+		for _, instruction := range code.Code {
+			var declaration Declaration
+			var err error
+			switch instr := instruction.(type) {
+			case classfileparser.Invokevirtual:
+				if instr.Name != bridge.Method.Name || instr.Type == bridge.Method.Type {
+					continue
+				}
+				declaration, err = h.ResolveMethod(classfileparser.Methodref{Class: instr.Class, Name: instr.Name, Type: instr.Type})
+			case classfileparser.Invokeinterface:
+				ref := instr.InterfaceMethodref
+				if ref.Name != bridge.Method.Name || ref.Type == bridge.Method.Type {
+					continue
+				}
+				declaration, err = h.ResolveInterfaceMethod(ref)
+			default:
+				continue
+			}
+			return declaration, err == nil
+		}
+	}
+	return Declaration{}, false
+}
@@ -0,0 +1,206 @@
+package hierarchy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// method returns the method name with descriptor, whose code is instructions if there are any
+func method(access classfileparser.MethodAccess, name, descriptor string, instructions ...classfileparser.Instruction) classfileparser.Method {
+	m := classfileparser.Method{Access: access, Name: name, Type: descriptor}
+	if len(instructions) > 0 {
+		m.Attributes = []classfileparser.Attribute{classfileparser.Code{Code: instructions}}
+	}
+	return m
+}
+
+// bridgeHierarchy returns classes overriding Supplier.get()Ljava/lang/Object; with a covariant
+// return, through the bridge methods javac generates
+func bridgeHierarchy() *Hierarchy {
+	const bridge = classfileparser.MethodPublic | classfileparser.MethodBridge | classfileparser.MethodSynthetic
+	return New(
+		&classfileparser.ClassStruct{ThisClass: Object, Access: classfileparser.ClassPublic},
+		&classfileparser.ClassStruct{
+			ThisClass: "com/x/Supplier",
+			Access:    classfileparser.ClassPublic | classfileparser.ClassInterface | classfileparser.ClassAbstract,
+			Methods:   []classfileparser.Method{method(classfileparser.MethodPublic|classfileparser.MethodAbstract, "get", "()Ljava/lang/Object;")},
+		},
+		&classfileparser.ClassStruct{
+			ThisClass:  "com/x/Text",
+			SuperClass: Object,
+			Interfaces: []string{"com/x/Supplier"},
+			Access:     classfileparser.ClassPublic,
+			Methods: []classfileparser.Method{
+				method(classfileparser.MethodPublic, "get", "()Ljava/lang/String;"),
+				method(bridge, "get", "()Ljava/lang/Object;",
+					classfileparser.Invokevirtual{Class: "com/x/Text", Name: "get", Type: "()Ljava/lang/String;"}),
+			},
+		},
+		&classfileparser.ClassStruct{
+			ThisClass:  "com/x/Number",
+			SuperClass: Object,
+			Interfaces: []string{"com/x/Supplier"},
+			Access:     classfileparser.ClassPublic,
+			Methods: []classfileparser.Method{
+				method(classfileparser.MethodPublic, "get", "()Ljava/lang/Integer;"),
+				method(bridge, "get", "()Ljava/lang/Object;",
+					classfileparser.Invokevirtual{Class: "com/x/Number", Name: "get", Type: "()Ljava/lang/Integer;"}),
+			},
+		},
+		&classfileparser.ClassStruct{
+			ThisClass:  "com/x/Plain",
+			SuperClass: Object,
+			Interfaces: []string{"com/x/Supplier"},
+			Access:     classfileparser.ClassPublic,
+			Methods:    []classfileparser.Method{method(classfileparser.MethodPublic, "get", "()Ljava/lang/Object;")},
+		},
+	)
+}
+
+func declaration(t *testing.T, h *Hierarchy, class, name, descriptor string) Declaration {
+	t.Helper()
+	c, ok := h.Class(class)
+	if !ok {
+		t.Fatalf("no class %s", class)
+	}
+	d, ok := declared(c, name, descriptor)
+	if !ok {
+		t.Fatalf("no method %s.%s%s", class, name, descriptor)
+	}
+	return d
+}
+
+func TestBridged(t *testing.T) {
+	h := bridgeHierarchy()
+	missing := declaration(t, h, "com/x/Number", "get", "()Ljava/lang/Object;")
+	missing.Method = &classfileparser.Method{Access: missing.Method.Access, Name: "get", Type: "()Ljava/lang/Object;",
+		Attributes: []classfileparser.Attribute{classfileparser.Code{Code: []classfileparser.Instruction{
+			classfileparser.Invokevirtual{Class: "com/x/Missing", Name: "get", Type: "()Ljava/lang/Long;"},
+		}}}}
+
+	tests := []struct {
+		name   string
+		bridge Declaration
+		want   string // Empty if the method bridges to nothing
+	}{
+		{"bridge", declaration(t, h, "com/x/Text", "get", "()Ljava/lang/Object;"), "com/x/Text.get()Ljava/lang/String;"},
+		{"not a bridge", declaration(t, h, "com/x/Text", "get", "()Ljava/lang/String;"), ""},
+		{"plain method", declaration(t, h, "com/x/Plain", "get", "()Ljava/lang/Object;"), ""},
+		{"unresolved target", missing, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := h.Bridged(test.bridge)
+			if test.want == "" {
+				if ok {
+					t.Fatalf("Bridged = %s, want none", got)
+				}
+				return
+			}
+			if !ok || got.String() != test.want {
+				t.Fatalf("Bridged = %v, %t, want %s", got.Method, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestTargetsBridges(t *testing.T) {
+	h := bridgeHierarchy()
+	resolved, err := h.ResolveInterfaceMethod(classfileparser.InterfaceMethodref{Class: "com/x/Supplier", Name: "get", Type: "()Ljava/lang/Object;"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		targets func(string, Declaration) ([]Declaration, error)
+		want    []string
+	}{
+		{"Targets", h.Targets, []string{
+			"com/x/Number.get()Ljava/lang/Object;",
+			"com/x/Plain.get()Ljava/lang/Object;",
+			"com/x/Text.get()Ljava/lang/Object;",
+		}},
+		{"SourceTargets", h.SourceTargets, []string{
+			"com/x/Number.get()Ljava/lang/Integer;",
+			"com/x/Plain.get()Ljava/lang/Object;",
+			"com/x/Text.get()Ljava/lang/String;",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targets, err := test.targets("com/x/Supplier", resolved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(targets) != len(test.want) {
+				t.Fatalf("targets = %v, want %v", targets, test.want)
+			}
+			for i, target := range targets {
+				if target.String() != test.want[i] {
+					t.Errorf("target %d = %s, want %s", i, target, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestSourceTargetsOverriddenBridge covers a subclass overriding the method a bridge inherited
+// from its superclass forwards to:
+//
+//	class A<T> { void foo(T t) }
+//	class B extends A<String> { void foo(String s) } // Plus the bridge foo(Object)
+//	class C extends B { void foo(String s) }
+func TestSourceTargetsOverriddenBridge(t *testing.T) {
+	const bridge = classfileparser.MethodPublic | classfileparser.MethodBridge | classfileparser.MethodSynthetic
+	h := New(
+		&classfileparser.ClassStruct{ThisClass: Object, Access: classfileparser.ClassPublic},
+		&classfileparser.ClassStruct{
+			ThisClass:  "A",
+			SuperClass: Object,
+			Access:     classfileparser.ClassPublic,
+			Methods:    []classfileparser.Method{method(classfileparser.MethodPublic, "foo", "(Ljava/lang/Object;)V")},
+		},
+		&classfileparser.ClassStruct{
+			ThisClass:  "B",
+			SuperClass: "A",
+			Access:     classfileparser.ClassPublic,
+			Methods: []classfileparser.Method{
+				method(classfileparser.MethodPublic, "foo", "(Ljava/lang/String;)V"),
+				method(bridge, "foo", "(Ljava/lang/Object;)V",
+					classfileparser.Invokevirtual{Class: "B", Name: "foo", Type: "(Ljava/lang/String;)V"}),
+			},
+		},
+		&classfileparser.ClassStruct{
+			ThisClass:  "C",
+			SuperClass: "B",
+			Access:     classfileparser.ClassPublic,
+			Methods:    []classfileparser.Method{method(classfileparser.MethodPublic, "foo", "(Ljava/lang/String;)V")},
+		},
+	)
+	resolved := declaration(t, h, "A", "foo", "(Ljava/lang/Object;)V")
+
+	tests := []struct {
+		name    string
+		targets func(string, Declaration) ([]Declaration, error)
+		want    []string
+	}{
+		{"Targets", h.Targets, []string{"A.foo(Ljava/lang/Object;)V", "B.foo(Ljava/lang/Object;)V"}},
+		{"SourceTargets", h.SourceTargets, []string{"A.foo(Ljava/lang/Object;)V", "B.foo(Ljava/lang/String;)V", "C.foo(Ljava/lang/String;)V"}},
+	}
+	for _, test := range tests {
+		targets, err := test.targets("A", resolved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, target := range targets {
+			got = append(got, target.String())
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%s = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,857 @@
+package classfileparser
+
+// Instruction is implemented by every bytecode instruction type
+type Instruction interface {
+	Opcode() uint8    // Opcode byte
+	Mnemonic() string // Mnemonic as written in the JVM specification (e.g. iload_0)
+	PC() int          // Offset of the opcode from the start of the method's code
+	Len() int         // Encoded length in bytes, operands and padding included
+}
+
+// Branch is implemented by the instructions jumping to a single target: conditional branches,
+// goto, jsr and their wide forms
+type Branch interface {
+	Instruction
+	Target() int // Absolute pc of the jump target, PC() plus the offset
+}
+
+// Location records where an instruction starts in its method's code
+type Location struct {
+	Pc int // Offset of the opcode from the start of the method's code
+}
+
+// PC returns the offset of the instruction from the start of its method's code
+func (l Location) PC() int { return l.Pc }
+
+func (Nop) Opcode() uint8    { return 0x00 }
+func (Nop) Mnemonic() string { return "nop" }
+func (Nop) Len() int         { return 1 }
+
+func (AconstNull) Opcode() uint8    { return 0x01 }
+func (AconstNull) Mnemonic() string { return "aconst_null" }
+func (AconstNull) Len() int         { return 1 }
+
+func (IconstM1) Opcode() uint8    { return 0x02 }
+func (IconstM1) Mnemonic() string { return "iconst_m1" }
+func (IconstM1) Len() int         { return 1 }
+
+func (Iconst0) Opcode() uint8    { return 0x03 }
+func (Iconst0) Mnemonic() string { return "iconst_0" }
+func (Iconst0) Len() int         { return 1 }
+
+func (Iconst1) Opcode() uint8    { return 0x04 }
+func (Iconst1) Mnemonic() string { return "iconst_1" }
+func (Iconst1) Len() int         { return 1 }
+
+func (Iconst2) Opcode() uint8    { return 0x05 }
+func (Iconst2) Mnemonic() string { return "iconst_2" }
+func (Iconst2) Len() int         { return 1 }
+
+func (Iconst3) Opcode() uint8    { return 0x06 }
+func (Iconst3) Mnemonic() string { return "iconst_3" }
+func (Iconst3) Len() int         { return 1 }
+
+func (Iconst4) Opcode() uint8    { return 0x07 }
+func (Iconst4) Mnemonic() string { return "iconst_4" }
+func (Iconst4) Len() int         { return 1 }
+
+func (Iconst5) Opcode() uint8    { return 0x08 }
+func (Iconst5) Mnemonic() string { return "iconst_5" }
+func (Iconst5) Len() int         { return 1 }
+
+func (Lconst0) Opcode() uint8    { return 0x09 }
+func (Lconst0) Mnemonic() string { return "lconst_0" }
+func (Lconst0) Len() int         { return 1 }
+
+func (Lconst1) Opcode() uint8    { return 0x0A }
+func (Lconst1) Mnemonic() string { return "lconst_1" }
+func (Lconst1) Len() int         { return 1 }
+
+func (Fconst0) Opcode() uint8    { return 0x0B }
+func (Fconst0) Mnemonic() string { return "fconst_0" }
+func (Fconst0) Len() int         { return 1 }
+
+func (Fconst1) Opcode() uint8    { return 0x0C }
+func (Fconst1) Mnemonic() string { return "fconst_1" }
+func (Fconst1) Len() int         { return 1 }
+
+func (Fconst2) Opcode() uint8    { return 0x0D }
+func (Fconst2) Mnemonic() string { return "fconst_2" }
+func (Fconst2) Len() int         { return 1 }
+
+func (Dconst0) Opcode() uint8    { return 0x0E }
+func (Dconst0) Mnemonic() string { return "dconst_0" }
+func (Dconst0) Len() int         { return 1 }
+
+func (Dconst1) Opcode() uint8    { return 0x0F }
+func (Dconst1) Mnemonic() string { return "dconst_1" }
+func (Dconst1) Len() int         { return 1 }
+
+func (Bipush) Opcode() uint8    { return 0x10 }
+func (Bipush) Mnemonic() string { return "bipush" }
+func (Bipush) Len() int         { return 2 }
+
+func (Sipush) Opcode() uint8    { return 0x11 }
+func (Sipush) Mnemonic() string { return "sipush" }
+func (Sipush) Len() int         { return 3 }
+
+func (Ldc) Opcode() uint8    { return 0x12 }
+func (Ldc) Mnemonic() string { return "ldc" }
+func (Ldc) Len() int         { return 2 }
+
+func (LdcW) Opcode() uint8    { return 0x13 }
+func (LdcW) Mnemonic() string { return "ldc_w" }
+func (LdcW) Len() int         { return 3 }
+
+func (Ldc2W) Opcode() uint8    { return 0x14 }
+func (Ldc2W) Mnemonic() string { return "ldc2_w" }
+func (Ldc2W) Len() int         { return 3 }
+
+func (Iload) Opcode() uint8    { return 0x15 }
+func (Iload) Mnemonic() string { return "iload" }
+func (Iload) Len() int         { return 2 }
+
+func (Lload) Opcode() uint8    { return 0x16 }
+func (Lload) Mnemonic() string { return "lload" }
+func (Lload) Len() int         { return 2 }
+
+func (Fload) Opcode() uint8    { return 0x17 }
+func (Fload) Mnemonic() string { return "fload" }
+func (Fload) Len() int         { return 2 }
+
+func (Dload) Opcode() uint8    { return 0x18 }
+func (Dload) Mnemonic() string { return "dload" }
+func (Dload) Len() int         { return 2 }
+
+func (Aload) Opcode() uint8    { return 0x19 }
+func (Aload) Mnemonic() string { return "aload" }
+func (Aload) Len() int         { return 2 }
+
+func (Iload0) Opcode() uint8    { return 0x1A }
+func (Iload0) Mnemonic() string { return "iload_0" }
+func (Iload0) Len() int         { return 1 }
+
+func (Iload1) Opcode() uint8    { return 0x1B }
+func (Iload1) Mnemonic() string { return "iload_1" }
+func (Iload1) Len() int         { return 1 }
+
+func (Iload2) Opcode() uint8    { return 0x1C }
+func (Iload2) Mnemonic() string { return "iload_2" }
+func (Iload2) Len() int         { return 1 }
+
+func (Iload3) Opcode() uint8    { return 0x1D }
+func (Iload3) Mnemonic() string { return "iload_3" }
+func (Iload3) Len() int         { return 1 }
+
+func (Lload0) Opcode() uint8    { return 0x1E }
+func (Lload0) Mnemonic() string { return "lload_0" }
+func (Lload0) Len() int         { return 1 }
+
+func (Lload1) Opcode() uint8    { return 0x1F }
+func (Lload1) Mnemonic() string { return "lload_1" }
+func (Lload1) Len() int         { return 1 }
+
+func (Lload2) Opcode() uint8    { return 0x20 }
+func (Lload2) Mnemonic() string { return "lload_2" }
+func (Lload2) Len() int         { return 1 }
+
+func (Lload3) Opcode() uint8    { return 0x21 }
+func (Lload3) Mnemonic() string { return "lload_3" }
+func (Lload3) Len() int         { return 1 }
+
+func (Fload0) Opcode() uint8    { return 0x22 }
+func (Fload0) Mnemonic() string { return "fload_0" }
+func (Fload0) Len() int         { return 1 }
+
+func (Fload1) Opcode() uint8    { return 0x23 }
+func (Fload1) Mnemonic() string { return "fload_1" }
+func (Fload1) Len() int         { return 1 }
+
+func (Fload2) Opcode() uint8    { return 0x24 }
+func (Fload2) Mnemonic() string { return "fload_2" }
+func (Fload2) Len() int         { return 1 }
+
+func (Fload3) Opcode() uint8    { return 0x25 }
+func (Fload3) Mnemonic() string { return "fload_3" }
+func (Fload3) Len() int         { return 1 }
+
+func (Dload0) Opcode() uint8    { return 0x26 }
+func (Dload0) Mnemonic() string { return "dload_0" }
+func (Dload0) Len() int         { return 1 }
+
+func (Dload1) Opcode() uint8    { return 0x27 }
+func (Dload1) Mnemonic() string { return "dload_1" }
+func (Dload1) Len() int         { return 1 }
+
+func (Dload2) Opcode() uint8    { return 0x28 }
+func (Dload2) Mnemonic() string { return "dload_2" }
+func (Dload2) Len() int         { return 1 }
+
+func (Dload3) Opcode() uint8    { return 0x29 }
+func (Dload3) Mnemonic() string { return "dload_3" }
+func (Dload3) Len() int         { return 1 }
+
+func (Aload0) Opcode() uint8    { return 0x2A }
+func (Aload0) Mnemonic() string { return "aload_0" }
+func (Aload0) Len() int         { return 1 }
+
+func (Aload1) Opcode() uint8    { return 0x2B }
+func (Aload1) Mnemonic() string { return "aload_1" }
+func (Aload1) Len() int         { return 1 }
+
+func (Aload2) Opcode() uint8    { return 0x2C }
+func (Aload2) Mnemonic() string { return "aload_2" }
+func (Aload2) Len() int         { return 1 }
+
+func (Aload3) Opcode() uint8    { return 0x2D }
+func (Aload3) Mnemonic() string { return "aload_3" }
+func (Aload3) Len() int         { return 1 }
+
+func (Iaload) Opcode() uint8    { return 0x2E }
+func (Iaload) Mnemonic() string { return "iaload" }
+func (Iaload) Len() int         { return 1 }
+
+func (Laload) Opcode() uint8    { return 0x2F }
+func (Laload) Mnemonic() string { return "laload" }
+func (Laload) Len() int         { return 1 }
+
+func (Faload) Opcode() uint8    { return 0x30 }
+func (Faload) Mnemonic() string { return "faload" }
+func (Faload) Len() int         { return 1 }
+
+func (Daload) Opcode() uint8    { return 0x31 }
+func (Daload) Mnemonic() string { return "daload" }
+func (Daload) Len() int         { return 1 }
+
+func (Aaload) Opcode() uint8    { return 0x32 }
+func (Aaload) Mnemonic() string { return "aaload" }
+func (Aaload) Len() int         { return 1 }
+
+func (Baload) Opcode() uint8    { return 0x33 }
+func (Baload) Mnemonic() string { return "baload" }
+func (Baload) Len() int         { return 1 }
+
+func (Caload) Opcode() uint8    { return 0x34 }
+func (Caload) Mnemonic() string { return "caload" }
+func (Caload) Len() int         { return 1 }
+
+func (Saload) Opcode() uint8    { return 0x35 }
+func (Saload) Mnemonic() string { return "saload" }
+func (Saload) Len() int         { return 1 }
+
+func (Istore) Opcode() uint8    { return 0x36 }
+func (Istore) Mnemonic() string { return "istore" }
+func (Istore) Len() int         { return 2 }
+
+func (Lstore) Opcode() uint8    { return 0x37 }
+func (Lstore) Mnemonic() string { return "lstore" }
+func (Lstore) Len() int         { return 2 }
+
+func (Fstore) Opcode() uint8    { return 0x38 }
+func (Fstore) Mnemonic() string { return "fstore" }
+func (Fstore) Len() int         { return 2 }
+
+func (Dstore) Opcode() uint8    { return 0x39 }
+func (Dstore) Mnemonic() string { return "dstore" }
+func (Dstore) Len() int         { return 2 }
+
+func (Astore) Opcode() uint8    { return 0x3A }
+func (Astore) Mnemonic() string { return "astore" }
+func (Astore) Len() int         { return 2 }
+
+func (Istore0) Opcode() uint8    { return 0x3B }
+func (Istore0) Mnemonic() string { return "istore_0" }
+func (Istore0) Len() int         { return 1 }
+
+func (Istore1) Opcode() uint8    { return 0x3C }
+func (Istore1) Mnemonic() string { return "istore_1" }
+func (Istore1) Len() int         { return 1 }
+
+func (Istore2) Opcode() uint8    { return 0x3D }
+func (Istore2) Mnemonic() string { return "istore_2" }
+func (Istore2) Len() int         { return 1 }
+
+func (Istore3) Opcode() uint8    { return 0x3E }
+func (Istore3) Mnemonic() string { return "istore_3" }
+func (Istore3) Len() int         { return 1 }
+
+func (Lstore0) Opcode() uint8    { return 0x3F }
+func (Lstore0) Mnemonic() string { return "lstore_0" }
+func (Lstore0) Len() int         { return 1 }
+
+func (Lstore1) Opcode() uint8    { return 0x40 }
+func (Lstore1) Mnemonic() string { return "lstore_1" }
+func (Lstore1) Len() int         { return 1 }
+
+func (Lstore2) Opcode() uint8    { return 0x41 }
+func (Lstore2) Mnemonic() string { return "lstore_2" }
+func (Lstore2) Len() int         { return 1 }
+
+func (Lstore3) Opcode() uint8    { return 0x42 }
+func (Lstore3) Mnemonic() string { return "lstore_3" }
+func (Lstore3) Len() int         { return 1 }
+
+func (Fstore0) Opcode() uint8    { return 0x43 }
+func (Fstore0) Mnemonic() string { return "fstore_0" }
+func (Fstore0) Len() int         { return 1 }
+
+func (Fstore1) Opcode() uint8    { return 0x44 }
+func (Fstore1) Mnemonic() string { return "fstore_1" }
+func (Fstore1) Len() int         { return 1 }
+
+func (Fstore2) Opcode() uint8    { return 0x45 }
+func (Fstore2) Mnemonic() string { return "fstore_2" }
+func (Fstore2) Len() int         { return 1 }
+
+func (Fstore3) Opcode() uint8    { return 0x46 }
+func (Fstore3) Mnemonic() string { return "fstore_3" }
+func (Fstore3) Len() int         { return 1 }
+
+func (Dstore0) Opcode() uint8    { return 0x47 }
+func (Dstore0) Mnemonic() string { return "dstore_0" }
+func (Dstore0) Len() int         { return 1 }
+
+func (Dstore1) Opcode() uint8    { return 0x48 }
+func (Dstore1) Mnemonic() string { return "dstore_1" }
+func (Dstore1) Len() int         { return 1 }
+
+func (Dstore2) Opcode() uint8    { return 0x49 }
+func (Dstore2) Mnemonic() string { return "dstore_2" }
+func (Dstore2) Len() int         { return 1 }
+
+func (Dstore3) Opcode() uint8    { return 0x4A }
+func (Dstore3) Mnemonic() string { return "dstore_3" }
+func (Dstore3) Len() int         { return 1 }
+
+func (Astore0) Opcode() uint8    { return 0x4B }
+func (Astore0) Mnemonic() string { return "astore_0" }
+func (Astore0) Len() int         { return 1 }
+
+func (Astore1) Opcode() uint8    { return 0x4C }
+func (Astore1) Mnemonic() string { return "astore_1" }
+func (Astore1) Len() int         { return 1 }
+
+func (Astore2) Opcode() uint8    { return 0x4D }
+func (Astore2) Mnemonic() string { return "astore_2" }
+func (Astore2) Len() int         { return 1 }
+
+func (Astore3) Opcode() uint8    { return 0x4E }
+func (Astore3) Mnemonic() string { return "astore_3" }
+func (Astore3) Len() int         { return 1 }
+
+func (Iastore) Opcode() uint8    { return 0x4F }
+func (Iastore) Mnemonic() string { return "iastore" }
+func (Iastore) Len() int         { return 1 }
+
+func (Lastore) Opcode() uint8    { return 0x50 }
+func (Lastore) Mnemonic() string { return "lastore" }
+func (Lastore) Len() int         { return 1 }
+
+func (Fastore) Opcode() uint8    { return 0x51 }
+func (Fastore) Mnemonic() string { return "fastore" }
+func (Fastore) Len() int         { return 1 }
+
+func (Dastore) Opcode() uint8    { return 0x52 }
+func (Dastore) Mnemonic() string { return "dastore" }
+func (Dastore) Len() int         { return 1 }
+
+func (Aastore) Opcode() uint8    { return 0x53 }
+func (Aastore) Mnemonic() string { return "aastore" }
+func (Aastore) Len() int         { return 1 }
+
+func (Bastore) Opcode() uint8    { return 0x54 }
+func (Bastore) Mnemonic() string { return "bastore" }
+func (Bastore) Len() int         { return 1 }
+
+func (Castore) Opcode() uint8    { return 0x55 }
+func (Castore) Mnemonic() string { return "castore" }
+func (Castore) Len() int         { return 1 }
+
+func (Sastore) Opcode() uint8    { return 0x56 }
+func (Sastore) Mnemonic() string { return "sastore" }
+func (Sastore) Len() int         { return 1 }
+
+func (Pop) Opcode() uint8    { return 0x57 }
+func (Pop) Mnemonic() string { return "pop" }
+func (Pop) Len() int         { return 1 }
+
+func (Pop2) Opcode() uint8    { return 0x58 }
+func (Pop2) Mnemonic() string { return "pop2" }
+func (Pop2) Len() int         { return 1 }
+
+func (Dup) Opcode() uint8    { return 0x59 }
+func (Dup) Mnemonic() string { return "dup" }
+func (Dup) Len() int         { return 1 }
+
+func (DupX1) Opcode() uint8    { return 0x5A }
+func (DupX1) Mnemonic() string { return "dup_x1" }
+func (DupX1) Len() int         { return 1 }
+
+func (DupX2) Opcode() uint8    { return 0x5B }
+func (DupX2) Mnemonic() string { return "dup_x2" }
+func (DupX2) Len() int         { return 1 }
+
+func (Dup2) Opcode() uint8    { return 0x5C }
+func (Dup2) Mnemonic() string { return "dup2" }
+func (Dup2) Len() int         { return 1 }
+
+func (Dup2X1) Opcode() uint8    { return 0x5D }
+func (Dup2X1) Mnemonic() string { return "dup2_x1" }
+func (Dup2X1) Len() int         { return 1 }
+
+func (Dup2X2) Opcode() uint8    { return 0x5E }
+func (Dup2X2) Mnemonic() string { return "dup2_x2" }
+func (Dup2X2) Len() int         { return 1 }
+
+func (Swap) Opcode() uint8    { return 0x5F }
+func (Swap) Mnemonic() string { return "swap" }
+func (Swap) Len() int         { return 1 }
+
+func (Iadd) Opcode() uint8    { return 0x60 }
+func (Iadd) Mnemonic() string { return "iadd" }
+func (Iadd) Len() int         { return 1 }
+
+func (Ladd) Opcode() uint8    { return 0x61 }
+func (Ladd) Mnemonic() string { return "ladd" }
+func (Ladd) Len() int         { return 1 }
+
+func (Fadd) Opcode() uint8    { return 0x62 }
+func (Fadd) Mnemonic() string { return "fadd" }
+func (Fadd) Len() int         { return 1 }
+
+func (Dadd) Opcode() uint8    { return 0x63 }
+func (Dadd) Mnemonic() string { return "dadd" }
+func (Dadd) Len() int         { return 1 }
+
+func (Isub) Opcode() uint8    { return 0x64 }
+func (Isub) Mnemonic() string { return "isub" }
+func (Isub) Len() int         { return 1 }
+
+func (Lsub) Opcode() uint8    { return 0x65 }
+func (Lsub) Mnemonic() string { return "lsub" }
+func (Lsub) Len() int         { return 1 }
+
+func (Fsub) Opcode() uint8    { return 0x66 }
+func (Fsub) Mnemonic() string { return "fsub" }
+func (Fsub) Len() int         { return 1 }
+
+func (Dsub) Opcode() uint8    { return 0x67 }
+func (Dsub) Mnemonic() string { return "dsub" }
+func (Dsub) Len() int         { return 1 }
+
+func (Imul) Opcode() uint8    { return 0x68 }
+func (Imul) Mnemonic() string { return "imul" }
+func (Imul) Len() int         { return 1 }
+
+func (Lmul) Opcode() uint8    { return 0x69 }
+func (Lmul) Mnemonic() string { return "lmul" }
+func (Lmul) Len() int         { return 1 }
+
+func (Fmul) Opcode() uint8    { return 0x6A }
+func (Fmul) Mnemonic() string { return "fmul" }
+func (Fmul) Len() int         { return 1 }
+
+func (Dmul) Opcode() uint8    { return 0x6B }
+func (Dmul) Mnemonic() string { return "dmul" }
+func (Dmul) Len() int         { return 1 }
+
+func (Idiv) Opcode() uint8    { return 0x6C }
+func (Idiv) Mnemonic() string { return "idiv" }
+func (Idiv) Len() int         { return 1 }
+
+func (Ldiv) Opcode() uint8    { return 0x6D }
+func (Ldiv) Mnemonic() string { return "ldiv" }
+func (Ldiv) Len() int         { return 1 }
+
+func (Fdiv) Opcode() uint8    { return 0x6E }
+func (Fdiv) Mnemonic() string { return "fdiv" }
+func (Fdiv) Len() int         { return 1 }
+
+func (Ddiv) Opcode() uint8    { return 0x6F }
+func (Ddiv) Mnemonic() string { return "ddiv" }
+func (Ddiv) Len() int         { return 1 }
+
+func (Irem) Opcode() uint8    { return 0x70 }
+func (Irem) Mnemonic() string { return "irem" }
+func (Irem) Len() int         { return 1 }
+
+func (Lrem) Opcode() uint8    { return 0x71 }
+func (Lrem) Mnemonic() string { return "lrem" }
+func (Lrem) Len() int         { return 1 }
+
+func (Frem) Opcode() uint8    { return 0x72 }
+func (Frem) Mnemonic() string { return "frem" }
+func (Frem) Len() int         { return 1 }
+
+func (Drem) Opcode() uint8    { return 0x73 }
+func (Drem) Mnemonic() string { return "drem" }
+func (Drem) Len() int         { return 1 }
+
+func (Ineg) Opcode() uint8    { return 0x74 }
+func (Ineg) Mnemonic() string { return "ineg" }
+func (Ineg) Len() int         { return 1 }
+
+func (Lneg) Opcode() uint8    { return 0x75 }
+func (Lneg) Mnemonic() string { return "lneg" }
+func (Lneg) Len() int         { return 1 }
+
+func (Fneg) Opcode() uint8    { return 0x76 }
+func (Fneg) Mnemonic() string { return "fneg" }
+func (Fneg) Len() int         { return 1 }
+
+func (Dneg) Opcode() uint8    { return 0x77 }
+func (Dneg) Mnemonic() string { return "dneg" }
+func (Dneg) Len() int         { return 1 }
+
+func (Ishl) Opcode() uint8    { return 0x78 }
+func (Ishl) Mnemonic() string { return "ishl" }
+func (Ishl) Len() int         { return 1 }
+
+func (Lshl) Opcode() uint8    { return 0x79 }
+func (Lshl) Mnemonic() string { return "lshl" }
+func (Lshl) Len() int         { return 1 }
+
+func (Ishr) Opcode() uint8    { return 0x7A }
+func (Ishr) Mnemonic() string { return "ishr" }
+func (Ishr) Len() int         { return 1 }
+
+func (Lshr) Opcode() uint8    { return 0x7B }
+func (Lshr) Mnemonic() string { return "lshr" }
+func (Lshr) Len() int         { return 1 }
+
+func (Iushr) Opcode() uint8    { return 0x7C }
+func (Iushr) Mnemonic() string { return "iushr" }
+func (Iushr) Len() int         { return 1 }
+
+func (Lushr) Opcode() uint8    { return 0x7D }
+func (Lushr) Mnemonic() string { return "lushr" }
+func (Lushr) Len() int         { return 1 }
+
+func (Iand) Opcode() uint8    { return 0x7E }
+func (Iand) Mnemonic() string { return "iand" }
+func (Iand) Len() int         { return 1 }
+
+func (Land) Opcode() uint8    { return 0x7F }
+func (Land) Mnemonic() string { return "land" }
+func (Land) Len() int         { return 1 }
+
+func (Ior) Opcode() uint8    { return 0x80 }
+func (Ior) Mnemonic() string { return "ior" }
+func (Ior) Len() int         { return 1 }
+
+func (Lor) Opcode() uint8    { return 0x81 }
+func (Lor) Mnemonic() string { return "lor" }
+func (Lor) Len() int         { return 1 }
+
+func (Ixor) Opcode() uint8    { return 0x82 }
+func (Ixor) Mnemonic() string { return "ixor" }
+func (Ixor) Len() int         { return 1 }
+
+func (Lxor) Opcode() uint8    { return 0x83 }
+func (Lxor) Mnemonic() string { return "lxor" }
+func (Lxor) Len() int         { return 1 }
+
+func (Iinc) Opcode() uint8    { return 0x84 }
+func (Iinc) Mnemonic() string { return "iinc" }
+func (Iinc) Len() int         { return 3 }
+
+func (I2l) Opcode() uint8    { return 0x85 }
+func (I2l) Mnemonic() string { return "i2l" }
+func (I2l) Len() int         { return 1 }
+
+func (I2f) Opcode() uint8    { return 0x86 }
+func (I2f) Mnemonic() string { return "i2f" }
+func (I2f) Len() int         { return 1 }
+
+func (I2d) Opcode() uint8    { return 0x87 }
+func (I2d) Mnemonic() string { return "i2d" }
+func (I2d) Len() int         { return 1 }
+
+func (L2i) Opcode() uint8    { return 0x88 }
+func (L2i) Mnemonic() string { return "l2i" }
+func (L2i) Len() int         { return 1 }
+
+func (L2f) Opcode() uint8    { return 0x89 }
+func (L2f) Mnemonic() string { return "l2f" }
+func (L2f) Len() int         { return 1 }
+
+func (L2d) Opcode() uint8    { return 0x8A }
+func (L2d) Mnemonic() string { return "l2d" }
+func (L2d) Len() int         { return 1 }
+
+func (F2i) Opcode() uint8    { return 0x8B }
+func (F2i) Mnemonic() string { return "f2i" }
+func (F2i) Len() int         { return 1 }
+
+func (F2l) Opcode() uint8    { return 0x8C }
+func (F2l) Mnemonic() string { return "f2l" }
+func (F2l) Len() int         { return 1 }
+
+func (F2d) Opcode() uint8    { return 0x8D }
+func (F2d) Mnemonic() string { return "f2d" }
+func (F2d) Len() int         { return 1 }
+
+func (D2i) Opcode() uint8    { return 0x8E }
+func (D2i) Mnemonic() string { return "d2i" }
+func (D2i) Len() int         { return 1 }
+
+func (D2l) Opcode() uint8    { return 0x8F }
+func (D2l) Mnemonic() string { return "d2l" }
+func (D2l) Len() int         { return 1 }
+
+func (D2f) Opcode() uint8    { return 0x90 }
+func (D2f) Mnemonic() string { return "d2f" }
+func (D2f) Len() int         { return 1 }
+
+func (I2b) Opcode() uint8    { return 0x91 }
+func (I2b) Mnemonic() string { return "i2b" }
+func (I2b) Len() int         { return 1 }
+
+func (I2c) Opcode() uint8    { return 0x92 }
+func (I2c) Mnemonic() string { return "i2c" }
+func (I2c) Len() int         { return 1 }
+
+func (I2s) Opcode() uint8    { return 0x93 }
+func (I2s) Mnemonic() string { return "i2s" }
+func (I2s) Len() int         { return 1 }
+
+func (Lcmp) Opcode() uint8    { return 0x94 }
+func (Lcmp) Mnemonic() string { return "lcmp" }
+func (Lcmp) Len() int         { return 1 }
+
+func (Fcmpl) Opcode() uint8    { return 0x95 }
+func (Fcmpl) Mnemonic() string { return "fcmpl" }
+func (Fcmpl) Len() int         { return 1 }
+
+func (Fcmpg) Opcode() uint8    { return 0x96 }
+func (Fcmpg) Mnemonic() string { return "fcmpg" }
+func (Fcmpg) Len() int         { return 1 }
+
+func (Dcmpl) Opcode() uint8    { return 0x97 }
+func (Dcmpl) Mnemonic() string { return "dcmpl" }
+func (Dcmpl) Len() int         { return 1 }
+
+func (Dcmpg) Opcode() uint8    { return 0x98 }
+func (Dcmpg) Mnemonic() string { return "dcmpg" }
+func (Dcmpg) Len() int         { return 1 }
+
+func (Ifeq) Opcode() uint8    { return 0x99 }
+func (Ifeq) Mnemonic() string { return "ifeq" }
+func (Ifeq) Len() int         { return 3 }
+func (i Ifeq) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Ifne) Opcode() uint8    { return 0x9A }
+func (Ifne) Mnemonic() string { return "ifne" }
+func (Ifne) Len() int         { return 3 }
+func (i Ifne) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Iflt) Opcode() uint8    { return 0x9B }
+func (Iflt) Mnemonic() string { return "iflt" }
+func (Iflt) Len() int         { return 3 }
+func (i Iflt) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Ifge) Opcode() uint8    { return 0x9C }
+func (Ifge) Mnemonic() string { return "ifge" }
+func (Ifge) Len() int         { return 3 }
+func (i Ifge) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Ifgt) Opcode() uint8    { return 0x9D }
+func (Ifgt) Mnemonic() string { return "ifgt" }
+func (Ifgt) Len() int         { return 3 }
+func (i Ifgt) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Ifle) Opcode() uint8    { return 0x9E }
+func (Ifle) Mnemonic() string { return "ifle" }
+func (Ifle) Len() int         { return 3 }
+func (i Ifle) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfIcmpeq) Opcode() uint8    { return 0x9F }
+func (IfIcmpeq) Mnemonic() string { return "if_icmpeq" }
+func (IfIcmpeq) Len() int         { return 3 }
+func (i IfIcmpeq) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfIcmpne) Opcode() uint8    { return 0xA0 }
+func (IfIcmpne) Mnemonic() string { return "if_icmpne" }
+func (IfIcmpne) Len() int         { return 3 }
+func (i IfIcmpne) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfIcmplt) Opcode() uint8    { return 0xA1 }
+func (IfIcmplt) Mnemonic() string { return "if_icmplt" }
+func (IfIcmplt) Len() int         { return 3 }
+func (i IfIcmplt) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfIcmpge) Opcode() uint8    { return 0xA2 }
+func (IfIcmpge) Mnemonic() string { return "if_icmpge" }
+func (IfIcmpge) Len() int         { return 3 }
+func (i IfIcmpge) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfIcmpgt) Opcode() uint8    { return 0xA3 }
+func (IfIcmpgt) Mnemonic() string { return "if_icmpgt" }
+func (IfIcmpgt) Len() int         { return 3 }
+func (i IfIcmpgt) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfIcmple) Opcode() uint8    { return 0xA4 }
+func (IfIcmple) Mnemonic() string { return "if_icmple" }
+func (IfIcmple) Len() int         { return 3 }
+func (i IfIcmple) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfAcmpeq) Opcode() uint8    { return 0xA5 }
+func (IfAcmpeq) Mnemonic() string { return "if_acmpeq" }
+func (IfAcmpeq) Len() int         { return 3 }
+func (i IfAcmpeq) Target() int    { return i.Pc + int(i.Offset) }
+
+func (IfAcmpne) Opcode() uint8    { return 0xA6 }
+func (IfAcmpne) Mnemonic() string { return "if_acmpne" }
+func (IfAcmpne) Len() int         { return 3 }
+func (i IfAcmpne) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Goto) Opcode() uint8    { return 0xA7 }
+func (Goto) Mnemonic() string { return "goto" }
+func (Goto) Len() int         { return 3 }
+func (i Goto) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Jsr) Opcode() uint8    { return 0xA8 }
+func (Jsr) Mnemonic() string { return "jsr" }
+func (Jsr) Len() int         { return 3 }
+func (i Jsr) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Ret) Opcode() uint8    { return 0xA9 }
+func (Ret) Mnemonic() string { return "ret" }
+func (Ret) Len() int         { return 2 }
+
+func (Tableswitch) Opcode() uint8    { return 0xAA }
+func (Tableswitch) Mnemonic() string { return "tableswitch" }
+func (i Tableswitch) Len() int       { return 1 + switchPadding(i.Pc) + 12 + 4*len(i.Offsets) }
+
+func (Lookupswitch) Opcode() uint8    { return 0xAB }
+func (Lookupswitch) Mnemonic() string { return "lookupswitch" }
+func (i Lookupswitch) Len() int       { return 1 + switchPadding(i.Pc) + 8 + 8*len(i.Pairs) }
+
+func (Ireturn) Opcode() uint8    { return 0xAC }
+func (Ireturn) Mnemonic() string { return "ireturn" }
+func (Ireturn) Len() int         { return 1 }
+
+func (Lreturn) Opcode() uint8    { return 0xAD }
+func (Lreturn) Mnemonic() string { return "lreturn" }
+func (Lreturn) Len() int         { return 1 }
+
+func (Freturn) Opcode() uint8    { return 0xAE }
+func (Freturn) Mnemonic() string { return "freturn" }
+func (Freturn) Len() int         { return 1 }
+
+func (Dreturn) Opcode() uint8    { return 0xAF }
+func (Dreturn) Mnemonic() string { return "dreturn" }
+func (Dreturn) Len() int         { return 1 }
+
+func (Areturn) Opcode() uint8    { return 0xB0 }
+func (Areturn) Mnemonic() string { return "areturn" }
+func (Areturn) Len() int         { return 1 }
+
+func (Return) Opcode() uint8    { return 0xB1 }
+func (Return) Mnemonic() string { return "return" }
+func (Return) Len() int         { return 1 }
+
+func (Getstatic) Opcode() uint8    { return 0xB2 }
+func (Getstatic) Mnemonic() string { return "getstatic" }
+func (Getstatic) Len() int         { return 3 }
+
+func (Putstatic) Opcode() uint8    { return 0xB3 }
+func (Putstatic) Mnemonic() string { return "putstatic" }
+func (Putstatic) Len() int         { return 3 }
+
+func (Getfield) Opcode() uint8    { return 0xB4 }
+func (Getfield) Mnemonic() string { return "getfield" }
+func (Getfield) Len() int         { return 3 }
+
+func (Putfield) Opcode() uint8    { return 0xB5 }
+func (Putfield) Mnemonic() string { return "putfield" }
+func (Putfield) Len() int         { return 3 }
+
+func (Invokevirtual) Opcode() uint8    { return 0xB6 }
+func (Invokevirtual) Mnemonic() string { return "invokevirtual" }
+func (Invokevirtual) Len() int         { return 3 }
+
+func (Invokespecial) Opcode() uint8    { return 0xB7 }
+func (Invokespecial) Mnemonic() string { return "invokespecial" }
+func (Invokespecial) Len() int         { return 3 }
+
+func (Invokestatic) Opcode() uint8    { return 0xB8 }
+func (Invokestatic) Mnemonic() string { return "invokestatic" }
+func (Invokestatic) Len() int         { return 3 }
+
+func (Invokeinterface) Opcode() uint8    { return 0xB9 }
+func (Invokeinterface) Mnemonic() string { return "invokeinterface" }
+func (Invokeinterface) Len() int         { return 5 }
+
+func (Invokedynamic) Opcode() uint8    { return 0xBA }
+func (Invokedynamic) Mnemonic() string { return "invokedynamic" }
+func (Invokedynamic) Len() int         { return 5 }
+
+func (New) Opcode() uint8    { return 0xBB }
+func (New) Mnemonic() string { return "new" }
+func (New) Len() int         { return 3 }
+
+func (Newarray) Opcode() uint8    { return 0xBC }
+func (Newarray) Mnemonic() string { return "newarray" }
+func (Newarray) Len() int         { return 2 }
+
+func (Anewarray) Opcode() uint8    { return 0xBD }
+func (Anewarray) Mnemonic() string { return "anewarray" }
+func (Anewarray) Len() int         { return 3 }
+
+func (Arraylength) Opcode() uint8    { return 0xBE }
+func (Arraylength) Mnemonic() string { return "arraylength" }
+func (Arraylength) Len() int         { return 1 }
+
+func (Athrow) Opcode() uint8    { return 0xBF }
+func (Athrow) Mnemonic() string { return "athrow" }
+func (Athrow) Len() int         { return 1 }
+
+func (Checkcast) Opcode() uint8    { return 0xC0 }
+func (Checkcast) Mnemonic() string { return "checkcast" }
+func (Checkcast) Len() int         { return 3 }
+
+func (Instanceof) Opcode() uint8    { return 0xC1 }
+func (Instanceof) Mnemonic() string { return "instanceof" }
+func (Instanceof) Len() int         { return 3 }
+
+func (Monitorenter) Opcode() uint8    { return 0xC2 }
+func (Monitorenter) Mnemonic() string { return "monitorenter" }
+func (Monitorenter) Len() int         { return 1 }
+
+func (Monitorexit) Opcode() uint8    { return 0xC3 }
+func (Monitorexit) Mnemonic() string { return "monitorexit" }
+func (Monitorexit) Len() int         { return 1 }
+
+func (Wide) Opcode() uint8    { return 0xC4 }
+func (Wide) Mnemonic() string { return "wide" }
+func (i Wide) Len() int {
+	if i.OpCode == 0x84 {
+		return 6
+	}
+	return 4
+}
+
+func (Multianewarray) Opcode() uint8    { return 0xC5 }
+func (Multianewarray) Mnemonic() string { return "multianewarray" }
+func (Multianewarray) Len() int         { return 4 }
+
+func (Ifnull) Opcode() uint8    { return 0xC6 }
+func (Ifnull) Mnemonic() string { return "ifnull" }
+func (Ifnull) Len() int         { return 3 }
+func (i Ifnull) Target() int    { return i.Pc + int(i.Offset) }
+
+func (Ifnonnull) Opcode() uint8    { return 0xC7 }
+func (Ifnonnull) Mnemonic() string { return "ifnonnull" }
+func (Ifnonnull) Len() int         { return 3 }
+func (i Ifnonnull) Target() int    { return i.Pc + int(i.Offset) }
+
+func (GotoW) Opcode() uint8    { return 0xC8 }
+func (GotoW) Mnemonic() string { return "goto_w" }
+func (GotoW) Len() int         { return 5 }
+func (i GotoW) Target() int    { return i.Pc + int(i.Offset) }
+
+func (JsrW) Opcode() uint8    { return 0xC9 }
+func (JsrW) Mnemonic() string { return "jsr_w" }
+func (JsrW) Len() int         { return 5 }
+func (i JsrW) Target() int    { return i.Pc + int(i.Offset) }
@@ -0,0 +1,68 @@
+package classfileparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInstructionLen(t *testing.T) {
+	tests := []struct {
+		instr Instruction
+		len   int
+	}{
+		{Nop{}, 1},
+		{Sipush{}, 3},
+		{LdcW{}, 3},
+		{Ldc2W{}, 3},
+		{Wide{OpCode: 0x15}, 4},
+		{Wide{OpCode: 0xA9}, 4},
+		{Wide{OpCode: 0x84}, 6},
+		{Tableswitch{Location: Location{Pc: 0}, Offsets: []int32{1, 2}}, 1 + 3 + 12 + 8},
+		{Tableswitch{Location: Location{Pc: 3}, Offsets: []int32{1}}, 1 + 0 + 12 + 4},
+		{Lookupswitch{Location: Location{Pc: 1}}, 1 + 2 + 8},
+		{Lookupswitch{Location: Location{Pc: 2}, Pairs: []MatchOffset{{}, {}}}, 1 + 1 + 8 + 16},
+		{GotoW{}, 5},
+	}
+	for _, test := range tests {
+		if got := test.instr.Len(); got != test.len {
+			t.Errorf("%s at pc %d: Len() = %d, want %d", test.instr.Mnemonic(), test.instr.PC(), got, test.len)
+		}
+	}
+}
+
+func TestInstructionIndex(t *testing.T) {
+	code := []byte{
+		0x11, 0, 1, // 0: sipush 1
+		0xC4, 0x84, 0, 1, 0, 200, // 3: wide iinc 1 200
+		0xC4, 0x15, 1, 44, // 9: wide iload 300
+		0x13, 0, 1, // 13: ldc_w #1
+		0xAA, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 16: tableswitch 0 to 0
+		0xB1, // 36: return
+	}
+	parsed, err := parseCode(bytes.NewReader(codeAttribute(code)), ConstantPool{1: int32(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcs := []int{0, 3, 9, 13, 16, 36}
+	if len(parsed.Code) != len(pcs) {
+		t.Fatalf("code = %#v", parsed.Code)
+	}
+	for i, instr := range parsed.Code {
+		if instr.PC() != pcs[i] {
+			t.Errorf("instruction %d (%s) at pc %d, want %d", i, instr.Mnemonic(), instr.PC(), pcs[i])
+		}
+		if i+1 < len(pcs) && instr.PC()+instr.Len() != pcs[i+1] {
+			t.Errorf("%s at pc %d: Len() = %d", instr.Mnemonic(), instr.PC(), instr.Len())
+		}
+		if index, ok := parsed.InstructionIndex(pcs[i]); !ok || index != i {
+			t.Errorf("InstructionIndex(%d) = %d, %t, want %d", pcs[i], index, ok, i)
+		}
+	}
+
+	// pcs inside an instruction or outside the code do not start one
+	for _, pc := range []int{-1, 1, 5, 14, 20, 37} {
+		if index, ok := parsed.InstructionIndex(pc); ok {
+			t.Errorf("InstructionIndex(%d) = %d, true", pc, index)
+		}
+	}
+}
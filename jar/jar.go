@@ -0,0 +1,177 @@
+// Package jar reads the class files and resources of jar and zip archives.
+package jar
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Slummp/classfileparser"
+)
+
+// ManifestName is the name of the manifest entry of a jar
+const ManifestName = "META-INF/MANIFEST.MF"
+
+// maxEntrySize bounds the content ReadAll returns, so that a crafted archive cannot exhaust memory
+var maxEntrySize int64 = 256 << 20
+
+// Archive is an opened jar or zip archive
+type Archive struct {
+	reader *zip.Reader
+	closer io.Closer
+}
+
+// Open opens the archive at path. The archive must be closed once done with it.
+func Open(path string) (*Archive, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	return &Archive{reader: &reader.Reader, closer: reader}, nil
+}
+
+// NewReader returns the archive read from r, which holds size bytes
+func NewReader(r io.ReaderAt, size int64) (*Archive, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return &Archive{reader: reader}, nil
+}
+
+// Close closes the archive if it was opened with Open
+func (a *Archive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// Entry is a file stored in an archive
+type Entry struct {
+	Name    string // Slash separated path of the entry, e.g. com/example/Main.class
+	Path    string // Name without the META-INF/versions/N/ prefix of an entry selected for a release
+	Release int    // Release N of the directory an entry selected for a release comes from, 0 for a base entry
+	file    *zip.File
+}
+
+// IsClass reports whether the entry holds a class file
+func (e Entry) IsClass() bool {
+	return strings.HasSuffix(e.Name, ".class")
+}
+
+// Size returns the uncompressed size of the entry
+func (e Entry) Size() int64 {
+	return int64(e.file.UncompressedSize64)
+}
+
+// Open returns a reader of the uncompressed content of the entry
+func (e Entry) Open() (io.ReadCloser, error) {
+	return e.file.Open()
+}
+
+// ReadAll returns the uncompressed content of the entry. Entries larger than maxEntrySize are
+// rejected, whatever size they declare.
+func (e Entry) ReadAll() ([]byte, error) {
+	if e.file.UncompressedSize64 > uint64(maxEntrySize) {
+		return nil, fmt.Errorf("%s is larger than %d bytes", e.Name, maxEntrySize)
+	}
+	rc, err := e.file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", e.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", e.Name, err)
+	}
+	if int64(len(data)) > maxEntrySize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", e.Name, maxEntrySize)
+	}
+	return data, nil
+}
+
+// Class is a class file read from an archive entry
+type Class struct {
+	Entry
+	ClassFile *classfileparser.ClassFile // nil if Err is set
+	Err       error                      // Error reading or parsing the entry
+}
+
+// Entries returns the files of the archive in the order of its central directory, directories excluded
+func (a *Archive) Entries() []Entry {
+	var entries []Entry
+	for _, file := range a.reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		entries = append(entries, Entry{Name: file.Name, Path: file.Name, file: file})
+	}
+	return entries
+}
+
+// Entry returns the entry called name
+func (a *Archive) Entry(name string) (Entry, bool) {
+	for _, file := range a.reader.File {
+		if file.Name == name && !file.FileInfo().IsDir() {
+			return Entry{Name: file.Name, Path: file.Name, file: file}, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Resources returns the entries that are not class files
+func (a *Archive) Resources() []Entry {
+	var resources []Entry
+	for _, entry := range a.Entries() {
+		if !entry.IsClass() {
+			resources = append(resources, entry)
+		}
+	}
+	return resources
+}
+
+// Classes parses every class file of the archive and calls fn with each of them, in the order
+// of the central directory. An entry that cannot be read or parsed is passed with Err set, and
+// the walk goes on. Classes stops and returns the error returned by fn, if any.
+func (a *Archive) Classes(fn func(Class) error) error {
+	for _, entry := range a.Entries() {
+		if !entry.IsClass() {
+			continue
+		}
+		if err := fn(entry.Class()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Class reads and parses the class file held by the entry
+func (e Entry) Class() Class {
+	class := Class{Entry: e}
+	data, err := e.ReadAll()
+	if err != nil {
+		class.Err = err
+		return class
+	}
+	if class.ClassFile, err = classfileparser.Open(bytes.NewReader(data)); err != nil {
+		class.Err = fmt.Errorf("failed to parse %s: %w", e.Name, err)
+	}
+	return class
+}
+
+// Manifest parses the META-INF/MANIFEST.MF entry of the archive. It returns nil and no error
+// if the archive has no manifest.
+func (a *Archive) Manifest() (*Manifest, error) {
+	entry, ok := a.Entry(ManifestName)
+	if !ok {
+		return nil, nil
+	}
+	data, err := entry.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data)
+}
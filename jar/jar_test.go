@@ -0,0 +1,205 @@
+package jar
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testEntry is a file of an archive built by buildArchive
+type testEntry struct {
+	name    string
+	data    string
+	corrupt bool // Stored with a wrong CRC-32, so that reading it fails
+}
+
+func buildArchive(t *testing.T, entries ...testEntry) *Archive {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		if entry.corrupt {
+			header := &zip.FileHeader{Name: entry.name, Method: zip.Store, CRC32: 1}
+			header.CompressedSize64 = uint64(len(entry.data))
+			header.UncompressedSize64 = uint64(len(entry.data))
+			raw, err := w.CreateRaw(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw.Write([]byte(entry.data))
+			continue
+		}
+		file, err := w.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(entry.data))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func testArchive(t *testing.T) *Archive {
+	t.Helper()
+	hello, err := os.ReadFile("../testdata/Hello.class")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buildArchive(t,
+		testEntry{name: "META-INF/"},
+		testEntry{name: ManifestName, data: "Manifest-Version: 1.0\r\nMain-Class: com.example.Ma\r\n in\r\n\r\nName: a/Hello.class\r\nSealed: true\r\n"},
+		testEntry{name: "a/Hello.class", data: string(hello)},
+		testEntry{name: "a/Junk.class", data: "not a class"},
+		testEntry{name: "a/Corrupt.class", data: string(hello), corrupt: true},
+		testEntry{name: "a/config.properties", data: "key=value"},
+		testEntry{name: "a/"},
+	)
+}
+
+func TestClasses(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string // Substring of Class.Err, empty for a class that parses
+	}{
+		{"a/Hello.class", ""},
+		{"a/Junk.class", "failed to parse a/Junk.class"},
+		{"a/Corrupt.class", "failed to read a/Corrupt.class"},
+	}
+	var classes []Class
+	if err := testArchive(t).Classes(func(class Class) error {
+		classes = append(classes, class)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(classes) != len(tests) {
+		t.Fatalf("%d classes, want %d", len(classes), len(tests))
+	}
+	for i, test := range tests {
+		class := classes[i]
+		if class.Name != test.name {
+			t.Errorf("class %d = %s, want %s", i, class.Name, test.name)
+		}
+		switch {
+		case test.err == "" && (class.Err != nil || class.ClassFile == nil):
+			t.Errorf("%s: %v", test.name, class.Err)
+		case test.err != "" && (class.Err == nil || !strings.Contains(class.Err.Error(), test.err) || class.ClassFile != nil):
+			t.Errorf("%s: error = %v, want %q", test.name, class.Err, test.err)
+		}
+	}
+	if !errors.Is(classes[2].Err, zip.ErrChecksum) {
+		t.Errorf("corrupt entry error = %v", classes[2].Err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := testArchive(t).Classes(func(Class) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Classes returned %v after %d calls", err, calls)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	archive := testArchive(t)
+	tests := []struct {
+		entries []Entry
+		names   []string
+	}{
+		{archive.Entries(), []string{ManifestName, "a/Hello.class", "a/Junk.class", "a/Corrupt.class", "a/config.properties"}},
+		{archive.Resources(), []string{ManifestName, "a/config.properties"}},
+	}
+	for _, test := range tests {
+		var names []string
+		for _, entry := range test.entries {
+			names = append(names, entry.Name)
+		}
+		if strings.Join(names, " ") != strings.Join(test.names, " ") {
+			t.Errorf("entries = %q, want %q", names, test.names)
+		}
+	}
+
+	entry, ok := archive.Entry("a/config.properties")
+	if !ok || entry.IsClass() || entry.Size() != 9 {
+		t.Fatalf("entry = %+v, %t", entry, ok)
+	}
+	if data, err := entry.ReadAll(); err != nil || string(data) != "key=value" {
+		t.Errorf("ReadAll() = %q, %v", data, err)
+	}
+	if _, ok := archive.Entry("a/"); ok {
+		t.Error("a directory is returned as an entry")
+	}
+	if _, ok := archive.Entry("missing"); ok {
+		t.Error("a missing entry is found")
+	}
+}
+
+func TestReadAllLimit(t *testing.T) {
+	defer func(size int64) { maxEntrySize = size }(maxEntrySize)
+	maxEntrySize = 8
+
+	archive := buildArchive(t, testEntry{name: "small", data: "12345678"}, testEntry{name: "large", data: "123456789"})
+	entry, _ := archive.Entry("small")
+	if _, err := entry.ReadAll(); err != nil {
+		t.Errorf("small: %v", err)
+	}
+	entry, _ = archive.Entry("large")
+	if _, err := entry.ReadAll(); err == nil || !strings.Contains(err.Error(), "large is larger than 8 bytes") {
+		t.Errorf("large: error = %v", err)
+	}
+
+	// An entry declaring a smaller size than its content is cut at the limit too
+	entry.file.UncompressedSize64 = 4
+	if _, err := entry.ReadAll(); err == nil {
+		t.Error("an entry larger than its declared size is read whole")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	manifest, err := testArchive(t).Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.Main.Get("main-class"); got != "com.example.Main" {
+		t.Errorf("Main-Class = %q", got)
+	}
+	if got := manifest.Entries["a/Hello.class"].Get("Sealed"); got != "true" {
+		t.Errorf("Sealed = %q", got)
+	}
+
+	if manifest, err := buildArchive(t, testEntry{name: "A.class"}).Manifest(); manifest != nil || err != nil {
+		t.Errorf("without a manifest: %v, %v", manifest, err)
+	}
+	if _, err := buildArchive(t, testEntry{name: ManifestName, data: "no colon"}).Manifest(); err == nil {
+		t.Error("a malformed manifest is accepted")
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"Manifest-Version: 1.0\n\nSealed: true\n", "has no Name"},
+		{"Manifest-Version 1.0\n", "malformed manifest line"},
+		{"Manifest-Version: 1.0\n\n\nName: a\nSealed: true\n", ""},
+		{"Manifest-Version: 1.0\rCreated-By: test\r", ""},
+	}
+	for _, test := range tests {
+		_, err := ParseManifest([]byte(test.data))
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%q: error = %v, want %q", test.data, err, test.err)
+		}
+	}
+}
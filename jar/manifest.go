@@ -0,0 +1,66 @@
+package jar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attributes are the name: value pairs of a manifest section
+type Attributes map[string]string
+
+// Get returns the value of the attribute called name. Attribute names are case-insensitive.
+func (a Attributes) Get(name string) string {
+	if value, ok := a[name]; ok {
+		return value
+	}
+	for key, value := range a {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Manifest is a parsed META-INF/MANIFEST.MF
+type Manifest struct {
+	Main    Attributes            // Main section, e.g. Main-Class or Multi-Release
+	Entries map[string]Attributes // Per-entry sections, indexed by their Name attribute
+}
+
+// ParseManifest parses the content of a manifest, joining continuation lines
+func ParseManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{Main: Attributes{}, Entries: map[string]Attributes{}}
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\n"), "\r", "\n")
+
+	// Join continuation lines, which start with a single space
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, " ") && len(lines) > 0 && lines[len(lines)-1] != "" {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	section := manifest.Main
+	for _, line := range lines {
+		if line == "" {
+			// Sections are separated by blank lines
+			section = nil
+			continue
+		}
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("malformed manifest line %q", line)
+		}
+		if section == nil {
+			if !strings.EqualFold(name, "Name") {
+				return nil, fmt.Errorf("manifest section starting with %q has no Name", line)
+			}
+			section = Attributes{}
+			manifest.Entries[value] = section
+		}
+		section[name] = value
+	}
+	return manifest, nil
+}
@@ -0,0 +1,108 @@
+package jar
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// versionsDir holds the versioned entries of a multi-release jar
+const versionsDir = "META-INF/versions/"
+
+// IsMultiRelease reports whether the manifest of the archive declares Multi-Release: true. The
+// resolving methods below treat an archive with a malformed manifest as not multi-release.
+func (a *Archive) IsMultiRelease() (bool, error) {
+	manifest, err := a.Manifest()
+	if err != nil || manifest == nil {
+		return false, err
+	}
+	return strings.EqualFold(strings.TrimSpace(manifest.Main.Get("Multi-Release")), "true"), nil
+}
+
+// splitVersioned splits the name of an entry stored under META-INF/versions/N/ into its path and N
+func splitVersioned(name string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(name, versionsDir)
+	if !ok {
+		return name, 0, false
+	}
+	dir, path, ok := strings.Cut(rest, "/")
+	if !ok || path == "" {
+		return name, 0, false
+	}
+	release, err := strconv.Atoi(dir)
+	// Versioned directories start at Java 9
+	if err != nil || release < 9 {
+		return name, 0, false
+	}
+	return path, release, true
+}
+
+// ReleaseEntries returns the entries seen by a Java release runtime, the way the JDK resolves
+// a multi-release jar: each path is taken from the highest META-INF/versions/N/ directory with
+// N at most release, or from the base entry otherwise, and versioned entries are hidden. Other
+// archives return their entries as they are. Each entry appears once, where its path first
+// appears in the central directory.
+func (a *Archive) ReleaseEntries(release int) []Entry {
+	entries := a.Entries()
+	if multiRelease, _ := a.IsMultiRelease(); !multiRelease {
+		return entries
+	}
+
+	var paths []string
+	selected := map[string]Entry{}
+	for _, entry := range entries {
+		path, version, versioned := splitVersioned(entry.Name)
+		if versioned && version > release {
+			continue
+		}
+		current, seen := selected[path]
+		if !seen {
+			paths = append(paths, path)
+		} else if current.Release >= version {
+			continue
+		}
+		entry.Path, entry.Release = path, version
+		selected[path] = entry
+	}
+
+	resolved := make([]Entry, len(paths))
+	for i, path := range paths {
+		resolved[i] = selected[path]
+	}
+	return resolved
+}
+
+// ReleaseClasses is like Classes, but walks the class files seen by a Java release runtime, as
+// selected by ReleaseEntries
+func (a *Archive) ReleaseClasses(release int, fn func(Class) error) error {
+	for _, entry := range a.ReleaseEntries(release) {
+		if !entry.IsClass() {
+			continue
+		}
+		if err := fn(entry.Class()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Variants returns every variant of the class file at path, such as com/example/Main.class: the
+// base entry and the entries of the META-INF/versions/N/ directories of a multi-release jar,
+// ordered by release. Their class file major version is available from ClassFile.MajorVersion.
+func (a *Archive) Variants(path string) []Class {
+	multiRelease, _ := a.IsMultiRelease()
+	var variants []Class
+	for _, entry := range a.Entries() {
+		entryPath, release, versioned := splitVersioned(entry.Name)
+		if versioned && !multiRelease {
+			continue
+		}
+		if entryPath != path {
+			continue
+		}
+		entry.Path, entry.Release = entryPath, release
+		variants = append(variants, entry.Class())
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Release < variants[j].Release })
+	return variants
+}
@@ -0,0 +1,103 @@
+package jar
+
+import (
+	"reflect"
+	"testing"
+)
+
+const multiReleaseManifest = "Manifest-Version: 1.0\nMulti-Release: true\n"
+
+func multiReleaseArchive(t *testing.T, manifest string) *Archive {
+	t.Helper()
+	return buildArchive(t,
+		testEntry{name: ManifestName, data: manifest},
+		testEntry{name: "META-INF/versions/11/a/A.class", data: "a11"},
+		testEntry{name: "a/A.class", data: "a"},
+		testEntry{name: "META-INF/versions/9/a/A.class", data: "a9"},
+		testEntry{name: "META-INF/versions/17/a/A.class", data: "a17"},
+		testEntry{name: "a/B.class", data: "b"},
+		testEntry{name: "META-INF/versions/17/a/C.class", data: "c17"},
+		testEntry{name: "META-INF/versions/8/a/D.class", data: "d8"},
+	)
+}
+
+// releaseView lists the Path, Release and Name of each entry seen by release
+func releaseView(archive *Archive, release int) [][3]any {
+	var view [][3]any
+	for _, entry := range archive.ReleaseEntries(release) {
+		if entry.IsClass() {
+			view = append(view, [3]any{entry.Path, entry.Release, entry.Name})
+		}
+	}
+	return view
+}
+
+func TestReleaseEntries(t *testing.T) {
+	archive := multiReleaseArchive(t, multiReleaseManifest)
+	tests := []struct {
+		release int
+		want    [][3]any
+	}{
+		{8, [][3]any{{"a/A.class", 0, "a/A.class"}, {"a/B.class", 0, "a/B.class"}, {"META-INF/versions/8/a/D.class", 0, "META-INF/versions/8/a/D.class"}}},
+		{9, [][3]any{{"a/A.class", 9, "META-INF/versions/9/a/A.class"}, {"a/B.class", 0, "a/B.class"}, {"META-INF/versions/8/a/D.class", 0, "META-INF/versions/8/a/D.class"}}},
+		// The highest N at most the release wins, wherever it is in the central directory
+		{16, [][3]any{{"a/A.class", 11, "META-INF/versions/11/a/A.class"}, {"a/B.class", 0, "a/B.class"}, {"META-INF/versions/8/a/D.class", 0, "META-INF/versions/8/a/D.class"}}},
+		{21, [][3]any{{"a/A.class", 17, "META-INF/versions/17/a/A.class"}, {"a/B.class", 0, "a/B.class"}, {"a/C.class", 17, "META-INF/versions/17/a/C.class"}, {"META-INF/versions/8/a/D.class", 0, "META-INF/versions/8/a/D.class"}}},
+	}
+	for _, test := range tests {
+		if got := releaseView(archive, test.release); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("release %d: %v, want %v", test.release, got, test.want)
+		}
+	}
+
+	var names []string
+	if err := archive.ReleaseClasses(16, func(class Class) error {
+		names = append(names, class.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"META-INF/versions/11/a/A.class", "a/B.class", "META-INF/versions/8/a/D.class"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReleaseClasses(16) = %q, want %q", names, want)
+	}
+}
+
+func TestReleaseEntriesNotMultiRelease(t *testing.T) {
+	for _, manifest := range []string{"Manifest-Version: 1.0\nMulti-Release: false\n", "Multi-Release true\n"} {
+		archive := multiReleaseArchive(t, manifest)
+		if multiRelease, _ := archive.IsMultiRelease(); multiRelease {
+			t.Errorf("%q is multi-release", manifest)
+		}
+		got := archive.ReleaseEntries(21)
+		if want := archive.Entries(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: ReleaseEntries() = %v, want %v", manifest, got, want)
+		}
+		if variants := archive.Variants("a/A.class"); len(variants) != 1 || variants[0].Name != "a/A.class" {
+			t.Errorf("%q: Variants() = %v", manifest, variants)
+		}
+	}
+	if _, err := multiReleaseArchive(t, "Multi-Release true\n").IsMultiRelease(); err == nil {
+		t.Error("IsMultiRelease hides a malformed manifest")
+	}
+}
+
+func TestVariants(t *testing.T) {
+	archive := multiReleaseArchive(t, multiReleaseManifest)
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"a/A.class", []string{"a/A.class", "META-INF/versions/9/a/A.class", "META-INF/versions/11/a/A.class", "META-INF/versions/17/a/A.class"}},
+		{"a/C.class", []string{"META-INF/versions/17/a/C.class"}},
+		{"a/D.class", nil},
+	}
+	for _, test := range tests {
+		var names []string
+		for _, variant := range archive.Variants(test.path) {
+			names = append(names, variant.Name)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("Variants(%s) = %q, want %q", test.path, names, test.want)
+		}
+	}
+}
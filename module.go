@@ -0,0 +1,143 @@
+package classfileparser
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// readModule reads the body of a Module attribute
+func readModule(reader *bytes.Reader, cp ConstantPool) (ModuleInfo, error) {
+	var module ModuleInfo
+	if err := read(reader, &module.NameIndex, &module.Flags, &module.VersionIndex); err != nil {
+		return module, err
+	}
+	name, err := constant[Module](cp, module.NameIndex)
+	if err != nil {
+		return module, err
+	}
+	version, err := optionalConstant[Utf8](cp, module.VersionIndex)
+	if err != nil {
+		return module, err
+	}
+	module.Name, module.Version = string(name), string(version)
+
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return module, err
+	}
+	module.Requires = make([]ModuleRequire, count)
+	for i := range module.Requires {
+		require := &module.Requires[i]
+		if err := read(reader, &require.NameIndex, &require.Flags, &require.VersionIndex); err != nil {
+			return module, err
+		}
+		name, err := constant[Module](cp, require.NameIndex)
+		if err != nil {
+			return module, fmt.Errorf("requires %d: %w", i, err)
+		}
+		version, err := optionalConstant[Utf8](cp, require.VersionIndex)
+		if err != nil {
+			return module, fmt.Errorf("requires %d: %w", i, err)
+		}
+		require.Name, require.Version = string(name), string(version)
+	}
+
+	if module.Exports, err = readModuleExports(reader, cp, "exports"); err != nil {
+		return module, err
+	}
+	if module.Opens, err = readModuleExports(reader, cp, "opens"); err != nil {
+		return module, err
+	}
+
+	if module.UsesIndex, err = readIndexes(reader); err != nil {
+		return module, err
+	}
+	if module.Uses, err = classNames(module.UsesIndex, cp); err != nil {
+		return module, fmt.Errorf("uses: %w", err)
+	}
+
+	if err := read(reader, &count); err != nil {
+		return module, err
+	}
+	module.Provides = make([]ModuleProvide, count)
+	for i := range module.Provides {
+		provide := &module.Provides[i]
+		if err := read(reader, &provide.ServiceIndex); err != nil {
+			return module, err
+		}
+		if provide.WithIndex, err = readIndexes(reader); err != nil {
+			return module, err
+		}
+		service, err := constant[Class](cp, provide.ServiceIndex)
+		if err != nil {
+			return module, fmt.Errorf("provides %d: %w", i, err)
+		}
+		provide.Service = string(service)
+		if provide.With, err = classNames(provide.WithIndex, cp); err != nil {
+			return module, fmt.Errorf("provides %s: %w", provide.Service, err)
+		}
+	}
+	return module, nil
+}
+
+// readModuleExports reads the exports or opens table of a Module attribute, which share their layout
+func readModuleExports(reader *bytes.Reader, cp ConstantPool, directive string) ([]ModuleExport, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	exports := make([]ModuleExport, count)
+	for i := range exports {
+		export := &exports[i]
+		if err := read(reader, &export.PackageIndex, &export.Flags); err != nil {
+			return nil, err
+		}
+		var err error
+		if export.ToIndex, err = readIndexes(reader); err != nil {
+			return nil, err
+		}
+		pkg, err := constant[Package](cp, export.PackageIndex)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", directive, i, err)
+		}
+		export.Package = string(pkg)
+		export.To = make([]string, len(export.ToIndex))
+		for j, index := range export.ToIndex {
+			to, err := constant[Module](cp, index)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", directive, export.Package, err)
+			}
+			export.To[j] = string(to)
+		}
+	}
+	return exports, nil
+}
+
+// readIndexes reads a u2 count followed by as many u2 constant pool indexes
+func readIndexes(reader *bytes.Reader) ([]uint16, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	if int(count)*2 > reader.Len() {
+		return nil, fmt.Errorf("%d indexes overrun the attribute", count)
+	}
+	indexes := make([]uint16, count)
+	if err := read(reader, indexes); err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
+
+// classNames resolves CONSTANT_Class indexes to internal names
+func classNames(indexes []uint16, cp ConstantPool) ([]string, error) {
+	names := make([]string, len(indexes))
+	for i, index := range indexes {
+		class, err := constant[Class](cp, index)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = string(class)
+	}
+	return names, nil
+}
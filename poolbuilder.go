@@ -0,0 +1,229 @@
+package classfileparser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// PoolBuilder allocates raw constant pool entries, reusing an identical entry whenever
+// one already exists. Seeded with the pool of a parsed class, existing indexes stay valid,
+// so attributes referring to them can be kept as they are.
+type PoolBuilder struct {
+	pool    []CpInfo
+	entries map[string]uint16
+	err     error
+}
+
+// NewPoolBuilder returns a PoolBuilder starting from a copy of pool
+func NewPoolBuilder(pool []CpInfo) *PoolBuilder {
+	b := &PoolBuilder{
+		pool:    append([]CpInfo(nil), pool...),
+		entries: map[string]uint16{},
+	}
+	for i, entry := range b.pool {
+		if entry.Tag == 0 {
+			continue
+		}
+		if _, ok := b.entries[poolKey(entry)]; !ok {
+			b.entries[poolKey(entry)] = uint16(i + 1)
+		}
+	}
+	return b
+}
+
+// Pool returns the raw constant pool built so far, ready to be stored in ClassFile.ConstantPool
+func (b *PoolBuilder) Pool() []CpInfo {
+	return append([]CpInfo(nil), b.pool...)
+}
+
+// Err reports whether the pool overflowed. Once it has, every allocation returns index 0.
+func (b *PoolBuilder) Err() error {
+	return b.err
+}
+
+func poolKey(entry CpInfo) string {
+	return string(entry.Tag) + string(entry.Info)
+}
+
+// add returns the index of the entry with the given tag and info, appending it if needed
+func (b *PoolBuilder) add(tag uint8, info []byte) uint16 {
+	entry := CpInfo{Tag: tag, Info: info}
+	if index, ok := b.entries[poolKey(entry)]; ok {
+		return index
+	}
+	if b.err != nil {
+		return 0
+	}
+	slots := 1
+	if tag == 5 || tag == 6 {
+		slots = 2
+	}
+	if len(b.pool)+slots >= 0xFFFF {
+		b.err = fmt.Errorf("constant pool is full")
+		return 0
+	}
+	b.pool = append(b.pool, entry)
+	index := uint16(len(b.pool))
+	if slots == 2 {
+		b.pool = append(b.pool, CpInfo{})
+	}
+	b.entries[poolKey(entry)] = index
+	return index
+}
+
+func u2(values ...uint16) []byte {
+	var info []byte
+	for _, v := range values {
+		info = binary.BigEndian.AppendUint16(info, v)
+	}
+	return info
+}
+
+// Utf8 returns the index of a CONSTANT_Utf8 entry holding s
+func (b *PoolBuilder) Utf8(s string) uint16 {
+	if len(s) > 0xFFFF {
+		if b.err == nil {
+			b.err = fmt.Errorf("string of %d bytes is too long for the constant pool", len(s))
+		}
+		return 0
+	}
+	return b.add(1, []byte(s))
+}
+
+// Integer returns the index of a CONSTANT_Integer entry holding v
+func (b *PoolBuilder) Integer(v int32) uint16 {
+	return b.add(3, binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+// Float returns the index of a CONSTANT_Float entry holding v
+func (b *PoolBuilder) Float(v float32) uint16 {
+	return b.add(4, binary.BigEndian.AppendUint32(nil, math.Float32bits(v)))
+}
+
+// Long returns the index of a CONSTANT_Long entry holding v
+func (b *PoolBuilder) Long(v int64) uint16 {
+	return b.add(5, binary.BigEndian.AppendUint64(nil, uint64(v)))
+}
+
+// Double returns the index of a CONSTANT_Double entry holding v
+func (b *PoolBuilder) Double(v float64) uint16 {
+	return b.add(6, binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+// Class returns the index of a CONSTANT_Class entry for the internal name
+func (b *PoolBuilder) Class(name string) uint16 {
+	return b.add(7, u2(b.Utf8(name)))
+}
+
+// String returns the index of a CONSTANT_String entry holding s
+func (b *PoolBuilder) String(s string) uint16 {
+	return b.add(8, u2(b.Utf8(s)))
+}
+
+// Fieldref returns the index of a CONSTANT_Fieldref entry
+func (b *PoolBuilder) Fieldref(class, name, descriptor string) uint16 {
+	return b.add(9, u2(b.Class(class), b.NameAndType(name, descriptor)))
+}
+
+// Methodref returns the index of a CONSTANT_Methodref entry
+func (b *PoolBuilder) Methodref(class, name, descriptor string) uint16 {
+	return b.add(10, u2(b.Class(class), b.NameAndType(name, descriptor)))
+}
+
+// InterfaceMethodref returns the index of a CONSTANT_InterfaceMethodref entry
+func (b *PoolBuilder) InterfaceMethodref(class, name, descriptor string) uint16 {
+	return b.add(11, u2(b.Class(class), b.NameAndType(name, descriptor)))
+}
+
+// NameAndType returns the index of a CONSTANT_NameAndType entry
+func (b *PoolBuilder) NameAndType(name, descriptor string) uint16 {
+	return b.add(12, u2(b.Utf8(name), b.Utf8(descriptor)))
+}
+
+// MethodHandle returns the index of a CONSTANT_MethodHandle entry
+func (b *PoolBuilder) MethodHandle(handle MethodHandle) (uint16, error) {
+	for kind, name := range referenceKinds {
+		if name != handle.Kind {
+			continue
+		}
+		var ref uint16
+		switch {
+		case kind <= 4:
+			ref = b.Fieldref(handle.Class, handle.Name, handle.Type)
+		case kind == 9 || handle.Interface:
+			ref = b.InterfaceMethodref(handle.Class, handle.Name, handle.Type)
+		default:
+			ref = b.Methodref(handle.Class, handle.Name, handle.Type)
+		}
+		return b.add(15, append([]byte{kind}, u2(ref)...)), nil
+	}
+	return 0, fmt.Errorf("unknown method handle kind %q", handle.Kind)
+}
+
+// MethodType returns the index of a CONSTANT_MethodType entry
+func (b *PoolBuilder) MethodType(descriptor string) uint16 {
+	return b.add(16, u2(b.Utf8(descriptor)))
+}
+
+// Dynamic returns the index of a CONSTANT_Dynamic entry using the given bootstrap method
+func (b *PoolBuilder) Dynamic(bootstrapIndex uint16, name, descriptor string) uint16 {
+	return b.add(17, u2(bootstrapIndex, b.NameAndType(name, descriptor)))
+}
+
+// InvokeDynamic returns the index of a CONSTANT_InvokeDynamic entry using the given bootstrap method
+func (b *PoolBuilder) InvokeDynamic(bootstrapIndex uint16, name, descriptor string) uint16 {
+	return b.add(18, u2(bootstrapIndex, b.NameAndType(name, descriptor)))
+}
+
+// Module returns the index of a CONSTANT_Module entry
+func (b *PoolBuilder) Module(name string) uint16 {
+	return b.add(19, u2(b.Utf8(name)))
+}
+
+// Package returns the index of a CONSTANT_Package entry
+func (b *PoolBuilder) Package(name string) uint16 {
+	return b.add(20, u2(b.Utf8(name)))
+}
+
+// Constant returns the index of the entry holding value, typed like the values of a ConstantPool
+func (b *PoolBuilder) Constant(value interface{}) (uint16, error) {
+	switch value := value.(type) {
+	case Utf8:
+		return b.Utf8(string(value)), nil
+	case int32:
+		return b.Integer(value), nil
+	case float32:
+		return b.Float(value), nil
+	case int64:
+		return b.Long(value), nil
+	case float64:
+		return b.Double(value), nil
+	case Class:
+		return b.Class(string(value)), nil
+	case string:
+		return b.String(value), nil
+	case Fieldref:
+		return b.Fieldref(value.Class, value.Name, value.Type), nil
+	case Methodref:
+		return b.Methodref(value.Class, value.Name, value.Type), nil
+	case InterfaceMethodref:
+		return b.InterfaceMethodref(value.Class, value.Name, value.Type), nil
+	case NameAndType:
+		return b.NameAndType(value.Name, value.Type), nil
+	case MethodHandle:
+		return b.MethodHandle(value)
+	case MethodType:
+		return b.MethodType(string(value)), nil
+	case Dynamic:
+		return b.Dynamic(value.BootstrapIndex, value.Name, value.Type), nil
+	case InvokeDynamic:
+		return b.InvokeDynamic(value.BootstrapIndex, value.Name, value.Type), nil
+	case Module:
+		return b.Module(string(value)), nil
+	case Package:
+		return b.Package(string(value)), nil
+	default:
+		return 0, fmt.Errorf("%T is not a constant pool value", value)
+	}
+}
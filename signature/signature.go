@@ -0,0 +1,534 @@
+// Package signature parses the generic signatures stored in Signature attributes (JVMS §4.7.9.1)
+// into typed values, which render back as signatures or as Java source.
+package signature
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+// Type is a Java type signature: a descriptor.Base, a ClassType, a TypeVariable or an Array
+type Type interface {
+	// String returns the signature of the type, e.g. Ljava/util/List<TT;>;
+	String() string
+	// JavaName returns the type as written in Java source, e.g. java.util.List<T>
+	JavaName() string
+}
+
+// ClassType is a possibly parameterized class or interface type
+type ClassType struct {
+	Outer         *ClassType     // Enclosing class for an inner class suffix, nil for the outermost class
+	Name          string         // Internal name of an outermost class (java/util/Map), simple name of an inner class (Entry)
+	TypeArguments []TypeArgument // Empty for a class that is not parameterized
+}
+
+// InternalName returns the internal name of the class, e.g. java/util/Map$Entry
+func (c ClassType) InternalName() string {
+	if c.Outer != nil {
+		return c.Outer.InternalName() + "$" + c.Name
+	}
+	return c.Name
+}
+
+// Erasure returns the type without its type arguments
+func (c ClassType) Erasure() descriptor.Object {
+	return descriptor.Object{Name: c.InternalName()}
+}
+
+func (c ClassType) String() string {
+	return "L" + c.signature() + ";"
+}
+
+func (c ClassType) signature() string {
+	var b strings.Builder
+	if c.Outer != nil {
+		b.WriteString(c.Outer.signature())
+		b.WriteByte('.')
+	}
+	b.WriteString(c.Name)
+	if len(c.TypeArguments) > 0 {
+		b.WriteByte('<')
+		for _, argument := range c.TypeArguments {
+			b.WriteString(argument.String())
+		}
+		b.WriteByte('>')
+	}
+	return b.String()
+}
+
+// JavaName returns the class type as written in Java source, e.g. java.util.Map<K, V>. Inner
+// classes of parameterized classes are joined with a dot, other inner classes with a dollar sign.
+func (c ClassType) JavaName() string {
+	var b strings.Builder
+	if c.Outer != nil {
+		b.WriteString(c.Outer.JavaName())
+		if len(c.Outer.TypeArguments) > 0 {
+			b.WriteByte('.')
+		} else {
+			b.WriteByte('$')
+		}
+		b.WriteString(c.Name)
+	} else {
+		b.WriteString(strings.ReplaceAll(c.Name, "/", "."))
+	}
+	if len(c.TypeArguments) > 0 {
+		arguments := make([]string, len(c.TypeArguments))
+		for i, argument := range c.TypeArguments {
+			arguments[i] = argument.JavaName()
+		}
+		b.WriteString("<" + strings.Join(arguments, ", ") + ">")
+	}
+	return b.String()
+}
+
+// Wildcard indicators of a TypeArgument
+const (
+	Exact   byte = 0   // T
+	Extends byte = '+' // ? extends T
+	Super   byte = '-' // ? super T
+	Any     byte = '*' // ?
+)
+
+// TypeArgument is a type argument of a parameterized type
+type TypeArgument struct {
+	Wildcard byte // Exact, Extends, Super or Any
+	Type     Type // Bound of the argument, nil for Any
+}
+
+func (a TypeArgument) String() string {
+	switch a.Wildcard {
+	case Any:
+		return "*"
+	case Exact:
+		return a.Type.String()
+	default:
+		return string(rune(a.Wildcard)) + a.Type.String()
+	}
+}
+
+// JavaName returns the type argument as written in Java source, e.g. ? extends java.lang.Number
+func (a TypeArgument) JavaName() string {
+	switch a.Wildcard {
+	case Any:
+		return "?"
+	case Extends:
+		return "? extends " + a.Type.JavaName()
+	case Super:
+		return "? super " + a.Type.JavaName()
+	default:
+		return a.Type.JavaName()
+	}
+}
+
+// TypeVariable is a reference to a type parameter
+type TypeVariable struct {
+	Name string
+}
+
+func (v TypeVariable) String() string {
+	return "T" + v.Name + ";"
+}
+
+// JavaName returns the name of the type variable
+func (v TypeVariable) JavaName() string {
+	return v.Name
+}
+
+// Array is an array type
+type Array struct {
+	Dimensions int  // Number of dimensions, from 1 to 255
+	Element    Type // Element type, anything but an Array
+}
+
+func (a Array) String() string {
+	return strings.Repeat("[", a.Dimensions) + a.Element.String()
+}
+
+// JavaName returns the array type as written in Java source, e.g. java.util.List<T>[]
+func (a Array) JavaName() string {
+	return a.Element.JavaName() + strings.Repeat("[]", a.Dimensions)
+}
+
+// TypeParameter is a type parameter declared by a generic class or method
+type TypeParameter struct {
+	Name            string
+	ClassBound      Type   // nil when the parameter only has interface bounds
+	InterfaceBounds []Type // Interface bounds, after the class bound
+}
+
+func (p TypeParameter) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	b.WriteByte(':')
+	if p.ClassBound != nil {
+		b.WriteString(p.ClassBound.String())
+	}
+	for _, bound := range p.InterfaceBounds {
+		b.WriteByte(':')
+		b.WriteString(bound.String())
+	}
+	return b.String()
+}
+
+// JavaName returns the type parameter as written in Java source, e.g. T extends java.lang.Comparable<T>.
+// A sole java.lang.Object bound is left out.
+func (p TypeParameter) JavaName() string {
+	var bounds []string
+	if p.ClassBound != nil {
+		if class, ok := p.ClassBound.(ClassType); !ok || class.Outer != nil || class.Name != "java/lang/Object" || len(p.InterfaceBounds) > 0 {
+			bounds = append(bounds, p.ClassBound.JavaName())
+		}
+	}
+	for _, bound := range p.InterfaceBounds {
+		bounds = append(bounds, bound.JavaName())
+	}
+	if len(bounds) == 0 {
+		return p.Name
+	}
+	return p.Name + " extends " + strings.Join(bounds, " & ")
+}
+
+// ClassSignature is the signature of a generic class or interface, or of a class extending or
+// implementing a parameterized type
+type ClassSignature struct {
+	TypeParameters []TypeParameter
+	Superclass     ClassType
+	Interfaces     []ClassType
+}
+
+func (s ClassSignature) String() string {
+	var b strings.Builder
+	writeTypeParameters(&b, s.TypeParameters)
+	b.WriteString(s.Superclass.String())
+	for _, iface := range s.Interfaces {
+		b.WriteString(iface.String())
+	}
+	return b.String()
+}
+
+// MethodSignature is the signature of a generic method, or of a method using parameterized types
+type MethodSignature struct {
+	TypeParameters []TypeParameter
+	Params         []Type
+	Return         Type   // descriptor.Void for methods returning nothing
+	Throws         []Type // ClassType or TypeVariable
+}
+
+func (s MethodSignature) String() string {
+	var b strings.Builder
+	writeTypeParameters(&b, s.TypeParameters)
+	b.WriteByte('(')
+	for _, param := range s.Params {
+		b.WriteString(param.String())
+	}
+	b.WriteByte(')')
+	b.WriteString(s.Return.String())
+	for _, throws := range s.Throws {
+		b.WriteByte('^')
+		b.WriteString(throws.String())
+	}
+	return b.String()
+}
+
+// JavaDeclaration returns the declaration of a method called name with this signature as written
+// in Java source, e.g. <T> java.util.List<T> asList(T[])
+func (s MethodSignature) JavaDeclaration(name string) string {
+	var b strings.Builder
+	if len(s.TypeParameters) > 0 {
+		parameters := make([]string, len(s.TypeParameters))
+		for i, parameter := range s.TypeParameters {
+			parameters[i] = parameter.JavaName()
+		}
+		b.WriteString("<" + strings.Join(parameters, ", ") + "> ")
+	}
+	params := make([]string, len(s.Params))
+	for i, param := range s.Params {
+		params[i] = param.JavaName()
+	}
+	b.WriteString(s.Return.JavaName() + " " + name + "(" + strings.Join(params, ", ") + ")")
+	if len(s.Throws) > 0 {
+		throws := make([]string, len(s.Throws))
+		for i, t := range s.Throws {
+			throws[i] = t.JavaName()
+		}
+		b.WriteString(" throws " + strings.Join(throws, ", "))
+	}
+	return b.String()
+}
+
+func writeTypeParameters(b *strings.Builder, parameters []TypeParameter) {
+	if len(parameters) == 0 {
+		return
+	}
+	b.WriteByte('<')
+	for _, parameter := range parameters {
+		b.WriteString(parameter.String())
+	}
+	b.WriteByte('>')
+}
+
+// ParseClass parses a class signature such as <T:Ljava/lang/Object;>Ljava/lang/Object;Ljava/lang/Comparable<TT;>;
+func ParseClass(s string) (ClassSignature, error) {
+	p := parser{s: s}
+	var signature ClassSignature
+	var err error
+	if signature.TypeParameters, err = p.typeParameters(); err != nil {
+		return ClassSignature{}, err
+	}
+	if signature.Superclass, err = p.classType(); err != nil {
+		return ClassSignature{}, err
+	}
+	for p.pos < len(s) {
+		iface, err := p.classType()
+		if err != nil {
+			return ClassSignature{}, err
+		}
+		signature.Interfaces = append(signature.Interfaces, iface)
+	}
+	return signature, nil
+}
+
+// ParseMethod parses a method signature such as <T:Ljava/lang/Object;>([TT;)Ljava/util/List<TT;>;
+func ParseMethod(s string) (MethodSignature, error) {
+	p := parser{s: s}
+	var signature MethodSignature
+	var err error
+	if signature.TypeParameters, err = p.typeParameters(); err != nil {
+		return MethodSignature{}, err
+	}
+	if !p.consume('(') {
+		return MethodSignature{}, p.errorf("expected '('")
+	}
+	signature.Params = []Type{}
+	for !p.consume(')') {
+		param, err := p.javaType()
+		if err != nil {
+			return MethodSignature{}, err
+		}
+		signature.Params = append(signature.Params, param)
+	}
+	if p.consume('V') {
+		signature.Return = descriptor.Void
+	} else if signature.Return, err = p.javaType(); err != nil {
+		return MethodSignature{}, err
+	}
+	for p.consume('^') {
+		var throws Type
+		if p.peek() == 'T' {
+			throws, err = p.typeVariable()
+		} else {
+			throws, err = p.classType()
+		}
+		if err != nil {
+			return MethodSignature{}, err
+		}
+		signature.Throws = append(signature.Throws, throws)
+	}
+	if p.pos != len(s) {
+		return MethodSignature{}, p.errorf("unexpected trailing characters")
+	}
+	return signature, nil
+}
+
+// ParseField parses a field signature, which is a reference type such as Ljava/util/List<Ljava/lang/String;>;
+func ParseField(s string) (Type, error) {
+	p := parser{s: s}
+	t, err := p.referenceType()
+	if err == nil && p.pos != len(s) {
+		err = p.errorf("unexpected trailing characters")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("malformed signature %q at %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// identifier reads an unqualified name, which ends at any of . ; [ / < > :
+func (p *parser) identifier() (string, error) {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(".;[/<>:", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected an identifier")
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *parser) typeParameters() ([]TypeParameter, error) {
+	if !p.consume('<') {
+		return nil, nil
+	}
+	var parameters []TypeParameter
+	for !p.consume('>') {
+		var parameter TypeParameter
+		var err error
+		if parameter.Name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+		if !p.consume(':') {
+			return nil, p.errorf("expected ':'")
+		}
+		if c := p.peek(); c == 'L' || c == 'T' || c == '[' {
+			if parameter.ClassBound, err = p.referenceType(); err != nil {
+				return nil, err
+			}
+		}
+		for p.consume(':') {
+			bound, err := p.referenceType()
+			if err != nil {
+				return nil, err
+			}
+			parameter.InterfaceBounds = append(parameter.InterfaceBounds, bound)
+		}
+		parameters = append(parameters, parameter)
+	}
+	if len(parameters) == 0 {
+		return nil, p.errorf("empty type parameters")
+	}
+	return parameters, nil
+}
+
+func (p *parser) javaType() (Type, error) {
+	switch c := descriptor.Base(p.peek()); c {
+	case descriptor.Byte, descriptor.Char, descriptor.Double, descriptor.Float,
+		descriptor.Int, descriptor.Long, descriptor.Short, descriptor.Boolean:
+		p.pos++
+		return c, nil
+	}
+	return p.referenceType()
+}
+
+func (p *parser) referenceType() (Type, error) {
+	switch p.peek() {
+	case 'L':
+		return p.classType()
+	case 'T':
+		return p.typeVariable()
+	case '[':
+		dimensions := 0
+		for p.consume('[') {
+			dimensions++
+		}
+		if dimensions > 255 {
+			return nil, p.errorf("array of %d dimensions exceeds the limit of 255", dimensions)
+		}
+		element, err := p.javaType()
+		if err != nil {
+			return nil, err
+		}
+		return Array{Dimensions: dimensions, Element: element}, nil
+	case 0:
+		return nil, p.errorf("unexpected end of signature")
+	default:
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+}
+
+func (p *parser) typeVariable() (TypeVariable, error) {
+	if !p.consume('T') {
+		return TypeVariable{}, p.errorf("expected 'T'")
+	}
+	name, err := p.identifier()
+	if err != nil {
+		return TypeVariable{}, err
+	}
+	if !p.consume(';') {
+		return TypeVariable{}, p.errorf("expected ';'")
+	}
+	return TypeVariable{Name: name}, nil
+}
+
+func (p *parser) classType() (ClassType, error) {
+	if !p.consume('L') {
+		return ClassType{}, p.errorf("expected 'L'")
+	}
+	// Package specifier and outermost class name
+	var class ClassType
+	for {
+		identifier, err := p.identifier()
+		if err != nil {
+			return ClassType{}, err
+		}
+		class.Name += identifier
+		if !p.consume('/') {
+			break
+		}
+		class.Name += "/"
+	}
+	for {
+		arguments, err := p.typeArguments()
+		if err != nil {
+			return ClassType{}, err
+		}
+		class.TypeArguments = arguments
+		if !p.consume('.') {
+			break
+		}
+		name, err := p.identifier()
+		if err != nil {
+			return ClassType{}, err
+		}
+		outer := class
+		class = ClassType{Outer: &outer, Name: name}
+	}
+	if !p.consume(';') {
+		return ClassType{}, p.errorf("expected ';'")
+	}
+	return class, nil
+}
+
+func (p *parser) typeArguments() ([]TypeArgument, error) {
+	if !p.consume('<') {
+		return nil, nil
+	}
+	var arguments []TypeArgument
+	for !p.consume('>') {
+		var argument TypeArgument
+		switch c := p.peek(); c {
+		case Any:
+			p.pos++
+			argument.Wildcard = Any
+			arguments = append(arguments, argument)
+			continue
+		case Extends, Super:
+			p.pos++
+			argument.Wildcard = c
+		}
+		var err error
+		if argument.Type, err = p.referenceType(); err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	if len(arguments) == 0 {
+		return nil, p.errorf("empty type arguments")
+	}
+	return arguments, nil
+}
@@ -0,0 +1,179 @@
+package signature
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Slummp/classfileparser/descriptor"
+)
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		signature string
+		javaName  string
+	}{
+		{"Ljava/lang/String;", "java.lang.String"},
+		{"TT;", "T"},
+		{"[TT;", "T[]"},
+		{"[[I", "int[][]"},
+		{"Ljava/util/List<Ljava/lang/String;>;", "java.util.List<java.lang.String>"},
+		{"Ljava/util/Map<TK;[TV;>;", "java.util.Map<K, V[]>"},
+		{"Ljava/util/List<*>;", "java.util.List<?>"},
+		{"Ljava/util/List<+Ljava/lang/Number;>;", "java.util.List<? extends java.lang.Number>"},
+		{"Ljava/util/Comparator<-TT;>;", "java.util.Comparator<? super T>"},
+		{"Ljava/util/Map$Entry;", "java.util.Map$Entry"},
+		{"Ljava/util/Map<TK;TV;>.Entry<TK;TV;>;", "java.util.Map<K, V>.Entry<K, V>"},
+		{"LOuter.Inner.Deep<TT;>;", "Outer$Inner$Deep<T>"},
+		{"LOuter<TT;>.Inner.Deep<[I>;", "Outer<T>.Inner$Deep<int[]>"},
+		{"Ljava/util/List<Ljava/util/List<+[Ljava/util/Map<*TK;>.Entry<-TK;TV;>;>;>;", "java.util.List<java.util.List<? extends java.util.Map<?, K>.Entry<? super K, V>[]>>"},
+	}
+	for _, test := range tests {
+		got, err := ParseField(test.signature)
+		if err != nil {
+			t.Errorf("ParseField(%q): %v", test.signature, err)
+			continue
+		}
+		if got.String() != test.signature {
+			t.Errorf("ParseField(%q).String() = %q", test.signature, got.String())
+		}
+		if got.JavaName() != test.javaName {
+			t.Errorf("ParseField(%q).JavaName() = %q, want %q", test.signature, got.JavaName(), test.javaName)
+		}
+	}
+}
+
+func TestParseInnerClass(t *testing.T) {
+	got, err := ParseField("Ljava/util/Map<TK;TV;>.Entry<TK;*>;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ClassType{
+		Outer: &ClassType{
+			Name:          "java/util/Map",
+			TypeArguments: []TypeArgument{{Type: TypeVariable{Name: "K"}}, {Type: TypeVariable{Name: "V"}}},
+		},
+		Name:          "Entry",
+		TypeArguments: []TypeArgument{{Type: TypeVariable{Name: "K"}}, {Wildcard: Any}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseField() = %#v, want %#v", got, want)
+	}
+	class := got.(ClassType)
+	if class.InternalName() != "java/util/Map$Entry" || class.Erasure() != (descriptor.Object{Name: "java/util/Map$Entry"}) {
+		t.Errorf("InternalName() = %q, Erasure() = %v", class.InternalName(), class.Erasure())
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	tests := []struct {
+		signature  string
+		parameters []string // JavaName of the type parameters
+		interfaces int
+	}{
+		{"Ljava/lang/Object;", nil, 0},
+		{"<T:Ljava/lang/Object;>Ljava/lang/Object;", []string{"T"}, 0},
+		{"<T::Ljava/lang/Comparable<TT;>;>Ljava/lang/Object;Ljava/lang/Runnable;", []string{"T extends java.lang.Comparable<T>"}, 1},
+		{"<T:Ljava/lang/Number;:Ljava/io/Serializable;:Ljava/lang/Cloneable;>Ljava/lang/Object;", []string{"T extends java.lang.Number & java.io.Serializable & java.lang.Cloneable"}, 0},
+		{"<K:Ljava/lang/Object;V:TK;>Ljava/util/AbstractMap<TK;TV;>;Ljava/util/Map<TK;TV;>;Ljava/io/Serializable;", []string{"K", "V extends K"}, 2},
+		{"<E:Ljava/lang/Enum<TE;>;>Ljava/lang/Object;", []string{"E extends java.lang.Enum<E>"}, 0},
+	}
+	for _, test := range tests {
+		got, err := ParseClass(test.signature)
+		if err != nil {
+			t.Errorf("ParseClass(%q): %v", test.signature, err)
+			continue
+		}
+		if got.String() != test.signature {
+			t.Errorf("ParseClass(%q).String() = %q", test.signature, got.String())
+		}
+		var parameters []string
+		for _, parameter := range got.TypeParameters {
+			parameters = append(parameters, parameter.JavaName())
+		}
+		if !reflect.DeepEqual(parameters, test.parameters) || len(got.Interfaces) != test.interfaces {
+			t.Errorf("ParseClass(%q): parameters %q, %d interfaces", test.signature, parameters, len(got.Interfaces))
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		signature   string
+		declaration string
+	}{
+		{"()V", "void m()"},
+		{"<T:Ljava/lang/Object;>([TT;)Ljava/util/List<TT;>;", "<T> java.util.List<T> m(T[])"},
+		{"(Ljava/util/List<*>;IJ)[Z", "boolean[] m(java.util.List<?>, int, long)"},
+		{"<X:Ljava/lang/Throwable;>()V^TX;", "<X extends java.lang.Throwable> void m() throws X"},
+		{"()V^Ljava/io/IOException;^TE;", "void m() throws java.io.IOException, E"},
+		{"<T::Ljava/lang/Comparable<-TT;>;>(Ljava/util/Collection<+TT;>;)TT;", "<T extends java.lang.Comparable<? super T>> T m(java.util.Collection<? extends T>)"},
+		{"(LOuter<TT;>.Inner;)V", "void m(Outer<T>.Inner)"},
+	}
+	for _, test := range tests {
+		got, err := ParseMethod(test.signature)
+		if err != nil {
+			t.Errorf("ParseMethod(%q): %v", test.signature, err)
+			continue
+		}
+		if got.String() != test.signature {
+			t.Errorf("ParseMethod(%q).String() = %q", test.signature, got.String())
+		}
+		if got.JavaDeclaration("m") != test.declaration {
+			t.Errorf("ParseMethod(%q).JavaDeclaration() = %q, want %q", test.signature, got.JavaDeclaration("m"), test.declaration)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		parse     func(string) error
+		signature string
+		err       string
+	}{
+		{parseField, "", "unexpected end of signature"},
+		{parseField, "I", "unexpected 'I'"},
+		{parseField, "Ljava/lang/String", "expected ';'"},
+		{parseField, "Ljava/util/List<>;", "empty type arguments"},
+		{parseField, "Ljava/util/List<I>;", "unexpected 'I'"},
+		{parseField, "Ljava/util/List<+>;", "unexpected '>'"},
+		{parseField, "LOuter.;", "expected an identifier"},
+		{parseField, "T;", "expected an identifier"},
+		{parseField, "TT", "expected ';'"},
+		{parseField, "TT;TU;", "unexpected trailing characters"},
+		{parseField, strings.Repeat("[", 256) + "I", "256 dimensions exceeds the limit"},
+		{parseClass, "", "expected 'L'"},
+		{parseClass, "<>Ljava/lang/Object;", "empty type parameters"},
+		{parseClass, "<T>Ljava/lang/Object;", "expected ':'"},
+		{parseClass, "<T:I>Ljava/lang/Object;", "at 4: expected ':'"},
+		{parseClass, "<T:Ljava/lang/Object;", "expected an identifier"},
+		{parseClass, "Ljava/lang/Object;TT;", "expected 'L'"},
+		{parseMethod, "V", "expected '('"},
+		{parseMethod, "(V)V", "unexpected 'V'"},
+		{parseMethod, "()", "unexpected end of signature"},
+		{parseMethod, "()V^", "expected 'L'"},
+		{parseMethod, "()V^I", "expected 'L'"},
+		{parseMethod, "()VV", "unexpected trailing characters"},
+	}
+	for _, test := range tests {
+		err := test.parse(test.signature)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: error = %v, want %q", test.signature, err, test.err)
+		}
+	}
+}
+
+func parseField(s string) error {
+	_, err := ParseField(s)
+	return err
+}
+
+func parseClass(s string) error {
+	_, err := ParseClass(s)
+	return err
+}
+
+func parseMethod(s string) error {
+	_, err := ParseMethod(s)
+	return err
+}
@@ -0,0 +1,258 @@
+package classfileparser
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Tags of the verification_type_info union
+const (
+	ItemTop               uint8 = 0
+	ItemInteger           uint8 = 1
+	ItemFloat             uint8 = 2
+	ItemDouble            uint8 = 3
+	ItemLong              uint8 = 4
+	ItemNull              uint8 = 5
+	ItemUninitializedThis uint8 = 6
+	ItemObject            uint8 = 7
+	ItemUninitialized     uint8 = 8
+)
+
+// FrameKind identifies the encoding of a StackMapFrame
+type FrameKind uint8
+
+const (
+	// SameFrame has the locals of the previous frame and an empty stack (frame types 0-63).
+	SameFrame FrameKind = iota
+	// SameLocals1StackItemFrame has the locals of the previous frame and one stack item (frame types 64-127).
+	SameLocals1StackItemFrame
+	// SameLocals1StackItemFrameExtended is SameLocals1StackItemFrame with an explicit offset delta (frame type 247).
+	SameLocals1StackItemFrameExtended
+	// ChopFrame removes the last 1 to 3 locals of the previous frame and has an empty stack (frame types 248-250).
+	ChopFrame
+	// SameFrameExtended is SameFrame with an explicit offset delta (frame type 251).
+	SameFrameExtended
+	// AppendFrame adds 1 to 3 locals to the previous frame and has an empty stack (frame types 252-254).
+	AppendFrame
+	// FullFrame lists every local and stack item (frame type 255).
+	FullFrame
+)
+
+var frameKindNames = map[FrameKind]string{
+	SameFrame:                         "same",
+	SameLocals1StackItemFrame:         "same_locals_1_stack_item",
+	SameLocals1StackItemFrameExtended: "same_locals_1_stack_item_extended",
+	ChopFrame:                         "chop",
+	SameFrameExtended:                 "same_extended",
+	AppendFrame:                       "append",
+	FullFrame:                         "full_frame",
+}
+
+func (k FrameKind) String() string {
+	if name, ok := frameKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("FrameKind(%d)", uint8(k))
+}
+
+// Kind returns the kind of the frame selected by its FrameType
+func (f StackMapFrame) Kind() FrameKind {
+	switch {
+	case f.FrameType <= 63:
+		return SameFrame
+	case f.FrameType <= 127:
+		return SameLocals1StackItemFrame
+	case f.FrameType == 247:
+		return SameLocals1StackItemFrameExtended
+	case f.FrameType >= 248 && f.FrameType <= 250:
+		return ChopFrame
+	case f.FrameType == 251:
+		return SameFrameExtended
+	case f.FrameType >= 252 && f.FrameType <= 254:
+		return AppendFrame
+	default:
+		// 255, the reserved types 128-246 are rejected when decoding
+		return FullFrame
+	}
+}
+
+// String renders the item the way javap does (e.g. int, class java/lang/String, uninitialized 12)
+func (i StackItem) String() string {
+	switch i.Tag {
+	case ItemTop:
+		return "top"
+	case ItemInteger:
+		return "int"
+	case ItemFloat:
+		return "float"
+	case ItemDouble:
+		return "double"
+	case ItemLong:
+		return "long"
+	case ItemNull:
+		return "null"
+	case ItemUninitializedThis:
+		return "uninitialized this"
+	case ItemObject:
+		return "class " + i.Class
+	case ItemUninitialized:
+		return fmt.Sprintf("uninitialized %d", i.Offset)
+	default:
+		return fmt.Sprintf("StackItem(%d)", i.Tag)
+	}
+}
+
+func readStackMapFrames(reader *bytes.Reader, cp ConstantPool) ([]StackMapFrame, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	frames := make([]StackMapFrame, count)
+	pc := -1
+	for i := range frames {
+		frame := &frames[i]
+		if err := read(reader, &frame.FrameType); err != nil {
+			return nil, err
+		}
+		if frame.FrameType >= 128 && frame.FrameType <= 246 {
+			return nil, fmt.Errorf("frame %d has the reserved frame type %d", i, frame.FrameType)
+		}
+
+		var err error
+		switch frame.Kind() {
+		case SameFrame:
+			frame.OffsetDelta = uint16(frame.FrameType)
+		case SameLocals1StackItemFrame:
+			frame.OffsetDelta = uint16(frame.FrameType - 64)
+			frame.StackItems, err = readStackItems(reader, 1, cp)
+		case SameLocals1StackItemFrameExtended:
+			if err = read(reader, &frame.OffsetDelta); err == nil {
+				frame.StackItems, err = readStackItems(reader, 1, cp)
+			}
+		case ChopFrame:
+			frame.Chopped = 251 - frame.FrameType
+			err = read(reader, &frame.OffsetDelta)
+		case SameFrameExtended:
+			err = read(reader, &frame.OffsetDelta)
+		case AppendFrame:
+			if err = read(reader, &frame.OffsetDelta); err == nil {
+				frame.Locals, err = readStackItems(reader, int(frame.FrameType-251), cp)
+			}
+		case FullFrame:
+			var numberOfLocals, numberOfStackItems uint16
+			if err = read(reader, &frame.OffsetDelta, &numberOfLocals); err != nil {
+				break
+			}
+			if frame.Locals, err = readStackItems(reader, int(numberOfLocals), cp); err != nil {
+				break
+			}
+			if err = read(reader, &numberOfStackItems); err != nil {
+				break
+			}
+			frame.StackItems, err = readStackItems(reader, int(numberOfStackItems), cp)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+
+		// The first frame applies at offset_delta, every following one at offset_delta + 1 past its predecessor
+		pc += int(frame.OffsetDelta) + 1
+		if pc > 0xFFFF {
+			return nil, fmt.Errorf("frame %d lies past the end of the code", i)
+		}
+		frame.Pc = uint16(pc)
+	}
+	return frames, nil
+}
+
+func readStackItems(reader *bytes.Reader, count int, cp ConstantPool) ([]StackItem, error) {
+	if count > reader.Len() {
+		return nil, fmt.Errorf("%d verification types overrun the attribute", count)
+	}
+	items := make([]StackItem, count)
+	for i := range items {
+		item := &items[i]
+		if err := read(reader, &item.Tag); err != nil {
+			return nil, err
+		}
+		switch item.Tag {
+		case ItemObject:
+			class, err := readConstant[Class](reader, cp)
+			if err != nil {
+				return nil, err
+			}
+			item.Class = string(class)
+		case ItemUninitialized:
+			if err := read(reader, &item.Offset); err != nil {
+				return nil, err
+			}
+		default:
+			if item.Tag > ItemUninitialized {
+				return nil, fmt.Errorf("unknown verification type tag %d", item.Tag)
+			}
+		}
+	}
+	return items, nil
+}
+
+// writeStackMapFrames encodes frames, recomputing their offset deltas from their pcs. A frame keeps
+// its kind unless its delta no longer fits, in which case the extended form is used.
+func writeStackMapFrames(buf *bytes.Buffer, frames []StackMapFrame, pool *PoolBuilder) error {
+	write(buf, uint16(len(frames)))
+	pc := -1
+	for i, frame := range frames {
+		if int(frame.Pc) <= pc {
+			return fmt.Errorf("frame %d at pc %d does not follow the previous frame", i, frame.Pc)
+		}
+		delta := uint16(int(frame.Pc) - pc - 1)
+		pc = int(frame.Pc)
+
+		switch kind := frame.Kind(); kind {
+		case SameFrame, SameFrameExtended:
+			if kind == SameFrame && delta <= 63 {
+				write(buf, uint8(delta))
+			} else {
+				write(buf, uint8(251), delta)
+			}
+		case SameLocals1StackItemFrame, SameLocals1StackItemFrameExtended:
+			if len(frame.StackItems) != 1 {
+				return fmt.Errorf("frame %d of kind %s has %d stack items", i, kind, len(frame.StackItems))
+			}
+			if kind == SameLocals1StackItemFrame && delta <= 63 {
+				write(buf, uint8(64+delta))
+			} else {
+				write(buf, uint8(247), delta)
+			}
+			writeStackItems(buf, frame.StackItems, pool)
+		case ChopFrame:
+			if frame.Chopped < 1 || frame.Chopped > 3 {
+				return fmt.Errorf("frame %d chops %d locals", i, frame.Chopped)
+			}
+			write(buf, 251-frame.Chopped, delta)
+		case AppendFrame:
+			if len(frame.Locals) < 1 || len(frame.Locals) > 3 {
+				return fmt.Errorf("frame %d appends %d locals", i, len(frame.Locals))
+			}
+			write(buf, uint8(251+len(frame.Locals)), delta)
+			writeStackItems(buf, frame.Locals, pool)
+		default:
+			write(buf, uint8(255), delta, uint16(len(frame.Locals)))
+			writeStackItems(buf, frame.Locals, pool)
+			write(buf, uint16(len(frame.StackItems)))
+			writeStackItems(buf, frame.StackItems, pool)
+		}
+	}
+	return pool.Err()
+}
+
+func writeStackItems(buf *bytes.Buffer, items []StackItem, pool *PoolBuilder) {
+	for _, item := range items {
+		write(buf, item.Tag)
+		switch item.Tag {
+		case ItemObject:
+			write(buf, pool.Class(item.Class))
+		case ItemUninitialized:
+			write(buf, item.Offset)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package classfileparser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stackMapPool holds Class A at #2, for both decoding and encoding frames
+var stackMapPool = []CpInfo{utf8Entry("A"), entry(7, 0, 1)}
+
+// stackMapFrames are the frames of stackMapData, one of each kind
+var stackMapFrames = []StackMapFrame{
+	{FrameType: 5, OffsetDelta: 5, Pc: 5},
+	{FrameType: 64, OffsetDelta: 0, Pc: 6, StackItems: []StackItem{{Tag: ItemInteger}}},
+	{FrameType: 247, OffsetDelta: 100, Pc: 107, StackItems: []StackItem{{Tag: ItemObject, Class: "A"}}},
+	{FrameType: 249, OffsetDelta: 0, Pc: 108, Chopped: 2},
+	{FrameType: 251, OffsetDelta: 256, Pc: 365},
+	{FrameType: 253, OffsetDelta: 1, Pc: 367, Locals: []StackItem{{Tag: ItemDouble}, {Tag: ItemUninitialized, Offset: 5}}},
+	{FrameType: 255, OffsetDelta: 2, Pc: 370, Locals: []StackItem{{Tag: ItemUninitializedThis}}, StackItems: []StackItem{{Tag: ItemNull}, {Tag: ItemLong}}},
+}
+
+var stackMapData = []byte{
+	0, 7,
+	5,
+	64, 1,
+	247, 0, 100, 7, 0, 2,
+	249, 0, 0,
+	251, 1, 0,
+	253, 0, 1, 3, 8, 0, 5,
+	255, 0, 2, 0, 1, 6, 0, 2, 5, 4,
+}
+
+func TestReadStackMapFrames(t *testing.T) {
+	cp, err := (&ClassFile{ConstantPool: stackMapPool}).GetConstantPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, err := readStackMapFrames(bytes.NewReader(stackMapData), cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(frames, stackMapFrames) {
+		t.Errorf("frames = %+v", frames)
+	}
+	kinds := []FrameKind{SameFrame, SameLocals1StackItemFrame, SameLocals1StackItemFrameExtended, ChopFrame, SameFrameExtended, AppendFrame, FullFrame}
+	for i, frame := range frames {
+		if frame.Kind() != kinds[i] {
+			t.Errorf("frame %d: kind %s, want %s", i, frame.Kind(), kinds[i])
+		}
+	}
+
+	for _, frameType := range []byte{128, 200, 246} {
+		_, err := readStackMapFrames(bytes.NewReader([]byte{0, 1, frameType}), cp)
+		if err == nil || !strings.Contains(err.Error(), "reserved frame type") {
+			t.Errorf("frame type %d: error = %v", frameType, err)
+		}
+	}
+	if _, err := readStackMapFrames(bytes.NewReader([]byte{0, 1, 64, 9}), cp); err == nil {
+		t.Error("an unknown verification type tag is accepted")
+	}
+}
+
+func TestWriteStackMapFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeStackMapFrames(&buf, stackMapFrames, NewPoolBuilder(stackMapPool)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), stackMapData) {
+		t.Errorf("frames = % X, want % X", buf.Bytes(), stackMapData)
+	}
+}
+
+func TestWriteStackMapFramesExtended(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame StackMapFrame
+		want  []byte
+	}{
+		{"same widened", StackMapFrame{FrameType: 0, Pc: 100}, []byte{251, 0, 100}},
+		{"same_locals_1_stack_item widened", StackMapFrame{FrameType: 64, Pc: 64, StackItems: []StackItem{{Tag: ItemFloat}}}, []byte{247, 0, 64, 2}},
+		{"same fits", StackMapFrame{FrameType: 0, Pc: 63}, []byte{63}},
+		{"same_extended kept", StackMapFrame{FrameType: 251, Pc: 3}, []byte{251, 0, 3}},
+		{"same_locals_1_stack_item_extended kept", StackMapFrame{FrameType: 247, Pc: 0, StackItems: []StackItem{{Tag: ItemTop}}}, []byte{247, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeStackMapFrames(&buf, []StackMapFrame{test.frame}, NewPoolBuilder(nil)); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if want := append([]byte{0, 1}, test.want...); !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: frames = % X, want % X", test.name, buf.Bytes(), want)
+		}
+	}
+}
+
+func TestWriteStackMapFramesInvalid(t *testing.T) {
+	tests := []struct {
+		frames []StackMapFrame
+		err    string
+	}{
+		{[]StackMapFrame{{Pc: 4}, {Pc: 4}}, "frame 1 at pc 4 does not follow"},
+		{[]StackMapFrame{{FrameType: 64, Pc: 0}}, "has 0 stack items"},
+		{[]StackMapFrame{{FrameType: 248, Pc: 0, Chopped: 4}}, "chops 4 locals"},
+		{[]StackMapFrame{{FrameType: 252, Pc: 0}}, "appends 0 locals"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := writeStackMapFrames(&buf, test.frames, NewPoolBuilder(nil))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("error = %v, want %q", err, test.err)
+		}
+	}
+}
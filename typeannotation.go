@@ -0,0 +1,210 @@
+package classfileparser
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Values of TypeAnnotation.TargetType
+const (
+	TargetClassTypeParameter                uint8 = 0x00
+	TargetMethodTypeParameter               uint8 = 0x01
+	TargetClassExtends                      uint8 = 0x10
+	TargetClassTypeParameterBound           uint8 = 0x11
+	TargetMethodTypeParameterBound          uint8 = 0x12
+	TargetField                             uint8 = 0x13
+	TargetMethodReturn                      uint8 = 0x14
+	TargetMethodReceiver                    uint8 = 0x15
+	TargetMethodFormalParameter             uint8 = 0x16
+	TargetThrows                            uint8 = 0x17
+	TargetLocalVariable                     uint8 = 0x40
+	TargetResourceVariable                  uint8 = 0x41
+	TargetExceptionParameter                uint8 = 0x42
+	TargetInstanceof                        uint8 = 0x43
+	TargetNew                               uint8 = 0x44
+	TargetConstructorReference              uint8 = 0x45
+	TargetMethodReference                   uint8 = 0x46
+	TargetCast                              uint8 = 0x47
+	TargetConstructorInvocationTypeArgument uint8 = 0x48
+	TargetMethodInvocationTypeArgument      uint8 = 0x49
+	TargetConstructorReferenceTypeArgument  uint8 = 0x4A
+	TargetMethodReferenceTypeArgument       uint8 = 0x4B
+)
+
+// Values of TypePathEntry.Kind
+const (
+	PathArray        uint8 = 0 // Deeper in an array type
+	PathNested       uint8 = 1 // Deeper in a nested type
+	PathWildcard     uint8 = 2 // On the bound of a wildcard type argument
+	PathTypeArgument uint8 = 3 // On a type argument of a parameterized type
+)
+
+// IsCodeTarget reports whether the annotation targets a type in the code of a method. Such
+// annotations only appear in the attributes of a Code attribute and refer to its pcs.
+func (a TypeAnnotation) IsCodeTarget() bool {
+	return a.TargetType >= TargetLocalVariable
+}
+
+// readTypeAnnotations reads the body of a Runtime*TypeAnnotations attribute
+func readTypeAnnotations(reader *bytes.Reader, cp ConstantPool) ([]TypeAnnotation, error) {
+	var count uint16
+	if err := read(reader, &count); err != nil {
+		return nil, err
+	}
+	if int(count) > reader.Len() {
+		return nil, fmt.Errorf("%d type annotations overrun the attribute", count)
+	}
+	annotations := make([]TypeAnnotation, count)
+	for i := range annotations {
+		annotation, err := readTypeAnnotation(reader, cp)
+		if err != nil {
+			return nil, fmt.Errorf("type annotation %d: %w", i, err)
+		}
+		annotations[i] = annotation
+	}
+	return annotations, nil
+}
+
+func readTypeAnnotation(reader *bytes.Reader, cp ConstantPool) (TypeAnnotation, error) {
+	var annotation TypeAnnotation
+	if err := read(reader, &annotation.TargetType); err != nil {
+		return annotation, err
+	}
+
+	var err error
+	switch annotation.TargetType {
+	case TargetClassTypeParameter, TargetMethodTypeParameter:
+		err = read(reader, &annotation.TypeParameterIndex)
+	case TargetClassExtends:
+		err = read(reader, &annotation.SupertypeIndex)
+	case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+		err = read(reader, &annotation.TypeParameterIndex, &annotation.BoundIndex)
+	case TargetField, TargetMethodReturn, TargetMethodReceiver:
+		// empty_target
+	case TargetMethodFormalParameter:
+		err = read(reader, &annotation.FormalParameterIndex)
+	case TargetThrows:
+		err = read(reader, &annotation.ThrowsTypeIndex)
+	case TargetLocalVariable, TargetResourceVariable:
+		var count uint16
+		if err = read(reader, &count); err != nil {
+			break
+		}
+		if int(count) > reader.Len() {
+			err = fmt.Errorf("%d local variable ranges overrun the attribute", count)
+			break
+		}
+		annotation.LocalVariables = make([]LocalVariableTarget, count)
+		err = read(reader, annotation.LocalVariables)
+	case TargetExceptionParameter:
+		err = read(reader, &annotation.ExceptionTableIndex)
+	case TargetInstanceof, TargetNew, TargetConstructorReference, TargetMethodReference:
+		err = read(reader, &annotation.Offset)
+	case TargetCast, TargetConstructorInvocationTypeArgument, TargetMethodInvocationTypeArgument,
+		TargetConstructorReferenceTypeArgument, TargetMethodReferenceTypeArgument:
+		err = read(reader, &annotation.Offset, &annotation.TypeArgumentIndex)
+	default:
+		err = fmt.Errorf("unknown target type 0x%02X", annotation.TargetType)
+	}
+	if err != nil {
+		return annotation, err
+	}
+
+	var pathLength uint8
+	if err := read(reader, &pathLength); err != nil {
+		return annotation, err
+	}
+	annotation.TypePath = make([]TypePathEntry, pathLength)
+	if err := read(reader, annotation.TypePath); err != nil {
+		return annotation, err
+	}
+	for _, entry := range annotation.TypePath {
+		if entry.Kind > PathTypeArgument {
+			return annotation, fmt.Errorf("unknown type path kind %d", entry.Kind)
+		}
+	}
+
+	if annotation.Annotation, err = readAnnotation(reader, cp, 0); err != nil {
+		return annotation, err
+	}
+	return annotation, nil
+}
+
+// writeTypeAnnotations encodes the body of a Runtime*TypeAnnotations attribute
+func writeTypeAnnotations(buf *bytes.Buffer, annotations []TypeAnnotation, pool *PoolBuilder) error {
+	if len(annotations) > 0xFFFF {
+		return fmt.Errorf("too many type annotations: %d", len(annotations))
+	}
+	write(buf, uint16(len(annotations)))
+	for i, annotation := range annotations {
+		write(buf, annotation.TargetType)
+		switch annotation.TargetType {
+		case TargetClassTypeParameter, TargetMethodTypeParameter:
+			write(buf, annotation.TypeParameterIndex)
+		case TargetClassExtends:
+			write(buf, annotation.SupertypeIndex)
+		case TargetClassTypeParameterBound, TargetMethodTypeParameterBound:
+			write(buf, annotation.TypeParameterIndex, annotation.BoundIndex)
+		case TargetField, TargetMethodReturn, TargetMethodReceiver:
+		case TargetMethodFormalParameter:
+			write(buf, annotation.FormalParameterIndex)
+		case TargetThrows:
+			write(buf, annotation.ThrowsTypeIndex)
+		case TargetLocalVariable, TargetResourceVariable:
+			if len(annotation.LocalVariables) > 0xFFFF {
+				return fmt.Errorf("type annotation %d: too many local variable ranges: %d", i, len(annotation.LocalVariables))
+			}
+			write(buf, uint16(len(annotation.LocalVariables)), annotation.LocalVariables)
+		case TargetExceptionParameter:
+			write(buf, annotation.ExceptionTableIndex)
+		case TargetInstanceof, TargetNew, TargetConstructorReference, TargetMethodReference:
+			write(buf, annotation.Offset)
+		case TargetCast, TargetConstructorInvocationTypeArgument, TargetMethodInvocationTypeArgument,
+			TargetConstructorReferenceTypeArgument, TargetMethodReferenceTypeArgument:
+			write(buf, annotation.Offset, annotation.TypeArgumentIndex)
+		default:
+			return fmt.Errorf("type annotation %d: unknown target type 0x%02X", i, annotation.TargetType)
+		}
+		if len(annotation.TypePath) > 0xFF {
+			return fmt.Errorf("type annotation %d: type path of %d steps is too long", i, len(annotation.TypePath))
+		}
+		write(buf, uint8(len(annotation.TypePath)), annotation.TypePath)
+		if err := writeAnnotation(buf, annotation.Annotation, pool); err != nil {
+			return fmt.Errorf("type annotation %d: %w", i, err)
+		}
+	}
+	return pool.Err()
+}
+
+// moveTypeAnnotations returns the annotations of a Code attribute with their pcs moved with pcs.
+// Local variable ranges of removed instructions are dropped, as are annotations left without a target.
+func moveTypeAnnotations(annotations []TypeAnnotation, pcs pcMap) []TypeAnnotation {
+	var moved []TypeAnnotation
+	for _, annotation := range annotations {
+		switch annotation.TargetType {
+		case TargetLocalVariable, TargetResourceVariable:
+			var ranges []LocalVariableTarget
+			for _, target := range annotation.LocalVariables {
+				start, okStart := pcs.start(int(target.StartPc))
+				end, okEnd := pcs.end(int(target.StartPc) + int(target.Length))
+				if okStart && okEnd {
+					ranges = append(ranges, LocalVariableTarget{StartPc: start, Length: end - start, Index: target.Index})
+				}
+			}
+			if len(ranges) == 0 {
+				continue
+			}
+			annotation.LocalVariables = ranges
+		case TargetExceptionParameter:
+			// Exception table entries keep their index
+		default:
+			pc, ok := pcs.start(int(annotation.Offset))
+			if !ok {
+				continue
+			}
+			annotation.Offset = pc
+		}
+		moved = append(moved, annotation)
+	}
+	return moved
+}
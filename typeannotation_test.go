@@ -0,0 +1,141 @@
+package classfileparser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// typeAnnotationTests pair the target_info and type_path of a type annotation with their decoded
+// fields. Each one is followed by the annotation LAnn; of annotationPool with no elements.
+var typeAnnotationTests = []struct {
+	data []byte
+	want TypeAnnotation
+}{
+	{[]byte{0x00, 1, 0}, TypeAnnotation{TargetType: TargetClassTypeParameter, TypeParameterIndex: 1}},
+	{[]byte{0x01, 2, 0}, TypeAnnotation{TargetType: TargetMethodTypeParameter, TypeParameterIndex: 2}},
+	{[]byte{0x10, 0xFF, 0xFF, 0}, TypeAnnotation{TargetType: TargetClassExtends, SupertypeIndex: 0xFFFF}},
+	{[]byte{0x11, 1, 2, 0}, TypeAnnotation{TargetType: TargetClassTypeParameterBound, TypeParameterIndex: 1, BoundIndex: 2}},
+	{[]byte{0x12, 0, 1, 0}, TypeAnnotation{TargetType: TargetMethodTypeParameterBound, BoundIndex: 1}},
+	{[]byte{0x13, 0}, TypeAnnotation{TargetType: TargetField}},
+	{[]byte{0x14, 0}, TypeAnnotation{TargetType: TargetMethodReturn}},
+	{[]byte{0x15, 0}, TypeAnnotation{TargetType: TargetMethodReceiver}},
+	{[]byte{0x16, 3, 0}, TypeAnnotation{TargetType: TargetMethodFormalParameter, FormalParameterIndex: 3}},
+	{[]byte{0x17, 0, 1, 0}, TypeAnnotation{TargetType: TargetThrows, ThrowsTypeIndex: 1}},
+	{
+		[]byte{0x40, 0, 2, 0, 0, 0, 5, 0, 1, 0, 8, 0, 2, 0, 3, 0},
+		TypeAnnotation{TargetType: TargetLocalVariable, LocalVariables: []LocalVariableTarget{{StartPc: 0, Length: 5, Index: 1}, {StartPc: 8, Length: 2, Index: 3}}},
+	},
+	{[]byte{0x41, 0, 1, 0, 1, 0, 2, 0, 4, 0}, TypeAnnotation{TargetType: TargetResourceVariable, LocalVariables: []LocalVariableTarget{{StartPc: 1, Length: 2, Index: 4}}}},
+	{[]byte{0x42, 0, 2, 0}, TypeAnnotation{TargetType: TargetExceptionParameter, ExceptionTableIndex: 2}},
+	{[]byte{0x43, 0, 7, 0}, TypeAnnotation{TargetType: TargetInstanceof, Offset: 7}},
+	{[]byte{0x44, 0, 8, 0}, TypeAnnotation{TargetType: TargetNew, Offset: 8}},
+	{[]byte{0x45, 0, 9, 0}, TypeAnnotation{TargetType: TargetConstructorReference, Offset: 9}},
+	{[]byte{0x46, 1, 0, 0}, TypeAnnotation{TargetType: TargetMethodReference, Offset: 256}},
+	{[]byte{0x47, 0, 3, 1, 0}, TypeAnnotation{TargetType: TargetCast, Offset: 3, TypeArgumentIndex: 1}},
+	{[]byte{0x48, 0, 4, 0, 0}, TypeAnnotation{TargetType: TargetConstructorInvocationTypeArgument, Offset: 4}},
+	{[]byte{0x49, 0, 5, 2, 0}, TypeAnnotation{TargetType: TargetMethodInvocationTypeArgument, Offset: 5, TypeArgumentIndex: 2}},
+	{[]byte{0x4A, 0, 6, 0, 0}, TypeAnnotation{TargetType: TargetConstructorReferenceTypeArgument, Offset: 6}},
+	{[]byte{0x4B, 0, 7, 3, 0}, TypeAnnotation{TargetType: TargetMethodReferenceTypeArgument, Offset: 7, TypeArgumentIndex: 3}},
+	{
+		[]byte{0x13, 4, 0, 0, 3, 1, 2, 0, 1, 0},
+		TypeAnnotation{TargetType: TargetField, TypePath: []TypePathEntry{{Kind: PathArray}, {Kind: PathTypeArgument, TypeArgumentIndex: 1}, {Kind: PathWildcard}, {Kind: PathNested}}},
+	},
+}
+
+// emptyAnnotation is LAnn; with no elements, as decoded from annotationPool
+var emptyAnnotation = Annotation{TypeIndex: 1, Type: "LAnn;", ElementValuePairs: []ElementValuePair{}}
+
+func TestReadTypeAnnotation(t *testing.T) {
+	for _, test := range typeAnnotationTests {
+		data := append(append([]byte(nil), test.data...), 0, 1, 0, 0)
+		annotation, err := readTypeAnnotation(bytes.NewReader(data), annotationPool)
+		if err != nil {
+			t.Errorf("target type 0x%02X: %v", test.data[0], err)
+			continue
+		}
+		want := test.want
+		want.Annotation = emptyAnnotation
+		if want.TypePath == nil {
+			want.TypePath = []TypePathEntry{}
+		}
+		if !reflect.DeepEqual(annotation, want) {
+			t.Errorf("target type 0x%02X: annotation = %+v, want %+v", test.data[0], annotation, want)
+		}
+		if annotation.IsCodeTarget() != (annotation.TargetType >= TargetLocalVariable) {
+			t.Errorf("target type 0x%02X: IsCodeTarget() = %t", test.data[0], annotation.IsCodeTarget())
+		}
+	}
+}
+
+func TestReadInvalidTypeAnnotation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"unknown target type", []byte{0x18, 0, 0, 1, 0, 0}, "unknown target type 0x18"},
+		{"unknown path kind", []byte{0x13, 1, 4, 0, 0, 1, 0, 0}, "unknown type path kind 4"},
+		{"local variables overrun", []byte{0x40, 0xFF, 0xFF}, "65535 local variable ranges overrun"},
+		{"truncated path", []byte{0x13, 2, 0, 0}, "EOF"},
+	}
+	for _, test := range tests {
+		_, err := readTypeAnnotation(bytes.NewReader(test.data), annotationPool)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestTypeAnnotationsRoundTrip(t *testing.T) {
+	var annotations []TypeAnnotation
+	for _, test := range typeAnnotationTests {
+		annotation := test.want
+		annotation.Annotation = annotationValue
+		annotations = append(annotations, annotation)
+	}
+
+	// Writing allocates the constants, reading them back and writing again reuses every one of them
+	pool := NewPoolBuilder(nil)
+	var first bytes.Buffer
+	if err := writeTypeAnnotations(&first, annotations, pool); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := (&ClassFile{ConstantPool: pool.Pool()}).GetConstantPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readTypeAnnotations(bytes.NewReader(first.Bytes()), cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(annotations) {
+		t.Fatalf("%d annotations decoded, want %d", len(decoded), len(annotations))
+	}
+	for i, annotation := range decoded {
+		if annotation.TargetType != annotations[i].TargetType || !reflect.DeepEqual(annotation.LocalVariables, annotations[i].LocalVariables) {
+			t.Errorf("annotation %d: target = %+v", i, annotation)
+		}
+		if annotation.Type != "LAnn;" || len(annotation.ElementValuePairs) != len(annotationValue.ElementValuePairs) {
+			t.Errorf("annotation %d: %+v", i, annotation.Annotation)
+		}
+		for j, pair := range annotation.ElementValuePairs {
+			if want := annotationValue.ElementValuePairs[j]; pair.Name != want.Name || pair.Value.Tag != want.Value.Tag {
+				t.Errorf("annotation %d: element %d = %+v, want %+v", i, j, pair, want)
+			}
+		}
+	}
+
+	again := NewPoolBuilder(pool.Pool())
+	var second bytes.Buffer
+	if err := writeTypeAnnotations(&second, decoded, again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("type annotations differ after a round trip")
+	}
+	if len(again.Pool()) != len(pool.Pool()) {
+		t.Errorf("%d constants added", len(again.Pool())-len(pool.Pool()))
+	}
+}